@@ -28,9 +28,10 @@ import (
 // belonging to the account.
 type AccountKey struct {
 	assertionBase
-	since  time.Time
-	until  time.Time
-	pubKey PublicKey
+	since   time.Time
+	until   time.Time
+	revoked bool
+	pubKey  PublicKey
 }
 
 // AccountID returns the account-id of this account-key.
@@ -38,6 +39,13 @@ func (ak *AccountKey) AccountID() string {
 	return ak.Header("account-id")
 }
 
+// Prerequisites returns references to the account the key belongs to.
+func (ak *AccountKey) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{ak.AccountID()}},
+	}
+}
+
 // Since returns the time when the account key starts being valid.
 func (ak *AccountKey) Since() time.Time {
 	return ak.since
@@ -48,6 +56,12 @@ func (ak *AccountKey) Until() time.Time {
 	return ak.until
 }
 
+// Revoked returns whether the account key was revoked, in which case
+// it cannot be trusted for any signature anymore.
+func (ak *AccountKey) Revoked() bool {
+	return ak.revoked
+}
+
 // PublicKeyID returns the key id (as used to match signatures to signing keys) for the account key.
 func (ak *AccountKey) PublicKeyID() string {
 	return ak.pubKey.ID()
@@ -106,8 +120,94 @@ func assembleAccountKey(assert assertionBase) (Assertion, error) {
 	if err != nil {
 		return nil, err
 	}
+	revoked, err := checkOptionalBool(assert.headers, "revoked")
+	if err != nil {
+		return nil, err
+	}
 	// ignore extra headers for future compatibility
 	return &AccountKey{
+		assertionBase: assert,
+		since:         since,
+		until:         until,
+		revoked:       revoked,
+		pubKey:        pubk,
+	}, nil
+}
+
+// AccountKeyRequest holds an account-key-request assertion, which is
+// a self-signed request to authorize the use of a public key by the
+// account.
+type AccountKeyRequest struct {
+	assertionBase
+	since  time.Time
+	until  time.Time
+	pubKey PublicKey
+}
+
+// AccountID returns the account-id of this account-key-request.
+func (akr *AccountKeyRequest) AccountID() string {
+	return akr.Header("account-id")
+}
+
+// Since returns the time when the requested account key starts being valid.
+func (akr *AccountKeyRequest) Since() time.Time {
+	return akr.since
+}
+
+// Until returns the time when the requested account key stops being
+// valid, zero if unspecified.
+func (akr *AccountKeyRequest) Until() time.Time {
+	return akr.until
+}
+
+// PublicKeyID returns the underlying public key ID of the requested account key.
+func (akr *AccountKeyRequest) PublicKeyID() string {
+	return akr.pubKey.ID()
+}
+
+// PublicKeyFingerprint returns the fingerprint of the requested account key.
+func (akr *AccountKeyRequest) PublicKeyFingerprint() string {
+	return akr.pubKey.Fingerprint()
+}
+
+func assembleAccountKeyRequest(assert assertionBase) (Assertion, error) {
+	accountID, err := checkNotEmpty(assert.headers, "account-id")
+	if err != nil {
+		return nil, err
+	}
+	if assert.AuthorityID() != accountID {
+		return nil, fmt.Errorf("authority-id and account-id must match, account-key-request assertions are expected to be signed by the account itself: %q != %q", assert.AuthorityID(), accountID)
+	}
+	since, err := checkRFC3339Date(assert.headers, "since")
+	if err != nil {
+		return nil, err
+	}
+	var until time.Time
+	if assert.headers["until"] != "" {
+		until, err = checkRFC3339Date(assert.headers, "until")
+		if err != nil {
+			return nil, err
+		}
+		if !until.After(since) {
+			return nil, fmt.Errorf("invalid 'since' and 'until' times (no gap after 'since' till 'until')")
+		}
+	}
+	pubk, err := checkPublicKey(&assert, "public-key-fingerprint", "public-key-id")
+	if err != nil {
+		return nil, err
+	}
+
+	// the request is self-signed with the requested key
+	sig, err := decodeSignature(assert.signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := pubk.verify(assert.content, sig); err != nil {
+		return nil, fmt.Errorf("account-key-request is not signed with the requested key: %v", err)
+	}
+
+	// ignore extra headers for future compatibility
+	return &AccountKeyRequest{
 		assertionBase: assert,
 		since:         since,
 		until:         until,
@@ -84,6 +84,10 @@ func (aks *accountKeySuite) TestDecodeOK(c *C) {
 	c.Check(accKey.PublicKeyID(), Equals, aks.keyid)
 	c.Check(accKey.Since(), Equals, aks.since)
 	c.Check(accKey.Until(), Equals, aks.until)
+	c.Check(accKey.Revoked(), Equals, false)
+	c.Check(accKey.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"acc-id1"}},
+	})
 }
 
 const (
@@ -117,6 +121,7 @@ func (aks *accountKeySuite) TestDecodeInvalidHeaders(c *C) {
 		{aks.untilLine, "until: \n", `"until" header should not be empty`},
 		{aks.untilLine, "until: " + aks.since.Format(time.RFC3339) + "\n", `invalid 'since' and 'until' times \(no gap after 'since' till 'until'\)`},
 		{aks.untilLine, "until: \n", `"until" header should not be empty`},
+		{aks.untilLine, aks.untilLine + "revoked: maybe\n", `"revoked" header must be "true" or "false"`},
 	}
 
 	for _, test := range invalidHeaderTests {
@@ -250,6 +255,175 @@ func (aks *accountKeySuite) TestAccountKeyAddAndFind(c *C) {
 	c.Check(found.Body(), DeepEquals, []byte(aks.pubKeyBody))
 }
 
+func (aks *accountKeySuite) TestAccountKeyRevocation(c *C) {
+	trustedKey := testPrivKey0
+
+	now := time.Now()
+	headers := map[string]string{
+		"authority-id":           "canonical",
+		"account-id":             "acc-id1",
+		"public-key-id":          aks.keyid,
+		"public-key-fingerprint": aks.fp,
+		"since":                  now.AddDate(0, 0, -1).Format(time.RFC3339),
+		"until":                  now.AddDate(1, 0, 0).Format(time.RFC3339),
+	}
+	accKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+
+	db := aks.openDB(c)
+	err = db.Add(accKey)
+	c.Assert(err, IsNil)
+
+	signed, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, map[string]string{
+		"authority-id": "acc-id1",
+		"primary-key":  "a",
+	}, nil, testPrivKey1)
+	c.Assert(err, IsNil)
+	c.Check(db.Check(signed), IsNil)
+
+	// revoke the key with a new revision of the account-key
+	headers["revision"] = "1"
+	headers["revoked"] = "true"
+	revokedAccKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+	c.Check(revokedAccKey.(*asserts.AccountKey).Revoked(), Equals, true)
+	err = db.Add(revokedAccKey)
+	c.Assert(err, IsNil)
+
+	err = db.Check(signed)
+	c.Check(err, ErrorMatches, `assertion is signed with revoked public key "[a-f0-9]+" from "acc-id1"`)
+
+	history, err := db.FindHistory(asserts.AccountKeyType, map[string]string{
+		"account-id":    "acc-id1",
+		"public-key-id": aks.keyid,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 2)
+	c.Check(history[0].Revision(), Equals, 1)
+	c.Check(history[1].Revision(), Equals, 0)
+}
+
+func (aks *accountKeySuite) TestAccountKeyRevocationHidesSignedAssertions(c *C) {
+	trustedKey := testPrivKey0
+
+	now := time.Now()
+	headers := map[string]string{
+		"authority-id":           "canonical",
+		"account-id":             "acc-id1",
+		"public-key-id":          aks.keyid,
+		"public-key-fingerprint": aks.fp,
+		"since":                  now.AddDate(0, 0, -1).Format(time.RFC3339),
+		"until":                  now.AddDate(1, 0, 0).Format(time.RFC3339),
+	}
+	accKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+
+	db := aks.openDB(c)
+	c.Assert(db.Add(accKey), IsNil)
+
+	signed, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, map[string]string{
+		"authority-id": "acc-id1",
+		"primary-key":  "a",
+	}, nil, testPrivKey1)
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(signed), IsNil)
+
+	_, err = db.Find(asserts.TestOnlyType, map[string]string{"primary-key": "a"})
+	c.Check(err, IsNil)
+	found, err := db.FindMany(asserts.TestOnlyType, nil)
+	c.Check(err, IsNil)
+	c.Check(found, HasLen, 1)
+
+	headers["revision"] = "1"
+	headers["revoked"] = "true"
+	revokedAccKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(revokedAccKey), IsNil)
+
+	// what the revoked key signed is not found anymore
+	_, err = db.Find(asserts.TestOnlyType, map[string]string{"primary-key": "a"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+	_, err = db.FindMany(asserts.TestOnlyType, nil)
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	// but the key itself is
+	a, err := db.Find(asserts.AccountKeyType, map[string]string{
+		"account-id":    "acc-id1",
+		"public-key-id": aks.keyid,
+	})
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.AccountKey).Revoked(), Equals, true)
+}
+
+func (aks *accountKeySuite) TestAccountKeyRevocationIsTransitive(c *C) {
+	trustedKey := testPrivKey0
+
+	now := time.Now()
+	headers := map[string]string{
+		"authority-id":           "canonical",
+		"account-id":             "acc-id1",
+		"public-key-id":          aks.keyid,
+		"public-key-fingerprint": aks.fp,
+		"since":                  now.AddDate(0, 0, -1).Format(time.RFC3339),
+		"until":                  now.AddDate(1, 0, 0).Format(time.RFC3339),
+	}
+	accKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+
+	// a second level key, signed by the first one
+	pubKey2, err := asserts.EncodePublicKey(testPrivKey2.PublicKey())
+	c.Assert(err, IsNil)
+	accKey2, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, map[string]string{
+		"authority-id":           "acc-id1",
+		"account-id":             "acc-id2",
+		"public-key-id":          testPrivKey2.PublicKey().ID(),
+		"public-key-fingerprint": testPrivKey2.PublicKey().Fingerprint(),
+		"since":                  now.AddDate(0, 0, -1).Format(time.RFC3339),
+		"until":                  now.AddDate(1, 0, 0).Format(time.RFC3339),
+	}, pubKey2, testPrivKey1)
+	c.Assert(err, IsNil)
+
+	db := aks.openDB(c)
+	c.Assert(db.Add(accKey), IsNil)
+	c.Assert(db.Add(accKey2), IsNil)
+
+	signed, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, map[string]string{
+		"authority-id": "acc-id2",
+		"primary-key":  "a",
+	}, nil, testPrivKey2)
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(signed), IsNil)
+
+	_, err = db.Find(asserts.TestOnlyType, map[string]string{"primary-key": "a"})
+	c.Check(err, IsNil)
+
+	// revoke the first level key
+	headers["revision"] = "1"
+	headers["revoked"] = "true"
+	revokedAccKey, err := asserts.AssembleAndSignInTest(asserts.AccountKeyType, headers, []byte(aks.pubKeyBody), trustedKey)
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(revokedAccKey), IsNil)
+
+	// neither the key it signed nor what that one signed is found
+	_, err = db.Find(asserts.AccountKeyType, map[string]string{
+		"account-id":    "acc-id2",
+		"public-key-id": testPrivKey2.PublicKey().ID(),
+	})
+	c.Check(err, Equals, asserts.ErrNotFound)
+	_, err = db.Find(asserts.TestOnlyType, map[string]string{"primary-key": "a"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+	_, err = db.FindMany(asserts.TestOnlyType, nil)
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	// and the second level key cannot sign anything new
+	other, err := asserts.AssembleAndSignInTest(asserts.TestOnlyType, map[string]string{
+		"authority-id": "acc-id2",
+		"primary-key":  "b",
+	}, nil, testPrivKey2)
+	c.Assert(err, IsNil)
+	c.Check(db.Check(other), ErrorMatches, `assertion is signed with public key ".*" from "acc-id2" whose signing chain was revoked`)
+}
+
 func (aks *accountKeySuite) TestPublicKeyIsValidAt(c *C) {
 	encoded := "type: account-key\n" +
 		"authority-id: canonical\n" +
@@ -274,3 +448,64 @@ func (aks *accountKeySuite) TestPublicKeyIsValidAt(c *C) {
 	c.Check(asserts.AccountKeyIsKeyValidAt(accKey, aks.until.AddDate(0, -1, 0)), Equals, true)
 	c.Check(asserts.AccountKeyIsKeyValidAt(accKey, aks.until.AddDate(0, 1, 0)), Equals, false)
 }
+
+func (aks *accountKeySuite) accountKeyRequestHeaders() map[string]string {
+	return map[string]string{
+		"authority-id":           "acc-id1",
+		"account-id":             "acc-id1",
+		"public-key-id":          aks.keyid,
+		"public-key-fingerprint": aks.fp,
+		"since":                  aks.since.Format(time.RFC3339),
+	}
+}
+
+func (aks *accountKeySuite) TestAccountKeyRequestSignAndDecode(c *C) {
+	db := aks.openDB(c)
+	err := db.ImportKey("acc-id1", testPrivKey1)
+	c.Assert(err, IsNil)
+
+	akr, err := db.Sign(asserts.AccountKeyRequestType, aks.accountKeyRequestHeaders(), []byte(aks.pubKeyBody), aks.keyid)
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode(asserts.Encode(akr))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.AccountKeyRequestType)
+	req := a.(*asserts.AccountKeyRequest)
+	c.Check(req.AuthorityID(), Equals, "acc-id1")
+	c.Check(req.AccountID(), Equals, "acc-id1")
+	c.Check(req.PublicKeyID(), Equals, aks.keyid)
+	c.Check(req.PublicKeyFingerprint(), Equals, aks.fp)
+	c.Check(req.Since().Equal(aks.since), Equals, true)
+	c.Check(req.Until().IsZero(), Equals, true)
+}
+
+func (aks *accountKeySuite) TestAccountKeyRequestInvalid(c *C) {
+	db := aks.openDB(c)
+	err := db.ImportKey("acc-id1", testPrivKey1)
+	c.Assert(err, IsNil)
+
+	tests := []struct{ header, value, expectedErr string }{
+		{"account-id", "acc-id2", `authority-id and account-id must match, account-key-request assertions are expected to be signed by the account itself: "acc-id1" != "acc-id2"`},
+		{"since", "", `"since" header should not be empty`},
+		{"since", "12:30", `"since" header is not a RFC3339 date: .*`},
+		{"until", aks.since.Format(time.RFC3339), `invalid 'since' and 'until' times \(no gap after 'since' till 'until'\)`},
+		{"public-key-fingerprint", "", `"public-key-fingerprint" header should not be empty`},
+	}
+	for _, test := range tests {
+		headers := aks.accountKeyRequestHeaders()
+		headers[test.header] = test.value
+		_, err := db.Sign(asserts.AccountKeyRequestType, headers, []byte(aks.pubKeyBody), aks.keyid)
+		c.Check(err, ErrorMatches, "cannot assemble assertion account-key-request: "+test.expectedErr)
+	}
+}
+
+func (aks *accountKeySuite) TestAccountKeyRequestNotSignedWithRequestedKey(c *C) {
+	db := aks.openDB(c)
+	err := db.ImportKey("acc-id1", testPrivKey1)
+	c.Assert(err, IsNil)
+	err = db.ImportKey("acc-id1", testPrivKey2)
+	c.Assert(err, IsNil)
+
+	_, err = db.Sign(asserts.AccountKeyRequestType, aks.accountKeyRequestHeaders(), []byte(aks.pubKeyBody), testPrivKey2.PublicKey().ID())
+	c.Check(err, ErrorMatches, "cannot assemble assertion account-key-request: account-key-request is not signed with the requested key: .*")
+}
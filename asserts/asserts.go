@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
@@ -40,29 +41,44 @@ type AssertionType struct {
 	PrimaryKey []string
 
 	assembler func(assert assertionBase) (Assertion, error)
+	flags     int
 }
 
+const (
+	noAuthority = 1 << iota
+)
+
 // Understood assertion types.
 var (
-	AccountType         = &AssertionType{"account", []string{"account-id"}, assembleAccount}
-	AccountKeyType      = &AssertionType{"account-key", []string{"account-id", "public-key-id"}, assembleAccountKey}
-	ModelType           = &AssertionType{"model", []string{"series", "brand-id", "model"}, assembleModel}
-	SerialType          = &AssertionType{"serial", []string{"brand-id", "model", "serial"}, assembleSerial}
-	SnapDeclarationType = &AssertionType{"snap-declaration", []string{"series", "snap-id"}, assembleSnapDeclaration}
-	SnapBuildType       = &AssertionType{"snap-build", []string{"series", "snap-id", "snap-digest"}, assembleSnapBuild}
-	SnapRevisionType    = &AssertionType{"snap-revision", []string{"series", "snap-id", "snap-digest"}, assembleSnapRevision}
+	AccountType           = &AssertionType{"account", []string{"account-id"}, assembleAccount, 0}
+	AccountKeyType        = &AssertionType{"account-key", []string{"account-id", "public-key-id"}, assembleAccountKey, 0}
+	AccountKeyRequestType = &AssertionType{"account-key-request", []string{"account-id", "public-key-id"}, assembleAccountKeyRequest, 0}
+	ModelType             = &AssertionType{"model", []string{"series", "brand-id", "model"}, assembleModel, 0}
+	SerialType            = &AssertionType{"serial", []string{"brand-id", "model", "serial"}, assembleSerial, 0}
+	SnapDeclarationType   = &AssertionType{"snap-declaration", []string{"series", "snap-id"}, assembleSnapDeclaration, 0}
+	SnapBuildType         = &AssertionType{"snap-build", []string{"series", "snap-id", "snap-digest"}, assembleSnapBuild, 0}
+	SnapRevisionType      = &AssertionType{"snap-revision", []string{"series", "snap-id", "snap-digest"}, assembleSnapRevision, 0}
+	ValidationType        = &AssertionType{"validation", []string{"series", "snap-id", "approved-snap-id", "approved-snap-revision"}, assembleValidation, 0}
+	SystemUserType        = &AssertionType{"system-user", []string{"brand-id", "email"}, assembleSystemUser, 0}
+
+	// no authority
+	SerialRequestType = &AssertionType{"serial-request", nil, assembleSerialRequest, noAuthority}
 
 // ...
 )
 
 var typeRegistry = map[string]*AssertionType{
-	AccountType.Name:         AccountType,
-	AccountKeyType.Name:      AccountKeyType,
-	ModelType.Name:           ModelType,
-	SerialType.Name:          SerialType,
-	SnapDeclarationType.Name: SnapDeclarationType,
-	SnapBuildType.Name:       SnapBuildType,
-	SnapRevisionType.Name:    SnapRevisionType,
+	AccountType.Name:           AccountType,
+	AccountKeyType.Name:        AccountKeyType,
+	AccountKeyRequestType.Name: AccountKeyRequestType,
+	ModelType.Name:             ModelType,
+	SerialType.Name:            SerialType,
+	SnapDeclarationType.Name:   SnapDeclarationType,
+	SnapBuildType.Name:         SnapBuildType,
+	SnapRevisionType.Name:      SnapRevisionType,
+	ValidationType.Name:        ValidationType,
+	SystemUserType.Name:        SystemUserType,
+	SerialRequestType.Name:     SerialRequestType,
 }
 
 // Type returns the AssertionType with name or nil
@@ -70,6 +86,41 @@ func Type(name string) *AssertionType {
 	return typeRegistry[name]
 }
 
+// Ref expresses a reference to an assertion through its type and the
+// values of its primary key headers.
+type Ref struct {
+	Type       *AssertionType
+	PrimaryKey []string
+}
+
+// String returns a human-friendly description of the reference.
+func (ref *Ref) String() string {
+	return fmt.Sprintf("%s (%s)", ref.Type.Name, strings.Join(ref.PrimaryKey, "; "))
+}
+
+// Unique returns a string identifying the referenced assertion, usable as a
+// map key.
+func (ref *Ref) Unique() string {
+	escaped := make([]string, len(ref.PrimaryKey))
+	for i, value := range ref.PrimaryKey {
+		escaped[i] = url.QueryEscape(value)
+	}
+	return fmt.Sprintf("%s/%s", ref.Type.Name, strings.Join(escaped, "/"))
+}
+
+// Resolve finds the referenced assertion using the given find function,
+// such as Database.Find.
+func (ref *Ref) Resolve(find func(*AssertionType, map[string]string) (Assertion, error)) (Assertion, error) {
+	if len(ref.PrimaryKey) != len(ref.Type.PrimaryKey) {
+		return nil, fmt.Errorf("%q assertion reference primary key has the wrong length (expected %v): %v", ref.Type.Name, ref.Type.PrimaryKey, ref.PrimaryKey)
+	}
+	headers := make(map[string]string, len(ref.PrimaryKey))
+	for i, name := range ref.Type.PrimaryKey {
+		headers[name] = ref.PrimaryKey[i]
+	}
+	return find(ref.Type, headers)
+}
+
 // Assertion represents an assertion through its general elements.
 type Assertion interface {
 	// Type returns the type of this assertion
@@ -90,6 +141,13 @@ type Assertion interface {
 
 	// Signature returns the signed content and its unprocessed signature
 	Signature() (content, signature []byte)
+
+	// Ref returns a reference to this assertion.
+	Ref() *Ref
+
+	// Prerequisites returns references to the assertions this one refers
+	// to, besides the account-key that signed it.
+	Prerequisites() []*Ref
 }
 
 // MediaType is the media type for enconded assertions on the wire.
@@ -147,9 +205,36 @@ func (ab *assertionBase) Signature() (content, signature []byte) {
 	return ab.content, ab.signature
 }
 
+// Ref returns a reference to the assertion.
+func (ab *assertionBase) Ref() *Ref {
+	assertType := ab.Type()
+	primaryKey := make([]string, len(assertType.PrimaryKey))
+	for i, name := range assertType.PrimaryKey {
+		primaryKey[i] = ab.headers[name]
+	}
+	return &Ref{Type: assertType, PrimaryKey: primaryKey}
+}
+
+// Prerequisites returns references to the prerequisite assertions of the
+// assertion, none by default.
+func (ab *assertionBase) Prerequisites() []*Ref {
+	return nil
+}
+
 // sanity check
 var _ Assertion = (*assertionBase)(nil)
 
+// SignKeyID returns the id of the public key whose private counterpart
+// signed the given assertion.
+func SignKeyID(assert Assertion) (string, error) {
+	_, signature := assert.Signature()
+	sig, err := decodeSignature(signature)
+	if err != nil {
+		return "", err
+	}
+	return sig.KeyID(), nil
+}
+
 var (
 	nl   = []byte("\n")
 	nlnl = []byte("\n\n")
@@ -472,10 +557,6 @@ func Assemble(headers map[string]string, body, content, signature []byte) (Asser
 		return nil, fmt.Errorf("assertion body length and declared body-length don't match: %v != %v", len(body), length)
 	}
 
-	if _, err := checkNotEmpty(headers, "authority-id"); err != nil {
-		return nil, fmt.Errorf("assertion: %v", err)
-	}
-
 	typ, err := checkNotEmpty(headers, "type")
 	if err != nil {
 		return nil, fmt.Errorf("assertion: %v", err)
@@ -485,6 +566,10 @@ func Assemble(headers map[string]string, body, content, signature []byte) (Asser
 		return nil, fmt.Errorf("unknown assertion type: %q", typ)
 	}
 
+	if err := checkAuthority(assertType, headers); err != nil {
+		return nil, fmt.Errorf("assertion: %v", err)
+	}
+
 	for _, primKey := range assertType.PrimaryKey {
 		if _, err := checkNotEmpty(headers, primKey); err != nil {
 			return nil, fmt.Errorf("assertion %s: %v", assertType.Name, err)
@@ -527,6 +612,19 @@ func writeHeader(buf *bytes.Buffer, headers map[string]string, name string) {
 	buf.WriteString(value)
 }
 
+// checkAuthority checks that headers set an authority-id as required by
+// the assertion type, only assertions of types with no authority don't.
+func checkAuthority(assertType *AssertionType, headers map[string]string) error {
+	if assertType.flags&noAuthority == 0 {
+		_, err := checkNotEmpty(headers, "authority-id")
+		return err
+	}
+	if _, ok := headers["authority-id"]; ok {
+		return fmt.Errorf("%q assertion cannot have authority-id set", assertType.Name)
+	}
+	return nil
+}
+
 func assembleAndSign(assertType *AssertionType, headers map[string]string, body []byte, privKey PrivateKey) (Assertion, error) {
 	err := checkAssertType(assertType)
 	if err != nil {
@@ -543,7 +641,7 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 	finalHeaders["type"] = assertType.Name
 	finalHeaders["body-length"] = strconv.Itoa(bodyLength)
 
-	if _, err := checkNotEmpty(finalHeaders, "authority-id"); err != nil {
+	if err := checkAuthority(assertType, finalHeaders); err != nil {
 		return nil, err
 	}
 
@@ -555,7 +653,9 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 	buf := bytes.NewBufferString("type: ")
 	buf.WriteString(assertType.Name)
 
-	writeHeader(buf, finalHeaders, "authority-id")
+	if assertType.flags&noAuthority == 0 {
+		writeHeader(buf, finalHeaders, "authority-id")
+	}
 	if revision > 0 {
 		writeHeader(buf, finalHeaders, "revision")
 	} else {
@@ -622,6 +722,18 @@ func assembleAndSign(assertType *AssertionType, headers map[string]string, body
 	return assert, nil
 }
 
+// SignWithoutAuthority assembles an assertion without a set authority
+// with the provided information and signs it with the given private key.
+func SignWithoutAuthority(assertType *AssertionType, headers map[string]string, body []byte, privKey PrivateKey) (Assertion, error) {
+	if err := checkAssertType(assertType); err != nil {
+		return nil, err
+	}
+	if assertType.flags&noAuthority == 0 {
+		return nil, fmt.Errorf("cannot sign assertions needing a definite authority with SignWithoutAuthority")
+	}
+	return assembleAndSign(assertType, headers, body, privKey)
+}
+
 // Encode serializes an assertion.
 func Encode(assert Assertion) []byte {
 	content, signature := assert.Signature()
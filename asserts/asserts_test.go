@@ -42,6 +42,45 @@ func (as *assertsSuite) TestUnknown(c *C) {
 	c.Check(asserts.Type("unknown"), IsNil)
 }
 
+func (as *assertsSuite) TestRef(c *C) {
+	ref := &asserts.Ref{
+		Type:       asserts.SnapDeclarationType,
+		PrimaryKey: []string{"16", "snap-id-1"},
+	}
+	c.Check(ref.String(), Equals, "snap-declaration (16; snap-id-1)")
+	c.Check(ref.Unique(), Equals, "snap-declaration/16/snap-id-1")
+
+	// key values are escaped
+	ref = &asserts.Ref{
+		Type:       asserts.TestOnlyType,
+		PrimaryKey: []string{"a/b c"},
+	}
+	c.Check(ref.Unique(), Equals, "test-only/a%2Fb+c")
+}
+
+func (as *assertsSuite) TestRefResolve(c *C) {
+	ref := &asserts.Ref{
+		Type:       asserts.SnapDeclarationType,
+		PrimaryKey: []string{"16", "snap-id-1"},
+	}
+	var headers map[string]string
+	find := func(assertType *asserts.AssertionType, hdrs map[string]string) (asserts.Assertion, error) {
+		c.Check(assertType, Equals, asserts.SnapDeclarationType)
+		headers = hdrs
+		return nil, asserts.ErrNotFound
+	}
+	_, err := ref.Resolve(find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+	c.Check(headers, DeepEquals, map[string]string{
+		"series":  "16",
+		"snap-id": "snap-id-1",
+	})
+
+	ref.PrimaryKey = []string{"16"}
+	_, err = ref.Resolve(find)
+	c.Check(err, ErrorMatches, `"snap-declaration" assertion reference primary key has the wrong length \(expected \[series snap-id\]\): \[16\]`)
+}
+
 const exampleEmptyBodyAllDefaults = "type: test-only\n" +
 	"authority-id: auth-id1\n" +
 	"primary-key: abc" +
@@ -58,6 +97,8 @@ func (as *assertsSuite) TestDecodeEmptyBodyAllDefaults(c *C) {
 	c.Check(a.Body(), IsNil)
 	c.Check(a.Header("header1"), Equals, "")
 	c.Check(a.AuthorityID(), Equals, "auth-id1")
+	c.Check(a.Ref(), DeepEquals, &asserts.Ref{Type: asserts.TestOnlyType, PrimaryKey: []string{"abc"}})
+	c.Check(a.Prerequisites(), HasLen, 0)
 }
 
 const exampleEmptyBody2NlNl = "type: test-only\n" +
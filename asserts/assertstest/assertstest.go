@@ -0,0 +1,100 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package assertstest provides helpers for testing code that involves assertions.
+package assertstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// GenerateKey generates a private/public key pair of the given bits. It
+// panics on error. Small sizes keep tests fast.
+func GenerateKey(bits int) asserts.PrivateKey {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		panic(fmt.Errorf("failed to create private key: %v", err))
+	}
+	return asserts.OpenPGPPrivateKey(packet.NewRSAPrivateKey(time.Unix(1, 0), priv))
+}
+
+// SigningDB embeds a signing assertion database with a default private
+// key and authority id.
+type SigningDB struct {
+	AuthorityID string
+	KeyID       string
+
+	*asserts.Database
+}
+
+// NewSigningDB creates a test signing assertion db with the given
+// default authority id and private key.
+func NewSigningDB(authorityID string, privKey asserts.PrivateKey) *SigningDB {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+	})
+	if err != nil {
+		panic(err)
+	}
+	if err := db.ImportKey(authorityID, privKey); err != nil {
+		panic(err)
+	}
+	return &SigningDB{
+		AuthorityID: authorityID,
+		KeyID:       privKey.PublicKey().ID(),
+		Database:    db,
+	}
+}
+
+// Sign signs an assertion of the given type with the default key,
+// setting authority-id to the default authority id if not given.
+func (db *SigningDB) Sign(assertType *asserts.AssertionType, headers map[string]string, body []byte) (asserts.Assertion, error) {
+	if _, ok := headers["authority-id"]; !ok {
+		headers["authority-id"] = db.AuthorityID
+	}
+	return db.Database.Sign(assertType, headers, body, db.KeyID)
+}
+
+// NewAccountKey returns an account-key assertion for the given account
+// and public key, signed by the db, valid from now for a year.
+func NewAccountKey(db *SigningDB, accountID string, pubKey asserts.PublicKey) *asserts.AccountKey {
+	encodedPubKey, err := asserts.EncodePublicKey(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	now := time.Now()
+	a, err := db.Sign(asserts.AccountKeyType, map[string]string{
+		"account-id":             accountID,
+		"public-key-id":          pubKey.ID(),
+		"public-key-fingerprint": pubKey.Fingerprint(),
+		"since":                  now.Format(time.RFC3339),
+		"until":                  now.AddDate(1, 0, 0).Format(time.RFC3339),
+	}, encodedPubKey)
+	if err != nil {
+		panic(err)
+	}
+	return a.(*asserts.AccountKey)
+}
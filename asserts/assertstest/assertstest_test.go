@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstest_test
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+)
+
+func TestAssertsTest(t *testing.T) { TestingT(t) }
+
+type helperSuite struct{}
+
+var _ = Suite(&helperSuite{})
+
+func (s *helperSuite) TestSigningDBAndAccountKey(c *C) {
+	rootKey := assertstest.GenerateKey(752)
+	signingDB := assertstest.NewSigningDB("canonical", rootKey)
+	c.Check(signingDB.KeyID, Equals, rootKey.PublicKey().ID())
+
+	devKey := assertstest.GenerateKey(752)
+	accKey := assertstest.NewAccountKey(signingDB, "developer1", devKey.PublicKey())
+	c.Check(accKey.AuthorityID(), Equals, "canonical")
+	c.Check(accKey.AccountID(), Equals, "developer1")
+	c.Check(accKey.PublicKeyID(), Equals, devKey.PublicKey().ID())
+
+	// a self-signed account-key can be used as trusted key
+	trustedKey := assertstest.NewAccountKey(signingDB, "canonical", rootKey.PublicKey())
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		TrustedKeys:    []*asserts.AccountKey{trustedKey},
+	})
+	c.Assert(err, IsNil)
+	c.Check(db.Add(accKey), IsNil)
+
+	acct, err := signingDB.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "developer1",
+		"display-name": "Developer One",
+		"validation":   "unproven",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Check(db.Add(acct), IsNil)
+}
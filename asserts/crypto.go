@@ -88,12 +88,7 @@ type openpgpSigner interface {
 }
 
 func signContent(content []byte, privateKey PrivateKey) ([]byte, error) {
-	signer, ok := privateKey.(openpgpSigner)
-	if !ok {
-		panic(fmt.Errorf("not an internally supported PrivateKey: %T", privateKey))
-	}
-
-	sig, err := signer.sign(content)
+	sig, err := privateKey.sign(content)
 	if err != nil {
 		return nil, err
 	}
@@ -240,11 +235,13 @@ func EncodePublicKey(pubKey PublicKey) ([]byte, error) {
 }
 
 // PrivateKey is a cryptographic private/public key pair.
+// Private keys whose material is available can also be encoded, see
+// encodePrivateKey, others are usable only for signing.
 type PrivateKey interface {
 	// PublicKey returns the public part of the pair.
 	PublicKey() PublicKey
 
-	keyEncoder
+	openpgpSigner
 }
 
 type openpgpPrivateKey struct {
@@ -317,7 +314,11 @@ func GenerateKey() (PrivateKey, error) {
 }
 
 func encodePrivateKey(privKey PrivateKey) ([]byte, error) {
-	return encodeKey(privKey, "private key")
+	encoder, ok := privKey.(keyEncoder)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode private key: private key material is not available")
+	}
+	return encodeKey(encoder, "private key")
 }
 
 // externally held key pairs
@@ -378,14 +379,6 @@ func (expk *extPGPPrivateKey) PublicKey() PublicKey {
 	return expk.pubKey
 }
 
-func (expk *extPGPPrivateKey) keyEncode(w io.Writer) error {
-	return fmt.Errorf("cannot access external private key to encode it")
-}
-
-func (expk *extPGPPrivateKey) keyFormat() string {
-	return ""
-}
-
 func (expk *extPGPPrivateKey) sign(content []byte) (*packet.Signature, error) {
 	out, err := expk.doSign(expk.pubKey.Fingerprint(), content)
 	if err != nil {
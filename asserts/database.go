@@ -42,8 +42,17 @@ type Backstore interface {
 	// Search returns assertions matching the given headers.
 	// It invokes foundCb for each found assertion.
 	Search(assertType *AssertionType, headers map[string]string, foundCb func(Assertion)) error
+	// History returns the retained revisions, at most
+	// maxRetainedRevisions and most recent first, of the assertion
+	// with the given unique key for its primary key headers.
+	// If none is present it returns ErrNotFound.
+	History(assertType *AssertionType, key []string) ([]Assertion, error)
 }
 
+// maxRetainedRevisions is how many revisions of each assertion,
+// the current one included, the backstores retain.
+const maxRetainedRevisions = 5
+
 type nullBackstore struct{}
 
 func (nbs nullBackstore) Put(t *AssertionType, a Assertion) error {
@@ -58,6 +67,10 @@ func (nbs nullBackstore) Search(t *AssertionType, h map[string]string, f func(As
 	return nil
 }
 
+func (nbs nullBackstore) History(t *AssertionType, k []string) ([]Assertion, error) {
+	return nil, ErrNotFound
+}
+
 // A KeypairManager is a manager and backstore for private/public key pairs.
 type KeypairManager interface {
 	// Put stores the given private/public key pair for identity,
@@ -70,8 +83,6 @@ type KeypairManager interface {
 	Get(authorityID, keyID string) (PrivateKey, error)
 }
 
-// TODO: for more flexibility plugging the keypair manager make PrivatKey private encoding methods optional, and add an explicit sign method.
-
 // DatabaseConfig for an assertion database.
 type DatabaseConfig struct {
 	// trusted account keys
@@ -235,6 +246,10 @@ func (db *Database) findAccountKey(authorityID, keyID string) (*AccountKey, erro
 
 // Check tests whether the assertion is properly signed and consistent with all the stored knowledge.
 func (db *Database) Check(assert Assertion) error {
+	if assert.Type().flags&noAuthority != 0 {
+		return fmt.Errorf("cannot check no-authority assertion type %q", assert.Type().Name)
+	}
+
 	_, signature := assert.Signature()
 	sig, err := decodeSignature(signature)
 	if err != nil {
@@ -299,14 +314,7 @@ func searchMatch(assert Assertion, expectedHeaders map[string]string) bool {
 	return true
 }
 
-// Find an assertion based on arbitrary headers.
-// Provided headers must contain the primary key for the assertion type.
-// It returns ErrNotFound if the assertion cannot be found.
-func (db *Database) Find(assertionType *AssertionType, headers map[string]string) (Assertion, error) {
-	err := checkAssertType(assertionType)
-	if err != nil {
-		return nil, err
-	}
+func primaryKeyValues(assertionType *AssertionType, headers map[string]string) ([]string, error) {
 	keyValues := make([]string, len(assertionType.PrimaryKey))
 	for i, k := range assertionType.PrimaryKey {
 		keyVal := headers[k]
@@ -315,12 +323,30 @@ func (db *Database) Find(assertionType *AssertionType, headers map[string]string
 		}
 		keyValues[i] = keyVal
 	}
+	return keyValues, nil
+}
+
+// Find an assertion based on arbitrary headers.
+// Provided headers must contain the primary key for the assertion type.
+// It returns ErrNotFound if the assertion cannot be found or was signed
+// with a key that has been revoked since.
+func (db *Database) Find(assertionType *AssertionType, headers map[string]string) (Assertion, error) {
+	err := checkAssertType(assertionType)
+	if err != nil {
+		return nil, err
+	}
+	keyValues, err := primaryKeyValues(assertionType, headers)
+	if err != nil {
+		return nil, err
+	}
 
 	var assert Assertion
+	trusted := false
 	for _, bs := range db.backstores {
 		a, err := bs.Get(assertionType, keyValues)
 		if err == nil {
 			assert = a
+			trusted = bs == db.trusted
 			break
 		}
 		if err != ErrNotFound {
@@ -331,11 +357,21 @@ func (db *Database) Find(assertionType *AssertionType, headers map[string]string
 	if assert == nil || !searchMatch(assert, headers) {
 		return nil, ErrNotFound
 	}
+	if !trusted {
+		revoked, err := db.signedWithRevokedKey(assert)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrNotFound
+		}
+	}
 
 	return assert, nil
 }
 
-// FindMany finds assertions based on arbitrary headers.
+// FindMany finds assertions based on arbitrary headers, leaving out those
+// signed with a key that has been revoked since.
 // It returns ErrNotFound if no assertion can be found.
 func (db *Database) FindMany(assertionType *AssertionType, headers map[string]string) ([]Assertion, error) {
 	err := checkAssertType(assertionType)
@@ -344,15 +380,32 @@ func (db *Database) FindMany(assertionType *AssertionType, headers map[string]st
 	}
 	res := []Assertion{}
 
-	foundCb := func(assert Assertion) {
-		res = append(res, assert)
-	}
-
+	var searchErr error
 	for _, bs := range db.backstores {
+		trusted := bs == db.trusted
+		foundCb := func(assert Assertion) {
+			if searchErr != nil {
+				return
+			}
+			if !trusted {
+				revoked, err := db.signedWithRevokedKey(assert)
+				if err != nil {
+					searchErr = err
+					return
+				}
+				if revoked {
+					return
+				}
+			}
+			res = append(res, assert)
+		}
 		err = bs.Search(assertionType, headers, foundCb)
 		if err != nil {
 			return nil, err
 		}
+		if searchErr != nil {
+			return nil, searchErr
+		}
 	}
 
 	if len(res) == 0 {
@@ -361,6 +414,73 @@ func (db *Database) FindMany(assertionType *AssertionType, headers map[string]st
 	return res, nil
 }
 
+// signedWithRevokedKey returns whether the assertion was signed with an
+// account-key that was revoked after the assertion was added, directly or
+// through any of the account-keys up its signing chain. Such assertions
+// stay stored but are not found anymore.
+func (db *Database) signedWithRevokedKey(assert Assertion) (bool, error) {
+	if assert.Type().flags&noAuthority != 0 {
+		return false, nil
+	}
+	seen := make(map[string]bool)
+	for {
+		keyID, err := SignKeyID(assert)
+		if err != nil {
+			return false, err
+		}
+		accKey, err := db.findAccountKey(assert.AuthorityID(), keyID)
+		if err == ErrNotFound {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if accKey.Revoked() {
+			return true, nil
+		}
+		// trusted account-keys end the chain
+		_, err = db.trusted.Get(AccountKeyType, []string{accKey.AccountID(), accKey.PublicKeyID()})
+		if err == nil {
+			return false, nil
+		}
+		if err != ErrNotFound {
+			return false, err
+		}
+		u := accKey.AccountID() + "/" + accKey.PublicKeyID()
+		if seen[u] {
+			return false, nil
+		}
+		seen[u] = true
+		assert = accKey
+	}
+}
+
+// FindHistory returns the retained revisions of an assertion, most
+// recent first. Provided headers must contain the primary key for the
+// assertion type, other headers are ignored.
+// It returns ErrNotFound if the assertion cannot be found.
+func (db *Database) FindHistory(assertionType *AssertionType, headers map[string]string) ([]Assertion, error) {
+	err := checkAssertType(assertionType)
+	if err != nil {
+		return nil, err
+	}
+	keyValues, err := primaryKeyValues(assertionType, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bs := range db.backstores {
+		history, err := bs.History(assertionType, keyValues)
+		if err == nil {
+			return history, nil
+		}
+		if err != ErrNotFound {
+			return nil, err
+		}
+	}
+	return nil, ErrNotFound
+}
+
 // assertion checkers
 
 // CheckSigningKeyIsNotExpired checks that the signing key is not expired.
@@ -371,6 +491,23 @@ func CheckSigningKeyIsNotExpired(assert Assertion, signature Signature, signingK
 	return nil
 }
 
+// CheckSigningKeyIsNotRevoked checks that the signing key was not revoked.
+// A key signed with a revoked key, directly or further up its signing
+// chain, is not found anymore and counts as revoked as well.
+func CheckSigningKeyIsNotRevoked(assert Assertion, signature Signature, signingKey *AccountKey, roDB RODatabase, checkTime time.Time) error {
+	if signingKey.Revoked() {
+		return fmt.Errorf("assertion is signed with revoked public key %q from %q", signature.KeyID(), assert.AuthorityID())
+	}
+	_, err := roDB.Find(AccountKeyType, map[string]string{
+		"account-id":    signingKey.AccountID(),
+		"public-key-id": signingKey.PublicKeyID(),
+	})
+	if err == ErrNotFound {
+		return fmt.Errorf("assertion is signed with public key %q from %q whose signing chain was revoked", signature.KeyID(), assert.AuthorityID())
+	}
+	return err
+}
+
 // CheckSignature checks that the signature is valid.
 func CheckSignature(assert Assertion, signature Signature, signingKey *AccountKey, roDB RODatabase, checkTime time.Time) error {
 	content, _ := assert.Signature()
@@ -381,6 +518,21 @@ func CheckSignature(assert Assertion, signature Signature, signingKey *AccountKe
 	return nil
 }
 
+// CheckSignedBy checks that the assertion carries a valid signature made
+// with the given account-key, without looking any further at the key
+// validity or the consistency of the assertion with other knowledge.
+func CheckSignedBy(assert Assertion, signingKey *AccountKey) error {
+	_, signature := assert.Signature()
+	sig, err := decodeSignature(signature)
+	if err != nil {
+		return err
+	}
+	if sig.KeyID() != signingKey.PublicKeyID() || assert.AuthorityID() != signingKey.AccountID() {
+		return fmt.Errorf("assertion is not signed with public key %q from %q", signingKey.PublicKeyID(), signingKey.AccountID())
+	}
+	return CheckSignature(assert, sig, signingKey, nil, time.Time{})
+}
+
 type timestamped interface {
 	Timestamp() time.Time
 }
@@ -421,6 +573,7 @@ func CheckCrossConsistency(assert Assertion, signature Signature, signingKey *Ac
 // DatabaseConfig.Checkers.
 var DefaultCheckers = []Checker{
 	CheckSigningKeyIsNotExpired,
+	CheckSigningKeyIsNotRevoked,
 	CheckSignature,
 	CheckTimestampVsSigningKeyValidity,
 	CheckCrossConsistency,
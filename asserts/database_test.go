@@ -229,6 +229,22 @@ func (chks *checkSuite) TestCheckForgery(c *C) {
 	c.Assert(err, ErrorMatches, "failed signature verification: .*")
 }
 
+func (chks *checkSuite) TestCheckSignedBy(c *C) {
+	signingKey := asserts.BootstrapAccountKeyForTest("canonical", testPrivKey0.PublicKey())
+	err := asserts.CheckSignedBy(chks.a, signingKey)
+	c.Check(err, IsNil)
+
+	otherKey := asserts.BootstrapAccountKeyForTest("canonical", testPrivKey1.PublicKey())
+	err = asserts.CheckSignedBy(chks.a, otherKey)
+	c.Check(err, ErrorMatches, `assertion is not signed with public key "[a-f0-9]+" from "canonical"`)
+
+	// tampered with after signing
+	tampered, err := asserts.Decode(bytes.Replace(asserts.Encode(chks.a), []byte("primary-key: 0"), []byte("primary-key: 1"), 1))
+	c.Assert(err, IsNil)
+	err = asserts.CheckSignedBy(tampered, signingKey)
+	c.Check(err, ErrorMatches, "failed signature verification: .*")
+}
+
 type signAddFindSuite struct {
 	signingDB    *asserts.Database
 	signingKeyID string
@@ -277,6 +293,19 @@ func (safs *signAddFindSuite) TestSign(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (safs *signAddFindSuite) TestSignKeyID(c *C) {
+	headers := map[string]string{
+		"authority-id": "canonical",
+		"primary-key":  "a",
+	}
+	a1, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
+	c.Assert(err, IsNil)
+
+	keyID, err := asserts.SignKeyID(a1)
+	c.Assert(err, IsNil)
+	c.Check(keyID, Equals, safs.signingKeyID)
+}
+
 func (safs *signAddFindSuite) TestSignEmptyKeyID(c *C) {
 	headers := map[string]string{
 		"authority-id": "canonical",
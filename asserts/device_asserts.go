@@ -21,6 +21,8 @@ package asserts
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -94,6 +96,13 @@ func (mod *Model) Timestamp() time.Time {
 	return mod.timestamp
 }
 
+// Prerequisites returns references to the account of the brand.
+func (mod *Model) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{mod.BrandID()}},
+	}
+}
+
 // Implement further consistency checks.
 func (mod *Model) checkConsistency(db RODatabase, acck *AccountKey) error {
 	// TODO: double check trust level of authority depending on class and possibly allowed-modes
@@ -176,6 +185,13 @@ func (ser *Serial) Timestamp() time.Time {
 	return ser.timestamp
 }
 
+// Prerequisites returns references to the account of the brand.
+func (ser *Serial) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{ser.BrandID()}},
+	}
+}
+
 // TODO: implement further consistency checks for Serial but first review approach
 
 func assembleSerial(assert assertionBase) (Assertion, error) {
@@ -202,3 +218,228 @@ func assembleSerial(assert assertionBase) (Assertion, error) {
 		pubKey:        pubKey,
 	}, nil
 }
+
+// SerialRequest holds a serial-request assertion, which is a self-signed
+// request to obtain a full device identity bound to the device public key.
+type SerialRequest struct {
+	assertionBase
+	pubKey PublicKey
+}
+
+// BrandID returns the brand identifier of the device making the request.
+func (sreq *SerialRequest) BrandID() string {
+	return sreq.Header("brand-id")
+}
+
+// Model returns the model name identifier of the device making the request.
+func (sreq *SerialRequest) Model() string {
+	return sreq.Header("model")
+}
+
+// RequestID returns the id for the request, obtained from and to be presented to the serial signing service.
+func (sreq *SerialRequest) RequestID() string {
+	return sreq.Header("request-id")
+}
+
+// DeviceKey returns the public key of the device making the request.
+func (sreq *SerialRequest) DeviceKey() PublicKey {
+	return sreq.pubKey
+}
+
+func assembleSerialRequest(assert assertionBase) (Assertion, error) {
+	_, err := checkNotEmpty(assert.headers, "brand-id")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkNotEmpty(assert.headers, "model")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkNotEmpty(assert.headers, "request-id")
+	if err != nil {
+		return nil, err
+	}
+
+	encodedKey, err := checkNotEmpty(assert.headers, "device-key")
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := decodePublicKey([]byte(encodedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	// the request is self-signed with the device key
+	sig, err := decodeSignature(assert.signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := pubKey.verify(assert.content, sig); err != nil {
+		return nil, fmt.Errorf("serial-request is not signed with the device key: %v", err)
+	}
+
+	// ignore extra headers and non-empty body for future compatibility
+	return &SerialRequest{
+		assertionBase: assert,
+		pubKey:        pubKey,
+	}, nil
+}
+
+// SystemUser holds a system-user assertion, which is a statement by a
+// brand allowing the creation of a local system user on the devices
+// of some of its models.
+type SystemUser struct {
+	assertionBase
+	series    []string
+	models    []string
+	sshKeys   []string
+	since     time.Time
+	until     time.Time
+	timestamp time.Time
+}
+
+// BrandID returns the brand identifier that signed this assertion.
+func (su *SystemUser) BrandID() string {
+	return su.Header("brand-id")
+}
+
+// Email returns the email address that this assertion is valid for.
+func (su *SystemUser) Email() string {
+	return su.Header("email")
+}
+
+// Series returns the series that this assertion is valid for.
+func (su *SystemUser) Series() []string {
+	return su.series
+}
+
+// Models returns the models that this assertion is valid for.
+func (su *SystemUser) Models() []string {
+	return su.models
+}
+
+// Name returns the full name of the user (e.g. Random Guy).
+func (su *SystemUser) Name() string {
+	return su.Header("name")
+}
+
+// Username returns the system user name that should be created (e.g. "foo").
+func (su *SystemUser) Username() string {
+	return su.Header("username")
+}
+
+// Password returns the crypt(3) compatible password hash for the user, if any.
+func (su *SystemUser) Password() string {
+	return su.Header("password")
+}
+
+// SSHKeys returns the ssh keys for the user.
+func (su *SystemUser) SSHKeys() []string {
+	return su.sshKeys
+}
+
+// Since returns the time since the assertion is valid.
+func (su *SystemUser) Since() time.Time {
+	return su.since
+}
+
+// Until returns the time until the assertion is valid.
+func (su *SystemUser) Until() time.Time {
+	return su.until
+}
+
+// ValidAt returns whether the system-user is valid at 'when' time.
+func (su *SystemUser) ValidAt(when time.Time) bool {
+	return !when.Before(su.since) && when.Before(su.until)
+}
+
+// Timestamp returns the time when the system-user assertion was issued.
+func (su *SystemUser) Timestamp() time.Time {
+	return su.timestamp
+}
+
+// Prerequisites returns references to the account of the brand.
+func (su *SystemUser) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{su.BrandID()}},
+	}
+}
+
+var (
+	validSystemUserUsername = regexp.MustCompile(`^[a-z0-9][-a-z0-9+._]*$`)
+	validSystemUserPassword = regexp.MustCompile(`^\$(?:1|2a|2y|5|6)\$[./0-9A-Za-z=$]+$`)
+)
+
+func systemUserSSHKeys(headers map[string]string) []string {
+	var sshKeys []string
+	for _, line := range strings.Split(headers["ssh-keys"], "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			sshKeys = append(sshKeys, line)
+		}
+	}
+	return sshKeys
+}
+
+func assembleSystemUser(assert assertionBase) (Assertion, error) {
+	if assert.headers["brand-id"] != assert.headers["authority-id"] {
+		return nil, fmt.Errorf("authority-id and brand-id must match, system-user assertions are expected to be signed by the brand: %q != %q", assert.headers["authority-id"], assert.headers["brand-id"])
+	}
+
+	if _, err := checkNotEmpty(assert.headers, "email"); err != nil {
+		return nil, err
+	}
+
+	series, err := checkCommaSepList(assert.headers, "series")
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := checkCommaSepList(assert.headers, "models")
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := checkNotEmpty(assert.headers, "username")
+	if err != nil {
+		return nil, err
+	}
+	if !validSystemUserUsername.MatchString(username) {
+		return nil, fmt.Errorf("%q header contains invalid characters: %q", "username", username)
+	}
+
+	if password := assert.headers["password"]; password != "" && !validSystemUserPassword.MatchString(password) {
+		return nil, fmt.Errorf("%q header must be a crypt(3) password hash", "password")
+	}
+
+	since, err := checkRFC3339Date(assert.headers, "since")
+	if err != nil {
+		return nil, err
+	}
+
+	until, err := checkRFC3339Date(assert.headers, "until")
+	if err != nil {
+		return nil, err
+	}
+	if !until.After(since) {
+		return nil, fmt.Errorf(`"until" header must be after "since" header`)
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	// ignore extra headers and non-empty body for future compatibility
+	return &SystemUser{
+		assertionBase: assert,
+		series:        series,
+		models:        models,
+		sshKeys:       systemUserSSHKeys(assert.headers),
+		since:         since,
+		until:         until,
+		timestamp:     timestamp,
+	}, nil
+}
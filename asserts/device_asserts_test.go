@@ -36,6 +36,8 @@ type modelSuite struct {
 var (
 	_ = Suite(&modelSuite{})
 	_ = Suite(&serialSuite{})
+	_ = Suite(&serialRequestSuite{})
+	_ = Suite(&systemUserSuite{})
 )
 
 func (mods *modelSuite) SetUpSuite(c *C) {
@@ -80,6 +82,9 @@ func (mods *modelSuite) TestDecodeOK(c *C) {
 	c.Check(model.Store(), Equals, "brand-store")
 	c.Check(model.AllowedModes(), HasLen, 0)
 	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
+	c.Check(model.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"brand-id1"}},
+	})
 }
 
 const (
@@ -197,6 +202,9 @@ func (ss *serialSuite) TestDecodeOK(c *C) {
 	c.Check(serial.Model(), Equals, "baz-3000")
 	c.Check(serial.Serial(), Equals, "2700")
 	c.Check(serial.DeviceKey().Fingerprint(), Equals, ss.deviceKey.PublicKey().Fingerprint())
+	c.Check(serial.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"brand-id1"}},
+	})
 }
 
 const (
@@ -229,3 +237,232 @@ func (ss *serialSuite) TestDecodeInvalid(c *C) {
 		c.Check(err, ErrorMatches, serialErrPrefix+test.expectedErr)
 	}
 }
+
+type serialRequestSuite struct {
+	deviceKey     asserts.PrivateKey
+	encodedDevKey string
+}
+
+func (srs *serialRequestSuite) SetUpSuite(c *C) {
+	srs.deviceKey = testPrivKey2
+	encodedPubKey, err := asserts.EncodePublicKey(srs.deviceKey.PublicKey())
+	c.Assert(err, IsNil)
+	srs.encodedDevKey = string(encodedPubKey)
+}
+
+func (srs *serialRequestSuite) TestSignAndDecodeOK(c *C) {
+	headers := map[string]string{
+		"brand-id":   "brand-id1",
+		"model":      "baz-3000",
+		"request-id": "REQID",
+		"device-key": srs.encodedDevKey,
+	}
+	sreq, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, headers, []byte("HW-DETAILS"), srs.deviceKey)
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode(asserts.Encode(sreq))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.SerialRequestType)
+	serialReq := a.(*asserts.SerialRequest)
+	c.Check(serialReq.AuthorityID(), Equals, "")
+	c.Check(serialReq.BrandID(), Equals, "brand-id1")
+	c.Check(serialReq.Model(), Equals, "baz-3000")
+	c.Check(serialReq.RequestID(), Equals, "REQID")
+	c.Check(serialReq.DeviceKey().Fingerprint(), Equals, srs.deviceKey.PublicKey().Fingerprint())
+	c.Check(serialReq.Body(), DeepEquals, []byte("HW-DETAILS"))
+}
+
+func (srs *serialRequestSuite) TestSignWithoutAuthorityNeedsNoAuthorityType(c *C) {
+	_, err := asserts.SignWithoutAuthority(asserts.ModelType, map[string]string{}, nil, srs.deviceKey)
+	c.Check(err, ErrorMatches, "cannot sign assertions needing a definite authority with SignWithoutAuthority")
+}
+
+func (srs *serialRequestSuite) TestSignWithoutAuthorityRefusesAuthorityID(c *C) {
+	headers := map[string]string{
+		"authority-id": "brand-id1",
+		"brand-id":     "brand-id1",
+		"model":        "baz-3000",
+		"request-id":   "REQID",
+		"device-key":   srs.encodedDevKey,
+	}
+	_, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, headers, nil, srs.deviceKey)
+	c.Check(err, ErrorMatches, `"serial-request" assertion cannot have authority-id set`)
+}
+
+func (srs *serialRequestSuite) TestDecodeInvalid(c *C) {
+	headers := map[string]string{
+		"brand-id":   "brand-id1",
+		"model":      "baz-3000",
+		"request-id": "REQID",
+		"device-key": srs.encodedDevKey,
+	}
+	sreq, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, headers, nil, srs.deviceKey)
+	c.Assert(err, IsNil)
+	encoded := string(asserts.Encode(sreq))
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"brand-id: brand-id1\n", "", `assertion serial-request: "brand-id" header is mandatory`},
+		{"model: baz-3000\n", "model: \n", `assertion serial-request: "model" header should not be empty`},
+		{"request-id: REQID\n", "", `assertion serial-request: "request-id" header is mandatory`},
+		{"request-id: REQID\n", "request-id: OTHER\n", `assertion serial-request: serial-request is not signed with the device key: .*`},
+		{"type: serial-request\n", "type: serial-request\nauthority-id: brand-id1\n", `assertion: "serial-request" assertion cannot have authority-id set`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, test.expectedErr)
+	}
+}
+
+func (srs *serialRequestSuite) TestCheckRefused(c *C) {
+	headers := map[string]string{
+		"brand-id":   "brand-id1",
+		"model":      "baz-3000",
+		"request-id": "REQID",
+		"device-key": srs.encodedDevKey,
+	}
+	sreq, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, headers, nil, srs.deviceKey)
+	c.Assert(err, IsNil)
+
+	_, _, db := makeSignAndCheckDbWithAccountKey(c, "brand-id1")
+	err = db.Check(sreq)
+	c.Check(err, ErrorMatches, `cannot check no-authority assertion type "serial-request"`)
+}
+
+type systemUserSuite struct {
+	until     time.Time
+	untilLine string
+	since     time.Time
+	sinceLine string
+	ts        time.Time
+	tsLine    string
+}
+
+func (sus *systemUserSuite) SetUpSuite(c *C) {
+	sus.ts = time.Now().Truncate(time.Second).UTC()
+	sus.tsLine = "timestamp: " + sus.ts.Format(time.RFC3339) + "\n"
+	sus.since = sus.ts.AddDate(0, 0, -1)
+	sus.sinceLine = "since: " + sus.since.Format(time.RFC3339) + "\n"
+	sus.until = sus.ts.AddDate(0, 1, 0)
+	sus.untilLine = "until: " + sus.until.Format(time.RFC3339) + "\n"
+}
+
+const systemUserExample = "type: system-user\n" +
+	"authority-id: brand-id1\n" +
+	"brand-id: brand-id1\n" +
+	"email: foo@example.com\n" +
+	"series: 16\n" +
+	"models: frobinator\n" +
+	"name: Nice Guy\n" +
+	"username: guy\n" +
+	"password: $6$salt$hash\n" +
+	"ssh-keys:\n" +
+	" ssh-rsa AAAABcdefg guy@example.com\n" +
+	" ssh-rsa AAAAHijklm guy@example.org\n" +
+	"SINCELINE" +
+	"UNTILLINE" +
+	"TSLINE" +
+	"body-length: 0" +
+	"\n\n" +
+	"openpgp c2ln"
+
+func (sus *systemUserSuite) encoded() string {
+	encoded := strings.Replace(systemUserExample, "SINCELINE", sus.sinceLine, 1)
+	encoded = strings.Replace(encoded, "UNTILLINE", sus.untilLine, 1)
+	return strings.Replace(encoded, "TSLINE", sus.tsLine, 1)
+}
+
+func (sus *systemUserSuite) TestDecodeOK(c *C) {
+	a, err := asserts.Decode([]byte(sus.encoded()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.SystemUserType)
+	systemUser := a.(*asserts.SystemUser)
+	c.Check(systemUser.BrandID(), Equals, "brand-id1")
+	c.Check(systemUser.Email(), Equals, "foo@example.com")
+	c.Check(systemUser.Series(), DeepEquals, []string{"16"})
+	c.Check(systemUser.Models(), DeepEquals, []string{"frobinator"})
+	c.Check(systemUser.Name(), Equals, "Nice Guy")
+	c.Check(systemUser.Username(), Equals, "guy")
+	c.Check(systemUser.Password(), Equals, "$6$salt$hash")
+	c.Check(systemUser.SSHKeys(), DeepEquals, []string{
+		"ssh-rsa AAAABcdefg guy@example.com",
+		"ssh-rsa AAAAHijklm guy@example.org",
+	})
+	c.Check(systemUser.Since().Equal(sus.since), Equals, true)
+	c.Check(systemUser.Until().Equal(sus.until), Equals, true)
+	c.Check(systemUser.Timestamp(), Equals, sus.ts)
+	c.Check(systemUser.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"brand-id1"}},
+	})
+}
+
+func (sus *systemUserSuite) TestValidAt(c *C) {
+	a, err := asserts.Decode([]byte(sus.encoded()))
+	c.Assert(err, IsNil)
+	systemUser := a.(*asserts.SystemUser)
+
+	c.Check(systemUser.ValidAt(sus.since), Equals, true)
+	c.Check(systemUser.ValidAt(sus.ts), Equals, true)
+	c.Check(systemUser.ValidAt(sus.since.Add(-time.Second)), Equals, false)
+	c.Check(systemUser.ValidAt(sus.until), Equals, false)
+}
+
+func (sus *systemUserSuite) TestDecodeNoPasswordNoSSHKeys(c *C) {
+	encoded := strings.Replace(sus.encoded(), "password: $6$salt$hash\n", "", 1)
+	encoded = strings.Replace(encoded, "ssh-keys:\n ssh-rsa AAAABcdefg guy@example.com\n ssh-rsa AAAAHijklm guy@example.org\n", "", 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	systemUser := a.(*asserts.SystemUser)
+	c.Check(systemUser.Password(), Equals, "")
+	c.Check(systemUser.SSHKeys(), HasLen, 0)
+}
+
+const (
+	systemUserErrPrefix = "assertion system-user: "
+)
+
+func (sus *systemUserSuite) TestDecodeInvalid(c *C) {
+	encoded := sus.encoded()
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"brand-id: brand-id1\n", "", `"brand-id" header is mandatory`},
+		{"brand-id: brand-id1\n", "brand-id: random\n", `authority-id and brand-id must match, system-user assertions are expected to be signed by the brand: "brand-id1" != "random"`},
+		{"email: foo@example.com\n", "", `"email" header is mandatory`},
+		{"email: foo@example.com\n", "email: \n", `"email" header should not be empty`},
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"series: 16\n", "series: 16,\n", `empty entry in comma separated "series" header: "16,"`},
+		{"models: frobinator\n", "", `"models" header is mandatory`},
+		{"username: guy\n", "", `"username" header is mandatory`},
+		{"username: guy\n", "username: \n", `"username" header should not be empty`},
+		{"username: guy\n", "username: Guy!\n", `"username" header contains invalid characters: "Guy!"`},
+		{"password: $6$salt$hash\n", "password: plain\n", `"password" header must be a crypt\(3\) password hash`},
+		{sus.sinceLine, "", `"since" header is mandatory`},
+		{sus.sinceLine, "since: 12:30\n", `"since" header is not a RFC3339 date: .*`},
+		{sus.untilLine, "", `"until" header is mandatory`},
+		{sus.untilLine, "until: " + sus.since.Format(time.RFC3339) + "\n", `"until" header must be after "since" header`},
+		{sus.tsLine, "", `"timestamp" header is mandatory`},
+		{sus.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, systemUserErrPrefix+test.expectedErr)
+	}
+}
+
+func (sus *systemUserSuite) TestSystemUserCheck(c *C) {
+	ex, err := asserts.Decode([]byte(sus.encoded()))
+	c.Assert(err, IsNil)
+
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "brand-id1")
+
+	headers := ex.Headers()
+	headers["timestamp"] = "2015-11-25T20:00:00Z"
+	systemUser, err := accSignDB.Sign(asserts.SystemUserType, headers, nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(systemUser)
+	c.Assert(err, IsNil)
+}
@@ -23,6 +23,8 @@ import (
 	"crypto"
 	"encoding/base64"
 	"fmt"
+	"io"
+	"os"
 )
 
 // EncodeDigest encodes a hash algorithm and a digest to be put in an assertion header.
@@ -39,3 +41,25 @@ func EncodeDigest(hash crypto.Hash, hashDigest []byte) (string, error) {
 	}
 	return fmt.Sprintf("%s-%s", algo, base64.RawURLEncoding.EncodeToString(hashDigest)), nil
 }
+
+// SnapFileSHA512 computes the SHA512 digest of the given snap file,
+// encoded to be put in a snap-revision assertion header, and its size.
+func SnapFileSHA512(snapPath string) (digest string, size uint64, err error) {
+	f, err := os.Open(snapPath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := crypto.SHA512.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	digest, err = EncodeDigest(crypto.SHA512, h.Sum(nil))
+	if err != nil {
+		return "", 0, err
+	}
+	return digest, uint64(n), nil
+}
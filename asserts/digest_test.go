@@ -23,6 +23,8 @@ import (
 	"crypto"
 	_ "crypto/sha256"
 	"encoding/base64"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
 
 	. "gopkg.in/check.v1"
@@ -54,3 +56,24 @@ func (eds *encodeDigestSuite) TestEncodeDigestErrors(c *C) {
 	_, err = asserts.EncodeDigest(crypto.SHA512, []byte{1, 2})
 	c.Check(err, ErrorMatches, "hash digest by sha512 should be 64 bytes")
 }
+
+func (eds *encodeDigestSuite) TestSnapFileSHA512(c *C) {
+	snapPath := filepath.Join(c.MkDir(), "foo.snap")
+	err := ioutil.WriteFile(snapPath, []byte("snap content"), 0644)
+	c.Assert(err, IsNil)
+
+	digest, size, err := asserts.SnapFileSHA512(snapPath)
+	c.Assert(err, IsNil)
+	c.Check(size, Equals, uint64(len("snap content")))
+
+	h := crypto.SHA512.New()
+	h.Write([]byte("snap content"))
+	expected, err := asserts.EncodeDigest(crypto.SHA512, h.Sum(nil))
+	c.Assert(err, IsNil)
+	c.Check(digest, Equals, expected)
+}
+
+func (eds *encodeDigestSuite) TestSnapFileSHA512Missing(c *C) {
+	_, _, err := asserts.SnapFileSHA512(filepath.Join(c.MkDir(), "missing.snap"))
+	c.Check(err, ErrorMatches, ".*no such file or directory")
+}
@@ -0,0 +1,217 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// An ExternalSigner gives access to key pairs whose private part is
+// held by an external signing service and never leaves it.
+type ExternalSigner interface {
+	// ExportPublicKey returns the binary OpenPGP export of the public
+	// key with the given key id of the authority, or nothing if the
+	// key is unknown.
+	ExportPublicKey(authorityID, keyID string) ([]byte, error)
+	// Sign returns a binary detached OpenPGP signature of content,
+	// using a SHA512 digest, made with the key of the authority with
+	// the given fingerprint.
+	Sign(authorityID, fingerprint string, content []byte) ([]byte, error)
+}
+
+type externalKeypairManager struct {
+	signer ExternalSigner
+}
+
+// NewExternalKeypairManager creates a new key pair manager delegating
+// signing to the given external signer.
+// Importing keys through the keypair manager interface is not
+// supported.
+func NewExternalKeypairManager(signer ExternalSigner) KeypairManager {
+	return &externalKeypairManager{
+		signer: signer,
+	}
+}
+
+func (ekm *externalKeypairManager) Put(authorityID string, privKey PrivateKey) error {
+	return fmt.Errorf("cannot import private key into external signer")
+}
+
+func (ekm *externalKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
+	out, err := ekm.signer.ExportPublicKey(authorityID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("cannot find key %q in external signer", keyID)
+	}
+
+	sign := func(fingerprint string, content []byte) ([]byte, error) {
+		sig, err := ekm.signer.Sign(authorityID, fingerprint, content)
+		if err != nil {
+			return nil, fmt.Errorf("cannot sign using external signer: %v", err)
+		}
+		return sig, nil
+	}
+	privKey, err := newExtPGPPrivateKey(bytes.NewBuffer(out), "external signer", sign)
+	if err != nil {
+		return nil, fmt.Errorf("cannot use external signer key %q: %v", keyID, err)
+	}
+	gotID := privKey.PublicKey().ID()
+	if gotID != keyID {
+		return nil, fmt.Errorf("got wrong key from external signer, expected %q: %s", keyID, gotID)
+	}
+	return privKey, nil
+}
+
+type commandSigner struct {
+	command string
+}
+
+// NewCommandSigner returns an external signer running the given
+// command as:
+//
+//	command export-key AUTHORITY-ID KEY-ID
+//
+// to get the public key export on standard output, and as:
+//
+//	command sign AUTHORITY-ID FINGERPRINT
+//
+// to get on standard output the signature of the content passed on
+// standard input.
+func NewCommandSigner(command string) ExternalSigner {
+	return &commandSigner{command: command}
+}
+
+func (cs *commandSigner) run(input []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command(cs.command, args...)
+	var outBuf bytes.Buffer
+	var errBuf bytes.Buffer
+
+	if len(input) != 0 {
+		cmd.Stdin = bytes.NewBuffer(input)
+	}
+
+	cmd.Stdout = &outBuf
+	cmd.Stderr = &errBuf
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %v (%q)", cs.command, strings.Join(args, " "), err, errBuf.Bytes())
+	}
+
+	return outBuf.Bytes(), nil
+}
+
+func (cs *commandSigner) ExportPublicKey(authorityID, keyID string) ([]byte, error) {
+	return cs.run(nil, "export-key", authorityID, keyID)
+}
+
+func (cs *commandSigner) Sign(authorityID, fingerprint string, content []byte) ([]byte, error) {
+	return cs.run(content, "sign", authorityID, fingerprint)
+}
+
+type httpSigner struct {
+	baseURL *url.URL
+	client  *http.Client
+}
+
+// NewHTTPSigner returns an external signer using the signing service
+// at baseURL, which is expected to offer:
+//
+//	GET  <baseURL>/keys/AUTHORITY-ID/KEY-ID
+//
+// replying with the public key export, or 404 if the key is unknown,
+// and:
+//
+//	POST <baseURL>/sign/AUTHORITY-ID/FINGERPRINT
+//
+// replying with the signature of the content in the request body.
+//
+// The requests are made with client, which can for example authenticate
+// them through its Transport. If client is nil a default one with a
+// timeout is used.
+func NewHTTPSigner(baseURL *url.URL, client *http.Client) ExternalSigner {
+	if client == nil {
+		client = &http.Client{Timeout: httpSignerTimeout}
+	}
+	return &httpSigner{
+		baseURL: baseURL,
+		client:  client,
+	}
+}
+
+const httpSignerTimeout = 30 * time.Second
+
+func (hs *httpSigner) endpoint(elems ...string) string {
+	u := *hs.baseURL
+	path := strings.TrimSuffix(u.Path, "/")
+	rawPath := strings.TrimSuffix(u.EscapedPath(), "/")
+	for _, elem := range elems {
+		path += "/" + elem
+		rawPath += "/" + url.PathEscape(elem)
+	}
+	u.Path = path
+	u.RawPath = rawPath
+	return u.String()
+}
+
+func (hs *httpSigner) do(req *http.Request, what string) ([]byte, error) {
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot %s: %v", what, err)
+	}
+	defer resp.Body.Close()
+
+	if req.Method == "GET" && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot %s: unexpected status %d", what, resp.StatusCode)
+	}
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot %s: %v", what, err)
+	}
+	return out, nil
+}
+
+func (hs *httpSigner) ExportPublicKey(authorityID, keyID string) ([]byte, error) {
+	req, err := http.NewRequest("GET", hs.endpoint("keys", authorityID, keyID), nil)
+	if err != nil {
+		return nil, err
+	}
+	return hs.do(req, "export key from signing service")
+}
+
+func (hs *httpSigner) Sign(authorityID, fingerprint string, content []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", hs.endpoint("sign", authorityID, fingerprint), bytes.NewBuffer(content))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return hs.do(req, "sign with signing service")
+}
@@ -0,0 +1,245 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"bytes"
+	"crypto"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/testutil"
+)
+
+// fakeSigner holds the private key behind the external signer interface.
+type fakeSigner struct {
+	privk *packet.PrivateKey
+}
+
+func (fs *fakeSigner) ExportPublicKey(authorityID, keyID string) ([]byte, error) {
+	if keyID != testKeyID {
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	err := fs.privk.PublicKey.Serialize(buf)
+	return buf.Bytes(), err
+}
+
+func (fs *fakeSigner) Sign(authorityID, fingerprint string, content []byte) ([]byte, error) {
+	sig := new(packet.Signature)
+	sig.PubKeyAlgo = packet.PubKeyAlgoRSA
+	sig.Hash = crypto.SHA512
+	sig.CreationTime = time.Now()
+	sig.IssuerKeyId = &fs.privk.KeyId
+
+	h := sig.Hash.New()
+	h.Write(content)
+	if err := sig.Sign(h, fs.privk, nil); err != nil {
+		return nil, err
+	}
+
+	buf := new(bytes.Buffer)
+	err := sig.Serialize(buf)
+	return buf.Bytes(), err
+}
+
+type extKeypairMgrSuite struct {
+	signer *fakeSigner
+}
+
+var _ = Suite(&extKeypairMgrSuite{})
+
+func (ekms *extKeypairMgrSuite) SetUpSuite(c *C) {
+	blk, err := armor.Decode(bytes.NewBuffer([]byte(testKey)))
+	c.Assert(err, IsNil)
+	pkPkt, err := packet.Read(blk.Body)
+	c.Assert(err, IsNil)
+	privk, ok := pkPkt.(*packet.PrivateKey)
+	c.Assert(ok, Equals, true)
+	ekms.signer = &fakeSigner{privk: privk}
+}
+
+func (ekms *extKeypairMgrSuite) signAndCheck(c *C, keypairMgr asserts.KeypairManager) {
+	devKey, err := keypairMgr.Get("dev1-id", testKeyID)
+	c.Assert(err, IsNil)
+	c.Check(devKey.PublicKey().Fingerprint(), Equals, testKeyFingerprint)
+
+	signDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: keypairMgr,
+	})
+	c.Assert(err, IsNil)
+
+	headers := map[string]string{
+		"authority-id": "dev1-id",
+		"series":       "16",
+		"snap-id":      "snap-id-1",
+		"snap-digest":  "sha512-...",
+		"grade":        "devel",
+		"snap-size":    "1025",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	snapBuild, err := signDB.Sign(asserts.SnapBuildType, headers, nil, testKeyID)
+	c.Assert(err, IsNil)
+
+	checkDB, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		Backstore:      asserts.NewMemoryBackstore(),
+		TrustedKeys:    []*asserts.AccountKey{asserts.BootstrapAccountKeyForTest("dev1-id", devKey.PublicKey())},
+	})
+	c.Assert(err, IsNil)
+	c.Check(checkDB.Check(snapBuild), IsNil)
+}
+
+func (ekms *extKeypairMgrSuite) TestGetAndSign(c *C) {
+	ekms.signAndCheck(c, asserts.NewExternalKeypairManager(ekms.signer))
+}
+
+func (ekms *extKeypairMgrSuite) TestGetNotFound(c *C) {
+	keypairMgr := asserts.NewExternalKeypairManager(ekms.signer)
+	got, err := keypairMgr.Get("dev1-id", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot find key "ffffffffffffffff" in external signer`)
+	c.Check(got, IsNil)
+}
+
+func (ekms *extKeypairMgrSuite) TestPutNotSupported(c *C) {
+	keypairMgr := asserts.NewExternalKeypairManager(ekms.signer)
+	err := keypairMgr.Put("dev1-id", testPrivKey1)
+	c.Check(err, ErrorMatches, "cannot import private key into external signer")
+}
+
+func (ekms *extKeypairMgrSuite) TestHTTPSigner(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/signer/keys/dev1-id/"+testKeyID:
+			out, err := ekms.signer.ExportPublicKey("dev1-id", testKeyID)
+			c.Assert(err, IsNil)
+			w.Write(out)
+		case r.Method == "POST" && r.URL.Path == "/signer/sign/dev1-id/"+testKeyFingerprint:
+			content, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, IsNil)
+			sig, err := ekms.signer.Sign("dev1-id", testKeyFingerprint, content)
+			c.Assert(err, IsNil)
+			w.Write(sig)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL + "/signer/")
+	c.Assert(err, IsNil)
+	keypairMgr := asserts.NewExternalKeypairManager(asserts.NewHTTPSigner(baseURL, nil))
+	ekms.signAndCheck(c, keypairMgr)
+
+	_, err = keypairMgr.Get("dev1-id", "ffffffffffffffff")
+	c.Check(err, ErrorMatches, `cannot find key "ffffffffffffffff" in external signer`)
+}
+
+func (ekms *extKeypairMgrSuite) TestHTTPSignerError(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL)
+	c.Assert(err, IsNil)
+	signer := asserts.NewHTTPSigner(baseURL, nil)
+	_, err = signer.ExportPublicKey("dev1-id", testKeyID)
+	c.Check(err, ErrorMatches, "cannot export key from signing service: unexpected status 500")
+	_, err = signer.Sign("dev1-id", testKeyFingerprint, []byte("content"))
+	c.Check(err, ErrorMatches, "cannot sign with signing service: unexpected status 500")
+}
+
+type authTransport struct{}
+
+func (authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set("Authorization", "Macaroon secret")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (ekms *extKeypairMgrSuite) TestHTTPSignerClientAndEscaping(c *C) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Header.Get("Authorization"), Equals, "Macaroon secret")
+		paths = append(paths, r.URL.EscapedPath())
+		w.Write([]byte("out"))
+	}))
+	defer server.Close()
+
+	baseURL, err := url.Parse(server.URL + "/signer")
+	c.Assert(err, IsNil)
+	signer := asserts.NewHTTPSigner(baseURL, &http.Client{Transport: authTransport{}})
+	_, err = signer.ExportPublicKey("dev/1 id", "../key?")
+	c.Assert(err, IsNil)
+	_, err = signer.Sign("dev/1 id", testKeyFingerprint, []byte("content"))
+	c.Assert(err, IsNil)
+	c.Check(paths, DeepEquals, []string{
+		"/signer/keys/dev%2F1%20id/..%2Fkey%3F",
+		"/signer/sign/dev%2F1%20id/" + testKeyFingerprint,
+	})
+}
+
+func (ekms *extKeypairMgrSuite) TestCommandSigner(c *C) {
+	dir := c.MkDir()
+	contentFile := filepath.Join(dir, "content")
+	cmd := testutil.MockCommand(c, "my-signer", `
+case "$1" in
+    export-key) printf "PUBKEY";;
+    sign) cat > `+contentFile+`; printf "SIGNATURE";;
+esac
+`)
+	defer cmd.Restore()
+
+	signer := asserts.NewCommandSigner("my-signer")
+	out, err := signer.ExportPublicKey("dev1-id", testKeyID)
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "PUBKEY")
+
+	out, err = signer.Sign("dev1-id", testKeyFingerprint, []byte("content"))
+	c.Assert(err, IsNil)
+	c.Check(string(out), Equals, "SIGNATURE")
+	signed, err := ioutil.ReadFile(contentFile)
+	c.Assert(err, IsNil)
+	c.Check(string(signed), Equals, "content")
+
+	c.Check(cmd.Calls(), DeepEquals, []string{
+		"export-key dev1-id " + testKeyID,
+		"sign dev1-id " + testKeyFingerprint,
+	})
+}
+
+func (ekms *extKeypairMgrSuite) TestCommandSignerFailure(c *C) {
+	cmd := testutil.MockCommand(c, "my-signer", `echo "no such key" >&2; exit 1`)
+	defer cmd.Restore()
+
+	keypairMgr := asserts.NewExternalKeypairManager(asserts.NewCommandSigner("my-signer"))
+	_, err := keypairMgr.Get("dev1-id", testKeyID)
+	c.Check(err, ErrorMatches, `my-signer export-key dev1-id `+testKeyID+` failed: exit status 1 \("no such key\\n"\)`)
+}
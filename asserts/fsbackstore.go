@@ -24,7 +24,11 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"sync"
+
+	"github.com/snapcore/snapd/osutil"
 )
 
 // the default filesystem based backstore for assertions
@@ -32,12 +36,27 @@ import (
 const (
 	assertionsLayoutVersion = "v0"
 	assertionsRoot          = "asserts-" + assertionsLayoutVersion
+	assertionsIndexRoot     = "asserts-index-" + assertionsLayoutVersion
 	activeFname             = "active"
 )
 
+// indexedHeaders are the commonly queried non primary key headers
+// for which the backstore keeps a secondary index. Searches by
+// primary key prefixes are already served by the storage layout.
+//
+// The index lives under:
+//
+// <index top>/<type>/<header>/<header value>/<primary key path>/active
+//
+// with empty entries. An entry is written before the assertion it
+// refers to and stale entries are removed after, so the index never
+// misses an assertion and searches simply skip dangling entries.
+var indexedHeaders = []string{"snap-id", "developer-id", "brand-id"}
+
 type filesystemBackstore struct {
-	top string
-	mu  sync.RWMutex
+	top      string
+	indexTop string
+	mu       sync.RWMutex
 }
 
 // OpenFSBackstore opens a filesystem backed assertions backstore under path.
@@ -47,7 +66,92 @@ func OpenFSBackstore(path string) (Backstore, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &filesystemBackstore{top: top}, nil
+	fsbs := &filesystemBackstore{
+		top:      top,
+		indexTop: filepath.Join(path, assertionsIndexRoot),
+	}
+	if !osutil.IsDirectory(fsbs.indexTop) {
+		if err := fsbs.buildIndex(); err != nil {
+			return nil, err
+		}
+	}
+	return fsbs, nil
+}
+
+// buildIndex builds the secondary index for the assertions already
+// stored, to switch to it atomically when done.
+func (fsbs *filesystemBackstore) buildIndex() error {
+	newIndexTop := fsbs.indexTop + ".new"
+	if err := os.RemoveAll(newIndexTop); err != nil {
+		return fmt.Errorf("cannot build assertion index: %v", err)
+	}
+	if err := ensureTop(newIndexTop); err != nil {
+		return err
+	}
+	for _, assertType := range typeRegistry {
+		n := len(assertType.PrimaryKey)
+		diskPattern := make([]string, n+1)
+		for i := 0; i < n; i++ {
+			diskPattern[i] = "*"
+		}
+		diskPattern[n] = activeFname
+		var indexErr error
+		foundCb := func(a Assertion) {
+			if indexErr != nil {
+				return
+			}
+			diskPrimaryPath := buildDiskPrimaryPath(primaryPathOf(assertType, a))
+			indexErr = writeIndexEntries(newIndexTop, assertType, a, diskPrimaryPath)
+		}
+		if err := fsbs.search(assertType, diskPattern, foundCb); err != nil {
+			return fmt.Errorf("cannot build assertion index: %v", err)
+		}
+		if indexErr != nil {
+			return fmt.Errorf("cannot build assertion index: %v", indexErr)
+		}
+	}
+	if err := os.Rename(newIndexTop, fsbs.indexTop); err != nil {
+		return fmt.Errorf("cannot build assertion index: %v", err)
+	}
+	return nil
+}
+
+func primaryPathOf(assertType *AssertionType, assert Assertion) []string {
+	primaryPath := make([]string, len(assertType.PrimaryKey))
+	for i, k := range assertType.PrimaryKey {
+		primaryPath[i] = assert.Header(k)
+	}
+	return primaryPath
+}
+
+func writeIndexEntries(indexTop string, assertType *AssertionType, assert Assertion, diskPrimaryPath string) error {
+	for _, h := range indexedHeaders {
+		v := assert.Header(h)
+		if v == "" {
+			continue
+		}
+		err := atomicWriteEntry(nil, false, indexTop, assertType.Name, h, url.QueryEscape(v), diskPrimaryPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaleIndexEntries removes the index entries of old that do
+// not apply to cur anymore.
+func removeStaleIndexEntries(indexTop string, assertType *AssertionType, old, cur Assertion, diskPrimaryPath string) error {
+	for _, h := range indexedHeaders {
+		v := old.Header(h)
+		if v == "" || v == cur.Header(h) {
+			continue
+		}
+		err := os.Remove(filepath.Join(indexTop, assertType.Name, h, url.QueryEscape(v), diskPrimaryPath))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
 }
 
 // guarantees that result assertion is of the expected type (both in the AssertionType and go type sense)
@@ -85,12 +189,7 @@ func (fsbs *filesystemBackstore) Put(assertType *AssertionType, assert Assertion
 	fsbs.mu.Lock()
 	defer fsbs.mu.Unlock()
 
-	primaryPath := make([]string, len(assertType.PrimaryKey))
-	for i, k := range assertType.PrimaryKey {
-		primaryPath[i] = assert.Header(k)
-	}
-
-	diskPrimaryPath := buildDiskPrimaryPath(primaryPath)
+	diskPrimaryPath := buildDiskPrimaryPath(primaryPathOf(assertType, assert))
 	curAssert, err := fsbs.readAssertion(assertType, diskPrimaryPath)
 	if err == nil {
 		curRev := curAssert.Revision()
@@ -101,10 +200,73 @@ func (fsbs *filesystemBackstore) Put(assertType *AssertionType, assert Assertion
 	} else if err != ErrNotFound {
 		return err
 	}
+	err = writeIndexEntries(fsbs.indexTop, assertType, assert, diskPrimaryPath)
+	if err != nil {
+		return fmt.Errorf("broken assertion storage, cannot write index entry: %v", err)
+	}
+	if curAssert != nil {
+		// retain the current revision in the history
+		err = atomicWriteEntry(Encode(curAssert), false, fsbs.top, assertType.Name, filepath.Dir(diskPrimaryPath), strconv.Itoa(curAssert.Revision()))
+		if err != nil {
+			return fmt.Errorf("broken assertion storage, cannot write assertion revision: %v", err)
+		}
+	}
 	err = atomicWriteEntry(Encode(assert), false, fsbs.top, assertType.Name, diskPrimaryPath)
 	if err != nil {
 		return fmt.Errorf("broken assertion storage, cannot write assertion: %v", err)
 	}
+	if curAssert != nil {
+		err = removeStaleIndexEntries(fsbs.indexTop, assertType, curAssert, assert, diskPrimaryPath)
+		if err != nil {
+			return fmt.Errorf("broken assertion storage, cannot remove stale index entry: %v", err)
+		}
+		err = fsbs.pruneHistory(assertType, diskPrimaryPath)
+		if err != nil {
+			return fmt.Errorf("broken assertion storage, cannot prune assertion history: %v", err)
+		}
+	}
+	return nil
+}
+
+// pastRevisions returns the revisions retained besides the current
+// one for the assertion at diskPrimaryPath, most recent first.
+func (fsbs *filesystemBackstore) pastRevisions(assertType *AssertionType, diskPrimaryPath string) ([]int, error) {
+	d, err := os.Open(filepath.Join(fsbs.top, assertType.Name, filepath.Dir(diskPrimaryPath)))
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	var revs []int
+	for _, name := range names {
+		rev, err := strconv.Atoi(name)
+		if err != nil {
+			// the current revision or a temporary file
+			continue
+		}
+		revs = append(revs, rev)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(revs)))
+	return revs, nil
+}
+
+func (fsbs *filesystemBackstore) pruneHistory(assertType *AssertionType, diskPrimaryPath string) error {
+	revs, err := fsbs.pastRevisions(assertType, diskPrimaryPath)
+	if err != nil {
+		return err
+	}
+	if len(revs) < maxRetainedRevisions {
+		return nil
+	}
+	dir := filepath.Join(fsbs.top, assertType.Name, filepath.Dir(diskPrimaryPath))
+	for _, rev := range revs[maxRetainedRevisions-1:] {
+		if err := os.Remove(filepath.Join(dir, strconv.Itoa(rev))); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -115,6 +277,33 @@ func (fsbs *filesystemBackstore) Get(assertType *AssertionType, key []string) (A
 	return fsbs.readAssertion(assertType, buildDiskPrimaryPath(key))
 }
 
+func (fsbs *filesystemBackstore) History(assertType *AssertionType, key []string) ([]Assertion, error) {
+	fsbs.mu.RLock()
+	defer fsbs.mu.RUnlock()
+
+	diskPrimaryPath := buildDiskPrimaryPath(key)
+	cur, err := fsbs.readAssertion(assertType, diskPrimaryPath)
+	if err != nil {
+		return nil, err
+	}
+	revs, err := fsbs.pastRevisions(assertType, diskPrimaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, cannot list assertion revisions: %v", err)
+	}
+	history := []Assertion{cur}
+	for _, rev := range revs {
+		a, err := fsbs.readAssertion(assertType, filepath.Join(filepath.Dir(diskPrimaryPath), strconv.Itoa(rev)))
+		if err == ErrNotFound {
+			return nil, fmt.Errorf("broken assertion storage, disappearing assertion revision: %s/%s", assertType.Name, diskPrimaryPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, a)
+	}
+	return history, nil
+}
+
 func (fsbs *filesystemBackstore) search(assertType *AssertionType, diskPattern []string, foundCb func(Assertion)) error {
 	assertTypeTop := filepath.Join(fsbs.top, assertType.Name)
 	candCb := func(diskPrimaryPath string) error {
@@ -156,5 +345,41 @@ func (fsbs *filesystemBackstore) Search(assertType *AssertionType, headers map[s
 			foundCb(a)
 		}
 	}
+
+	for _, h := range indexedHeaders {
+		v := headers[h]
+		if v == "" {
+			continue
+		}
+		return fsbs.searchIndex(assertType, h, v, diskPattern, candCb)
+	}
 	return fsbs.search(assertType, diskPattern, candCb)
 }
+
+// searchIndex looks up the candidates matching diskPattern among the
+// assertions indexed with header value v.
+func (fsbs *filesystemBackstore) searchIndex(assertType *AssertionType, header, v string, diskPattern []string, foundCb func(Assertion)) error {
+	indexTop := filepath.Join(fsbs.indexTop, assertType.Name, header)
+	indexPattern := append([]string{url.QueryEscape(v)}, diskPattern...)
+	candCb := func(indexPath string) error {
+		diskPrimaryPath, err := filepath.Rel(indexPattern[0], indexPath)
+		if err != nil {
+			return err
+		}
+		a, err := fsbs.readAssertion(assertType, diskPrimaryPath)
+		if err == ErrNotFound {
+			// dangling entry of an interrupted Put
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		foundCb(a)
+		return nil
+	}
+	err := findWildcard(indexTop, indexPattern, candCb)
+	if err != nil {
+		return fmt.Errorf("broken assertion storage, searching index for %s: %v", assertType.Name, err)
+	}
+	return nil
+}
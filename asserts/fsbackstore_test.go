@@ -20,6 +20,8 @@
 package asserts_test
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"syscall"
@@ -27,6 +29,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
 )
 
 type fsBackstoreSuite struct{}
@@ -102,3 +105,180 @@ func (fsbss *fsBackstoreSuite) TestPutOldRevision(c *C) {
 	c.Check(err, ErrorMatches, `revision 0 is older than current revision 1`)
 	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 1, Used: 0})
 }
+
+func (fsbss *fsBackstoreSuite) TestSearchIndexed(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	a1, err := asserts.Decode([]byte("type: test-only\n" +
+		"authority-id: auth-id1\n" +
+		"primary-key: foo\n" +
+		"snap-id: snap-id-1\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	a2, err := asserts.Decode([]byte("type: test-only\n" +
+		"authority-id: auth-id1\n" +
+		"primary-key: bar\n" +
+		"snap-id: snap-id-2\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	c.Assert(bs.Put(asserts.TestOnlyType, a1), IsNil)
+	c.Assert(bs.Put(asserts.TestOnlyType, a2), IsNil)
+
+	c.Check(osutil.FileExists(filepath.Join(topDir, "asserts-index-v0", "test-only", "snap-id", "snap-id-1", "foo", "active")), Equals, true)
+
+	var found []asserts.Assertion
+	foundCb := func(a asserts.Assertion) {
+		found = append(found, a)
+	}
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-1"}, foundCb)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Header("primary-key"), Equals, "foo")
+
+	found = nil
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-2", "primary-key": "foo"}, foundCb)
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+
+	found = nil
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-3"}, foundCb)
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+}
+
+func (fsbss *fsBackstoreSuite) TestSearchIndexFollowsPut(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	a0, err := asserts.Decode([]byte("type: test-only\n" +
+		"authority-id: auth-id1\n" +
+		"primary-key: foo\n" +
+		"snap-id: snap-id-1\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	a1, err := asserts.Decode([]byte("type: test-only\n" +
+		"authority-id: auth-id1\n" +
+		"primary-key: foo\n" +
+		"revision: 1\n" +
+		"snap-id: snap-id-2\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	c.Assert(bs.Put(asserts.TestOnlyType, a0), IsNil)
+	c.Assert(bs.Put(asserts.TestOnlyType, a1), IsNil)
+
+	c.Check(osutil.FileExists(filepath.Join(topDir, "asserts-index-v0", "test-only", "snap-id", "snap-id-1", "foo", "active")), Equals, false)
+
+	var found []asserts.Assertion
+	foundCb := func(a asserts.Assertion) {
+		found = append(found, a)
+	}
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-1"}, foundCb)
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-2"}, foundCb)
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Revision(), Equals, 1)
+}
+
+func (fsbss *fsBackstoreSuite) TestOpenBuildsIndex(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode([]byte("type: test-only-2\n" +
+		"authority-id: auth-id1\n" +
+		"pk1: a\n" +
+		"pk2: b\n" +
+		"brand-id: my-brand\n" +
+		"\n" +
+		"openpgp c2ln"))
+	c.Assert(err, IsNil)
+	c.Assert(bs.Put(asserts.TestOnly2Type, a), IsNil)
+
+	// simulate a store from before the index
+	indexTop := filepath.Join(topDir, "asserts-index-v0")
+	c.Assert(os.RemoveAll(indexTop), IsNil)
+
+	bs, err = asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(filepath.Join(indexTop, "test-only-2", "brand-id", "my-brand", "a", "b", "active")), Equals, true)
+
+	var found []asserts.Assertion
+	err = bs.Search(asserts.TestOnly2Type, map[string]string{"brand-id": "my-brand"}, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 1)
+}
+
+func (fsbss *fsBackstoreSuite) TestSearchSkipsDanglingIndexEntries(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	// as left behind by an interrupted Put
+	entry := filepath.Join(topDir, "asserts-index-v0", "test-only", "snap-id", "snap-id-1", "foo", "active")
+	c.Assert(os.MkdirAll(filepath.Dir(entry), 0775), IsNil)
+	c.Assert(ioutil.WriteFile(entry, nil, 0664), IsNil)
+
+	var found []asserts.Assertion
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"snap-id": "snap-id-1"}, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Check(found, HasLen, 0)
+}
+
+func (fsbss *fsBackstoreSuite) TestHistory(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	_, err = bs.History(asserts.TestOnlyType, []string{"foo"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	for rev := 0; rev < 7; rev++ {
+		a, err := asserts.Decode([]byte(fmt.Sprintf("type: test-only\n"+
+			"authority-id: auth-id1\n"+
+			"primary-key: foo\n"+
+			"revision: %d\n"+
+			"\n"+
+			"openpgp c2ln", rev)))
+		c.Assert(err, IsNil)
+		c.Assert(bs.Put(asserts.TestOnlyType, a), IsNil)
+	}
+
+	history, err := bs.History(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	revs := make([]int, len(history))
+	for i, a := range history {
+		revs[i] = a.Revision()
+	}
+	c.Check(revs, DeepEquals, []int{6, 5, 4, 3, 2})
+
+	// older revisions were pruned
+	dir := filepath.Join(topDir, "asserts-v0", "test-only", "foo")
+	c.Check(osutil.FileExists(filepath.Join(dir, "2")), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(dir, "1")), Equals, false)
+
+	a, err := bs.Get(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	c.Check(a.Revision(), Equals, 6)
+
+	var found []asserts.Assertion
+	err = bs.Search(asserts.TestOnlyType, map[string]string{"primary-key": "foo"}, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Revision(), Equals, 6)
+}
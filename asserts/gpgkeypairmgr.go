@@ -21,6 +21,7 @@ package asserts
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -53,11 +54,12 @@ func runGPGImpl(homedir string, input []byte, args ...string) ([]byte, error) {
 
 var runGPG = runGPGImpl
 
-type gpgKeypairManager struct {
+// A GPGKeypairManager is a key pair manager backed by a local GnuPG setup.
+type GPGKeypairManager struct {
 	homedir string
 }
 
-func (gkm *gpgKeypairManager) gpg(input []byte, args ...string) ([]byte, error) {
+func (gkm *GPGKeypairManager) gpg(input []byte, args ...string) ([]byte, error) {
 	return runGPG(gkm.homedir, input, args...)
 }
 
@@ -67,18 +69,18 @@ func (gkm *gpgKeypairManager) gpg(input []byte, args ...string) ([]byte, error)
 // Importing keys through the keypair manager interface is not
 // suppored.
 // Main purpose is allowing signing using keys from a GPG setup.
-func NewGPGKeypairManager(homedir string) KeypairManager {
-	return &gpgKeypairManager{
+func NewGPGKeypairManager(homedir string) *GPGKeypairManager {
+	return &GPGKeypairManager{
 		homedir: homedir,
 	}
 }
 
-func (gkm *gpgKeypairManager) Put(authorityID string, privKey PrivateKey) error {
+func (gkm *GPGKeypairManager) Put(authorityID string, privKey PrivateKey) error {
 	// NOTE: we don't need this initially at least and this keypair mgr is not for general arbitrary usage
 	return fmt.Errorf("cannot import private key into GPG keyring")
 }
 
-func (gkm *gpgKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
+func (gkm *GPGKeypairManager) Get(authorityID, keyID string) (PrivateKey, error) {
 	out, err := gkm.gpg(nil, "--batch", "--export", "--export-options", "export-minimal,export-clean,no-export-attributes", "0x"+keyID)
 	if err != nil {
 		return nil, err
@@ -99,10 +101,99 @@ func (gkm *gpgKeypairManager) Get(authorityID, keyID string) (PrivateKey, error)
 	return privKey, nil
 }
 
-func (gkm *gpgKeypairManager) sign(fingerprint string, content []byte) ([]byte, error) {
+func (gkm *GPGKeypairManager) sign(fingerprint string, content []byte) ([]byte, error) {
 	out, err := gkm.gpg(content, "--personal-digest-preferences", "SHA512", "--default-key", "0x"+fingerprint, "--detach-sign")
 	if err != nil {
 		return nil, fmt.Errorf("cannot sign using GPG: %v", err)
 	}
 	return out, nil
 }
+
+// Generate creates a new 4096-bit RSA key pair in the GPG keyring with
+// the given name as its user id.
+func (gkm *GPGKeypairManager) Generate(name string) error {
+	_, err := gkm.GetByName(name)
+	if err == nil {
+		return fmt.Errorf("key named %q already exists in GPG keyring", name)
+	}
+	generateParams := fmt.Sprintf(`Key-Type: RSA
+Key-Length: 4096
+Name-Real: %s
+%%no-protection
+%%commit
+`, name)
+	_, err = gkm.gpg([]byte(generateParams), "--batch", "--gen-key")
+	if err != nil {
+		return fmt.Errorf("cannot generate GPG key named %q: %v", name, err)
+	}
+	return nil
+}
+
+// Walk iterates over all the RSA private keys in the GPG keyring,
+// calling consider with each key, its fingerprint and its user id.
+func (gkm *GPGKeypairManager) Walk(consider func(privk PrivateKey, fingerprint string, uid string) error) error {
+	out, err := gkm.gpg(nil, "--batch", "--list-secret-keys", "--fixed-list-mode", "--with-colons", "--with-fingerprint")
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(out), "\n")
+	n := len(lines)
+	for i := 0; i < n; i++ {
+		// see GPG source doc/DETAILS
+		fields := strings.Split(lines[i], ":")
+		if fields[0] != "sec" || len(fields) < 5 {
+			continue
+		}
+		// only RSA keys are supported
+		if fields[3] != "1" {
+			continue
+		}
+		keyID := strings.ToLower(fields[4])
+		var fingerprint, uid string
+		for j := i + 1; j < n; j++ {
+			fields := strings.Split(lines[j], ":")
+			if fields[0] == "sec" || fields[0] == "ssb" {
+				break
+			}
+			if len(fields) < 10 {
+				continue
+			}
+			switch {
+			case fields[0] == "fpr" && fingerprint == "":
+				fingerprint = strings.ToLower(fields[9])
+			case fields[0] == "uid" && uid == "":
+				uid = fields[9]
+			}
+		}
+		privKey, err := gkm.Get("", keyID)
+		if err != nil {
+			return err
+		}
+		err = consider(privKey, fingerprint, uid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errStopWalk = errors.New("stop walking")
+
+// GetByName looks up a private key by the user id it was generated with.
+func (gkm *GPGKeypairManager) GetByName(name string) (PrivateKey, error) {
+	var privKey PrivateKey
+	err := gkm.Walk(func(privk PrivateKey, fingerprint string, uid string) error {
+		if uid == name {
+			privKey = privk
+			return errStopWalk
+		}
+		return nil
+	})
+	if err != nil && err != errStopWalk {
+		return nil, err
+	}
+	if privKey == nil {
+		return nil, fmt.Errorf("cannot find key named %q in GPG keyring", name)
+	}
+	return privKey, nil
+}
@@ -40,7 +40,7 @@ import (
 
 type gpgKeypairMgrSuite struct {
 	homedir    string
-	keypairMgr asserts.KeypairManager
+	keypairMgr *asserts.GPGKeypairManager
 }
 
 var _ = Suite(&gpgKeypairMgrSuite{})
@@ -408,3 +408,37 @@ func (gkms *gpgKeypairMgrSuite) TestUseInSigningFailure(c *C) {
 	_, err = signDB.Sign(asserts.SnapBuildType, headers, nil, testKeyID)
 	c.Check(err, ErrorMatches, "cannot sign assertion: cannot sign using GPG: boom")
 }
+
+func (gkms *gpgKeypairMgrSuite) TestWalk(c *C) {
+	type key struct {
+		keyID, fingerprint, uid string
+	}
+	var keys []key
+	err := gkms.keypairMgr.Walk(func(privk asserts.PrivateKey, fingerprint string, uid string) error {
+		keys = append(keys, key{privk.PublicKey().ID(), fingerprint, uid})
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Check(keys, DeepEquals, []key{{testKeyID, testKeyFingerprint, " (test)"}})
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGetByNameNotFound(c *C) {
+	_, err := gkms.keypairMgr.GetByName("missing")
+	c.Check(err, ErrorMatches, `cannot find key named "missing" in GPG keyring`)
+}
+
+func (gkms *gpgKeypairMgrSuite) TestGenerateAndGetByName(c *C) {
+	err := gkms.keypairMgr.Generate("my-key")
+	c.Assert(err, IsNil)
+
+	privKey, err := gkms.keypairMgr.GetByName("my-key")
+	c.Assert(err, IsNil)
+	c.Check(privKey.PublicKey().ID(), Not(Equals), testKeyID)
+
+	got, err := gkms.keypairMgr.Get("auth-id1", privKey.PublicKey().ID())
+	c.Assert(err, IsNil)
+	c.Check(got.PublicKey().Fingerprint(), Equals, privKey.PublicKey().Fingerprint())
+
+	err = gkms.keypairMgr.Generate("my-key")
+	c.Check(err, ErrorMatches, `key named "my-key" already exists in GPG keyring`)
+}
@@ -77,6 +77,18 @@ func checkInteger(headers map[string]string, name string, defl int) (int, error)
 	return value, nil
 }
 
+// false if missing
+func checkOptionalBool(headers map[string]string, name string) (bool, error) {
+	switch headers[name] {
+	case "", "false":
+		return false, nil
+	case "true":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%q header must be \"true\" or \"false\"", name)
+	}
+}
+
 func checkRFC3339Date(headers map[string]string, name string) (time.Time, error) {
 	dateStr, err := checkNotEmpty(headers, name)
 	if err != nil {
@@ -0,0 +1,268 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SnapConstraints restricts the snaps a plug or slot rule matches by
+// their type, snap id or publisher id. Empty lists match any value.
+type SnapConstraints struct {
+	SnapTypes    []string
+	SnapIDs      []string
+	PublisherIDs []string
+
+	never bool
+}
+
+var (
+	// AlwaysMatch matches all snaps, it is what a rule set to true stands for.
+	AlwaysMatch = &SnapConstraints{}
+	// NeverMatch matches no snap, it is what a rule set to false stands for.
+	NeverMatch = &SnapConstraints{never: true}
+)
+
+func matchesValue(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches returns whether a snap with the given type, snap id and
+// publisher id satisfies the constraints.
+func (c *SnapConstraints) Matches(snapType, snapID, publisherID string) bool {
+	if c.never {
+		return false
+	}
+	return matchesValue(c.SnapTypes, snapType) && matchesValue(c.SnapIDs, snapID) && matchesValue(c.PublisherIDs, publisherID)
+}
+
+// InterfaceRule holds the rules of a snap-declaration or of the base
+// declaration about the plugs or the slots of one interface.
+//
+// For plug rules the installation constraints are about the snap with
+// the plug and the connection constraints about the snap with the slot,
+// and the other way around for slot rules. A nil constraint is not set.
+type InterfaceRule struct {
+	Interface string
+
+	AllowInstallation *SnapConstraints
+	DenyInstallation  *SnapConstraints
+
+	AllowConnection *SnapConstraints
+	DenyConnection  *SnapConstraints
+
+	AllowAutoConnection *SnapConstraints
+	DenyAutoConnection  *SnapConstraints
+}
+
+// parseInterfaceRules parses the plug or slot rules given as a YAML map from
+// interface names to rules. side is "plug" or "slot".
+func parseInterfaceRules(text, side string) (map[string]*InterfaceRule, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("cannot parse %s rules: %v", side, err)
+	}
+	otherSide := "slot"
+	if side == "slot" {
+		otherSide = "plug"
+	}
+	rules := make(map[string]*InterfaceRule, len(raw))
+	for iface, v := range raw {
+		rule := &InterfaceRule{Interface: iface}
+		var m map[interface{}]interface{}
+		switch x := v.(type) {
+		case nil:
+		case map[interface{}]interface{}:
+			m = x
+		default:
+			return nil, fmt.Errorf("%s rule for interface %q must be a map", side, iface)
+		}
+		fields := []struct {
+			name   string
+			prefix string
+			dst    **SnapConstraints
+		}{
+			{"allow-installation", side, &rule.AllowInstallation},
+			{"deny-installation", side, &rule.DenyInstallation},
+			{"allow-connection", otherSide, &rule.AllowConnection},
+			{"deny-connection", otherSide, &rule.DenyConnection},
+			{"allow-auto-connection", otherSide, &rule.AllowAutoConnection},
+			{"deny-auto-connection", otherSide, &rule.DenyAutoConnection},
+		}
+		known := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			known[field.name] = true
+			value, ok := m[field.name]
+			if !ok {
+				continue
+			}
+			constraints, err := parseSnapConstraints(value, field.prefix)
+			if err != nil {
+				return nil, fmt.Errorf("%s rule %s for interface %q: %v", side, field.name, iface, err)
+			}
+			*field.dst = constraints
+		}
+		for k := range m {
+			if name, ok := k.(string); !ok || !known[name] {
+				return nil, fmt.Errorf("%s rule for interface %q has unknown entry %v", side, iface, k)
+			}
+		}
+		rules[iface] = rule
+	}
+	return rules, nil
+}
+
+func parseSnapConstraints(value interface{}, prefix string) (*SnapConstraints, error) {
+	switch x := value.(type) {
+	case bool:
+		if x {
+			return AlwaysMatch, nil
+		}
+		return NeverMatch, nil
+	case string:
+		switch x {
+		case "true":
+			return AlwaysMatch, nil
+		case "false":
+			return NeverMatch, nil
+		}
+	case map[interface{}]interface{}:
+		c := &SnapConstraints{}
+		fields := map[string]*[]string{
+			prefix + "-snap-type":    &c.SnapTypes,
+			prefix + "-snap-id":      &c.SnapIDs,
+			prefix + "-publisher-id": &c.PublisherIDs,
+		}
+		for k, v := range x {
+			name, _ := k.(string)
+			dst, ok := fields[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown constraint %v", k)
+			}
+			list, err := parseStringList(v)
+			if err != nil {
+				return nil, fmt.Errorf("constraint %s: %v", name, err)
+			}
+			*dst = list
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("must be true, false or a map of constraints")
+}
+
+func parseStringList(value interface{}) ([]string, error) {
+	switch x := value.(type) {
+	case string:
+		return []string{x}, nil
+	case []interface{}:
+		list := make([]string, len(x))
+		for i, item := range x {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("must be a list of strings")
+			}
+			list[i] = s
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("must be a list of strings")
+}
+
+// interfaceRules holds the plug and slot rules of a declaration by interface.
+type interfaceRules struct {
+	plugRules map[string]*InterfaceRule
+	slotRules map[string]*InterfaceRule
+}
+
+func parseInterfaceRulesHeaders(headers map[string]string) (*interfaceRules, error) {
+	rules := &interfaceRules{}
+	var err error
+	if text, ok := headers["plugs"]; ok {
+		rules.plugRules, err = parseInterfaceRules(text, "plug")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if text, ok := headers["slots"]; ok {
+		rules.slotRules, err = parseInterfaceRules(text, "slot")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// PlugRule returns the rule for the plugs of the given interface, or nil.
+func (r *interfaceRules) PlugRule(interfaceName string) *InterfaceRule {
+	return r.plugRules[interfaceName]
+}
+
+// SlotRule returns the rule for the slots of the given interface, or nil.
+func (r *interfaceRules) SlotRule(interfaceName string) *InterfaceRule {
+	return r.slotRules[interfaceName]
+}
+
+// PlugInterfaces returns the sorted names of the interfaces with plug rules.
+func (r *interfaceRules) PlugInterfaces() []string {
+	return sortedRuleNames(r.plugRules)
+}
+
+// SlotInterfaces returns the sorted names of the interfaces with slot rules.
+func (r *interfaceRules) SlotInterfaces() []string {
+	return sortedRuleNames(r.slotRules)
+}
+
+func sortedRuleNames(rules map[string]*InterfaceRule) []string {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BaseDeclaration holds the plug and slot rules that apply to all snaps
+// for the interfaces their snap-declaration has no rules about.
+type BaseDeclaration struct {
+	interfaceRules
+}
+
+// NewBaseDeclaration builds a base declaration from the YAML text of its
+// plug and slot rules, in the same format as the plugs and slots headers
+// of snap-declaration.
+func NewBaseDeclaration(plugs, slots string) (*BaseDeclaration, error) {
+	headers := map[string]string{"plugs": plugs, "slots": slots}
+	rules, err := parseInterfaceRulesHeaders(headers)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build base declaration: %v", err)
+	}
+	return &BaseDeclaration{*rules}, nil
+}
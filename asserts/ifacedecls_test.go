@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+var _ = Suite(&ifaceDeclSuite{})
+
+type ifaceDeclSuite struct {
+	tsLine string
+}
+
+func (s *ifaceDeclSuite) SetUpSuite(c *C) {
+	s.tsLine = "timestamp: " + time.Now().Truncate(time.Second).UTC().Format(time.RFC3339) + "\n"
+}
+
+func (s *ifaceDeclSuite) snapDeclaration(rules string) string {
+	return "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"snap-name: first\n" +
+		"publisher-id: dev-id1\n" +
+		"gates: \n" +
+		rules +
+		s.tsLine +
+		"body-length: 0" +
+		"\n\n" +
+		"openpgp c2ln"
+}
+
+func (s *ifaceDeclSuite) TestSnapDeclarationRules(c *C) {
+	encoded := s.snapDeclaration("plugs:\n" +
+		" snapd-control:\n" +
+		"   allow-installation: true\n" +
+		"   allow-auto-connection:\n" +
+		"     slot-snap-type:\n" +
+		"       - os\n" +
+		"slots:\n" +
+		" network-manager:\n" +
+		"   allow-installation: true\n" +
+		"   deny-connection:\n" +
+		"     plug-publisher-id: [evil-id]\n" +
+		"   deny-auto-connection: false\n")
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+
+	c.Check(snapDecl.PlugInterfaces(), DeepEquals, []string{"snapd-control"})
+	c.Check(snapDecl.SlotInterfaces(), DeepEquals, []string{"network-manager"})
+	c.Check(snapDecl.PlugRule("network-manager"), IsNil)
+
+	plugRule := snapDecl.PlugRule("snapd-control")
+	c.Assert(plugRule, NotNil)
+	c.Check(plugRule.Interface, Equals, "snapd-control")
+	c.Check(plugRule.AllowInstallation, Equals, asserts.AlwaysMatch)
+	c.Check(plugRule.DenyInstallation, IsNil)
+	c.Check(plugRule.AllowAutoConnection.SnapTypes, DeepEquals, []string{"os"})
+	c.Check(plugRule.AllowAutoConnection.Matches("os", "", ""), Equals, true)
+	c.Check(plugRule.AllowAutoConnection.Matches("app", "", ""), Equals, false)
+
+	slotRule := snapDecl.SlotRule("network-manager")
+	c.Assert(slotRule, NotNil)
+	c.Check(slotRule.DenyConnection.PublisherIDs, DeepEquals, []string{"evil-id"})
+	c.Check(slotRule.DenyConnection.Matches("app", "snap-id-2", "evil-id"), Equals, true)
+	c.Check(slotRule.DenyConnection.Matches("app", "snap-id-2", "good-id"), Equals, false)
+	c.Check(slotRule.DenyAutoConnection, Equals, asserts.NeverMatch)
+	c.Check(slotRule.DenyAutoConnection.Matches("app", "", ""), Equals, false)
+}
+
+func (s *ifaceDeclSuite) TestSnapDeclarationWithoutRules(c *C) {
+	a, err := asserts.Decode([]byte(s.snapDeclaration("")))
+	c.Assert(err, IsNil)
+	snapDecl := a.(*asserts.SnapDeclaration)
+	c.Check(snapDecl.PlugInterfaces(), HasLen, 0)
+	c.Check(snapDecl.SlotRule("network-manager"), IsNil)
+}
+
+func (s *ifaceDeclSuite) TestSnapDeclarationInvalidRules(c *C) {
+	invalidTests := []struct{ rules, expectedErr string }{
+		{"plugs: foo\n", `(?s)cannot parse plug rules: .*`},
+		{"plugs:\n iface: foo\n", `plug rule for interface "iface" must be a map`},
+		{"plugs:\n iface:\n   allow-something: true\n", `plug rule for interface "iface" has unknown entry allow-something`},
+		{"plugs:\n iface:\n   allow-connection: maybe\n", `plug rule allow-connection for interface "iface": must be true, false or a map of constraints`},
+		{"plugs:\n iface:\n   allow-connection:\n     plug-snap-type: app\n", `plug rule allow-connection for interface "iface": unknown constraint plug-snap-type`},
+		{"slots:\n iface:\n   allow-installation:\n     slot-snap-id: [1]\n", `slot rule allow-installation for interface "iface": constraint slot-snap-id: must be a list of strings`},
+	}
+
+	for _, test := range invalidTests {
+		_, err := asserts.Decode([]byte(s.snapDeclaration(test.rules)))
+		c.Check(err, ErrorMatches, snapDeclErrPrefix+test.expectedErr, Commentf(strings.TrimSpace(test.rules)))
+	}
+}
+
+func (s *ifaceDeclSuite) TestBaseDeclaration(c *C) {
+	baseDecl, err := asserts.NewBaseDeclaration(`
+snapd-control:
+  deny-auto-connection: true
+`, `
+bluez:
+  allow-installation:
+    slot-snap-type: [os]
+`)
+	c.Assert(err, IsNil)
+	c.Check(baseDecl.PlugRule("snapd-control").DenyAutoConnection, Equals, asserts.AlwaysMatch)
+	c.Check(baseDecl.SlotRule("bluez").AllowInstallation.SnapTypes, DeepEquals, []string{"os"})
+	c.Check(baseDecl.SlotRule("snapd-control"), IsNil)
+
+	_, err = asserts.NewBaseDeclaration("", "bluez: [1]")
+	c.Check(err, ErrorMatches, `cannot build base declaration: slot rule for interface "bluez" must be a map`)
+}
@@ -30,13 +30,15 @@ type memoryBackstore struct {
 
 type memBSNode interface {
 	put(key []string, assert Assertion) error
-	get(key []string) (Assertion, error)
+	get(key []string) ([]Assertion, error)
 	search(hint []string, found func(Assertion))
 }
 
 type memBSBranch map[string]memBSNode
 
-type memBSLeaf map[string]Assertion
+// memBSLeaf holds the retained revisions of each assertion, most
+// recent first
+type memBSLeaf map[string][]Assertion
 
 func (br memBSBranch) put(key []string, assert Assertion) error {
 	key0 := key[0]
@@ -54,19 +56,22 @@ func (br memBSBranch) put(key []string, assert Assertion) error {
 
 func (leaf memBSLeaf) put(key []string, assert Assertion) error {
 	key0 := key[0]
-	cur := leaf[key0]
-	if cur != nil {
+	history := leaf[key0]
+	if len(history) != 0 {
 		rev := assert.Revision()
-		curRev := cur.Revision()
+		curRev := history[0].Revision()
 		if curRev >= rev {
 			return &RevisionError{Current: curRev, Used: rev}
 		}
 	}
-	leaf[key0] = assert
+	if len(history) == maxRetainedRevisions {
+		history = history[:maxRetainedRevisions-1]
+	}
+	leaf[key0] = append([]Assertion{assert}, history...)
 	return nil
 }
 
-func (br memBSBranch) get(key []string) (Assertion, error) {
+func (br memBSBranch) get(key []string) ([]Assertion, error) {
 	key0 := key[0]
 	down := br[key0]
 	if down == nil {
@@ -75,13 +80,13 @@ func (br memBSBranch) get(key []string) (Assertion, error) {
 	return down.get(key[1:])
 }
 
-func (leaf memBSLeaf) get(key []string) (Assertion, error) {
+func (leaf memBSLeaf) get(key []string) ([]Assertion, error) {
 	key0 := key[0]
-	cur := leaf[key0]
-	if cur == nil {
+	history := leaf[key0]
+	if len(history) == 0 {
 		return nil, ErrNotFound
 	}
-	return cur, nil
+	return history, nil
 }
 
 func (br memBSBranch) search(hint []string, found func(Assertion)) {
@@ -102,15 +107,15 @@ func (br memBSBranch) search(hint []string, found func(Assertion)) {
 func (leaf memBSLeaf) search(hint []string, found func(Assertion)) {
 	hint0 := hint[0]
 	if hint0 == "" {
-		for _, a := range leaf {
-			found(a)
+		for _, history := range leaf {
+			found(history[0])
 		}
 		return
 	}
 
-	cur := leaf[hint0]
-	if cur != nil {
-		found(cur)
+	history := leaf[hint0]
+	if len(history) != 0 {
+		found(history[0])
 	}
 }
 
@@ -135,10 +140,7 @@ func (mbs *memoryBackstore) Put(assertType *AssertionType, assert Assertion) err
 	return err
 }
 
-func (mbs *memoryBackstore) Get(assertType *AssertionType, key []string) (Assertion, error) {
-	mbs.mu.RLock()
-	defer mbs.mu.RUnlock()
-
+func (mbs *memoryBackstore) get(assertType *AssertionType, key []string) ([]Assertion, error) {
 	internalKey := make([]string, 1+len(assertType.PrimaryKey))
 	internalKey[0] = assertType.Name
 	copy(internalKey[1:], key)
@@ -146,6 +148,30 @@ func (mbs *memoryBackstore) Get(assertType *AssertionType, key []string) (Assert
 	return mbs.top.get(internalKey)
 }
 
+func (mbs *memoryBackstore) Get(assertType *AssertionType, key []string) (Assertion, error) {
+	mbs.mu.RLock()
+	defer mbs.mu.RUnlock()
+
+	history, err := mbs.get(assertType, key)
+	if err != nil {
+		return nil, err
+	}
+	return history[0], nil
+}
+
+func (mbs *memoryBackstore) History(assertType *AssertionType, key []string) ([]Assertion, error) {
+	mbs.mu.RLock()
+	defer mbs.mu.RUnlock()
+
+	history, err := mbs.get(assertType, key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Assertion, len(history))
+	copy(res, history)
+	return res, nil
+}
+
 func (mbs *memoryBackstore) Search(assertType *AssertionType, headers map[string]string, foundCb func(Assertion)) error {
 	mbs.mu.RLock()
 	defer mbs.mu.RUnlock()
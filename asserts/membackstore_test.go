@@ -20,6 +20,8 @@
 package asserts_test
 
 import (
+	"fmt"
+
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
@@ -196,3 +198,39 @@ func (mbss *memBackstoreSuite) TestPutOldRevision(c *C) {
 	c.Check(err, ErrorMatches, `revision 0 is older than current revision 1`)
 	c.Check(err, DeepEquals, &asserts.RevisionError{Current: 1, Used: 0})
 }
+
+func (mbss *memBackstoreSuite) TestHistory(c *C) {
+	_, err := mbss.bs.History(asserts.TestOnlyType, []string{"foo"})
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	for rev := 0; rev < 7; rev++ {
+		a, err := asserts.Decode([]byte(fmt.Sprintf("type: test-only\n"+
+			"authority-id: auth-id1\n"+
+			"primary-key: foo\n"+
+			"revision: %d\n"+
+			"\n"+
+			"openpgp c2ln", rev)))
+		c.Assert(err, IsNil)
+		c.Assert(mbss.bs.Put(asserts.TestOnlyType, a), IsNil)
+	}
+
+	history, err := mbss.bs.History(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	revs := make([]int, len(history))
+	for i, a := range history {
+		revs[i] = a.Revision()
+	}
+	c.Check(revs, DeepEquals, []int{6, 5, 4, 3, 2})
+
+	a, err := mbss.bs.Get(asserts.TestOnlyType, []string{"foo"})
+	c.Assert(err, IsNil)
+	c.Check(a.Revision(), Equals, 6)
+
+	var found []asserts.Assertion
+	err = mbss.bs.Search(asserts.TestOnlyType, map[string]string{"primary-key": "foo"}, func(a asserts.Assertion) {
+		found = append(found, a)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(found, HasLen, 1)
+	c.Check(found[0].Revision(), Equals, 6)
+}
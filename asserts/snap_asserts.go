@@ -20,14 +20,20 @@
 package asserts
 
 import (
+	"fmt"
 	"time"
 )
 
 // SnapDeclaration holds a snap-declaration assertion, declaring a
 // snap binding its identifying snap-id to a name, asserting its
 // publisher and its other properties.
+//
+// The optional plugs and slots headers hold the rules, as a YAML map from
+// interface names, that decide whether the plugs and slots of the snap
+// can be installed and connected, overriding those of the base declaration.
 type SnapDeclaration struct {
 	assertionBase
+	interfaceRules
 	gates     []string
 	timestamp time.Time
 }
@@ -52,6 +58,14 @@ func (snapdcl *SnapDeclaration) PublisherID() string {
 	return snapdcl.Header("publisher-id")
 }
 
+// Prerequisites returns references to the account of the publisher of the
+// declared snap.
+func (snapdcl *SnapDeclaration) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: AccountType, PrimaryKey: []string{snapdcl.PublisherID()}},
+	}
+}
+
 // Gates returns the list of snap-ids gated by this snap.
 func (snapdcl *SnapDeclaration) Gates() []string {
 	return snapdcl.gates
@@ -85,10 +99,16 @@ func assembleSnapDeclaration(assert assertionBase) (Assertion, error) {
 		return nil, err
 	}
 
+	rules, err := parseInterfaceRulesHeaders(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
 	return &SnapDeclaration{
-		assertionBase: assert,
-		gates:         gates,
-		timestamp:     timestamp,
+		assertionBase:  assert,
+		interfaceRules: *rules,
+		gates:          gates,
+		timestamp:      timestamp,
 	}, nil
 }
 
@@ -131,6 +151,13 @@ func (snapbld *SnapBuild) Timestamp() time.Time {
 	return snapbld.timestamp
 }
 
+// Prerequisites returns references to the declaration of the snap.
+func (snapbld *SnapBuild) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{snapbld.Series(), snapbld.SnapID()}},
+	}
+}
+
 func assembleSnapBuild(assert assertionBase) (Assertion, error) {
 	// TODO: more parsing/checking of snap-digest
 
@@ -204,6 +231,15 @@ func (snaprev *SnapRevision) Timestamp() time.Time {
 	return snaprev.timestamp
 }
 
+// Prerequisites returns references to the declaration of the snap and to the
+// account of its developer.
+func (snaprev *SnapRevision) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{snaprev.Series(), snaprev.SnapID()}},
+		{Type: AccountType, PrimaryKey: []string{snaprev.DeveloperID()}},
+	}
+}
+
 // Implement further consistency checks.
 func (snaprev *SnapRevision) checkConsistency(db RODatabase, acck *AccountKey) error {
 	return nil
@@ -242,3 +278,103 @@ func assembleSnapRevision(assert assertionBase) (Assertion, error) {
 		timestamp:     timestamp,
 	}, nil
 }
+
+// Validation holds a validation assertion, by which the publisher of a
+// gating snap approves, or revokes the approval of, a revision of one of
+// the snaps it gates.
+type Validation struct {
+	assertionBase
+	revoked              bool
+	approvedSnapRevision uint64
+	timestamp            time.Time
+}
+
+// Series returns the series for which the validation holds.
+func (validation *Validation) Series() string {
+	return validation.Header("series")
+}
+
+// SnapID returns the snap id of the gating snap.
+func (validation *Validation) SnapID() string {
+	return validation.Header("snap-id")
+}
+
+// ApprovedSnapID returns the snap id of the gated snap.
+func (validation *Validation) ApprovedSnapID() string {
+	return validation.Header("approved-snap-id")
+}
+
+// ApprovedSnapRevision returns the approved revision of the gated snap.
+func (validation *Validation) ApprovedSnapRevision() uint64 {
+	return validation.approvedSnapRevision
+}
+
+// Revoked returns whether the approval has been revoked.
+func (validation *Validation) Revoked() bool {
+	return validation.revoked
+}
+
+// Timestamp returns the time when the validation was issued.
+func (validation *Validation) Timestamp() time.Time {
+	return validation.timestamp
+}
+
+// Prerequisites returns references to the declarations of the gating and
+// of the gated snap.
+func (validation *Validation) Prerequisites() []*Ref {
+	return []*Ref{
+		{Type: SnapDeclarationType, PrimaryKey: []string{validation.Series(), validation.SnapID()}},
+		{Type: SnapDeclarationType, PrimaryKey: []string{validation.Series(), validation.ApprovedSnapID()}},
+	}
+}
+
+// Implement further consistency checks.
+func (validation *Validation) checkConsistency(db RODatabase, acck *AccountKey) error {
+	a, err := db.Find(SnapDeclarationType, map[string]string{
+		"series":  validation.Series(),
+		"snap-id": validation.SnapID(),
+	})
+	if err == ErrNotFound {
+		return fmt.Errorf("no snap-declaration for the gating snap %q", validation.SnapID())
+	}
+	if err != nil {
+		return err
+	}
+	gating := a.(*SnapDeclaration)
+	if gating.PublisherID() != validation.AuthorityID() {
+		return fmt.Errorf("validation must be signed by the publisher of the gating snap %q (%q), not %q", gating.SnapName(), gating.PublisherID(), validation.AuthorityID())
+	}
+	for _, gated := range gating.Gates() {
+		if gated == validation.ApprovedSnapID() {
+			return nil
+		}
+	}
+	return fmt.Errorf("snap %q does not gate snap %q", gating.SnapName(), validation.ApprovedSnapID())
+}
+
+// sanity
+var _ consistencyChecker = (*Validation)(nil)
+
+func assembleValidation(assert assertionBase) (Assertion, error) {
+	approvedSnapRevision, err := checkUint(assert.headers, "approved-snap-revision", 64)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := checkOptionalBool(assert.headers, "revoked")
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validation{
+		assertionBase:        assert,
+		revoked:              revoked,
+		approvedSnapRevision: approvedSnapRevision,
+		timestamp:            timestamp,
+	}, nil
+}
@@ -33,6 +33,7 @@ var (
 	_ = Suite(&snapDeclSuite{})
 	_ = Suite(&snapBuildSuite{})
 	_ = Suite(&snapRevSuite{})
+	_ = Suite(&validationSuite{})
 )
 
 type snapDeclSuite struct {
@@ -68,6 +69,9 @@ func (sds *snapDeclSuite) TestDecodeOK(c *C) {
 	c.Check(snapDecl.SnapName(), Equals, "first")
 	c.Check(snapDecl.PublisherID(), Equals, "dev-id1")
 	c.Check(snapDecl.Gates(), DeepEquals, []string{"snap-id-3", "snap-id-4"})
+	c.Check(snapDecl.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"dev-id1"}},
+	})
 }
 
 func (sds *snapDeclSuite) TestEmptySnapName(c *C) {
@@ -161,6 +165,9 @@ func (sbs *snapBuildSuite) TestDecodeOK(c *C) {
 	c.Check(snapBuild.SnapDigest(), Equals, "sha256 ...")
 	c.Check(snapBuild.SnapSize(), Equals, uint64(10000))
 	c.Check(snapBuild.Grade(), Equals, "stable")
+	c.Check(snapBuild.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", "snap-id-1"}},
+	})
 }
 
 const (
@@ -350,6 +357,10 @@ func (srs *snapRevSuite) TestDecodeOK(c *C) {
 	c.Check(snapRev.SnapRevision(), Equals, uint64(1))
 	c.Check(snapRev.DeveloperID(), Equals, "dev-id1")
 	c.Check(snapRev.Revision(), Equals, 1)
+	c.Check(snapRev.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", "snap-id-1"}},
+		{Type: asserts.AccountType, PrimaryKey: []string{"dev-id1"}},
+	})
 }
 
 const (
@@ -427,3 +438,150 @@ func (srs *snapRevSuite) TestPrimaryKey(c *C) {
 	})
 	c.Assert(err, IsNil)
 }
+
+type validationSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+func (vs *validationSuite) SetUpSuite(c *C) {
+	vs.ts = time.Now().Truncate(time.Second).UTC()
+	vs.tsLine = "timestamp: " + vs.ts.Format(time.RFC3339) + "\n"
+}
+
+func (vs *validationSuite) makeValidEncoded() string {
+	return "type: validation\n" +
+		"authority-id: dev-id1\n" +
+		"series: 16\n" +
+		"snap-id: snap-id-1\n" +
+		"approved-snap-id: snap-id-2\n" +
+		"approved-snap-revision: 42\n" +
+		"revision: 1\n" +
+		vs.tsLine +
+		"body-length: 0" +
+		"\n\n" +
+		"openpgp c2ln"
+}
+
+func (vs *validationSuite) makeHeaders(overrides map[string]string) map[string]string {
+	headers := map[string]string{
+		"authority-id":           "dev-id1",
+		"series":                 "16",
+		"snap-id":                "snap-id-1",
+		"approved-snap-id":       "snap-id-2",
+		"approved-snap-revision": "42",
+		"timestamp":              "2015-11-25T20:00:00Z",
+	}
+	for k, v := range overrides {
+		headers[k] = v
+	}
+	return headers
+}
+
+func (vs *validationSuite) TestDecodeOK(c *C) {
+	encoded := vs.makeValidEncoded()
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ValidationType)
+	validation := a.(*asserts.Validation)
+	c.Check(validation.AuthorityID(), Equals, "dev-id1")
+	c.Check(validation.Timestamp(), Equals, vs.ts)
+	c.Check(validation.Series(), Equals, "16")
+	c.Check(validation.SnapID(), Equals, "snap-id-1")
+	c.Check(validation.ApprovedSnapID(), Equals, "snap-id-2")
+	c.Check(validation.ApprovedSnapRevision(), Equals, uint64(42))
+	c.Check(validation.Revoked(), Equals, false)
+	c.Check(validation.Revision(), Equals, 1)
+	c.Check(validation.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", "snap-id-1"}},
+		{Type: asserts.SnapDeclarationType, PrimaryKey: []string{"16", "snap-id-2"}},
+	})
+}
+
+func (vs *validationSuite) TestDecodeRevoked(c *C) {
+	encoded := strings.Replace(vs.makeValidEncoded(), vs.tsLine, vs.tsLine+"revoked: true\n", 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.Validation).Revoked(), Equals, true)
+}
+
+const (
+	validationErrPrefix = "assertion validation: "
+)
+
+func (vs *validationSuite) TestDecodeInvalid(c *C) {
+	encoded := vs.makeValidEncoded()
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"snap-id: snap-id-1\n", "", `"snap-id" header is mandatory`},
+		{"approved-snap-id: snap-id-2\n", "", `"approved-snap-id" header is mandatory`},
+		{"approved-snap-revision: 42\n", "", `"approved-snap-revision" header is mandatory`},
+		{"approved-snap-revision: 42\n", "approved-snap-revision: z\n", `"approved-snap-revision" header is not an unsigned integer: z`},
+		{vs.tsLine, vs.tsLine + "revoked: maybe\n", `"revoked" header must be "true" or "false"`},
+		{vs.tsLine, "", `"timestamp" header is mandatory`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, validationErrPrefix+test.expectedErr)
+	}
+}
+
+func (vs *validationSuite) addGatingDecl(c *C, db *asserts.Database, publisherID, gates string) {
+	headers := map[string]string{
+		"authority-id": "canonical",
+		"series":       "16",
+		"snap-id":      "snap-id-1",
+		"snap-name":    "gating",
+		"publisher-id": publisherID,
+		"gates":        gates,
+		"timestamp":    "2015-11-25T20:00:00Z",
+	}
+	snapDecl, err := asserts.AssembleAndSignInTest(asserts.SnapDeclarationType, headers, nil, testPrivKey0)
+	c.Assert(err, IsNil)
+	c.Assert(db.Add(snapDecl), IsNil)
+}
+
+func (vs *validationSuite) TestValidationCheck(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id1", "snap-id-2")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, IsNil)
+}
+
+func (vs *validationSuite) TestValidationCheckNoGatingDecl(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: no snap-declaration for the gating snap "snap-id-1"`)
+}
+
+func (vs *validationSuite) TestValidationCheckNotByPublisher(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id2", "snap-id-2")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: validation must be signed by the publisher of the gating snap "gating" \("dev-id2"\), not "dev-id1"`)
+}
+
+func (vs *validationSuite) TestValidationCheckNotGated(c *C) {
+	signingKeyID, accSignDB, db := makeSignAndCheckDbWithAccountKey(c, "dev-id1")
+	vs.addGatingDecl(c, db, "dev-id1", "snap-id-3")
+
+	validation, err := accSignDB.Sign(asserts.ValidationType, vs.makeHeaders(nil), nil, signingKeyID)
+	c.Assert(err, IsNil)
+
+	err = db.Check(validation)
+	c.Assert(err, ErrorMatches, `validation assertion violates other knowledge: snap "gating" does not gate snap "snap-id-2"`)
+}
@@ -28,19 +28,84 @@ import (
 	"strconv"
 
 	"github.com/snapcore/snapd/asserts" // for parsing
+	"github.com/snapcore/snapd/snap"
 )
 
-// Ack tries to add an assertion to the system assertion
-// database. To succeed the assertion must be valid, its signature
-// verified with a known public key and the assertion consistent with
-// and its prerequisite in the database.
-func (client *Client) Ack(b []byte) error {
-	var rsp interface{}
-	if _, err := client.doSync("POST", "/v2/assertions", nil, nil, bytes.NewReader(b), &rsp); err != nil {
-		return fmt.Errorf("cannot assert: %v", err)
+// Outcomes of adding an assertion with Ack.
+const (
+	AckAdded                = "added"
+	AckAlreadyPresent       = "already-present"
+	AckNewerRevisionPresent = "newer-revision-present"
+)
+
+// AckResult holds the outcome of adding one assertion with Ack.
+type AckResult struct {
+	Type       string   `json:"type"`
+	PrimaryKey []string `json:"primary-key"`
+	Result     string   `json:"result"`
+}
+
+// Ack tries to add a stream of assertions to the system assertion
+// database. To succeed each assertion must be valid, its signature
+// verified with a known public key or one of the stream, and the
+// assertion consistent with and its prerequisite in the database.
+// The assertions are added each after its prerequisites, and none is
+// if any signature fails verification. The outcome for each assertion
+// is returned in the order they were added in.
+func (client *Client) Ack(b []byte) ([]*AckResult, error) {
+	var results []*AckResult
+	if _, err := client.doSync("POST", "/v2/assertions", nil, nil, bytes.NewReader(b), &results); err != nil {
+		return nil, fmt.Errorf("cannot assert: %v", err)
+	}
+
+	return results, nil
+}
+
+// Validation describes a validation, by a gating snap, of a revision of a
+// snap it gates.
+type Validation struct {
+	GatingSnapID     string        `json:"gating-snap-id"`
+	GatingSnap       string        `json:"gating-snap,omitempty"`
+	ApprovedSnapID   string        `json:"approved-snap-id"`
+	ApprovedSnap     string        `json:"approved-snap,omitempty"`
+	ApprovedRevision snap.Revision `json:"approved-revision"`
+	Revoked          bool          `json:"revoked,omitempty"`
+}
+
+// Validations returns the validations of revisions of gated snaps known
+// to the system.
+func (client *Client) Validations() ([]*Validation, error) {
+	var validations []*Validation
+	if _, err := client.doSync("GET", "/v2/validations", nil, nil, nil, &validations); err != nil {
+		return nil, fmt.Errorf("cannot list validations: %v", err)
 	}
+	return validations, nil
+}
 
-	return nil
+// Model describes the model of the device, as given by its model
+// assertion, and its serial if registered.
+type Model struct {
+	BrandID       string   `json:"brand-id"`
+	Model         string   `json:"model"`
+	Series        string   `json:"series"`
+	OS            string   `json:"os"`
+	Architecture  string   `json:"architecture"`
+	Gadget        string   `json:"gadget"`
+	Kernel        string   `json:"kernel"`
+	Store         string   `json:"store"`
+	Class         string   `json:"class"`
+	AllowedModes  []string `json:"allowed-modes,omitempty"`
+	RequiredSnaps []string `json:"required-snaps,omitempty"`
+	Serial        string   `json:"serial,omitempty"`
+}
+
+// Model returns the model of the device.
+func (client *Client) Model() (*Model, error) {
+	var model Model
+	if _, err := client.doSync("GET", "/v2/model", nil, nil, nil, &model); err != nil {
+		return nil, fmt.Errorf("cannot get the device model: %v", err)
+	}
+	return &model, nil
 }
 
 // Known queries assertions with type assertTypeName and matching assertion headers.
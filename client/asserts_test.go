@@ -28,21 +28,83 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
 )
 
 func (cs *clientSuite) TestClientAssert(c *C) {
 	cs.rsp = `{
 		"type": "sync",
-		"result": {}
+		"result": [
+			{"type": "account", "primary-key": ["dev-id"], "result": "already-present"},
+			{"type": "account-key", "primary-key": ["dev-id", "abcd"], "result": "added"}
+		]
 	}`
 	a := []byte("Assertion.")
-	err := cs.cli.Ack(a)
+	results, err := cs.cli.Ack(a)
 	c.Assert(err, IsNil)
 	body, err := ioutil.ReadAll(cs.req.Body)
 	c.Assert(err, IsNil)
 	c.Check(body, DeepEquals, a)
 	c.Check(cs.req.Method, Equals, "POST")
 	c.Check(cs.req.URL.Path, Equals, "/v2/assertions")
+	c.Check(results, DeepEquals, []*client.AckResult{
+		{Type: "account", PrimaryKey: []string{"dev-id"}, Result: client.AckAlreadyPresent},
+		{Type: "account-key", PrimaryKey: []string{"dev-id", "abcd"}, Result: client.AckAdded},
+	})
+}
+
+func (cs *clientSuite) TestClientValidations(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [
+			{"gating-snap-id": "gating-id", "gating-snap": "gating", "approved-snap-id": "gated-id", "approved-revision": "7"},
+			{"gating-snap-id": "gating-id", "approved-snap-id": "gated-id", "approved-revision": "8", "revoked": true}
+		]
+	}`
+	validations, err := cs.cli.Validations()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/validations")
+	c.Check(validations, DeepEquals, []*client.Validation{
+		{GatingSnapID: "gating-id", GatingSnap: "gating", ApprovedSnapID: "gated-id", ApprovedRevision: snap.R(7)},
+		{GatingSnapID: "gating-id", ApprovedSnapID: "gated-id", ApprovedRevision: snap.R(8), Revoked: true},
+	})
+}
+
+func (cs *clientSuite) TestClientModel(c *C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"brand-id": "my-brand", "model": "my-model", "series": "16",
+			"os": "core", "architecture": "amd64", "gadget": "my-gadget",
+			"kernel": "my-kernel", "store": "my-brand-store", "class": "fixed",
+			"required-snaps": ["foo", "bar"], "serial": "9999"
+		}
+	}`
+	model, err := cs.cli.Model()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "GET")
+	c.Check(cs.req.URL.Path, Equals, "/v2/model")
+	c.Check(model, DeepEquals, &client.Model{
+		BrandID:       "my-brand",
+		Model:         "my-model",
+		Series:        "16",
+		OS:            "core",
+		Architecture:  "amd64",
+		Gadget:        "my-gadget",
+		Kernel:        "my-kernel",
+		Store:         "my-brand-store",
+		Class:         "fixed",
+		RequiredSnaps: []string{"foo", "bar"},
+		Serial:        "9999",
+	})
+}
+
+func (cs *clientSuite) TestClientModelError(c *C) {
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "no model assertion known for this device"}}`
+	_, err := cs.cli.Model()
+	c.Check(err, ErrorMatches, "cannot get the device model: no model assertion known for this device")
 }
 
 func (cs *clientSuite) TestClientAssertsCallsEndpoint(c *C) {
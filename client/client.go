@@ -29,6 +29,7 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"github.com/snapcore/snapd/dirs"
 )
@@ -52,6 +53,15 @@ type Config struct {
 type Client struct {
 	baseURL url.URL
 	doer    doer
+
+	maintenance error
+}
+
+// Maintenance returns an error reflecting the daemon maintenance status
+// as reported by the last response, or nil. It is set for example when
+// the daemon is about to restart.
+func (client *Client) Maintenance() error {
+	return client.maintenance
 }
 
 // New returns a new instance of Client
@@ -122,11 +132,60 @@ func (client *Client) raw(method, urlpath string, query url.Values, headers map[
 	return client.doer.Do(req)
 }
 
+var (
+	// doRetry is the initial interval between retries of requests
+	// that failed because the daemon could not be reached; it doubles
+	// with every retry up to doMaxRetry.
+	doRetry    = 250 * time.Millisecond
+	doMaxRetry = 2 * time.Second
+	// restartTimeout is for how long requests are retried after the
+	// daemon announced it is restarting.
+	restartTimeout = 60 * time.Second
+)
+
+// retryable returns whether the request can be safely retried after
+// failing with the given error: only requests that cannot have effects
+// are retried, and only when the daemon could not be talked to at all.
+func retryable(method string, body io.Reader, err error) bool {
+	if method != "GET" || body != nil {
+		return false
+	}
+	switch err.(type) {
+	case *url.Error, net.Error:
+		return true
+	}
+	return false
+}
+
+// rawWithRetry is like raw but retries GET requests with backoff while
+// the daemon cannot be reached after it announced it is restarting. A
+// daemon that is simply not running is reported right away.
+func (client *Client) rawWithRetry(method, urlpath string, query url.Values, headers map[string]string, body io.Reader) (*http.Response, error) {
+	maint, ok := client.maintenance.(*Error)
+	if !ok || maint.Kind != ErrorKindDaemonRestart {
+		return client.raw(method, urlpath, query, headers, body)
+	}
+
+	retry := doRetry
+	deadline := time.Now().Add(restartTimeout)
+	for {
+		rsp, err := client.raw(method, urlpath, query, headers, body)
+		if err == nil || !retryable(method, body, err) || time.Now().Add(retry).After(deadline) {
+			return rsp, err
+		}
+		time.Sleep(retry)
+		retry *= 2
+		if retry > doMaxRetry {
+			retry = doMaxRetry
+		}
+	}
+}
+
 // do performs a request and decodes the resulting json into the given
 // value. It's low-level, for testing/experimenting only; you should
 // usually use a higher level interface that builds on this.
 func (client *Client) do(method, path string, query url.Values, headers map[string]string, body io.Reader, v interface{}) error {
-	rsp, err := client.raw(method, path, query, headers, body)
+	rsp, err := client.rawWithRetry(method, path, query, headers, body)
 	if err != nil {
 		return err
 	}
@@ -151,6 +210,7 @@ func (client *Client) doSync(method, path string, query url.Values, headers map[
 	if err := client.do(method, path, query, headers, body, &rsp); err != nil {
 		return nil, fmt.Errorf("cannot communicate with server: %s", err)
 	}
+	client.setMaintenance(&rsp)
 	if err := rsp.err(); err != nil {
 		return nil, err
 	}
@@ -173,6 +233,7 @@ func (client *Client) doAsync(method, path string, query url.Values, headers map
 	if err := client.do(method, path, query, headers, body, &rsp); err != nil {
 		return "", fmt.Errorf("cannot communicate with server: %v", err)
 	}
+	client.setMaintenance(&rsp)
 	if err := rsp.err(); err != nil {
 		return "", err
 	}
@@ -189,6 +250,14 @@ func (client *Client) doAsync(method, path string, query url.Values, headers map
 	return rsp.Change, nil
 }
 
+func (client *Client) setMaintenance(rsp *response) {
+	if rsp.Maintenance != nil {
+		client.maintenance = rsp.Maintenance
+	} else {
+		client.maintenance = nil
+	}
+}
+
 func (client *Client) ServerVersion() (string, error) {
 	sysInfo, err := client.SysInfo()
 	if err != nil {
@@ -212,6 +281,10 @@ type response struct {
 	Type       string          `json:"type"`
 	Change     string          `json:"change"`
 
+	// Maintenance is set when the daemon is about to go away,
+	// e.g. to restart.
+	Maintenance *Error `json:"maintenance"`
+
 	ResultInfo
 }
 
@@ -231,6 +304,8 @@ const (
 	ErrorKindTwoFactorRequired = "two-factor-required"
 	ErrorKindTwoFactorFailed   = "two-factor-failed"
 	ErrorKindLoginRequired     = "login-required"
+
+	ErrorKindDaemonRestart = "daemon-restart"
 )
 
 // IsTwoFactorError returns whether the given error is due to problems
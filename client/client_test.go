@@ -26,10 +26,12 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -84,6 +86,88 @@ func (cs *clientSuite) TestClientDoReportsErrors(c *check.C) {
 	c.Check(err, check.Equals, cs.err)
 }
 
+type flakyDoer struct {
+	failures int
+	calls    int
+}
+
+func (d *flakyDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls++
+	if d.calls <= d.failures {
+		return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: errors.New("connection refused")}
+	}
+	return &http.Response{
+		Body:       ioutil.NopCloser(strings.NewReader(`{"type": "sync", "result": {"series": "16"}}`)),
+		StatusCode: http.StatusOK,
+	}, nil
+}
+
+var restartMaintenance = &client.Error{
+	Kind:    client.ErrorKindDaemonRestart,
+	Message: "daemon is restarting",
+}
+
+func (cs *clientSuite) TestClientRetriesGETWhileRestarting(c *check.C) {
+	restore := client.MockDoRetry(time.Millisecond, time.Second)
+	defer restore()
+	doer := &flakyDoer{failures: 3}
+	cs.cli.SetDoer(doer)
+	cs.cli.SetMaintenance(restartMaintenance)
+
+	sysInfo, err := cs.cli.SysInfo()
+	c.Assert(err, check.IsNil)
+	c.Check(sysInfo.Series, check.Equals, "16")
+	c.Check(doer.calls, check.Equals, 4)
+}
+
+func (cs *clientSuite) TestClientRetriesGETUntilTimeout(c *check.C) {
+	restore := client.MockDoRetry(time.Millisecond, 10*time.Millisecond)
+	defer restore()
+	doer := &flakyDoer{failures: 1000}
+	cs.cli.SetDoer(doer)
+	cs.cli.SetMaintenance(restartMaintenance)
+
+	_, err := cs.cli.SysInfo()
+	c.Assert(err, check.ErrorMatches, `.*connection refused`)
+	c.Check(doer.calls > 1, check.Equals, true)
+	c.Check(doer.calls < 1000, check.Equals, true)
+}
+
+func (cs *clientSuite) TestClientDoesNotRetryWithoutRestart(c *check.C) {
+	restore := client.MockDoRetry(time.Millisecond, time.Second)
+	defer restore()
+	doer := &flakyDoer{failures: 1}
+	cs.cli.SetDoer(doer)
+
+	_, err := cs.cli.SysInfo()
+	c.Assert(err, check.ErrorMatches, `.*connection refused`)
+	c.Check(doer.calls, check.Equals, 1)
+}
+
+func (cs *clientSuite) TestClientDoesNotRetryPOST(c *check.C) {
+	restore := client.MockDoRetry(time.Millisecond, time.Second)
+	defer restore()
+	doer := &flakyDoer{failures: 1}
+	cs.cli.SetDoer(doer)
+	cs.cli.SetMaintenance(restartMaintenance)
+
+	err := cs.cli.Do("POST", "/", nil, nil, nil)
+	c.Assert(err, check.ErrorMatches, `.*connection refused`)
+	c.Check(doer.calls, check.Equals, 1)
+}
+
+func (cs *clientSuite) TestClientMaintenance(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": {"series": "16"}, "maintenance": {"kind": "daemon-restart", "message": "daemon is restarting"}}`
+	_, err := cs.cli.SysInfo()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.cli.Maintenance(), check.DeepEquals, restartMaintenance)
+
+	cs.rsp = `{"type": "sync", "result": {"series": "16"}}`
+	_, err = cs.cli.SysInfo()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.cli.Maintenance(), check.IsNil)
+}
+
 func (cs *clientSuite) TestClientWorks(c *check.C) {
 	var v []int
 	cs.rsp = `[1,2]`
@@ -22,6 +22,7 @@ package client
 import (
 	"io"
 	"net/url"
+	"time"
 )
 
 // SetDoer sets the client's doer to the given one
@@ -40,3 +41,20 @@ var ParseErrorInTest = parseError
 // expose read and write auth helpers for testing
 var TestWriteAuth = writeAuthData
 var TestReadAuth = readAuthData
+
+// SetMaintenance sets the maintenance status as if reported by the daemon.
+func (client *Client) SetMaintenance(err error) {
+	client.maintenance = err
+}
+
+// MockDoRetry mocks the intervals used to retry requests.
+func MockDoRetry(retry, timeout time.Duration) (restore func()) {
+	oldRetry := doRetry
+	oldTimeout := restartTimeout
+	doRetry = retry
+	restartTimeout = timeout
+	return func() {
+		doRetry = oldRetry
+		restartTimeout = oldTimeout
+	}
+}
@@ -58,10 +58,21 @@ type SlotRef struct {
 	Name string `json:"slot"`
 }
 
+// Connection describes a connection between a plug and a slot along with the
+// attributes set by either side when it was made.
+type Connection struct {
+	Plug      PlugRef                `json:"plug"`
+	Slot      SlotRef                `json:"slot"`
+	PlugAttrs map[string]interface{} `json:"plug-attrs,omitempty"`
+	SlotAttrs map[string]interface{} `json:"slot-attrs,omitempty"`
+	Auto      bool                   `json:"auto,omitempty"`
+}
+
 // Interfaces contains information about all plugs, slots and their connections
 type Interfaces struct {
-	Plugs []Plug `json:"plugs"`
-	Slots []Slot `json:"slots"`
+	Plugs       []Plug       `json:"plugs"`
+	Slots       []Slot       `json:"slots"`
+	Connections []Connection `json:"connections,omitempty"`
 }
 
 // InterfaceAction represents an action performed on the interface system.
@@ -89,10 +100,17 @@ func (client *Client) performInterfaceAction(sa *InterfaceAction) (changeID stri
 // Connect establishes a connection between a plug and a slot.
 // The plug and the slot must have the same interface.
 func (client *Client) Connect(plugSnapName, plugName, slotSnapName, slotName string) (changeID string, err error) {
+	return client.ConnectWithAttrs(plugSnapName, plugName, slotSnapName, slotName, nil, nil)
+}
+
+// ConnectWithAttrs establishes a connection between a plug and a slot like
+// Connect, setting the given attributes on the plug and the slot side of the
+// connection.
+func (client *Client) ConnectWithAttrs(plugSnapName, plugName, slotSnapName, slotName string, plugAttrs, slotAttrs map[string]interface{}) (changeID string, err error) {
 	return client.performInterfaceAction(&InterfaceAction{
 		Action: "connect",
-		Plugs:  []Plug{{Snap: plugSnapName, Name: plugName}},
-		Slots:  []Slot{{Snap: slotSnapName, Name: slotName}},
+		Plugs:  []Plug{{Snap: plugSnapName, Name: plugName, Attrs: plugAttrs}},
+		Slots:  []Slot{{Snap: slotSnapName, Name: slotName, Attrs: slotAttrs}},
 	})
 }
 
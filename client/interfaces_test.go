@@ -58,6 +58,13 @@ func (cs *clientSuite) TestClientInterfaces(c *check.C) {
 						{"snap": "canonical-pi2", "plug": "pin-13"}
 					]
 				}
+			],
+			"connections": [
+				{
+					"plug": {"snap": "canonical-pi2", "plug": "pin-13"},
+					"slot": {"snap": "keyboard-lights", "slot": "capslock-led"},
+					"slot-attrs": {"active-low": true}
+				}
 			]
 		}
 	}`
@@ -92,6 +99,13 @@ func (cs *clientSuite) TestClientInterfaces(c *check.C) {
 				},
 			},
 		},
+		Connections: []client.Connection{
+			{
+				Plug:      client.PlugRef{Snap: "canonical-pi2", Name: "pin-13"},
+				Slot:      client.SlotRef{Snap: "keyboard-lights", Name: "capslock-led"},
+				SlotAttrs: map[string]interface{}{"active-low": true},
+			},
+		},
 	})
 }
 
@@ -132,6 +146,38 @@ func (cs *clientSuite) TestClientConnect(c *check.C) {
 	})
 }
 
+func (cs *clientSuite) TestClientConnectWithAttrs(c *check.C) {
+	cs.rsp = `{
+		"type": "async",
+                "status-code": 202,
+		"result": { },
+                "change": "foo"
+	}`
+	id, err := cs.cli.ConnectWithAttrs("producer", "plug", "consumer", "slot", nil, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "foo")
+	var body map[string]interface{}
+	decoder := json.NewDecoder(cs.req.Body)
+	err = decoder.Decode(&body)
+	c.Check(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action": "connect",
+		"plugs": []interface{}{
+			map[string]interface{}{
+				"snap": "producer",
+				"plug": "plug",
+			},
+		},
+		"slots": []interface{}{
+			map[string]interface{}{
+				"snap":  "consumer",
+				"slot":  "slot",
+				"attrs": map[string]interface{}{"path": "/dev/ttyUSB0"},
+			},
+		},
+	})
+}
+
 func (cs *clientSuite) TestClientDisconnectCallsEndpoint(c *check.C) {
 	cs.cli.Disconnect("producer", "plug", "consumer", "slot")
 	c.Check(cs.req.Method, check.Equals, "POST")
@@ -50,10 +50,37 @@ type Snap struct {
 	Apps          []AppInfo     `json:"apps"`
 
 	Prices map[string]float64 `json:"prices"`
+
+	// the following are only filled in when asking about a single snap
+	TrackingChannel string                  `json:"tracking-channel,omitempty"`
+	Revisions       []InstalledRevision     `json:"revisions,omitempty"`
+	DiskSize        int64                   `json:"disk-size,omitempty"`
+	Plugs           []Plug                  `json:"plugs,omitempty"`
+	Slots           []Slot                  `json:"slots,omitempty"`
+	Channels        map[string]*ChannelInfo `json:"channels,omitempty"`
 }
 
 type AppInfo struct {
-	Name string `json:"name"`
+	Name   string `json:"name"`
+	Daemon string `json:"daemon,omitempty"`
+}
+
+// InstalledRevision holds the details of an installed revision of a snap.
+type InstalledRevision struct {
+	Revision    snap.Revision `json:"revision"`
+	Version     string        `json:"version,omitempty"`
+	Channel     string        `json:"channel,omitempty"`
+	InstallDate time.Time     `json:"install-date"`
+	Size        int64         `json:"size"`
+	Current     bool          `json:"current,omitempty"`
+}
+
+// ChannelInfo holds what a store channel offers of a snap.
+type ChannelInfo struct {
+	Revision    snap.Revision `json:"revision"`
+	Version     string        `json:"version"`
+	Size        int64         `json:"size"`
+	Confinement string        `json:"confinement"`
 }
 
 // Statuses and types a snap may have.
@@ -79,9 +106,12 @@ type ResultInfo struct {
 // FindOptions supports exactly one of the following options:
 // - Refresh: only return snaps that are refreshable
 // - Query: only return snaps that match the query string
+// - Name: only return the snap with exactly the given name, together
+//   with its channel map
 type FindOptions struct {
 	Refresh bool
 	Query   string
+	Name    string
 }
 
 // List returns the list of all snaps installed on the system
@@ -123,6 +153,9 @@ func (client *Client) Find(opts *FindOptions) ([]*Snap, *ResultInfo, error) {
 	if opts.Refresh {
 		q.Set("select", "refresh")
 	}
+	if opts.Name != "" {
+		q.Set("name", opts.Name)
+	}
 
 	return client.snapsFromPath("/v2/find", q)
 }
@@ -147,3 +180,18 @@ func (client *Client) Snap(name string) (*Snap, *ResultInfo, error) {
 	}
 	return snap, ri, nil
 }
+
+// SecurityProfiles returns the security profiles generated by the given
+// backend for the applications of an installed snap, indexed by application
+// name.
+func (client *Client) SecurityProfiles(name, backend string) (map[string]string, error) {
+	var profiles map[string]string
+	path := fmt.Sprintf("/v2/snaps/%s/security", name)
+	q := url.Values{}
+	q.Set("backend", backend)
+	_, err := client.doSync("GET", path, q, nil, nil, &profiles)
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve security profiles of snap %q: %s", name, err)
+	}
+	return profiles, nil
+}
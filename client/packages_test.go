@@ -27,6 +27,7 @@ import (
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
 )
 
 func (cs *clientSuite) TestClientSnapsCallsEndpoint(c *check.C) {
@@ -156,3 +157,87 @@ func (cs *clientSuite) TestClientSnap(c *check.C) {
 		TryMode:       true,
 	})
 }
+
+func (cs *clientSuite) TestClientSecurityProfiles(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {"app": "# plug foo:plug (interface iface)\nsnippet\n"}
+	}`
+	profiles, err := cs.cli.SecurityProfiles("foo", "apparmor")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo/security")
+	c.Check(cs.req.URL.Query().Get("backend"), check.Equals, "apparmor")
+	c.Check(profiles, check.DeepEquals, map[string]string{
+		"app": "# plug foo:plug (interface iface)\nsnippet\n",
+	})
+}
+
+func (cs *clientSuite) TestClientSnapDetails(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"name": "chatroom",
+			"status": "active",
+			"tracking-channel": "beta",
+			"disk-size": 2048,
+			"apps": [{"name": "server", "daemon": "simple"}],
+			"revisions": [
+				{"revision": "10", "version": "0.2", "channel": "beta", "install-date": "2016-01-02T15:04:05Z", "size": 1024, "current": true},
+				{"revision": "9", "version": "0.1", "channel": "stable", "install-date": "2016-01-01T15:04:05Z", "size": 1024}
+			],
+			"plugs": [{"snap": "chatroom", "plug": "network", "interface": "network", "connections": [{"snap": "ubuntu-core", "slot": "network"}]}]
+		}
+	}`
+	pkg, _, err := cs.cli.Snap(pkgName)
+	c.Assert(err, check.IsNil)
+	c.Check(pkg.TrackingChannel, check.Equals, "beta")
+	c.Check(pkg.DiskSize, check.Equals, int64(2048))
+	c.Check(pkg.Apps, check.DeepEquals, []client.AppInfo{{Name: "server", Daemon: "simple"}})
+	c.Check(pkg.Revisions, check.DeepEquals, []client.InstalledRevision{{
+		Revision:    snap.R(10),
+		Version:     "0.2",
+		Channel:     "beta",
+		InstallDate: time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+		Size:        1024,
+		Current:     true,
+	}, {
+		Revision:    snap.R(9),
+		Version:     "0.1",
+		Channel:     "stable",
+		InstallDate: time.Date(2016, 1, 1, 15, 4, 5, 0, time.UTC),
+		Size:        1024,
+	}})
+	c.Check(pkg.Plugs, check.DeepEquals, []client.Plug{{
+		Snap:        "chatroom",
+		Name:        "network",
+		Interface:   "network",
+		Connections: []client.SlotRef{{Snap: "ubuntu-core", Name: "network"}},
+	}})
+}
+
+func (cs *clientSuite) TestClientFindByName(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": [{
+			"name": "chatroom",
+			"status": "available",
+			"channels": {
+				"stable": {"revision": "9", "version": "0.1", "size": 1024, "confinement": "strict"}
+			}
+		}]
+	}`
+	snaps, _, err := cs.cli.Find(&client.FindOptions{Name: "chatroom"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/find")
+	c.Check(cs.req.URL.Query().Get("name"), check.Equals, "chatroom")
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0].Channels, check.DeepEquals, map[string]*client.ChannelInfo{
+		"stable": {
+			Revision:    snap.R(9),
+			Version:     "0.1",
+			Size:        1024,
+			Confinement: client.StrictConfinement,
+		},
+	})
+}
@@ -33,6 +33,9 @@ import (
 type SnapOptions struct {
 	Channel string `json:"channel,omitempty"`
 	DevMode bool   `json:"devmode,omitempty"`
+	// Assertions are the paths of files with assertion streams to
+	// verify a sideloaded snap with.
+	Assertions []string `json:"-"`
 }
 
 type actionData struct {
@@ -81,6 +84,21 @@ func (client *Client) InstallPath(path string, options *SnapOptions) (changeID s
 		return "", fmt.Errorf("cannot open: %q", path)
 	}
 
+	var assertFiles []*os.File
+	if options != nil {
+		for _, assertPath := range options.Assertions {
+			af, err := os.Open(assertPath)
+			if err != nil {
+				f.Close()
+				for _, af := range assertFiles {
+					af.Close()
+				}
+				return "", fmt.Errorf("cannot open: %q", assertPath)
+			}
+			assertFiles = append(assertFiles, af)
+		}
+	}
+
 	action := actionData{
 		Action:      "install",
 		SnapPath:    path,
@@ -89,7 +107,7 @@ func (client *Client) InstallPath(path string, options *SnapOptions) (changeID s
 
 	pr, pw := io.Pipe()
 	mw := multipart.NewWriter(pw)
-	go sendSnapFile(path, f, pw, mw, &action)
+	go sendSnapFile(path, f, assertFiles, pw, mw, &action)
 
 	headers := map[string]string{
 		"Content-Type": mw.FormDataContentType(),
@@ -118,8 +136,11 @@ func (client *Client) Try(path string, options *SnapOptions) (changeID string, e
 	return client.doAsync("POST", "/v2/snaps", nil, headers, buf)
 }
 
-func sendSnapFile(snapPath string, snapFile *os.File, pw *io.PipeWriter, mw *multipart.Writer, action *actionData) {
+func sendSnapFile(snapPath string, snapFile *os.File, assertFiles []*os.File, pw *io.PipeWriter, mw *multipart.Writer, action *actionData) {
 	defer snapFile.Close()
+	for _, af := range assertFiles {
+		defer af.Close()
+	}
 
 	if action.SnapOptions == nil {
 		action.SnapOptions = &SnapOptions{}
@@ -138,6 +159,18 @@ func sendSnapFile(snapPath string, snapFile *os.File, pw *io.PipeWriter, mw *mul
 		}
 	}
 
+	for _, af := range assertFiles {
+		fw, err := mw.CreateFormFile("assertion", filepath.Base(af.Name()))
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(fw, af); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
+
 	fw, err := mw.CreateFormFile("snap", filepath.Base(snapPath))
 	if err != nil {
 		pw.CloseWithError(err)
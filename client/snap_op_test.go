@@ -145,6 +145,40 @@ func (cs *clientSuite) TestClientOpInstallPath(c *check.C) {
 	c.Check(id, check.Equals, "66b3")
 }
 
+func (cs *clientSuite) TestClientOpInstallPathWithAssertions(c *check.C) {
+	cs.rsp = `{
+		"change": "66b3",
+		"status-code": 202,
+		"type": "async"
+	}`
+	dir := c.MkDir()
+	snap := filepath.Join(dir, "foo.snap")
+	err := ioutil.WriteFile(snap, []byte("snap-data"), 0644)
+	c.Assert(err, check.IsNil)
+	assertPath := filepath.Join(dir, "foo.assert")
+	err = ioutil.WriteFile(assertPath, []byte("assert-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	id, err := cs.cli.InstallPath(snap, &client.SnapOptions{Assertions: []string{assertPath}})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "66b3")
+
+	_, params, err := mime.ParseMediaType(cs.req.Header.Get("Content-Type"))
+	c.Assert(err, check.IsNil)
+	form := formToMap(c, multipart.NewReader(cs.req.Body, params["boundary"]))
+	c.Check(form["snap"], check.Equals, "snap-data")
+	c.Check(form["assertion"], check.Equals, "assert-data")
+}
+
+func (cs *clientSuite) TestClientOpInstallPathMissingAssertions(c *check.C) {
+	snap := filepath.Join(c.MkDir(), "foo.snap")
+	err := ioutil.WriteFile(snap, []byte("snap-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	_, err = cs.cli.InstallPath(snap, &client.SnapOptions{Assertions: []string{"/does/not/exist"}})
+	c.Check(err, check.ErrorMatches, `cannot open: "/does/not/exist"`)
+}
+
 func formToMap(c *check.C, mr *multipart.Reader) map[string]string {
 	formData := map[string]string{}
 	for {
@@ -0,0 +1,55 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CreateUserResult holds the result of a user creation.
+type CreateUserResult struct {
+	Username string   `json:"username"`
+	SSHKeys  []string `json:"ssh-keys"`
+}
+
+// CreateUserRequest holds the details of a user creation.
+type CreateUserRequest struct {
+	Email string `json:"email"`
+}
+
+// CreateUser creates a local system user. See CreateUserRequest for details.
+func (client *Client) CreateUser(request *CreateUserRequest) (*CreateUserResult, error) {
+	if request.Email == "" {
+		return nil, fmt.Errorf("cannot create a user without providing an email")
+	}
+
+	var result CreateUserResult
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := client.doSync("POST", "/v2/create-user", nil, nil, bytes.NewReader(data), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
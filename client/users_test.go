@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestClientCreateUser(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {
+			"username": "karl",
+			"ssh-keys": ["one", "two"]
+		}
+	}`
+	result, err := cs.cli.CreateUser(&client.CreateUserRequest{Email: "one@email.com"})
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/create-user")
+
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"email": "one@email.com",
+	})
+	c.Check(result, check.DeepEquals, &client.CreateUserResult{
+		Username: "karl",
+		SSHKeys:  []string{"one", "two"},
+	})
+}
+
+func (cs *clientSuite) TestClientCreateUserNoEmail(c *check.C) {
+	_, err := cs.cli.CreateUser(&client.CreateUserRequest{})
+	c.Assert(err, check.ErrorMatches, "cannot create a user without providing an email")
+}
+
+func (cs *clientSuite) TestClientCreateUserError(c *check.C) {
+	cs.rsp = `{
+		"type": "error",
+		"result": {"message": "cannot create user karl: adduser failed"},
+		"status-code": 400
+	}`
+	_, err := cs.cli.CreateUser(&client.CreateUserRequest{Email: "one@email.com"})
+	c.Assert(err, check.ErrorMatches, "cannot create user karl: adduser failed")
+}
@@ -21,7 +21,9 @@ package main
 
 import (
 	"io/ioutil"
+	"strings"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 
 	"github.com/jessevdk/go-flags"
@@ -33,16 +35,20 @@ type cmdAck struct {
 	} `positional-args:"true" required:"true"`
 }
 
-var shortAckHelp = i18n.G("Adds an assertion to the system")
+var shortAckHelp = i18n.G("Adds assertions to the system")
 var longAckHelp = i18n.G(`
-The ack command tries to add an assertion to the system assertion database.
+The ack command tries to add the assertions in the given file to the system
+assertion database. The file can hold a single assertion or a stream of them,
+such as the one written by the download command.
 
-The assertion may also be a newer revision of a preexisting assertion that it
-will replace.
+The assertions are added each after the ones it depends on, whatever their
+order in the file. An assertion may also be a newer revision of a preexisting
+assertion that it will replace.
 
-To succeed the assertion must be valid, its signature verified with a known
-public key and the assertion consistent with and its prerequisite in the
-database.
+To succeed each assertion must be valid, its signature verified with a known
+public key or one from the file, and the assertion consistent with and its
+prerequisite in the database. Nothing is added if any signature fails
+verification.
 `)
 
 func init() {
@@ -51,6 +57,12 @@ func init() {
 	})
 }
 
+var ackResultText = map[string]string{
+	client.AckAdded:                i18n.G("added"),
+	client.AckAlreadyPresent:       i18n.G("already present"),
+	client.AckNewerRevisionPresent: i18n.G("newer revision present"),
+}
+
 func (x *cmdAck) Execute(args []string) error {
 	assertFile := x.AckOptions.AssertionFile
 
@@ -59,5 +71,27 @@ func (x *cmdAck) Execute(args []string) error {
 		return err
 	}
 
-	return Client().Ack(assertData)
+	results, err := Client().Ack(assertData)
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return writeStructured(results)
+	}
+
+	t := newColumnTable(
+		column{"type", i18n.G("Type")},
+		column{"key", i18n.G("Key")},
+		column{"result", i18n.G("Result")},
+	)
+	for _, res := range results {
+		text := ackResultText[res.Result]
+		if text == "" {
+			text = res.Result
+		}
+		t.addRow(res.Type, strings.Join(res.PrimaryKey, "; "), text)
+	}
+
+	return t.write()
 }
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !integrationcoverage
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const ackResultsJSON = `{"type": "sync", "result": [
+{"type": "account", "primary-key": ["dev-id"], "result": "already-present"},
+{"type": "account-key", "primary-key": ["dev-id", "abcd"], "result": "added"},
+{"type": "snap-declaration", "primary-key": ["16", "foo-id"], "result": "newer-revision-present"}
+]}`
+
+func (s *SnapSuite) TestAck(c *check.C) {
+	assertFile := filepath.Join(c.MkDir(), "foo.assert")
+	c.Assert(ioutil.WriteFile(assertFile, []byte("assertions"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/assertions")
+		body, err := ioutil.ReadAll(r.Body)
+		c.Check(err, check.IsNil)
+		c.Check(string(body), check.Equals, "assertions")
+		fmt.Fprintln(w, ackResultsJSON)
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"ack", assertFile})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `Type              Key           Result
+account           dev-id        already present
+account-key       dev-id; abcd  added
+snap-declaration  16; foo-id    newer revision present
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestAckJSON(c *check.C) {
+	assertFile := filepath.Join(c.MkDir(), "foo.assert")
+	c.Assert(ioutil.WriteFile(assertFile, []byte("assertions"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, ackResultsJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"--format=json", "ack", assertFile})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?s)\[
+  \{
+    "type": "account",
+    "primary-key": \[
+      "dev-id"
+    \],
+    "result": "already-present"
+  \},
+.*`)
+}
+
+func (s *SnapSuite) TestAckError(c *check.C) {
+	assertFile := filepath.Join(c.MkDir(), "foo.assert")
+	c.Assert(ioutil.WriteFile(assertFile, []byte("assertions"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 400, "result": {"message": "assert failed: cannot verify signature of account (dev-id): failed signature verification: boom"}}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"ack", assertFile})
+	c.Assert(err, check.ErrorMatches, `cannot assert: assert failed: cannot verify signature of account \(dev-id\): .*`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
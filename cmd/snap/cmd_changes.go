@@ -86,25 +86,35 @@ func (c *cmdChanges) Execute([]string) error {
 		return err
 	}
 
+	sort.Sort(changesByTime(changes))
+
+	if structuredOutput() {
+		return writeStructured(changes)
+	}
+
 	if len(changes) == 0 {
 		return fmt.Errorf(i18n.G("no changes found"))
 	}
 
-	sort.Sort(changesByTime(changes))
-
-	w := tabWriter()
-
-	fmt.Fprintf(w, i18n.G("ID\tStatus\tSpawn\tReady\tSummary\n"))
+	t := newColumnTable(
+		column{"id", i18n.G("ID")},
+		column{"status", i18n.G("Status")},
+		column{"spawn", i18n.G("Spawn")},
+		column{"ready", i18n.G("Ready")},
+		column{"summary", i18n.G("Summary")},
+	)
 	for _, chg := range changes {
 		spawnTime := chg.SpawnTime.UTC().Format(time.RFC3339)
 		readyTime := chg.ReadyTime.UTC().Format(time.RFC3339)
 		if chg.ReadyTime.IsZero() {
 			readyTime = "-"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", chg.ID, chg.Status, spawnTime, readyTime, chg.Summary)
+		t.addRow(chg.ID, chg.Status, spawnTime, readyTime, chg.Summary)
 	}
 
-	w.Flush()
+	if err := t.write(); err != nil {
+		return err
+	}
 	fmt.Fprintln(Stdout)
 
 	return nil
@@ -117,19 +127,28 @@ func (c *cmdChange) Execute([]string) error {
 		return err
 	}
 
-	w := tabWriter()
+	if structuredOutput() {
+		return writeStructured(chg)
+	}
 
-	fmt.Fprintf(w, i18n.G("Status\tSpawn\tReady\tSummary\n"))
+	tbl := newColumnTable(
+		column{"status", i18n.G("Status")},
+		column{"spawn", i18n.G("Spawn")},
+		column{"ready", i18n.G("Ready")},
+		column{"summary", i18n.G("Summary")},
+	)
 	for _, t := range chg.Tasks {
 		spawnTime := t.SpawnTime.UTC().Format(time.RFC3339)
 		readyTime := t.ReadyTime.UTC().Format(time.RFC3339)
 		if t.ReadyTime.IsZero() {
 			readyTime = "-"
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Status, spawnTime, readyTime, t.Summary)
+		tbl.addRow(t.Status, spawnTime, readyTime, t.Summary)
 	}
 
-	w.Flush()
+	if err := tbl.write(); err != nil {
+		return err
+	}
 
 	for _, t := range chg.Tasks {
 		if len(t.Log) == 0 {
@@ -54,10 +54,12 @@ first of these snaps that has a matching plug name is used and the command
 proceeds as above.
 
 Application Options:
-      --version            print the version and exit
+      --version                  print the version and exit
+      --format=[table|json|yaml] output format (default: table)
+      --columns=                 comma-separated table columns to show
 
 Help Options:
-  -h, --help               Show this help message
+  -h, --help                     Show this help message
 `
 	rest, err := Parser().ParseArgs([]string{"connect", "--help"})
 	c.Assert(err.Error(), Equals, msg)
@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortCreateKeyHelp = i18n.G("Creates a key pair to be used with signing assertions")
+var longCreateKeyHelp = i18n.G(`
+The create-key command creates a cryptographic key pair that can be
+used for signing assertions.
+`)
+
+type cmdCreateKey struct {
+	Positional struct {
+		KeyName string `positional-arg-name:"<key-name>" description:"name of key to create; defaults to 'default'"`
+	} `positional-args:"true"`
+}
+
+func init() {
+	addCommand("create-key", shortCreateKeyHelp, longCreateKeyHelp, func() flags.Commander { return &cmdCreateKey{} })
+}
+
+func (x *cmdCreateKey) Execute(args []string) error {
+	keyName := x.Positional.KeyName
+	if keyName == "" {
+		keyName = "default"
+	}
+
+	manager, err := getKeypairManager()
+	if err != nil {
+		return err
+	}
+	if err := manager.Generate(keyName); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("created key %q\n"), keyName)
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !integrationcoverage
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapKeysSuite) TestCreateKey(c *C) {
+	rest, err := snap.Parser().ParseArgs([]string{"create-key", "another"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, "created key \"another\"\n")
+
+	s.stdout.Reset()
+	_, err = snap.Parser().ParseArgs([]string{"keys"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Matches, `(?s)Name +ID +Fingerprint
+default +3ff295be3d1ac31e +bc8ec9852edeeca51650835f3ff295be3d1ac31e
+another +[0-9a-f]{16} +[0-9a-f]{40}
+`)
+}
+
+func (s *SnapKeysSuite) TestCreateKeyExists(c *C) {
+	_, err := snap.Parser().ParseArgs([]string{"create-key"})
+	c.Assert(err, ErrorMatches, `key named "default" already exists in GPG keyring`)
+	c.Check(s.Stdout(), Equals, "")
+}
@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortCreateUserHelp = i18n.G("Creates a local system user")
+var longCreateUserHelp = i18n.G(`
+The create-user command creates a local system user with the username and SSH
+keys registered on the store account identified by the provided email address.
+
+If a system-user assertion known to the system matches the email address, the
+user is instead created with the username, password and SSH keys it states.
+
+An account can be setup at https://login.ubuntu.com.
+`)
+
+type cmdCreateUser struct {
+	Positional struct {
+		Email string `positional-arg-name:"email" description:"an email of a user on login.ubuntu.com"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addCommand("create-user", shortCreateUserHelp, longCreateUserHelp, func() flags.Commander { return &cmdCreateUser{} })
+}
+
+func (x *cmdCreateUser) Execute(args []string) error {
+	cli := Client()
+
+	request := client.CreateUserRequest{
+		Email: x.Positional.Email,
+	}
+
+	rsp, err := cli.CreateUser(&request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("created user %q\n"), rsp.Username)
+
+	return nil
+}
@@ -0,0 +1,69 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type cmdDebugProfile struct {
+	Backend    string `long:"backend" description:"security backend of the profile" choice:"apparmor" choice:"seccomp" choice:"udev" choice:"dbus" choice:"mount" default:"apparmor"`
+	Positional struct {
+		App string `positional-arg-name:"<snap.app>" description:"the application of a snap"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+var shortDebugProfileHelp = i18n.G("Shows the security profile of an application")
+var longDebugProfileHelp = i18n.G(`
+The profile command shows the security profile generated for the given
+application by the selected security backend. Each snippet of the profile is
+preceded by a comment naming the plug or slot and the interface that
+produced it.
+`)
+
+func init() {
+	addDebugCommand("profile", shortDebugProfileHelp, longDebugProfileHelp, func() flags.Commander {
+		return &cmdDebugProfile{}
+	})
+}
+
+func (x *cmdDebugProfile) Execute(args []string) error {
+	snapName, appName := x.Positional.App, x.Positional.App
+	if i := strings.IndexRune(x.Positional.App, '.'); i >= 0 {
+		snapName, appName = x.Positional.App[:i], x.Positional.App[i+1:]
+	}
+
+	profiles, err := Client().SecurityProfiles(snapName, x.Backend)
+	if err != nil {
+		return err
+	}
+	profile, ok := profiles[appName]
+	if !ok {
+		return fmt.Errorf(i18n.G("no %s profile for application %q of snap %q"), x.Backend, appName, snapName)
+	}
+	fmt.Fprint(Stdout, profile)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugProfile(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo/security")
+			c.Check(r.URL.Query().Get("backend"), check.Equals, "seccomp")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"bar": "default\n# plug foo:plug (interface iface)\nsnippet\n"}}`)
+		default:
+			c.Fatalf("expected to get 1 request, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"debug", "profile", "--backend=seccomp", "foo.bar"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, "default\n# plug foo:plug (interface iface)\nsnippet\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugProfileDefaultsToAppArmorAndSnapApp(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo/security")
+		c.Check(r.URL.Query().Get("backend"), check.Equals, "apparmor")
+		fmt.Fprintln(w, `{"type": "sync", "result": {"bar": "profile\n"}}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"debug", "profile", "foo"})
+	c.Assert(err, check.ErrorMatches, `no apparmor profile for application "foo" of snap "foo"`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
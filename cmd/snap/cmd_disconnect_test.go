@@ -49,10 +49,12 @@ $ snap disconnect <snap>
 Disconnects all plugs from the provided snap.
 
 Application Options:
-      --version            print the version and exit
+      --version                  print the version and exit
+      --format=[table|json|yaml] output format (default: table)
+      --columns=                 comma-separated table columns to show
 
 Help Options:
-  -h, --help               Show this help message
+  -h, --help                     Show this help message
 `
 	rest, err := Parser().ParseArgs([]string{"disconnect", "--help"})
 	c.Assert(err.Error(), Equals, msg)
@@ -0,0 +1,181 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+var shortDownloadHelp = i18n.G("Downloads the given snap")
+var longDownloadHelp = i18n.G(`
+The download command downloads the given snap and its supporting assertions
+to the current directory, without installing it.
+
+The snap is written to <name>_<revision>.snap and the assertions needed to
+verify it (snap-declaration, snap-revision and the account and account-key
+assertions they depend on) to <name>_<revision>.assert, so that on a device
+without store access
+
+    snap ack <name>_<revision>.assert
+    snap install <name>_<revision>.snap
+
+installs it verified against those assertions, with the snap id and the
+revision the store assigned to it, though not following any channel.
+`)
+
+type cmdDownload struct {
+	Channel string `long:"channel" description:"use this channel instead of stable"`
+
+	Positional struct {
+		Snap string `positional-arg-name:"<snap>"`
+	} `positional-args:"true" required:"true"`
+}
+
+func init() {
+	addCommand("download", shortDownloadHelp, longDownloadHelp, func() flags.Commander {
+		return &cmdDownload{}
+	})
+}
+
+var storeConfig = (*store.SnapUbuntuStoreConfig)(nil)
+
+func newStore() *store.SnapUbuntuStoreRepository {
+	return store.NewUbuntuStoreSnapRepository(storeConfig, os.Getenv("UBUNTU_STORE_ID"))
+}
+
+func (x *cmdDownload) Execute([]string) (err error) {
+	name := x.Positional.Snap
+	channel := x.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+
+	sto := newStore()
+	info, err := sto.Snap(name, channel, nil)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot find snap %q in channel %q: %v"), name, channel, err)
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Fetching snap %q\n"), name)
+	tmpPath, err := sto.Download(info, progress.NewTextProgress(), nil)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	base := fmt.Sprintf("%s_%s", name, info.Revision)
+	snapPath := base + ".snap"
+	assertPath := base + ".assert"
+	if err := osutil.CopyFile(tmpPath, snapPath, osutil.CopyFlagOverwrite); err != nil {
+		return err
+	}
+	// don't leave a snap behind that cannot be acked and installed
+	defer func() {
+		if err != nil {
+			os.Remove(snapPath)
+			os.Remove(assertPath)
+		}
+	}()
+
+	fmt.Fprintf(Stdout, i18n.G("Fetching assertions for %q\n"), name)
+	assertions, err := fetchSnapAssertions(sto, snapPath, info)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(assertPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	enc := asserts.NewEncoder(f)
+	for _, a := range assertions {
+		if err := enc.Encode(a); err != nil {
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Wrote %s and %s\n"), snapPath, assertPath)
+	return nil
+}
+
+// fetchSnapAssertions fetches the snap-declaration and snap-revision
+// assertions for the given downloaded snap together with the account and
+// account-key assertions they depend on. The result is ordered so that
+// each assertion comes after its prerequisites.
+func fetchSnapAssertions(sto *store.SnapUbuntuStoreRepository, snapPath string, info *snap.Info) ([]asserts.Assertion, error) {
+	if info.SnapID == "" {
+		return nil, fmt.Errorf(i18n.G("cannot fetch assertions for snap %q: the store did not provide a snap id"), info.Name())
+	}
+
+	digest, size, err := asserts.SnapFileSHA512(snapPath)
+	if err != nil {
+		return nil, err
+	}
+
+	snapDecl, err := sto.Assertion(asserts.SnapDeclarationType, []string{release.Series, info.SnapID}, nil)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot fetch snap-declaration for snap %q: %v"), info.Name(), err)
+	}
+	snapRev, err := sto.Assertion(asserts.SnapRevisionType, []string{release.Series, info.SnapID, digest}, nil)
+	if err != nil {
+		return nil, fmt.Errorf(i18n.G("cannot fetch snap-revision for snap %q: %v"), info.Name(), err)
+	}
+	if snapRev.(*asserts.SnapRevision).SnapSize() != size {
+		return nil, fmt.Errorf(i18n.G("cannot use snap %q: size does not match its snap-revision assertion"), info.Name())
+	}
+
+	var chain []asserts.Assertion
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		a, err := sto.Assertion(ref.Type, ref.PrimaryKey, nil)
+		if err == store.ErrAssertionNotFound {
+			// the trusted keys and accounts that every device knows
+			// already are not available from the store
+			return nil, asserts.ErrNotFound
+		}
+		return a, err
+	}
+	save := func(a asserts.Assertion) error {
+		chain = append(chain, a)
+		return nil
+	}
+	f := assertstate.NewSavingFetcher(retrieve, save)
+	for _, a := range []asserts.Assertion{snapDecl, snapRev} {
+		if err := f.Save(a); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
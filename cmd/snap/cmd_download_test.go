@@ -0,0 +1,216 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp/packet"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+type downloadSuite struct {
+	SnapSuite
+
+	server     *httptest.Server
+	assertions map[string]asserts.Assertion
+	snapData   []byte
+	restore    func()
+	oldCwd     string
+}
+
+var _ = check.Suite(&downloadSuite{})
+
+const downloadSearchJSON = `{"_embedded": {"clickindex:package": [{
+"package_name": "foo", "snap_id": "foo-id", "revision": 7, "version": "1.0",
+"origin": "acme", "anon_download_url": "%s/download/foo_7.snap"}]}}`
+
+func (s *downloadSuite) SetUpTest(c *check.C) {
+	s.SnapSuite.SetUpTest(c)
+
+	var err error
+	s.oldCwd, err = os.Getwd()
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Chdir(c.MkDir()), check.IsNil)
+
+	s.snapData = []byte("snap-data")
+	s.assertions = make(map[string]asserts.Assertion)
+	s.makeAssertions(c)
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	base, err := url.Parse(s.server.URL + "/")
+	c.Assert(err, check.IsNil)
+	searchURI, err := base.Parse("search")
+	c.Assert(err, check.IsNil)
+	assertionsURI, err := base.Parse("assertions/")
+	c.Assert(err, check.IsNil)
+	s.restore = snap.MockStoreConfig(&store.SnapUbuntuStoreConfig{
+		SearchURI:     searchURI,
+		AssertionsURI: assertionsURI,
+	})
+}
+
+func (s *downloadSuite) TearDownTest(c *check.C) {
+	s.restore()
+	s.server.Close()
+	c.Assert(os.Chdir(s.oldCwd), check.IsNil)
+	s.SnapSuite.TearDownTest(c)
+}
+
+func (s *downloadSuite) handle(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/search":
+		fmt.Fprintf(w, downloadSearchJSON, s.server.URL)
+	case r.URL.Path == "/download/foo_7.snap":
+		w.Write(s.snapData)
+	case strings.HasPrefix(r.URL.Path, "/assertions/"):
+		a := s.assertions[strings.TrimPrefix(r.URL.Path, "/assertions/")]
+		if a == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(404)
+			io.WriteString(w, `{"status": 404, "title": "not found"}`)
+			return
+		}
+		w.Header().Set("Content-Type", asserts.MediaType)
+		w.Write(asserts.Encode(a))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *downloadSuite) makeAssertions(c *check.C) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 752)
+	c.Assert(err, check.IsNil)
+	privKey := asserts.OpenPGPPrivateKey(packet.NewRSAPrivateKey(time.Unix(1, 0), rsaKey))
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(db.ImportKey("canonical", privKey), check.IsNil)
+	keyID := privKey.PublicKey().ID()
+
+	sign := func(assertType *asserts.AssertionType, headers map[string]string, body []byte) {
+		headers["authority-id"] = "canonical"
+		a, err := db.Sign(assertType, headers, body, keyID)
+		c.Assert(err, check.IsNil)
+		primaryKey := make([]string, len(assertType.PrimaryKey))
+		for i, k := range assertType.PrimaryKey {
+			primaryKey[i] = a.Header(k)
+		}
+		s.assertions[assertType.Name+"/"+strings.Join(primaryKey, "/")] = a
+	}
+
+	now := time.Now().Format(time.RFC3339)
+
+	pubKey, err := asserts.EncodePublicKey(privKey.PublicKey())
+	c.Assert(err, check.IsNil)
+	sign(asserts.AccountKeyType, map[string]string{
+		"account-id":             "canonical",
+		"public-key-id":          keyID,
+		"public-key-fingerprint": privKey.PublicKey().Fingerprint(),
+		"since":                  now,
+		"until":                  time.Now().AddDate(1, 0, 0).Format(time.RFC3339),
+	}, pubKey)
+	sign(asserts.AccountType, map[string]string{
+		"account-id":   "acme-id",
+		"display-name": "Acme",
+		"validation":   "certified",
+		"timestamp":    now,
+	}, nil)
+	sign(asserts.SnapDeclarationType, map[string]string{
+		"series":       "16",
+		"snap-id":      "foo-id",
+		"snap-name":    "foo",
+		"publisher-id": "acme-id",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+
+	f := "digest-source"
+	c.Assert(ioutil.WriteFile(f, s.snapData, 0644), check.IsNil)
+	digest, size, err := asserts.SnapFileSHA512(f)
+	c.Assert(err, check.IsNil)
+	c.Assert(os.Remove(f), check.IsNil)
+	sign(asserts.SnapRevisionType, map[string]string{
+		"series":        "16",
+		"snap-id":       "foo-id",
+		"snap-digest":   digest,
+		"snap-size":     fmt.Sprint(size),
+		"snap-revision": "7",
+		"developer-id":  "acme-id",
+		"timestamp":     now,
+	}, nil)
+}
+
+func (s *downloadSuite) TestDownload(c *check.C) {
+	_, err := snap.Parser().ParseArgs([]string{"download", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*Wrote foo_7.snap and foo_7.assert$`)
+
+	data, err := ioutil.ReadFile("foo_7.snap")
+	c.Assert(err, check.IsNil)
+	c.Check(data, check.DeepEquals, s.snapData)
+
+	data, err = ioutil.ReadFile("foo_7.assert")
+	c.Assert(err, check.IsNil)
+	dec := asserts.NewDecoder(bytes.NewReader(data))
+	var types []string
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.IsNil)
+		types = append(types, a.Type().Name)
+	}
+	// prerequisites come first
+	c.Check(types, check.DeepEquals, []string{"account-key", "account", "snap-declaration", "snap-revision"})
+}
+
+func (s *downloadSuite) TestDownloadMissingSnapRevision(c *check.C) {
+	for k := range s.assertions {
+		if strings.HasPrefix(k, "snap-revision/") {
+			delete(s.assertions, k)
+		}
+	}
+	_, err := snap.Parser().ParseArgs([]string{"download", "foo"})
+	c.Assert(err, check.ErrorMatches, `cannot fetch snap-revision for snap "foo": assertion not found`)
+	// no partial download is left behind
+	_, err = os.Stat("foo_7.snap")
+	c.Check(os.IsNotExist(err), check.Equals, true)
+	_, err = os.Stat("foo_7.assert")
+	c.Check(os.IsNotExist(err), check.Equals, true)
+}
@@ -0,0 +1,96 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortExportKeyHelp = i18n.G("Exports a public key")
+var longExportKeyHelp = i18n.G(`
+The export-key command exports the public part of a key pair created with
+create-key.
+
+With --account, it instead outputs an account-key-request assertion for the
+key, self-signed on behalf of the given account, to be submitted for the
+key to be authorized for signing assertions of that account.
+`)
+
+type cmdExportKey struct {
+	Account    string `long:"account" description:"format public key material as a request for an account-key for this account-id"`
+	Positional struct {
+		KeyName string `positional-arg-name:"<key-name>" description:"name of key to export; defaults to 'default'"`
+	} `positional-args:"true"`
+}
+
+func init() {
+	addCommand("export-key", shortExportKeyHelp, longExportKeyHelp, func() flags.Commander { return &cmdExportKey{} })
+}
+
+func (x *cmdExportKey) Execute(args []string) error {
+	keyName := x.Positional.KeyName
+	if keyName == "" {
+		keyName = "default"
+	}
+
+	manager, err := getKeypairManager()
+	if err != nil {
+		return err
+	}
+	privKey, err := manager.GetByName(keyName)
+	if err != nil {
+		return err
+	}
+	pubKey := privKey.PublicKey()
+	encodedPubKey, err := asserts.EncodePublicKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	if x.Account == "" {
+		_, err = fmt.Fprintf(Stdout, "%s\n", encodedPubKey)
+		return err
+	}
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: manager,
+	})
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		"authority-id":           x.Account,
+		"account-id":             x.Account,
+		"public-key-id":          pubKey.ID(),
+		"public-key-fingerprint": pubKey.Fingerprint(),
+		"since":                  time.Now().UTC().Format(time.RFC3339),
+	}
+	req, err := db.Sign(asserts.AccountKeyRequestType, headers, encodedPubKey, pubKey.ID())
+	if err != nil {
+		return err
+	}
+
+	return asserts.NewEncoder(Stdout).Encode(req)
+}
@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !integrationcoverage
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapKeysSuite) TestExportKey(c *C) {
+	rest, err := snap.Parser().ParseArgs([]string{"export-key"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	privKey, err := asserts.NewGPGKeypairManager(s.GnupgHome).Get("", defaultKeyID)
+	c.Assert(err, IsNil)
+	encoded, err := asserts.EncodePublicKey(privKey.PublicKey())
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, string(encoded)+"\n")
+}
+
+func (s *SnapKeysSuite) TestExportKeyNonexistent(c *C) {
+	_, err := snap.Parser().ParseArgs([]string{"export-key", "nonexistent"})
+	c.Assert(err, ErrorMatches, `cannot find key named "nonexistent" in GPG keyring`)
+	c.Check(s.Stdout(), Equals, "")
+}
+
+func (s *SnapKeysSuite) TestExportKeyAccount(c *C) {
+	before := time.Now().UTC().Truncate(time.Second)
+	_, err := snap.Parser().ParseArgs([]string{"export-key", "default", "--account=developer1"})
+	c.Assert(err, IsNil)
+	a, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.AccountKeyRequestType)
+	req := a.(*asserts.AccountKeyRequest)
+	c.Check(req.AuthorityID(), Equals, "developer1")
+	c.Check(req.AccountID(), Equals, "developer1")
+	c.Check(req.PublicKeyID(), Equals, defaultKeyID)
+	c.Check(req.PublicKeyFingerprint(), Equals, defaultKeyFingerprint)
+	c.Check(req.Since().Before(before), Equals, false)
+}
@@ -88,16 +88,23 @@ func findSnaps(opts *client.FindOptions) error {
 		return err
 	}
 
+	sort.Sort(snapsByName(snaps))
+
+	if structuredOutput() {
+		return writeStructured(snaps)
+	}
+
 	if len(snaps) == 0 {
 		return fmt.Errorf("no snaps found for %q", opts.Query)
 	}
 
-	sort.Sort(snapsByName(snaps))
-
-	w := tabWriter()
-	defer w.Flush()
-
-	fmt.Fprintln(w, i18n.G("Name\tVersion\tDeveloper\tNotes\tSummary"))
+	t := newColumnTable(
+		column{"name", i18n.G("Name")},
+		column{"version", i18n.G("Version")},
+		column{"developer", i18n.G("Developer")},
+		column{"notes", i18n.G("Notes")},
+		column{"summary", i18n.G("Summary")},
+	)
 
 	for _, snap := range snaps {
 		notes := &Notes{
@@ -106,8 +113,8 @@ func findSnaps(opts *client.FindOptions) error {
 			Price:       getPrice(snap.Prices, resInfo.SuggestedCurrency),
 		}
 		// TODO: get snap.Publisher, so we can only show snap.Developer if it's different
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", snap.Name, snap.Version, snap.Developer, notes, snap.Summary)
+		t.addRow(snap.Name, snap.Version, snap.Developer, notes.String(), snap.Summary)
 	}
 
-	return nil
+	return t.write()
 }
@@ -50,6 +50,8 @@ platform.
 
 Application Options:
  +--version +print the version and exit
+ +--format=\[table\|json\|yaml\] +output format \(default: table\)
+ +--columns= +comma-separated table columns to show
 
 Help Options:
  +-h, --help +Show this help message
@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+
+	"github.com/jessevdk/go-flags"
+)
+
+var shortInfoHelp = i18n.G("Show detailed information about snaps")
+var longInfoHelp = i18n.G(`
+The info command shows detailed information about the given snaps.
+
+For installed snaps it shows all installed revisions, the apps and services
+and the plugs and slots with their connections; for snaps that are not
+installed it shows what each channel of the store offers.
+`)
+
+type cmdSnapInfo struct {
+	Positional struct {
+		Snaps []string `positional-arg-name:"<snap>" required:"1"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func init() {
+	addCommand("info", shortInfoHelp, longInfoHelp, func() flags.Commander {
+		return &cmdSnapInfo{}
+	})
+}
+
+func (x *cmdSnapInfo) Execute([]string) error {
+	if err := checkNoColumns("info"); err != nil {
+		return err
+	}
+
+	cli := Client()
+
+	installed, err := cli.List(x.Positional.Snaps)
+	if err != nil {
+		return err
+	}
+	isInstalled := make(map[string]bool, len(installed))
+	for _, snap := range installed {
+		isInstalled[snap.Name] = true
+	}
+
+	snaps := make([]*client.Snap, 0, len(x.Positional.Snaps))
+	for _, name := range x.Positional.Snaps {
+		if isInstalled[name] {
+			snap, _, err := cli.Snap(name)
+			if err != nil {
+				return err
+			}
+			snaps = append(snaps, snap)
+			continue
+		}
+
+		found, _, err := cli.Find(&client.FindOptions{Name: name})
+		if err != nil {
+			return err
+		}
+		if len(found) != 1 {
+			return fmt.Errorf(i18n.G("no snap found for %q"), name)
+		}
+		snaps = append(snaps, found[0])
+	}
+
+	if structuredOutput() {
+		return writeStructured(snaps)
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	for i, snap := range snaps {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		if isInstalled[snap.Name] {
+			printInstalledInfo(w, snap)
+		} else {
+			printStoreInfo(w, snap)
+		}
+	}
+
+	return nil
+}
+
+func printCommonInfo(w io.Writer, snap *client.Snap) {
+	fmt.Fprintf(w, "name:\t%s\n", snap.Name)
+	fmt.Fprintf(w, "summary:\t%s\n", snap.Summary)
+	fmt.Fprintf(w, "publisher:\t%s\n", snap.Developer)
+	fmt.Fprintf(w, "type:\t%s\n", snap.Type)
+	if desc := strings.TrimSpace(snap.Description); desc != "" {
+		fmt.Fprintln(w, "description: |")
+		for _, line := range strings.Split(desc, "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+}
+
+func printInstalledInfo(w io.Writer, snap *client.Snap) {
+	printCommonInfo(w, snap)
+
+	tracking := snap.TrackingChannel
+	if tracking == "" {
+		tracking = "-"
+	}
+	fmt.Fprintf(w, "tracking:\t%s\n", tracking)
+	fmt.Fprintf(w, "disk-size:\t%s\n", sizeString(snap.DiskSize))
+
+	fmt.Fprintln(w, "revisions:")
+	for _, rev := range snap.Revisions {
+		date := "-"
+		if !rev.InstallDate.IsZero() {
+			date = rev.InstallDate.UTC().Format("2006-01-02")
+		}
+		channel := rev.Channel
+		if channel == "" {
+			channel = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s", rev.Revision, rev.Version, channel, date, sizeString(rev.Size))
+		if rev.Current {
+			fmt.Fprintf(w, "\t%s", i18n.G("current"))
+		}
+		fmt.Fprintln(w)
+	}
+
+	var apps, services []client.AppInfo
+	for _, app := range snap.Apps {
+		if app.Daemon != "" {
+			services = append(services, app)
+		} else {
+			apps = append(apps, app)
+		}
+	}
+	if len(apps) > 0 {
+		fmt.Fprintln(w, "apps:")
+		for _, app := range apps {
+			fmt.Fprintf(w, "  %s\n", appCommand(snap.Name, app.Name))
+		}
+	}
+	if len(services) > 0 {
+		fmt.Fprintln(w, "services:")
+		for _, app := range services {
+			fmt.Fprintf(w, "  %s\t%s\n", appCommand(snap.Name, app.Name), app.Daemon)
+		}
+	}
+
+	if len(snap.Plugs) > 0 {
+		fmt.Fprintln(w, "plugs:")
+		for _, plug := range snap.Plugs {
+			conns := make([]string, len(plug.Connections))
+			for i, slot := range plug.Connections {
+				conns[i] = fmt.Sprintf("%s:%s", slot.Snap, slot.Name)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", plug.Name, plug.Interface, connectionStatus(conns))
+		}
+	}
+	if len(snap.Slots) > 0 {
+		fmt.Fprintln(w, "slots:")
+		for _, slot := range snap.Slots {
+			conns := make([]string, len(slot.Connections))
+			for i, plug := range slot.Connections {
+				conns[i] = fmt.Sprintf("%s:%s", plug.Snap, plug.Name)
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", slot.Name, slot.Interface, connectionStatus(conns))
+		}
+	}
+}
+
+func printStoreInfo(w io.Writer, snap *client.Snap) {
+	printCommonInfo(w, snap)
+
+	fmt.Fprintln(w, "channels:")
+	channels := make([]string, 0, len(snap.Channels))
+	for channel := range snap.Channels {
+		channels = append(channels, channel)
+	}
+	sort.Sort(byRisk(channels))
+	for _, channel := range channels {
+		ch := snap.Channels[channel]
+		fmt.Fprintf(w, "  %s:\t%s\t(%s)\t%s\t%s\n", channel, ch.Version, ch.Revision, sizeString(ch.Size), ch.Confinement)
+	}
+}
+
+// appCommand returns the command that runs the given app.
+func appCommand(snapName, appName string) string {
+	if snapName == appName {
+		return appName
+	}
+	return snapName + "." + appName
+}
+
+func connectionStatus(conns []string) string {
+	if len(conns) == 0 {
+		return "-"
+	}
+	return strings.Join(conns, ",")
+}
+
+var channelRisks = []string{"stable", "candidate", "beta", "edge"}
+
+// byRisk sorts channel names from the most to the least stable one.
+type byRisk []string
+
+func riskIndex(channel string) int {
+	for i, risk := range channelRisks {
+		if risk == channel {
+			return i
+		}
+	}
+	return len(channelRisks)
+}
+
+func (r byRisk) Len() int      { return len(r) }
+func (r byRisk) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRisk) Less(i, j int) bool {
+	ri, rj := riskIndex(r[i]), riskIndex(r[j])
+	if ri != rj {
+		return ri < rj
+	}
+	return r[i] < r[j]
+}
+
+// sizeString returns a human readable representation of the given
+// amount of bytes.
+func sizeString(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
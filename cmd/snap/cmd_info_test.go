@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestInfoInstalled(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": [{"name": "foo", "status": "active", "version": "4.2", "developer": "bar", "revision": 17}]}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+			fmt.Fprintln(w, `{"type": "sync", "result": {
+"name": "foo", "status": "active", "version": "4.2", "developer": "bar", "revision": 17, "type": "app",
+"summary": "The foo", "description": "Foo does\nthings.", "tracking-channel": "stable", "disk-size": 2000,
+"revisions": [{"revision": 17, "version": "4.2", "channel": "stable", "install-date": "2016-01-02T15:04:05Z", "size": 1000, "current": true},
+              {"revision": 15, "version": "4.1", "channel": "stable", "install-date": "2016-01-01T15:04:05Z", "size": 1000}],
+"apps": [{"name": "foo"}, {"name": "svc", "daemon": "simple"}],
+"plugs": [{"snap": "foo", "plug": "network", "interface": "network", "connections": [{"snap": "ubuntu-core", "slot": "network"}]}],
+"slots": [{"snap": "foo", "slot": "data", "interface": "content"}]
+}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"info", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `name:       foo
+summary:    The foo
+publisher:  bar
+type:       app
+description: |
+  Foo does
+  things.
+tracking:   stable
+disk-size:  2.0kB
+revisions:
+  17  4.2  stable  2016-01-02  1.0kB  current
+  15  4.1  stable  2016-01-01  1.0kB
+apps:
+  foo
+services:
+  foo.svc  simple
+plugs:
+  network  network  ubuntu-core:network
+slots:
+  data  content  -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestInfoStore(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		case 1:
+			c.Check(r.URL.Path, check.Equals, "/v2/find")
+			c.Check(r.URL.Query().Get("name"), check.Equals, "hello")
+			fmt.Fprintln(w, `{"type": "sync", "result": [{
+"name": "hello", "status": "available", "version": "2.0", "developer": "canonical", "type": "app", "summary": "Hello",
+"channels": {
+  "edge": {"revision": 20, "version": "2.1", "size": 65536, "confinement": "devmode"},
+  "stable": {"revision": 18, "version": "2.0", "size": 65536, "confinement": "strict"}
+}}]}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	_, err := snap.Parser().ParseArgs([]string{"info", "hello"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `name:       hello
+summary:    Hello
+publisher:  canonical
+type:       app
+channels:
+  stable:  2.0  (18)  65.5kB  strict
+  edge:    2.1  (20)  65.5kB  devmode
+`)
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestInfoNotFound(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		case 1:
+			fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	_, err := snap.Parser().ParseArgs([]string{"info", "hello"})
+	c.Assert(err, check.ErrorMatches, `no snap found for "hello"`)
+}
@@ -21,12 +21,20 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 
 	"github.com/jessevdk/go-flags"
 )
 
+// connRef identifies a connection between a plug and a slot.
+type connRef struct {
+	plug client.PlugRef
+	slot client.SlotRef
+}
+
 type cmdInterfaces struct {
 	Interface   string `short:"i" description:"constrain listing to specific interfaces"`
 	Positionals struct {
@@ -51,6 +59,8 @@ Lists the slots offered and plugs used by the specified snap.
 $ snap interfaces -i=<interface> [<snap>]
 
 Filters the complete output so only plugs and/or slots matching the provided details are listed.
+
+Connections are marked with (auto) when snapd made them automatically and with (manual) when they were requested.
 `)
 
 func init() {
@@ -61,69 +71,126 @@ func init() {
 
 func (x *cmdInterfaces) Execute(args []string) error {
 	ifaces, err := Client().Interfaces()
-	if err == nil {
-		if len(ifaces.Plugs) == 0 && len(ifaces.Slots) == 0 {
-			return fmt.Errorf(i18n.G("no interfaces found"))
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return writeStructured(x.filter(ifaces))
+	}
+
+	if len(ifaces.Plugs) == 0 && len(ifaces.Slots) == 0 {
+		return fmt.Errorf(i18n.G("no interfaces found"))
+	}
+	ifaces = x.filter(ifaces)
+
+	// How each connection was made, when the daemon tells.
+	how := make(map[connRef]string, len(ifaces.Connections))
+	for _, conn := range ifaces.Connections {
+		key := connRef{conn.Plug, conn.Slot}
+		if conn.Auto {
+			how[key] = i18n.G("auto")
+		} else {
+			how[key] = i18n.G("manual")
 		}
-		w := tabWriter()
-		fmt.Fprintln(w, i18n.G("Slot\tPlug"))
-		defer w.Flush()
-		for _, slot := range ifaces.Slots {
-			if wanted := x.Positionals.Query.Snap; wanted != "" {
-				ok := wanted == slot.Snap
-				for i := 0; i < len(slot.Connections) && !ok; i++ {
-					ok = wanted == slot.Connections[i].Snap
-				}
-				if !ok {
-					continue
-				}
-			}
-			if x.Positionals.Query.Name != "" && x.Positionals.Query.Name != slot.Name {
-				continue
-			}
-			if x.Interface != "" && slot.Interface != x.Interface {
-				continue
-			}
-			// The OS snap (always ubuntu-core) is special and enable abbreviated
-			// display syntax on the slot-side of the connection.
-			if slot.Snap == "ubuntu-core" {
-				fmt.Fprintf(w, ":%s\t", slot.Name)
+	}
+
+	t := newColumnTable(
+		column{"slot", i18n.G("Slot")},
+		column{"plug", i18n.G("Plug")},
+	)
+	for _, slot := range ifaces.Slots {
+		var slotName string
+		// The OS snap (always ubuntu-core) is special and enable abbreviated
+		// display syntax on the slot-side of the connection.
+		if slot.Snap == "ubuntu-core" {
+			slotName = fmt.Sprintf(":%s", slot.Name)
+		} else {
+			slotName = fmt.Sprintf("%s:%s", slot.Snap, slot.Name)
+		}
+		plugNames := make([]string, len(slot.Connections))
+		for i, plug := range slot.Connections {
+			if plug.Name != slot.Name {
+				plugNames[i] = fmt.Sprintf("%s:%s", plug.Snap, plug.Name)
 			} else {
-				fmt.Fprintf(w, "%s:%s\t", slot.Snap, slot.Name)
+				plugNames[i] = plug.Snap
 			}
-			for i := 0; i < len(slot.Connections); i++ {
-				if i > 0 {
-					fmt.Fprint(w, ",")
-				}
-				if slot.Connections[i].Name != slot.Name {
-					fmt.Fprintf(w, "%s:%s", slot.Connections[i].Snap, slot.Connections[i].Name)
-				} else {
-					fmt.Fprintf(w, "%s", slot.Connections[i].Snap)
-				}
+			key := connRef{plug, client.SlotRef{Snap: slot.Snap, Name: slot.Name}}
+			if how, ok := how[key]; ok {
+				plugNames[i] += fmt.Sprintf(" (%s)", how)
 			}
-			// Display visual indicator for disconnected slots
-			if len(slot.Connections) == 0 {
-				fmt.Fprint(w, "-")
-			}
-			fmt.Fprintf(w, "\n")
 		}
-		// Plugs are treated differently. Since the loop above already printed each connected
-		// plug, the loop below focuses on printing just the disconnected plugs.
-		for _, plug := range ifaces.Plugs {
-			if x.Positionals.Query.Snap != "" && x.Positionals.Query.Snap != plug.Snap {
-				continue
-			}
-			if x.Positionals.Query.Name != "" && x.Positionals.Query.Name != plug.Name {
-				continue
+		// Display visual indicator for disconnected slots
+		if len(slot.Connections) == 0 {
+			plugNames = []string{"-"}
+		}
+		t.addRow(slotName, strings.Join(plugNames, ","))
+	}
+	// Plugs are treated differently. Since the loop above already printed each connected
+	// plug, the loop below focuses on printing just the disconnected plugs.
+	for _, plug := range ifaces.Plugs {
+		// Display visual indicator for disconnected plugs.
+		if len(plug.Connections) == 0 {
+			t.addRow("-", fmt.Sprintf("%s:%s", plug.Snap, plug.Name))
+		}
+	}
+
+	return t.write()
+}
+
+// filter returns the slots and plugs matching the query and the requested
+// interface.
+func (x *cmdInterfaces) filter(ifaces client.Interfaces) client.Interfaces {
+	var filtered client.Interfaces
+	for _, slot := range ifaces.Slots {
+		if wanted := x.Positionals.Query.Snap; wanted != "" {
+			ok := wanted == slot.Snap
+			for i := 0; i < len(slot.Connections) && !ok; i++ {
+				ok = wanted == slot.Connections[i].Snap
 			}
-			if x.Interface != "" && plug.Interface != x.Interface {
+			if !ok {
 				continue
 			}
-			// Display visual indicator for disconnected plugs.
-			if len(plug.Connections) == 0 {
-				fmt.Fprintf(w, "-\t%s:%s\n", plug.Snap, plug.Name)
-			}
+		}
+		if x.Positionals.Query.Name != "" && x.Positionals.Query.Name != slot.Name {
+			continue
+		}
+		if x.Interface != "" && slot.Interface != x.Interface {
+			continue
+		}
+		filtered.Slots = append(filtered.Slots, slot)
+	}
+	for _, plug := range ifaces.Plugs {
+		if x.Positionals.Query.Snap != "" && x.Positionals.Query.Snap != plug.Snap {
+			continue
+		}
+		if x.Positionals.Query.Name != "" && x.Positionals.Query.Name != plug.Name {
+			continue
+		}
+		if x.Interface != "" && plug.Interface != x.Interface {
+			continue
+		}
+		filtered.Plugs = append(filtered.Plugs, plug)
+	}
+	for _, conn := range ifaces.Connections {
+		if involvesAny(conn, filtered.Slots, filtered.Plugs) {
+			filtered.Connections = append(filtered.Connections, conn)
+		}
+	}
+	return filtered
+}
+
+// involvesAny returns whether the connection involves one of the given slots
+// or plugs.
+func involvesAny(conn client.Connection, slots []client.Slot, plugs []client.Plug) bool {
+	for _, slot := range slots {
+		if slot.Snap == conn.Slot.Snap && slot.Name == conn.Slot.Name {
+			return true
+		}
+	}
+	for _, plug := range plugs {
+		if plug.Snap == conn.Plug.Snap && plug.Name == conn.Plug.Name {
+			return true
 		}
 	}
-	return err
+	return false
 }
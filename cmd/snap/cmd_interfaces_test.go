@@ -51,8 +51,13 @@ $ snap interfaces -i=<interface> [<snap>]
 Filters the complete output so only plugs and/or slots matching the provided
 details are listed.
 
+Connections are marked with (auto) when snapd made them automatically and with
+(manual) when they were requested.
+
 Application Options:
       --version                    print the version and exit
+      --format=[table|json|yaml]   output format (default: table)
+      --columns=                   comma-separated table columns to show
 
 Help Options:
   -h, --help                       Show this help message
@@ -526,3 +531,59 @@ func (s *SnapSuite) TestInterfacesNothingAtAll(c *C) {
 	c.Assert(s.Stdout(), Equals, "")
 	c.Assert(s.Stderr(), Equals, "")
 }
+
+func (s *SnapSuite) TestInterfacesMarksAutoAndManualConnections(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/interfaces")
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": client.Interfaces{
+				Slots: []client.Slot{
+					{
+						Snap:      "ubuntu-core",
+						Name:      "network",
+						Interface: "network",
+						Connections: []client.PlugRef{
+							{Snap: "foo", Name: "network"},
+							{Snap: "bar", Name: "net"},
+						},
+					},
+				},
+				Plugs: []client.Plug{
+					{
+						Snap:        "foo",
+						Name:        "network",
+						Interface:   "network",
+						Connections: []client.SlotRef{{Snap: "ubuntu-core", Name: "network"}},
+					},
+					{
+						Snap:        "bar",
+						Name:        "net",
+						Interface:   "network",
+						Connections: []client.SlotRef{{Snap: "ubuntu-core", Name: "network"}},
+					},
+				},
+				Connections: []client.Connection{
+					{
+						Plug: client.PlugRef{Snap: "foo", Name: "network"},
+						Slot: client.SlotRef{Snap: "ubuntu-core", Name: "network"},
+						Auto: true,
+					},
+					{
+						Plug: client.PlugRef{Snap: "bar", Name: "net"},
+						Slot: client.SlotRef{Snap: "ubuntu-core", Name: "network"},
+					},
+				},
+			},
+		})
+	})
+	rest, err := Parser().ParseArgs([]string{"interfaces"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	expectedStdout := "" +
+		"Slot      Plug\n" +
+		":network  foo (auto),bar:net (manual)\n"
+	c.Assert(s.Stdout(), Equals, expectedStdout)
+	c.Assert(s.Stderr(), Equals, "")
+}
@@ -0,0 +1,80 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortKeysHelp = i18n.G("Lists key pairs usable for signing assertions")
+var longKeysHelp = i18n.G(`
+The keys command lists the cryptographic key pairs that can be used for
+signing assertions, as created with create-key.
+`)
+
+type cmdKeys struct{}
+
+func init() {
+	addCommand("keys", shortKeysHelp, longKeysHelp, func() flags.Commander { return &cmdKeys{} })
+}
+
+// keyJSON is the machine-readable representation of a key pair.
+type keyJSON struct {
+	Name        string `json:"name"`
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+func (x *cmdKeys) Execute(args []string) error {
+	manager, err := getKeypairManager()
+	if err != nil {
+		return err
+	}
+
+	var keys []keyJSON
+	err = manager.Walk(func(privk asserts.PrivateKey, fingerprint string, uid string) error {
+		keys = append(keys, keyJSON{
+			Name:        uid,
+			ID:          privk.PublicKey().ID(),
+			Fingerprint: fingerprint,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if structuredOutput() {
+		return writeStructured(keys)
+	}
+
+	t := newColumnTable(
+		column{"name", i18n.G("Name")},
+		column{"id", i18n.G("ID")},
+		column{"fingerprint", i18n.G("Fingerprint")},
+	)
+	for _, key := range keys {
+		t.addRow(key.Name, key.ID, key.Fingerprint)
+	}
+
+	return t.write()
+}
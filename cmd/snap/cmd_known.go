@@ -51,7 +51,17 @@ func init() {
 
 var nl = []byte{'\n'}
 
+// assertionJSON is the machine-readable representation of an assertion.
+type assertionJSON struct {
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
 func (x *cmdKnown) Execute(args []string) error {
+	if err := checkNoColumns("known"); err != nil {
+		return err
+	}
+
 	// TODO: share this kind of parsing once it's clearer how often is used in snap
 	headers := map[string]string{}
 	for _, headerFilter := range x.KnownOptions.HeaderFilters {
@@ -67,6 +77,17 @@ func (x *cmdKnown) Execute(args []string) error {
 		return err
 	}
 
+	if structuredOutput() {
+		out := make([]assertionJSON, len(assertions))
+		for i, a := range assertions {
+			out[i] = assertionJSON{
+				Headers: a.Headers(),
+				Body:    string(a.Body()),
+			}
+		}
+		return writeStructured(out)
+	}
+
 	enc := asserts.NewEncoder(Stdout)
 	for _, a := range assertions {
 		enc.Encode(a)
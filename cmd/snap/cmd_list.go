@@ -59,16 +59,25 @@ func listSnaps(names []string) error {
 	snaps, err := cli.List(names)
 	if err != nil {
 		return err
-	} else if len(snaps) == 0 {
-		fmt.Fprintln(Stderr, i18n.G("No snaps are installed yet. Try 'snap install hello-world'."))
-		return nil
 	}
 	sort.Sort(snapsByName(snaps))
 
-	w := tabWriter()
-	defer w.Flush()
+	if structuredOutput() {
+		return writeStructured(snaps)
+	}
+
+	if len(snaps) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No snaps are installed yet. Try 'snap install hello-world'."))
+		return nil
+	}
 
-	fmt.Fprintln(w, i18n.G("Name\tVersion\tRev\tDeveloper\tNotes"))
+	t := newColumnTable(
+		column{"name", i18n.G("Name")},
+		column{"version", i18n.G("Version")},
+		column{"rev", i18n.G("Rev")},
+		column{"developer", i18n.G("Developer")},
+		column{"notes", i18n.G("Notes")},
+	)
 
 	for _, snap := range snaps {
 		notes := &Notes{
@@ -76,10 +85,10 @@ func listSnaps(names []string) error {
 			DevMode: snap.DevMode,
 			TryMode: snap.TryMode,
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", snap.Name, snap.Version, snap.Revision, snap.Developer, notes)
+		t.addRow(snap.Name, snap.Version, snap.Revision.String(), snap.Developer, notes.String())
 	}
 
-	return nil
+	return t.write()
 }
 
 func tabWriter() *tabwriter.Writer {
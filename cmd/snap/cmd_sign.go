@@ -0,0 +1,112 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
+)
+
+var shortSignHelp = i18n.G("Signs an assertion")
+var longSignHelp = i18n.G(`
+The sign command reads the headers of an assertion as a JSON or YAML mapping
+from standard input, signs it with the given key and writes the encoded
+assertion to standard output.
+
+The type of the assertion is taken from its "type" header. A "body" entry,
+if present, is used as the body of the assertion.
+`)
+
+type cmdSign struct {
+	KeyName string `short:"k" long:"key" default:"default" description:"name of the key to use, otherwise use the default key"`
+}
+
+func init() {
+	addCommand("sign", shortSignHelp, longSignHelp, func() flags.Commander { return &cmdSign{} })
+}
+
+func (x *cmdSign) Execute(args []string) error {
+	input, err := ioutil.ReadAll(Stdin)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot read assertion input: %v"), err)
+	}
+
+	var statement map[string]interface{}
+	if err := yaml.Unmarshal(input, &statement); err != nil {
+		return fmt.Errorf(i18n.G("cannot parse the assertion input as JSON or YAML: %v"), err)
+	}
+
+	headers := make(map[string]string, len(statement))
+	var body []byte
+	for name, value := range statement {
+		var str string
+		switch v := value.(type) {
+		case nil:
+			// empty header
+		case string:
+			str = v
+		case int, bool:
+			str = fmt.Sprint(v)
+		default:
+			return fmt.Errorf(i18n.G("header %q must be a string"), name)
+		}
+		if name == "body" {
+			body = []byte(str)
+			continue
+		}
+		headers[name] = str
+	}
+
+	typeName := headers["type"]
+	if typeName == "" {
+		return fmt.Errorf(i18n.G("missing assertion type header"))
+	}
+	assertType := asserts.Type(typeName)
+	if assertType == nil {
+		return fmt.Errorf(i18n.G("invalid assertion type: %q"), typeName)
+	}
+
+	manager, err := getKeypairManager()
+	if err != nil {
+		return err
+	}
+	privKey, err := manager.GetByName(x.KeyName)
+	if err != nil {
+		return err
+	}
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		KeypairManager: manager,
+	})
+	if err != nil {
+		return err
+	}
+	a, err := db.Sign(assertType, headers, body, privKey.PublicKey().ID())
+	if err != nil {
+		return err
+	}
+
+	return asserts.NewEncoder(Stdout).Encode(a)
+}
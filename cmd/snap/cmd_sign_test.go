@@ -0,0 +1,148 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+// +build !integrationcoverage
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const statement = `{
+  "type": "snap-build",
+  "authority-id": "devel1",
+  "series": "16",
+  "snap-id": "snapidsnapidsnapidsnapidsnapidsn",
+  "snap-digest": "sha512-abcd",
+  "grade": "devel",
+  "snap-size": "1025",
+  "timestamp": "2015-11-25T20:00:00Z"
+}`
+
+func (s *SnapKeysSuite) TestSignJSON(c *C) {
+	s.stdin.Write([]byte(statement))
+
+	rest, err := snap.Parser().ParseArgs([]string{"sign", "-k", "default"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+
+	a, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.SnapBuildType)
+	c.Check(a.AuthorityID(), Equals, "devel1")
+	c.Check(a.Header("snap-digest"), Equals, "sha512-abcd")
+	keyID, err := asserts.SignKeyID(a)
+	c.Assert(err, IsNil)
+	c.Check(keyID, Equals, defaultKeyID)
+}
+
+func (s *SnapKeysSuite) TestSignYAML(c *C) {
+	s.stdin.Write([]byte(`type: model
+authority-id: brand1
+series: 16
+brand-id: brand1
+model: baz-3000
+class: fridge
+os: core
+architecture: amd64
+gadget: brand-gadget
+kernel: baz-linux
+store: brand-store
+allowed-modes:
+required-snaps: foo, bar
+timestamp: 2015-11-25T20:00:00Z
+revision: 2
+`))
+
+	_, err := snap.Parser().ParseArgs([]string{"sign"})
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ModelType)
+	c.Check(a.Revision(), Equals, 2)
+	model := a.(*asserts.Model)
+	c.Check(model.Model(), Equals, "baz-3000")
+	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
+}
+
+func (s *SnapKeysSuite) TestSignYAMLBool(c *C) {
+	s.stdin.Write([]byte(`type: validation
+authority-id: canonical
+series: 16
+snap-id: snapidsnapidsnapidsnapidsnapidsn
+approved-snap-id: othersnapidothersnapidothersnapi
+approved-snap-revision: 42
+revoked: true
+timestamp: 2015-11-25T20:00:00Z
+`))
+
+	_, err := snap.Parser().ParseArgs([]string{"sign"})
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(a.Header("revoked"), Equals, "true")
+	c.Check(a.(*asserts.Validation).Revoked(), Equals, true)
+}
+
+func (s *SnapKeysSuite) TestSignWithBody(c *C) {
+	s.stdin.Write([]byte(`type: account
+authority-id: canonical
+account-id: devel1
+display-name: Devel One
+validation: unproven
+timestamp: 2015-11-25T20:00:00Z
+body: some body
+`))
+
+	_, err := snap.Parser().ParseArgs([]string{"sign"})
+	c.Assert(err, IsNil)
+
+	a, err := asserts.Decode([]byte(s.Stdout()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.AccountType)
+	c.Check(string(a.Body()), Equals, "some body")
+}
+
+func (s *SnapKeysSuite) TestSignErrors(c *C) {
+	tests := []struct {
+		input, expectedErr string
+	}{
+		{"- a\n- b\n", "(?s)cannot parse the assertion input as JSON or YAML: .*"},
+		{"series: \"16\"\n", "missing assertion type header"},
+		{"type: unknown\n", `invalid assertion type: "unknown"`},
+		{"type: model\nrequired-snaps: [foo, bar]\n", `header "required-snaps" must be a string`},
+	}
+	for _, test := range tests {
+		s.stdin.Reset()
+		s.stdin.Write([]byte(test.input))
+		_, err := snap.Parser().ParseArgs([]string{"sign"})
+		c.Check(err, ErrorMatches, test.expectedErr)
+	}
+	c.Check(s.Stdout(), Equals, "")
+}
+
+func (s *SnapKeysSuite) TestSignNonexistentKey(c *C) {
+	s.stdin.Write([]byte(statement))
+	_, err := snap.Parser().ParseArgs([]string{"sign", "--key", "nonexistent"})
+	c.Assert(err, ErrorMatches, `cannot find key named "nonexistent" in GPG keyring`)
+}
@@ -103,6 +103,10 @@ var (
 
 var longInstallHelp = i18n.G(`
 The install command installs the named snap in the system.
+
+A snap file can be verified with the assertions in the files given with
+--assertions, or the ones acknowledged before with 'snap ack', to install
+it as coming from the store.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -145,8 +149,9 @@ func (x *cmdRemove) Execute([]string) error {
 }
 
 type cmdInstall struct {
-	Channel    string `long:"channel" description:"Install from this channel instead of the device's default"`
-	DevMode    bool   `long:"devmode" description:"Install the snap with non-enforcing security"`
+	Channel    string   `long:"channel" description:"Install from this channel instead of the device's default"`
+	DevMode    bool     `long:"devmode" description:"Install the snap with non-enforcing security"`
+	Assertions []string `long:"assertions" description:"Verify the snap file with the assertions in this file (can be repeated)"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
@@ -162,8 +167,12 @@ func (x *cmdInstall) Execute([]string) error {
 	opts := &client.SnapOptions{Channel: x.Channel, DevMode: x.DevMode}
 	if strings.Contains(name, "/") || strings.HasSuffix(name, ".snap") || strings.Contains(name, ".snap.") {
 		installFromFile = true
+		opts.Assertions = x.Assertions
 		changeID, err = cli.InstallPath(name, opts)
 	} else {
+		if len(x.Assertions) > 0 {
+			return fmt.Errorf(i18n.G("--assertions can only be used when installing a snap file"))
+		}
 		changeID, err = cli.Install(name, opts)
 	}
 	if err != nil {
@@ -175,6 +175,37 @@ func (s *SnapOpSuite) TestInstallPathDevMode(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestInstallPathWithAssertions(c *check.C) {
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		postData, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(postData), check.Matches, "(?s).*\r\nsnap-data\r\n.*")
+		c.Assert(string(postData), check.Matches, "(?s).*Content-Disposition: form-data; name=\"assertion\"; filename=\"foo.assert\"\r\n.*\r\nassert-data\r\n.*")
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	dir := c.MkDir()
+	snapPath := filepath.Join(dir, "foo.snap")
+	err := ioutil.WriteFile(snapPath, []byte("snap-data"), 0644)
+	c.Assert(err, check.IsNil)
+	assertPath := filepath.Join(dir, "foo.assert")
+	err = ioutil.WriteFile(assertPath, []byte("assert-data"), 0644)
+	c.Assert(err, check.IsNil)
+
+	rest, err := snap.Parser().ParseArgs([]string{"install", "--assertions", assertPath, snapPath})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo\s+1.0\s+42\s+bar.*`)
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestInstallAssertionsNeedSnapFile(c *check.C) {
+	_, err := snap.Parser().ParseArgs([]string{"install", "--assertions", "foo.assert", "foo"})
+	c.Assert(err, check.ErrorMatches, `--assertions can only be used when installing a snap file`)
+}
+
 func (s *SnapSuite) TestRefreshList(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
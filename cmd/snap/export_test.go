@@ -20,9 +20,21 @@
 
 package main
 
+import (
+	"github.com/snapcore/snapd/store"
+)
+
 var RunMain = run
 
 var (
 	SnapExecAppEnv = snapExecAppEnv
 	SnapRun        = snapRun
 )
+
+func MockStoreConfig(cfg *store.SnapUbuntuStoreConfig) (restore func()) {
+	old := storeConfig
+	storeConfig = cfg
+	return func() {
+		storeConfig = old
+	}
+}
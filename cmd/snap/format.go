@@ -0,0 +1,205 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+// Output formats supported by the listing commands via --format.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// structuredOutput returns whether a machine-readable output format
+// was requested.
+func structuredOutput() bool {
+	return optionsData.Format == formatJSON || optionsData.Format == formatYAML
+}
+
+// checkNoColumns rejects --columns for the given command, which has no
+// table output.
+func checkNoColumns(cmd string) error {
+	if optionsData.Columns != "" {
+		return fmt.Errorf(i18n.G("--columns cannot be used with %q, it has no table output"), cmd)
+	}
+	return nil
+}
+
+// writeStructured writes v to Stdout using the requested machine-readable
+// format. The JSON is the plain encoding of the client package types, so
+// scripts can rely on the same field names the REST API uses; the YAML
+// output uses the very same keys.
+func writeStructured(v interface{}) error {
+	if optionsData.Columns != "" {
+		return fmt.Errorf(i18n.G("--columns can only be used with the table format"))
+	}
+
+	// make empty lists show up as such and not as null
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.IsNil() {
+		v = []interface{}{}
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if optionsData.Format == formatJSON {
+		_, err = fmt.Fprintf(Stdout, "%s\n", b)
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return err
+	}
+	y, err := yaml.Marshal(fromJSONNumbers(generic))
+	if err != nil {
+		return err
+	}
+	_, err = Stdout.Write(y)
+	return err
+}
+
+// fromJSONNumbers replaces json.Number values with int64 or float64 so
+// that they get rendered as plain numbers in the YAML output.
+func fromJSONNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = fromJSONNumbers(e)
+		}
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+		return x.String()
+	}
+	return v
+}
+
+// column describes a column of the table output of a listing command.
+type column struct {
+	// name is what identifies the column for --columns.
+	name string
+	// header is the (translated) heading shown for the column.
+	header string
+}
+
+// columnTable accumulates the rows of a listing command and writes
+// them honouring the --columns selection.
+type columnTable struct {
+	columns []column
+	rows    [][]string
+}
+
+func newColumnTable(columns ...column) *columnTable {
+	return &columnTable{columns: columns}
+}
+
+// addRow adds a row; cells must be given in the order of the columns.
+func (t *columnTable) addRow(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// selected returns the indexes of the columns to show.
+func (t *columnTable) selected() ([]int, error) {
+	if optionsData.Columns == "" {
+		idxs := make([]int, len(t.columns))
+		for i := range t.columns {
+			idxs[i] = i
+		}
+		return idxs, nil
+	}
+
+	var idxs []int
+	for _, name := range strings.Split(optionsData.Columns, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		idx := -1
+		for i, col := range t.columns {
+			if col.name == name {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			names := make([]string, len(t.columns))
+			for i, col := range t.columns {
+				names[i] = col.name
+			}
+			return nil, fmt.Errorf(i18n.G("unknown column %q (available: %s)"), name, strings.Join(names, ", "))
+		}
+		idxs = append(idxs, idx)
+	}
+	if len(idxs) == 0 {
+		return nil, fmt.Errorf(i18n.G("no columns selected"))
+	}
+
+	return idxs, nil
+}
+
+// write writes the table to Stdout.
+func (t *columnTable) write() error {
+	idxs, err := t.selected()
+	if err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	cells := make([]string, len(idxs))
+	for i, idx := range idxs {
+		cells[i] = t.columns[idx].header
+	}
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+
+	for _, row := range t.rows {
+		for i, idx := range idxs {
+			cells[i] = row[idx]
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const listJSON = `{"type": "sync", "result": [{"name": "foo", "status": "active", "version": "4.2", "developer": "bar", "revision": 17, "installed-size": 1048576}]}`
+
+func (s *SnapSuite) TestListFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+		fmt.Fprintln(w, listJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"list", "--format=json"})
+	c.Assert(err, check.IsNil)
+
+	var snaps []*client.Snap
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &snaps), check.IsNil)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0].Name, check.Equals, "foo")
+	c.Check(snaps[0].Revision.N, check.Equals, 17)
+	c.Check(snaps[0].InstalledSize, check.Equals, int64(1048576))
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListFormatJSONEmpty(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"--format=json", "list"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "[]\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestListFormatYAML(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, listJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"list", "--format=yaml"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?ms)^- .*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*  name: foo$.*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*  installed-size: 1048576$.*`)
+	c.Check(s.Stdout(), check.Matches, `(?ms).*  revision: "17"$.*`)
+}
+
+func (s *SnapSuite) TestListFormatBogus(c *check.C) {
+	_, err := snap.Parser().ParseArgs([]string{"list", "--format=xml"})
+	c.Assert(err, check.ErrorMatches, `.*Allowed values are: table, json or yaml`)
+}
+
+func (s *SnapSuite) TestListColumns(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, listJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"list", "--columns=rev,name"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Rev  Name\n17   foo\n")
+}
+
+func (s *SnapSuite) TestListColumnsUnknown(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, listJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"list", "--columns=name,size"})
+	c.Assert(err, check.ErrorMatches, `unknown column "size" \(available: name, version, rev, developer, notes\)`)
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestColumnsNeedTable(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, listJSON)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"list", "--format=json", "--columns=name"})
+	c.Assert(err, check.ErrorMatches, `--columns can only be used with the table format`)
+}
+
+func (s *SnapSuite) TestColumnsUnsupported(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"known", "--columns=name", "model"})
+	c.Check(err, check.ErrorMatches, `--columns cannot be used with "known", it has no table output`)
+	_, err = snap.Parser().ParseArgs([]string{"info", "--columns=name", "foo"})
+	c.Check(err, check.ErrorMatches, `--columns cannot be used with "info", it has no table output`)
+}
+
+func (s *SnapSuite) TestChangesFormatJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		fmt.Fprintln(w, `{"type": "sync", "result": [{"id": "42", "kind": "install-snap", "summary": "some summary", "status": "Done", "ready": true, "spawn-time": "2016-04-21T01:02:03Z", "ready-time": "2016-04-21T01:02:04Z"}]}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"changes", "--format=json"})
+	c.Assert(err, check.IsNil)
+
+	var chgs []*client.Change
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &chgs), check.IsNil)
+	c.Assert(chgs, check.HasLen, 1)
+	c.Check(chgs[0].ID, check.Equals, "42")
+	c.Check(chgs[0].Status, check.Equals, "Done")
+	c.Check(chgs[0].Ready, check.Equals, true)
+}
+
+func (s *SnapSuite) TestInterfacesFormatJSONFiltered(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		EncodeResponseBody(c, w, map[string]interface{}{
+			"type": "sync",
+			"result": client.Interfaces{
+				Plugs: []client.Plug{
+					{Snap: "canonical-pi2", Name: "pin-13", Interface: "bool-file"},
+					{Snap: "keyboard-lights", Name: "capslock-led", Interface: "bool-file"},
+				},
+			},
+		})
+	})
+	_, err := snap.Parser().ParseArgs([]string{"interfaces", "--format=json", "keyboard-lights"})
+	c.Assert(err, check.IsNil)
+
+	var ifaces client.Interfaces
+	c.Assert(json.Unmarshal(s.stdout.Bytes(), &ifaces), check.IsNil)
+	c.Check(ifaces.Plugs, check.DeepEquals, []client.Plug{
+		{Snap: "keyboard-lights", Name: "capslock-led", Interface: "bool-file"},
+	})
+	c.Check(ifaces.Slots, check.HasLen, 0)
+}
@@ -0,0 +1,53 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// gpgHome returns the GnuPG home holding the snap signing keys, it
+// defaults to ~/.snap/gnupg and can be overridden with $SNAP_GNUPG_HOME.
+func gpgHome() (string, error) {
+	if homedir := os.Getenv("SNAP_GNUPG_HOME"); homedir != "" {
+		return homedir, nil
+	}
+	home, err := osutil.CurrentHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".snap", "gnupg"), nil
+}
+
+// getKeypairManager returns the key pair manager used by the key
+// handling commands, making sure its GnuPG home exists.
+func getKeypairManager() (*asserts.GPGKeypairManager, error) {
+	homedir, err := gpgHome()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(homedir, 0700); err != nil {
+		return nil, err
+	}
+	return asserts.NewGPGKeypairManager(homedir), nil
+}
@@ -42,6 +42,8 @@ var (
 
 type options struct {
 	Version func() `long:"version" description:"print the version and exit"`
+	Format  string `long:"format" description:"output format" choice:"table" choice:"json" choice:"yaml" default:"table"`
+	Columns string `long:"columns" description:"comma-separated table columns to show"`
 }
 
 var optionsData options
@@ -59,6 +61,9 @@ var commands []*cmdInfo
 // experimentalCommands holds information about all experimental commands.
 var experimentalCommands []*cmdInfo
 
+// debugCommands holds information about all debug commands.
+var debugCommands []*cmdInfo
+
 // addCommand replaces parser.addCommand() in a way that is compatible with
 // re-constructing a pristine parser.
 func addCommand(name, shortHelp, longHelp string, builder func() flags.Commander) *cmdInfo {
@@ -86,6 +91,20 @@ func addExperimentalCommand(name, shortHelp, longHelp string, builder func() fla
 	return info
 }
 
+// addDebugCommand replaces parser.addCommand() in a way that is
+// compatible with re-constructing a pristine parser. It is meant for
+// adding debug commands.
+func addDebugCommand(name, shortHelp, longHelp string, builder func() flags.Commander) *cmdInfo {
+	info := &cmdInfo{
+		name:      name,
+		shortHelp: shortHelp,
+		longHelp:  longHelp,
+		builder:   builder,
+	}
+	debugCommands = append(debugCommands, info)
+	return info
+}
+
 type parserSetter interface {
 	setParser(*flags.Parser)
 }
@@ -94,6 +113,7 @@ type parserSetter interface {
 // Since commands have local state a fresh parser is required to isolate tests
 // from each other.
 func Parser() *flags.Parser {
+	optionsData = options{}
 	optionsData.Version = func() {
 		cv, err := Client().ServerVersion()
 		if err != nil {
@@ -138,6 +158,20 @@ The snap tool interacts with the snapd daemon to control the snappy software pla
 		}
 		cmd.Hidden = c.hidden
 	}
+	// Add the debug command
+	debugCommand, err := parser.AddCommand("debug", shortDebugHelp, longDebugHelp, &cmdDebug{})
+	if err != nil {
+		logger.Panicf("cannot add command %q: %v", "debug", err)
+	}
+	debugCommand.Hidden = true
+	// Add all the sub-commands of the debug command
+	for _, c := range debugCommands {
+		cmd, err := debugCommand.AddCommand(c.name, c.shortHelp, strings.TrimSpace(c.longHelp), c.builder())
+		if err != nil {
+			logger.Panicf("cannot add debug command %q: %v", c.name, err)
+		}
+		cmd.Hidden = c.hidden
+	}
 	return parser
 }
 
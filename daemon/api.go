@@ -30,6 +30,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -41,7 +42,9 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -49,6 +52,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var api = []*Command{
@@ -60,14 +64,18 @@ var api = []*Command{
 	findCmd,
 	snapsCmd,
 	snapCmd,
+	snapSecurityCmd,
 	//FIXME: renenable config for GA
 	//snapConfigCmd,
 	interfacesCmd,
 	assertsCmd,
 	assertsFindManyCmd,
+	validationsCmd,
+	modelCmd,
 	eventsCmd,
 	stateChangeCmd,
 	stateChangesCmd,
+	createUserCmd,
 }
 
 var (
@@ -120,6 +128,12 @@ var (
 		GET:    getSnapInfo,
 		POST:   postSnap,
 	}
+	snapSecurityCmd = &Command{
+		Path:   "/v2/snaps/{name}/security",
+		UserOK: true,
+		GET:    getSnapSecurity,
+	}
+
 	//FIXME: renenable config for GA
 	/*
 		snapConfigCmd = &Command{
@@ -148,6 +162,18 @@ var (
 		GET:    assertsFindMany,
 	}
 
+	validationsCmd = &Command{
+		Path:   "/v2/validations",
+		UserOK: true,
+		GET:    getValidations,
+	}
+
+	modelCmd = &Command{
+		Path:   "/v2/model",
+		UserOK: true,
+		GET:    getModel,
+	}
+
 	eventsCmd = &Command{
 		Path: "/v2/events",
 		GET:  getEvents,
@@ -165,6 +191,11 @@ var (
 		UserOK: true,
 		GET:    getChanges,
 	}
+
+	createUserCmd = &Command{
+		Path: "/v2/create-user",
+		POST: postCreateUser,
+	}
 )
 
 func tbd(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -260,6 +291,110 @@ func logoutUser(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(nil, nil)
 }
 
+var (
+	storeFindUserInfo  = store.FindUserInfo
+	osutilAddExtraUser = osutil.AddExtraUser
+)
+
+type createUserResponseData struct {
+	Username string   `json:"username"`
+	SSHKeys  []string `json:"ssh-keys"`
+}
+
+// findSystemUser returns the system-user assertion for email that is
+// valid for this device and model right now, if any.
+func findSystemUser(db asserts.RODatabase, model *asserts.Model, email string) (*asserts.SystemUser, error) {
+	assertions, err := db.FindMany(asserts.SystemUserType, map[string]string{
+		"email": email,
+	})
+	if err == asserts.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, a := range assertions {
+		systemUser := a.(*asserts.SystemUser)
+		if !strutil.ListContains(systemUser.Series(), release.Series) {
+			continue
+		}
+		if systemUser.BrandID() != model.BrandID() {
+			continue
+		}
+		if !strutil.ListContains(systemUser.Models(), model.Model()) {
+			continue
+		}
+		if !systemUser.ValidAt(now) {
+			continue
+		}
+		return systemUser, nil
+	}
+	return nil, nil
+}
+
+func postCreateUser(c *Command, r *http.Request, user *auth.UserState) Response {
+	var createData struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&createData); err != nil {
+		return BadRequest("cannot decode create-user data from request body: %v", err)
+	}
+
+	if createData.Email == "" {
+		return BadRequest("cannot create user: 'email' field is empty")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	// system-user assertions only apply to devices with a known model,
+	// other devices get the user from the store
+	var systemUser *asserts.SystemUser
+	model, err := devicestate.Model(st)
+	switch err {
+	case nil:
+		systemUser, err = findSystemUser(assertstate.DB(st), model, createData.Email)
+	case state.ErrNoState, asserts.ErrNotFound:
+		err = nil
+	}
+	st.Unlock()
+	if err != nil {
+		return InternalError("cannot create user: %v", err)
+	}
+
+	var username, gecos, password string
+	var sshKeys []string
+	if systemUser != nil {
+		username = systemUser.Username()
+		gecos = systemUser.Name()
+		if gecos == "" {
+			gecos = systemUser.Email()
+		}
+		password = systemUser.Password()
+		sshKeys = systemUser.SSHKeys()
+	} else {
+		userInfo, err := storeFindUserInfo(createData.Email)
+		if err != nil {
+			return BadRequest("cannot create user %q: %v", createData.Email, err)
+		}
+		username = userInfo.Username
+		gecos = createData.Email
+		sshKeys = userInfo.SSHKeys
+	}
+
+	if err := osutilAddExtraUser(username, sshKeys, gecos, password); err != nil {
+		return BadRequest("cannot create user %s: %v", username, err)
+	}
+
+	return SyncResponse(&createUserResponseData{
+		Username: username,
+		SSHKeys:  sshKeys,
+	}, nil)
+}
+
 // UserFromRequest extracts user information from request and return the respective user in state, if valid
 // It requires the state to be locked
 func UserFromRequest(st *state.State, req *http.Request) (*auth.UserState, error) {
@@ -321,9 +456,73 @@ func getSnapInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	result := webify(mapLocal(localSnap, active), url.String())
 
+	revisions, diskSize := localRevisions(name, active)
+	result["revisions"] = revisions
+	result["disk-size"] = diskSize
+
+	plugs, slots := snapInterfaces(c.d.overlord.InterfaceManager().Repository(), name)
+	result["plugs"] = plugs
+	result["slots"] = slots
+
+	return SyncResponse(result, nil)
+}
+
+// getSnapSecurity returns the security profiles generated for the
+// applications of a snap by the backend named in the query, with each snippet
+// annotated with the plug or slot and the interface that produced it.
+func getSnapSecurity(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	name := vars["name"]
+
+	backend := r.URL.Query().Get("backend")
+	if backend == "" {
+		return BadRequest("cannot show security profiles: backend not specified")
+	}
+
+	localSnap, active, err := localSnapInfo(c.d.overlord.State(), name)
+	if err != nil {
+		if err == errNoSnap {
+			return NotFound("cannot find snap %q", name)
+		}
+
+		return InternalError("%v", err)
+	}
+
+	profiles, err := c.d.overlord.InterfaceManager().SecurityProfiles(localSnap, active.DevMode(), backend)
+	if err == ifacestate.ErrUnknownSecurityBackend {
+		return BadRequest("cannot show security profiles: unknown security backend %q", backend)
+	}
+	if err != nil {
+		return InternalError("cannot show security profiles: %v", err)
+	}
+
+	result := make(map[string]string, len(profiles))
+	for appName, profile := range profiles {
+		result[appName] = string(profile)
+	}
+
 	return SyncResponse(result, nil)
 }
 
+// snapInterfaces returns the plugs and slots of the given snap, with
+// their connections.
+func snapInterfaces(repo *interfaces.Repository, name string) ([]*interfaces.Plug, []*interfaces.Slot) {
+	ifaces := repo.Interfaces()
+	plugs := []*interfaces.Plug{}
+	for _, plug := range ifaces.Plugs {
+		if plug.Snap.Name() == name {
+			plugs = append(plugs, plug)
+		}
+	}
+	slots := []*interfaces.Slot{}
+	for _, slot := range ifaces.Slots {
+		if slot.Snap.Name() == name {
+			slots = append(slots, slot)
+		}
+	}
+	return plugs, slots
+}
+
 func webify(result map[string]interface{}, resource string) map[string]interface{} {
 	result["resource"] = resource
 
@@ -368,6 +567,13 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError("%v", err)
 	}
 
+	if name := query.Get("name"); name != "" {
+		if query.Get("q") != "" {
+			return BadRequest("cannot use 'q' with 'name'")
+		}
+		return findByName(c, route, name, auther)
+	}
+
 	store := getStore(c)
 	found, err := store.Find(query.Get("q"), query.Get("channel"), auther)
 	if err != nil {
@@ -382,6 +588,66 @@ func searchStore(c *Command, r *http.Request, user *auth.UserState) Response {
 	return sendStorePackages(route, meta, found)
 }
 
+// storeChannels are the channels looked at to build the channel map
+// of a store snap, in order of preference.
+var storeChannels = []string{"stable", "candidate", "beta", "edge"}
+
+// channelJSON contains the json for what a channel of a store snap offers
+type channelJSON struct {
+	Revision    snap.Revision        `json:"revision"`
+	Version     string               `json:"version"`
+	Size        int64                `json:"size"`
+	Confinement snap.ConfinementType `json:"confinement"`
+}
+
+// findByName looks up the store snap with exactly the given name,
+// together with its channel map.
+func findByName(c *Command, route *mux.Route, name string, auther store.Authenticator) Response {
+	theStore := getStore(c)
+
+	var found *snap.Info
+	channels := make(map[string]*channelJSON)
+	for _, channel := range storeChannels {
+		info, err := theStore.Snap(name, channel, auther)
+		if err == store.ErrSnapNotFound {
+			continue
+		}
+		if err != nil {
+			return InternalError("cannot get details of snap %q in channel %q: %v", name, channel, err)
+		}
+		if found == nil {
+			found = info
+		}
+		confinement := info.Confinement
+		if confinement == "" {
+			confinement = snap.StrictConfinement
+		}
+		channels[channel] = &channelJSON{
+			Revision:    info.Revision,
+			Version:     info.Version,
+			Size:        info.Size,
+			Confinement: confinement,
+		}
+	}
+	if found == nil {
+		return NotFound("cannot find snap %q in the store", name)
+	}
+
+	url, err := route.URL("name", name)
+	if err != nil {
+		return InternalError("cannot build URL for snap %q: %v", name, err)
+	}
+	result := webify(mapRemote(found), url.String())
+	result["channels"] = channels
+
+	meta := &Meta{
+		SuggestedCurrency: theStore.SuggestedCurrency(),
+		Sources:           []string{"store"},
+	}
+
+	return SyncResponse([]map[string]interface{}{result}, meta)
+}
+
 func shouldSearchStore(r *http.Request) bool {
 	// we should jump to the old behaviour iff q is given, or if
 	// sources is given and either empty or contains the word
@@ -444,6 +710,17 @@ func storeUpdates(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError("cannot list updates: %v", err)
 	}
 
+	st := c.d.overlord.State()
+	st.Lock()
+	updates, err = snapstate.ValidateRefreshes(st, updates, storeAssertionRetriever(c, user))
+	st.Unlock()
+	if verr, ok := err.(*assertstate.RefreshValidationError); ok {
+		// the refused updates are just not offered
+		logger.Noticef("%v", verr)
+	} else if err != nil {
+		return InternalError("cannot validate updates: %v", err)
+	}
+
 	return sendStorePackages(route, nil, updates)
 }
 
@@ -544,11 +821,14 @@ type snapInstruction struct {
 	// The fields below should not be unmarshalled into. Do not export them.
 	snap   string
 	userID int
+	store  snapstate.StoreService
+	auther store.Authenticator
 }
 
 var snapstateInstall = snapstate.Install
 var snapstateUpdate = snapstate.Update
 var snapstateInstallPath = snapstate.InstallPath
+var snapstateInstallVerifiedPath = snapstate.InstallVerifiedPath
 var snapstateTryPath = snapstate.TryPath
 var snapstateGet = snapstate.Get
 
@@ -591,6 +871,62 @@ func withEnsureUbuntuCore(st *state.State, targetSnap string, userID int, instal
 	return []*state.TaskSet{ts}, nil
 }
 
+// withDefaultProviders adds to the task sets installing the given snap the
+// installation of the snaps named by the default-provider attribute of its
+// plugs, unless already installed, and makes the installation of the snap,
+// the last of tsets, wait for them so that its plugs can be connected.
+func withDefaultProviders(st *state.State, info *snap.Info, userID int, tsets []*state.TaskSet) ([]*state.TaskSet, error) {
+	if info == nil {
+		return tsets, nil
+	}
+	ts := tsets[len(tsets)-1]
+
+	// ubuntu-core is either installed or taken care of by
+	// withEnsureUbuntuCore
+	seen := map[string]bool{info.Name(): true, "ubuntu-core": true}
+	var names []string
+	for _, plug := range info.Plugs {
+		provider := plug.DefaultProvider()
+		if provider == "" || seen[provider] {
+			continue
+		}
+		seen[provider] = true
+		names = append(names, provider)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var ss snapstate.SnapState
+		err := snapstateGet(st, name, &ss)
+		if err == nil {
+			continue
+		}
+		if err != state.ErrNoState {
+			return nil, err
+		}
+		providerTs, err := snapstateInstall(st, name, "stable", userID, 0)
+		if err != nil {
+			return nil, fmt.Errorf("cannot install default provider %q: %v", name, err)
+		}
+		ts.WaitAll(providerTs)
+		tsets = append(tsets, providerTs)
+	}
+	return tsets, nil
+}
+
+// storeSnapInfo returns the information of the snap the instruction is
+// about as found in the store, or nil if no store is available.
+func (inst *snapInstruction) storeSnapInfo() (*snap.Info, error) {
+	if inst.store == nil {
+		return nil, nil
+	}
+	channel := inst.Channel
+	if channel == "" {
+		channel = "stable"
+	}
+	return inst.store.Snap(inst.snap, channel, inst.auther)
+}
+
 func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
 	flags := snapstate.Flags(0)
 	if inst.DevMode {
@@ -606,6 +942,15 @@ func snapInstall(inst *snapInstruction, st *state.State) (string, []*state.TaskS
 		return "", nil, err
 	}
 
+	info, err := inst.storeSnapInfo()
+	if err != nil {
+		return "", nil, err
+	}
+	tsets, err = withDefaultProviders(st, info, inst.userID, tsets)
+	if err != nil {
+		return "", nil, err
+	}
+
 	msg := fmt.Sprintf(i18n.G("Install %q snap"), inst.snap)
 	if inst.Channel != "stable" && inst.Channel != "" {
 		msg = fmt.Sprintf(i18n.G("Install %q snap from %q channel"), inst.snap, inst.Channel)
@@ -682,7 +1027,9 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	if user != nil {
 		inst.userID = user.ID
+		inst.auther = user.Authenticator()
 	}
+	inst.store = getStore(c)
 
 	vars := muxVars(r)
 	inst.snap = vars["name"]
@@ -806,6 +1153,22 @@ out:
 		return BadRequest(`cannot find "snap" file field in provided multipart/form-data payload`)
 	}
 
+	// the optional "assertion" file fields carry assertion streams
+	// to verify the snap with
+	var assertions []asserts.Assertion
+	for _, fheader := range form.File["assertion"] {
+		f, err := fheader.Open()
+		if err != nil {
+			return BadRequest(`cannot open uploaded "assertion" file: %v`, err)
+		}
+		as, err := decodeAssertions(f)
+		f.Close()
+		if err != nil {
+			return BadRequest("cannot decode assertions: %v", err)
+		}
+		assertions = append(assertions, as...)
+	}
+
 	tmpf, err := ioutil.TempFile("", "snapd-sideload-pkg-")
 	if err != nil {
 		return InternalError("cannot create temporary file: %v", err)
@@ -829,6 +1192,20 @@ out:
 	}
 	snapName := info.Name()
 
+	// the snap is verified against the uploaded assertions, or the ones
+	// already known, and only then the uploaded ones are added
+	sideInfo, err := verifySnapFile(c.d.overlord.AssertManager().DB(), assertions, tempPath, snapName)
+	if err != nil {
+		os.Remove(tempPath)
+		return BadRequest("cannot verify snap file: %v", err)
+	}
+	if len(assertions) > 0 {
+		if _, err := addAssertions(c, user, assertions); err != nil {
+			os.Remove(tempPath)
+			return BadRequest("cannot add assertions: %v", err)
+		}
+	}
+
 	st := c.d.overlord.State()
 	st.Lock()
 	defer st.Unlock()
@@ -845,9 +1222,15 @@ out:
 
 	tsets, err := withEnsureUbuntuCore(st, snapName, userID,
 		func() (*state.TaskSet, error) {
+			if sideInfo != nil {
+				return snapstateInstallVerifiedPath(st, sideInfo, tempPath, "", flags)
+			}
 			return snapstateInstallPath(st, snapName, tempPath, "", flags)
 		},
 	)
+	if err == nil {
+		tsets, err = withDefaultProviders(st, info, userID, tsets)
+	}
 	if err != nil {
 		return InternalError("cannot install snap file: %v", err)
 	}
@@ -867,6 +1250,133 @@ out:
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
 }
 
+// decodeAssertions decodes all the assertions in the given stream.
+func decodeAssertions(r io.Reader) ([]asserts.Assertion, error) {
+	var assertions []asserts.Assertion
+	dec := asserts.NewDecoder(r)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return assertions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		assertions = append(assertions, a)
+	}
+}
+
+// addAssertions adds the given assertions to the database, each after
+// its prerequisites, whatever the order they were given in. Prerequisites
+// that are neither in the database nor among the given assertions are
+// retrieved from the store. Nothing is added if any signature fails
+// verification.
+func addAssertions(c *Command, user *auth.UserState, assertions []asserts.Assertion) ([]*assertstate.AddResult, error) {
+	db := c.d.overlord.AssertManager().DB()
+	return assertstate.AddMany(db, assertions, storeAssertionRetriever(c, user))
+}
+
+// storeAssertionRetriever returns a function retrieving assertions from
+// the store on behalf of the given user.
+func storeAssertionRetriever(c *Command, user *auth.UserState) func(*asserts.Ref) (asserts.Assertion, error) {
+	var auther store.Authenticator
+	if user != nil {
+		auther = user.Authenticator()
+	}
+	theStore := getStore(c)
+	return func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return theStore.Assertion(ref.Type, ref.PrimaryKey, auther)
+	}
+}
+
+// findAssertions returns the assertions of the given type with the given
+// headers among the given ones or, if there are none, in the database.
+func findAssertions(db *asserts.Database, given []asserts.Assertion, assertType *asserts.AssertionType, headers map[string]string) ([]asserts.Assertion, error) {
+	var found []asserts.Assertion
+	for _, a := range given {
+		if a.Type() != assertType {
+			continue
+		}
+		match := true
+		for k, v := range headers {
+			if a.Header(k) != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			found = append(found, a)
+		}
+	}
+	if len(found) != 0 {
+		return found, nil
+	}
+	return db.FindMany(assertType, headers)
+}
+
+// verifySnapFile checks the snap file against the snap-revision and
+// snap-declaration assertions among the given ones or in the database and
+// returns the side info describing its identity in the store. If no
+// assertions are given and there is no snap-revision for the snap file it
+// returns nil, as the snap is then a local one.
+func verifySnapFile(db *asserts.Database, given []asserts.Assertion, snapPath, snapName string) (*snap.SideInfo, error) {
+	digest, size, err := asserts.SnapFileSHA512(snapPath)
+	if err != nil {
+		return nil, err
+	}
+
+	revs, err := findAssertions(db, given, asserts.SnapRevisionType, map[string]string{
+		"series":      release.Series,
+		"snap-digest": digest,
+	})
+	if err == asserts.ErrNotFound && len(given) == 0 {
+		return nil, nil
+	}
+	if err == asserts.ErrNotFound {
+		return nil, fmt.Errorf("no snap-revision assertion for digest %s", digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) != 1 {
+		return nil, fmt.Errorf("found %d snap-revision assertions for digest %s", len(revs), digest)
+	}
+	snapRev := revs[0].(*asserts.SnapRevision)
+	if snapRev.SnapSize() != size {
+		return nil, fmt.Errorf("snap size %d does not match snap-revision size %d", size, snapRev.SnapSize())
+	}
+
+	decls, err := findAssertions(db, given, asserts.SnapDeclarationType, map[string]string{
+		"series":  release.Series,
+		"snap-id": snapRev.SnapID(),
+	})
+	if err == asserts.ErrNotFound {
+		return nil, fmt.Errorf("no snap-declaration assertion for snap id %q", snapRev.SnapID())
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapDecl := decls[0].(*asserts.SnapDeclaration)
+	if snapDecl.SnapName() != snapName {
+		return nil, fmt.Errorf("snap %q does not match the snap-declaration for %q", snapName, snapDecl.SnapName())
+	}
+
+	developer := snapRev.DeveloperID()
+	if accts, err := findAssertions(db, given, asserts.AccountType, map[string]string{"account-id": developer}); err == nil {
+		if username := accts[0].(*asserts.Account).Username(); username != "" {
+			developer = username
+		}
+	}
+
+	return &snap.SideInfo{
+		OfficialName: snapName,
+		SnapID:       snapRev.SnapID(),
+		Revision:     snap.R(int(snapRev.SnapRevision())),
+		Developer:    developer,
+		Size:         int64(size),
+	}, nil
+}
+
 func readSnapInfoImpl(snapPath string) (*snap.Info, error) {
 	// TODO Only open if in devmode or we have the assertion proving content right.
 	snapf, err := snap.Open(snapPath)
@@ -905,8 +1415,11 @@ func appIconGet(c *Command, r *http.Request, user *auth.UserState) Response {
 
 // getInterfaces returns all plugs and slots.
 func getInterfaces(c *Command, r *http.Request, user *auth.UserState) Response {
-	repo := c.d.overlord.InterfaceManager().Repository()
-	return SyncResponse(repo.Interfaces(), nil)
+	ifaces, err := c.d.overlord.InterfaceManager().Interfaces()
+	if err != nil {
+		return InternalError("cannot get interfaces: %v", err)
+	}
+	return SyncResponse(ifaces, nil)
 }
 
 // plugJSON aids in marshaling Plug into JSON.
@@ -975,7 +1488,7 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	switch a.Action {
 	case "connect":
 		summary = fmt.Sprintf("Connect %s:%s to %s:%s", a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
-		taskset, err = ifacestate.Connect(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
+		taskset, err = ifacestate.ConnectWithAttrs(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name, a.Plugs[0].Attrs, a.Slots[0].Attrs)
 	case "disconnect":
 		summary = fmt.Sprintf("Disconnect %s:%s from %s:%s", a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
 		taskset, err = ifacestate.Disconnect(state, a.Plugs[0].Snap, a.Plugs[0].Name, a.Slots[0].Snap, a.Slots[0].Name)
@@ -993,26 +1506,130 @@ func changeInterfaces(c *Command, r *http.Request, user *auth.UserState) Respons
 	return AsyncResponse(nil, &Meta{Change: change.ID()})
 }
 
+// assertResult holds the outcome of adding one of the assertions of a
+// stream posted to /v2/assertions.
+type assertResult struct {
+	Type       string   `json:"type"`
+	PrimaryKey []string `json:"primary-key"`
+	Result     string   `json:"result"`
+}
+
 func doAssert(c *Command, r *http.Request, user *auth.UserState) Response {
-	b, err := ioutil.ReadAll(r.Body)
+	assertions, err := decodeAssertions(r.Body)
 	if err != nil {
-		return BadRequest("reading assert request body gave %v", err)
+		return BadRequest("cannot decode request body into an assertion stream: %v", err)
 	}
-	a, err := asserts.Decode(b)
-	if err != nil {
-		return BadRequest("cannot decode request body into an assertion: %v", err)
+	if len(assertions) == 0 {
+		return BadRequest("cannot decode request body into an assertion stream: no assertions")
 	}
 	// TODO/XXX: turn this into a Change/Task combination
-	amgr := c.d.overlord.AssertManager()
-	if err := amgr.DB().Add(a); err != nil {
-		// TODO: have a specific error to be able to return  409 for not newer revision?
+	results, err := addAssertions(c, user, assertions)
+	if err != nil {
 		return BadRequest("assert failed: %v", err)
 	}
-	// TODO: what more info do we want to return on success?
-	return &resp{
-		Type:   ResponseTypeSync,
-		Status: http.StatusOK,
+
+	result := make([]*assertResult, len(results))
+	for i, res := range results {
+		result[i] = &assertResult{
+			Type:       res.Ref.Type.Name,
+			PrimaryKey: res.Ref.PrimaryKey,
+			Result:     res.Outcome,
+		}
+	}
+	return SyncResponse(result, nil)
+}
+
+// validationJSON describes a validation, by a gating snap, of a revision
+// of a snap it gates.
+type validationJSON struct {
+	GatingSnapID     string        `json:"gating-snap-id"`
+	GatingSnap       string        `json:"gating-snap,omitempty"`
+	ApprovedSnapID   string        `json:"approved-snap-id"`
+	ApprovedSnap     string        `json:"approved-snap,omitempty"`
+	ApprovedRevision snap.Revision `json:"approved-revision"`
+	Revoked          bool          `json:"revoked,omitempty"`
+}
+
+func getValidations(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	validations, err := assertstate.Validations(st)
+	if err != nil {
+		return InternalError("cannot list validations: %v", err)
 	}
+
+	snapName := func(snapID string) string {
+		snapDecl, err := assertstate.SnapDeclaration(st, snapID)
+		if err != nil {
+			return ""
+		}
+		return snapDecl.SnapName()
+	}
+
+	results := make([]*validationJSON, len(validations))
+	for i, validation := range validations {
+		results[i] = &validationJSON{
+			GatingSnapID:     validation.SnapID(),
+			GatingSnap:       snapName(validation.SnapID()),
+			ApprovedSnapID:   validation.ApprovedSnapID(),
+			ApprovedSnap:     snapName(validation.ApprovedSnapID()),
+			ApprovedRevision: snap.R(int(validation.ApprovedSnapRevision())),
+			Revoked:          validation.Revoked(),
+		}
+	}
+
+	return SyncResponse(results, nil)
+}
+
+type modelJSON struct {
+	BrandID       string   `json:"brand-id"`
+	Model         string   `json:"model"`
+	Series        string   `json:"series"`
+	OS            string   `json:"os"`
+	Architecture  string   `json:"architecture"`
+	Gadget        string   `json:"gadget"`
+	Kernel        string   `json:"kernel"`
+	Store         string   `json:"store"`
+	Class         string   `json:"class"`
+	AllowedModes  []string `json:"allowed-modes,omitempty"`
+	RequiredSnaps []string `json:"required-snaps,omitempty"`
+	Serial        string   `json:"serial,omitempty"`
+}
+
+func getModel(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	model, err := devicestate.Model(st)
+	if err == state.ErrNoState {
+		return NotFound("no model assertion known for this device")
+	}
+	if err != nil {
+		return InternalError("cannot get the device model: %v", err)
+	}
+
+	device, err := auth.Device(st)
+	if err != nil {
+		return InternalError("cannot get the device state: %v", err)
+	}
+
+	return SyncResponse(&modelJSON{
+		BrandID:       model.BrandID(),
+		Model:         model.Model(),
+		Series:        model.Series(),
+		OS:            model.OS(),
+		Architecture:  model.Architecture(),
+		Gadget:        model.Gadget(),
+		Kernel:        model.Kernel(),
+		Store:         model.Store(),
+		Class:         model.Class(),
+		AllowedModes:  model.AllowedModes(),
+		RequiredSnaps: model.RequiredSnaps(),
+		Serial:        device.Serial,
+	}, nil)
 }
 
 func assertsFindMany(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -29,6 +29,7 @@ import (
 	"go/token"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -38,8 +39,11 @@ import (
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -62,6 +66,7 @@ type apiSuite struct {
 	auther            store.Authenticator
 	restoreBackends   func()
 	refreshCandidates []*store.RefreshCandidate
+	storeAssertions   []asserts.Assertion
 }
 
 var _ = check.Suite(&apiSuite{})
@@ -92,6 +97,16 @@ func (s *apiSuite) SuggestedCurrency() string {
 	return s.suggestedCurrency
 }
 
+func (s *apiSuite) Assertion(assertType *asserts.AssertionType, primaryKey []string, auther store.Authenticator) (asserts.Assertion, error) {
+	ref := &asserts.Ref{Type: assertType, PrimaryKey: primaryKey}
+	for _, a := range s.storeAssertions {
+		if a.Ref().Unique() == ref.Unique() {
+			return a, nil
+		}
+	}
+	return nil, store.ErrAssertionNotFound
+}
+
 func (s *apiSuite) Download(*snap.Info, progress.Meter, store.Authenticator) (string, error) {
 	panic("Download not expected to be called")
 }
@@ -123,6 +138,7 @@ func (s *apiSuite) SetUpTest(c *check.C) {
 	s.auther = nil
 	s.d = nil
 	s.refreshCandidates = nil
+	s.storeAssertions = nil
 	// Disable real security backends for all API tests
 	s.restoreBackends = ifacestate.MockSecurityBackends(nil)
 }
@@ -133,7 +149,10 @@ func (s *apiSuite) TearDownTest(c *check.C) {
 	snapstateInstall = snapstate.Install
 	snapstateGet = snapstate.Get
 	snapstateInstallPath = snapstate.InstallPath
+	snapstateInstallVerifiedPath = snapstate.InstallVerifiedPath
 	readSnapInfo = readSnapInfoImpl
+	storeFindUserInfo = store.FindUserInfo
+	osutilAddExtraUser = osutil.AddExtraUser
 }
 
 func (s *apiSuite) daemon(c *check.C) *Daemon {
@@ -230,6 +249,18 @@ func (s *apiSuite) TestSnapInfoOneIntegration(c *check.C) {
 	c.Check(m["install-date"], check.FitsTypeOf, time.Time{})
 	delete(m, "install-date")
 
+	// installed revisions come most recent first
+	revs, ok := m["revisions"].([]revisionJSON)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(revs, check.HasLen, 2)
+	c.Check(revs[0].Revision, check.Equals, snap.R(10))
+	c.Check(revs[0].Version, check.Equals, "v1")
+	c.Check(revs[0].Current, check.Equals, true)
+	c.Check(revs[1].Revision, check.Equals, snap.R(5))
+	c.Check(revs[1].Version, check.Equals, "v0")
+	c.Check(revs[1].Current, check.Equals, false)
+	delete(m, "revisions")
+
 	meta := &Meta{}
 	expected := &resp{
 		Type:   ResponseTypeSync,
@@ -252,6 +283,11 @@ func (s *apiSuite) TestSnapInfoOneIntegration(c *check.C) {
 			"confinement": snap.StrictConfinement,
 			"trymode":     false,
 			"apps":        []appJSON{},
+
+			"tracking-channel": "",
+			"disk-size":        int64(0),
+			"plugs":            []*interfaces.Plug{},
+			"slots":            []*interfaces.Slot{},
 		},
 		Meta: meta,
 	}
@@ -259,6 +295,88 @@ func (s *apiSuite) TestSnapInfoOneIntegration(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, expected.Result)
 }
 
+func (s *apiSuite) TestSnapInfoWithAppsAndInterfaces(c *check.C) {
+	d := s.daemon(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	repo := d.overlord.InterfaceManager().Repository()
+	c.Assert(repo.AddInterface(&interfaces.TestInterface{InterfaceName: "test"}), check.IsNil)
+
+	info := s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, `apps:
+ svc:
+  command: svc
+  daemon: simple
+plugs:
+ plug:
+  interface: test
+`)
+	c.Assert(repo.AddPlug(&interfaces.Plug{PlugInfo: info.Plugs["plug"]}), check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapInfo(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, http.StatusOK)
+	m := rsp.Result.(map[string]interface{})
+
+	c.Check(m["apps"], check.DeepEquals, []appJSON{{Name: "svc", Daemon: "simple"}})
+	plugs := m["plugs"].([]*interfaces.Plug)
+	c.Assert(plugs, check.HasLen, 1)
+	c.Check(plugs[0].Name, check.Equals, "plug")
+	c.Check(m["slots"], check.HasLen, 0)
+}
+
+func (s *apiSuite) TestFindByName(c *check.C) {
+	s.suggestedCurrency = "EUR"
+	s.rsnaps = []*snap.Info{{
+		Version: "v2",
+		SideInfo: snap.SideInfo{
+			OfficialName: "store",
+			Developer:    "foo",
+			Revision:     snap.R(7),
+			Size:         1024,
+		},
+	}}
+
+	req, err := http.NewRequest("GET", "/v2/find?name=store", nil)
+	c.Assert(err, check.IsNil)
+	rsp := searchStore(findCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+
+	snaps := rsp.Result.([]map[string]interface{})
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["name"], check.Equals, "store")
+	chInfo := &channelJSON{
+		Revision:    snap.R(7),
+		Version:     "v2",
+		Size:        1024,
+		Confinement: snap.StrictConfinement,
+	}
+	c.Check(snaps[0]["channels"], check.DeepEquals, map[string]*channelJSON{
+		"stable":    chInfo,
+		"candidate": chInfo,
+		"beta":      chInfo,
+		"edge":      chInfo,
+	})
+	c.Check(rsp.SuggestedCurrency, check.Equals, "EUR")
+}
+
+func (s *apiSuite) TestFindByNameNotFound(c *check.C) {
+	s.err = store.ErrSnapNotFound
+
+	req, err := http.NewRequest("GET", "/v2/find?name=store", nil)
+	c.Assert(err, check.IsNil)
+	rsp := searchStore(findCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, http.StatusNotFound)
+}
+
+func (s *apiSuite) TestFindByNameNotQ(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/find?name=store&q=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := searchStore(findCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, "cannot use 'q' with 'name'")
+}
+
 func (s *apiSuite) TestSnapInfoWithAuth(c *check.C) {
 	state := snapCmd.d.overlord.State()
 	state.Lock()
@@ -305,6 +423,53 @@ func (s *apiSuite) TestSnapInfoIgnoresRemoteErrors(c *check.C) {
 	c.Check(rsp.Result, check.NotNil)
 }
 
+func (s *apiSuite) TestSnapSecurity(c *check.C) {
+	backend := &interfaces.TestSecurityBackend{
+		ProfilesCallback: func(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+			c.Check(snapInfo.Name(), check.Equals, "foo")
+			return map[string][]byte{"app": []byte("# plug foo:plug (interface iface)\nsnippet\n")}, nil
+		},
+	}
+	s.restoreBackends()
+	s.restoreBackends = ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{backend})
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+	s.vars = map[string]string{"name": "foo"}
+
+	req, err := http.NewRequest("GET", "/v2/snaps/foo/security?backend=test", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getSnapSecurity(snapSecurityCmd, req, nil).(*resp)
+
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, map[string]string{
+		"app": "# plug foo:plug (interface iface)\nsnippet\n",
+	})
+}
+
+func (s *apiSuite) TestSnapSecurityErrors(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+
+	for _, t := range []struct {
+		name, query string
+		status      int
+		message     string
+	}{
+		{"foo", "", http.StatusBadRequest, "cannot show security profiles: backend not specified"},
+		{"foo", "?backend=apparmor", http.StatusBadRequest, `cannot show security profiles: unknown security backend "apparmor"`},
+		{"bar", "?backend=apparmor", http.StatusNotFound, `cannot find snap "bar"`},
+	} {
+		s.vars = map[string]string{"name": t.name}
+		req, err := http.NewRequest("GET", "/v2/snaps/"+t.name+"/security"+t.query, nil)
+		c.Assert(err, check.IsNil)
+		rsp := getSnapSecurity(snapSecurityCmd, req, nil).(*resp)
+
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, t.status)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, t.message)
+	}
+}
+
 func (s *apiSuite) TestListIncludesAll(c *check.C) {
 	// Very basic check to help stop us from not adding all the
 	// commands to the command list.
@@ -339,14 +504,18 @@ func (s *apiSuite) TestListIncludesAll(c *check.C) {
 		"maxReadBuflen",
 		"muxVars",
 		"errNothingToInstall",
+		"storeChannels",
 		// snapInstruction vars:
 		"snapInstructionDispTable",
 		"snapstateInstall",
 		"snapstateUpdate",
 		"snapstateInstallPath",
+		"snapstateInstallVerifiedPath",
 		"snapstateTryPath",
 		"snapstateGet",
 		"readSnapInfo",
+		"storeFindUserInfo",
+		"osutilAddExtraUser",
 	}
 	c.Check(found, check.Equals, len(api)+len(exceptions),
 		check.Commentf(`At a glance it looks like you've not added all the Commands defined in api to the api list. If that is not the case, please add the exception to the "exceptions" list in this test.`))
@@ -568,6 +737,264 @@ func (s *apiSuite) TestLoginUserInvalidCredentialsError(c *check.C) {
 	c.Check(rsp.Result.(*errorResult).Message, testutil.Contains, "cannot get discharge macaroon")
 }
 
+// mockSystemUser writes a trusted key for the daemon and returns a
+// system-user assertion for email signed by a brand, with its key and
+// the brand models "my-model" and "other-model".
+func mockSystemUser(c *check.C, email string, since, until time.Time) []asserts.Assertion {
+	rootKey := assertstest.GenerateKey(752)
+	signingDB := assertstest.NewSigningDB("canonical", rootKey)
+	trustedKey := assertstest.NewAccountKey(signingDB, "canonical", rootKey.PublicKey())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755), check.IsNil)
+	err := ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(trustedKey), 0640)
+	c.Assert(err, check.IsNil)
+
+	brandKey := assertstest.GenerateKey(752)
+	brandAccKey := assertstest.NewAccountKey(signingDB, "my-brand", brandKey.PublicKey())
+	brandDB := assertstest.NewSigningDB("my-brand", brandKey)
+
+	systemUser, err := brandDB.Sign(asserts.SystemUserType, map[string]string{
+		"brand-id":  "my-brand",
+		"email":     email,
+		"series":    "16",
+		"models":    "my-model",
+		"name":      "Boring Guy",
+		"username":  "guy",
+		"password":  "$6$salt$hash",
+		"ssh-keys":  "ssh-rsa AAAABcdefg\nssh-rsa AAAAHijklm",
+		"since":     since.Format(time.RFC3339),
+		"until":     until.Format(time.RFC3339),
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, check.IsNil)
+
+	assertions := []asserts.Assertion{brandAccKey, systemUser}
+	for _, name := range []string{"my-model", "other-model"} {
+		model, err := brandDB.Sign(asserts.ModelType, map[string]string{
+			"series":         "16",
+			"brand-id":       "my-brand",
+			"model":          name,
+			"os":             "core",
+			"architecture":   "amd64",
+			"gadget":         "my-gadget",
+			"kernel":         "my-kernel",
+			"store":          "my-brand-store",
+			"class":          "fixed",
+			"allowed-modes":  "",
+			"required-snaps": "foo, bar",
+			"timestamp":      time.Now().Format(time.RFC3339),
+		}, nil)
+		c.Assert(err, check.IsNil)
+		assertions = append(assertions, model)
+	}
+	return assertions
+}
+
+func (s *apiSuite) TestPostCreateUserFromAssertion(c *check.C) {
+	now := time.Now()
+	assertions := mockSystemUser(c, "guy@example.com", now.AddDate(0, 0, -1), now.AddDate(0, 1, 0))
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+	st := d.overlord.State()
+	st.Lock()
+	err := auth.SetDevice(st, &auth.DeviceState{Brand: "my-brand", Model: "my-model"})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	storeFindUserInfo = func(string) (*store.UserInfo, error) {
+		c.Fatalf("store lookup not expected")
+		return nil, nil
+	}
+	osutilAddExtraUser = func(username string, sshKeys []string, gecos, password string) error {
+		c.Check(username, check.Equals, "guy")
+		c.Check(sshKeys, check.DeepEquals, []string{"ssh-rsa AAAABcdefg", "ssh-rsa AAAAHijklm"})
+		c.Check(gecos, check.Equals, "Boring Guy")
+		c.Check(password, check.Equals, "$6$salt$hash")
+		return nil
+	}
+
+	buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &createUserResponseData{
+		Username: "guy",
+		SSHKeys:  []string{"ssh-rsa AAAABcdefg", "ssh-rsa AAAAHijklm"},
+	})
+}
+
+func (s *apiSuite) TestPostCreateUserAssertionForOtherModel(c *check.C) {
+	now := time.Now()
+	assertions := mockSystemUser(c, "guy@example.com", now.AddDate(0, 0, -1), now.AddDate(0, 1, 0))
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+	st := d.overlord.State()
+	st.Lock()
+	err := auth.SetDevice(st, &auth.DeviceState{Brand: "my-brand", Model: "other-model"})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	storeFindUserInfo = func(email string) (*store.UserInfo, error) {
+		return &store.UserInfo{Username: "karl"}, nil
+	}
+	osutilAddExtraUser = func(username string, sshKeys []string, gecos, password string) error {
+		c.Check(username, check.Equals, "karl")
+		c.Check(password, check.Equals, "")
+		return nil
+	}
+
+	buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result.(*createUserResponseData).Username, check.Equals, "karl")
+}
+
+func (s *apiSuite) TestPostCreateUserWithoutModelUsesStore(c *check.C) {
+	now := time.Now()
+	assertions := mockSystemUser(c, "guy@example.com", now.AddDate(0, 0, -1), now.AddDate(0, 1, 0))
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		// the model assertions are missing
+		if a.Type() != asserts.ModelType {
+			c.Assert(db.Add(a), check.IsNil)
+		}
+	}
+
+	storeFindUserInfo = func(email string) (*store.UserInfo, error) {
+		return &store.UserInfo{Username: "karl"}, nil
+	}
+	osutilAddExtraUser = func(username string, sshKeys []string, gecos, password string) error {
+		c.Check(username, check.Equals, "karl")
+		c.Check(password, check.Equals, "")
+		return nil
+	}
+
+	// neither without a model nor with an unknown one
+	for _, device := range []*auth.DeviceState{nil, {Brand: "my-brand", Model: "my-model"}} {
+		if device != nil {
+			st := d.overlord.State()
+			st.Lock()
+			err := auth.SetDevice(st, device)
+			st.Unlock()
+			c.Assert(err, check.IsNil)
+		}
+
+		buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+		req, err := http.NewRequest("POST", "/v2/create-user", buf)
+		c.Assert(err, check.IsNil)
+
+		rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+		c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+		c.Check(rsp.Result.(*createUserResponseData).Username, check.Equals, "karl")
+	}
+}
+
+func (s *apiSuite) TestPostCreateUserFromStore(c *check.C) {
+	// an expired system-user is not used
+	now := time.Now()
+	assertions := mockSystemUser(c, "guy@example.com", now.AddDate(0, -2, 0), now.AddDate(0, -1, 0))
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+
+	storeFindUserInfo = func(email string) (*store.UserInfo, error) {
+		c.Check(email, check.Equals, "guy@example.com")
+		return &store.UserInfo{
+			Username: "karl",
+			SSHKeys:  []string{"ssh-rsa AAAABcdefg"},
+		}, nil
+	}
+	osutilAddExtraUser = func(username string, sshKeys []string, gecos, password string) error {
+		c.Check(username, check.Equals, "karl")
+		c.Check(sshKeys, check.DeepEquals, []string{"ssh-rsa AAAABcdefg"})
+		c.Check(gecos, check.Equals, "guy@example.com")
+		c.Check(password, check.Equals, "")
+		return nil
+	}
+
+	buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &createUserResponseData{
+		Username: "karl",
+		SSHKeys:  []string{"ssh-rsa AAAABcdefg"},
+	})
+}
+
+func (s *apiSuite) TestPostCreateUserNoEmail(c *check.C) {
+	s.daemon(c)
+
+	buf := bytes.NewBufferString(`{}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot create user: 'email' field is empty")
+}
+
+func (s *apiSuite) TestPostCreateUserUnknownEmail(c *check.C) {
+	s.daemon(c)
+
+	storeFindUserInfo = func(email string) (*store.UserInfo, error) {
+		return nil, fmt.Errorf("unknown email %q", email)
+	}
+	osutilAddExtraUser = func(string, []string, string, string) error {
+		c.Fatalf("user creation not expected")
+		return nil
+	}
+
+	buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot create user "guy@example.com": unknown email "guy@example.com"`)
+}
+
+func (s *apiSuite) TestPostCreateUserAddFails(c *check.C) {
+	s.daemon(c)
+
+	storeFindUserInfo = func(string) (*store.UserInfo, error) {
+		return &store.UserInfo{Username: "karl"}, nil
+	}
+	osutilAddExtraUser = func(string, []string, string, string) error {
+		return fmt.Errorf("adduser failed")
+	}
+
+	buf := bytes.NewBufferString(`{"email": "guy@example.com"}`)
+	req, err := http.NewRequest("POST", "/v2/create-user", buf)
+	c.Assert(err, check.IsNil)
+
+	rsp := postCreateUser(createUserCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "cannot create user karl: adduser failed")
+}
+
 func (s *apiSuite) TestUserFromRequestNoHeader(c *check.C) {
 	req, _ := http.NewRequest("GET", "http://example.com", nil)
 
@@ -778,6 +1205,163 @@ func (s *apiSuite) TestFindRefreshes(c *check.C) {
 	c.Check(s.refreshCandidates, check.HasLen, 1)
 }
 
+// mockValidations writes a trusted key for the daemon and returns the
+// assertions by which the "gating" snap, which gets the snap-id of the
+// snaps made by mkInstalledInState, gates "gated" and "other-gated",
+// approving revision 7 of "gated" only.
+func mockValidations(c *check.C) []asserts.Assertion {
+	rootKey := assertstest.GenerateKey(752)
+	signingDB := assertstest.NewSigningDB("canonical", rootKey)
+	trustedKey := assertstest.NewAccountKey(signingDB, "canonical", rootKey.PublicKey())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755), check.IsNil)
+	err := ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(trustedKey), 0640)
+	c.Assert(err, check.IsNil)
+
+	devKey := assertstest.GenerateKey(752)
+	devAccKey := assertstest.NewAccountKey(signingDB, "dev-id", devKey.PublicKey())
+	devDB := assertstest.NewSigningDB("dev-id", devKey)
+
+	now := time.Now().Format(time.RFC3339)
+	assertions := []asserts.Assertion{devAccKey}
+	for _, decl := range []struct{ snapID, name, gates string }{
+		{"funky-snap-id", "gating", "gated-id,other-gated-id"},
+		{"gated-id", "gated", ""},
+	} {
+		snapDecl, err := signingDB.Sign(asserts.SnapDeclarationType, map[string]string{
+			"series":       "16",
+			"snap-id":      decl.snapID,
+			"snap-name":    decl.name,
+			"publisher-id": "dev-id",
+			"gates":        decl.gates,
+			"timestamp":    now,
+		}, nil)
+		c.Assert(err, check.IsNil)
+		assertions = append(assertions, snapDecl)
+	}
+	validation, err := devDB.Sign(asserts.ValidationType, map[string]string{
+		"series":                 "16",
+		"snap-id":                "funky-snap-id",
+		"approved-snap-id":       "gated-id",
+		"approved-snap-revision": "7",
+		"timestamp":              now,
+	}, nil)
+	c.Assert(err, check.IsNil)
+	return append(assertions, validation)
+}
+
+func (s *apiSuite) TestFindRefreshesGated(c *check.C) {
+	assertions := mockValidations(c)
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+	s.mkInstalledInState(c, d, "gating", "bar", "v1", snap.R(1), true, "")
+
+	s.rsnaps = []*snap.Info{{
+		SideInfo: snap.SideInfo{
+			OfficialName: "gated",
+			SnapID:       "gated-id",
+			Revision:     snap.R(7),
+		},
+	}, {
+		SideInfo: snap.SideInfo{
+			OfficialName: "other-gated",
+			SnapID:       "other-gated-id",
+			Revision:     snap.R(3),
+		},
+	}}
+
+	req, err := http.NewRequest("GET", "/v2/find?select=refresh", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := searchStore(findCmd, req, nil).(*resp)
+
+	snaps := snapList(rsp.Result)
+	c.Assert(snaps, check.HasLen, 1)
+	c.Check(snaps[0]["name"], check.Equals, "gated")
+}
+
+func (s *apiSuite) TestGetModel(c *check.C) {
+	assertions := mockSystemUser(c, "guy@example.com", time.Now(), time.Now().AddDate(0, 1, 0))
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+	st := d.overlord.State()
+	st.Lock()
+	err := auth.SetDevice(st, &auth.DeviceState{Brand: "my-brand", Model: "my-model", Serial: "9999"})
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	req, err := http.NewRequest("GET", "/v2/model", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getModel(modelCmd, req, nil).(*resp)
+
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, &modelJSON{
+		BrandID:       "my-brand",
+		Model:         "my-model",
+		Series:        "16",
+		OS:            "core",
+		Architecture:  "amd64",
+		Gadget:        "my-gadget",
+		Kernel:        "my-kernel",
+		Store:         "my-brand-store",
+		Class:         "fixed",
+		RequiredSnaps: []string{"foo", "bar"},
+		Serial:        "9999",
+	})
+}
+
+func (s *apiSuite) TestGetModelNone(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/model", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getModel(modelCmd, req, nil).(*resp)
+
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, http.StatusNotFound)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "no model assertion known for this device")
+}
+
+func (s *apiSuite) TestGetValidations(c *check.C) {
+	assertions := mockValidations(c)
+	d := s.daemon(c)
+	db := d.overlord.AssertManager().DB()
+	for _, a := range assertions {
+		c.Assert(db.Add(a), check.IsNil)
+	}
+
+	req, err := http.NewRequest("GET", "/v2/validations", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getValidations(validationsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*validationJSON{{
+		GatingSnapID:     "funky-snap-id",
+		GatingSnap:       "gating",
+		ApprovedSnapID:   "gated-id",
+		ApprovedSnap:     "gated",
+		ApprovedRevision: snap.R(7),
+	}})
+}
+
+func (s *apiSuite) TestGetValidationsNone(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/validations", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getValidations(validationsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Result, check.DeepEquals, []*validationJSON{})
+}
+
 func (s *apiSuite) TestFindRefreshNotQ(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/find?select=refresh&q=foo", nil)
 	c.Assert(err, check.IsNil)
@@ -1151,6 +1735,233 @@ func (s *apiSuite) TestSideloadSnapDevMode(c *check.C) {
 	c.Check(chgSummary, check.Equals, `Install "local" snap from file "x"`)
 }
 
+// makeSideloadAssertions writes a trusted key for the daemon and returns
+// an assertion stream verifying a "local" snap with the given content,
+// with the assertions in an order that needs reordering to be added.
+func makeSideloadAssertions(c *check.C, content string) []byte {
+	rootKey := assertstest.GenerateKey(752)
+	signingDB := assertstest.NewSigningDB("canonical", rootKey)
+	trustedKey := assertstest.NewAccountKey(signingDB, "canonical", rootKey.PublicKey())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755), check.IsNil)
+	err := ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(trustedKey), 0640)
+	c.Assert(err, check.IsNil)
+
+	devKey := assertstest.GenerateKey(752)
+	devAccKey := assertstest.NewAccountKey(signingDB, "dev-id", devKey.PublicKey())
+	devDB := assertstest.NewSigningDB("dev-id", devKey)
+
+	snapPath := filepath.Join(c.MkDir(), "local.snap")
+	c.Assert(ioutil.WriteFile(snapPath, []byte(content), 0644), check.IsNil)
+	digest, size, err := asserts.SnapFileSHA512(snapPath)
+	c.Assert(err, check.IsNil)
+
+	now := time.Now().Format(time.RFC3339)
+	acct, err := signingDB.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "dev-id",
+		"username":     "dev",
+		"display-name": "Dev",
+		"validation":   "unproven",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, check.IsNil)
+	// the declaration is signed by the developer's key here, to need
+	// its account-key in
+	snapDecl, err := devDB.Sign(asserts.SnapDeclarationType, map[string]string{
+		"series":       "16",
+		"snap-id":      "local-id",
+		"snap-name":    "local",
+		"publisher-id": "dev-id",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, check.IsNil)
+	snapRev, err := signingDB.Sign(asserts.SnapRevisionType, map[string]string{
+		"series":        "16",
+		"snap-id":       "local-id",
+		"snap-digest":   digest,
+		"snap-size":     fmt.Sprint(size),
+		"snap-revision": "42",
+		"developer-id":  "dev-id",
+		"timestamp":     now,
+	}, nil)
+	c.Assert(err, check.IsNil)
+
+	buf := bytes.NewBuffer(nil)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range []asserts.Assertion{snapRev, snapDecl, acct, devAccKey} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+	return buf.Bytes()
+}
+
+func sideloadRequest(c *check.C, snapContent string, assertions []byte) *http.Request {
+	buf := bytes.NewBuffer(nil)
+	w := multipart.NewWriter(buf)
+	fw, err := w.CreateFormFile("snap", "local.snap")
+	c.Assert(err, check.IsNil)
+	io.WriteString(fw, snapContent)
+	fw, err = w.CreateFormFile("assertion", "local.assert")
+	c.Assert(err, check.IsNil)
+	fw.Write(assertions)
+	c.Assert(w.Close(), check.IsNil)
+
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func (s *apiSuite) TestSideloadSnapWithAssertions(c *check.C) {
+	assertions := makeSideloadAssertions(c, "xyzzy")
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	readSnapInfo = func(path string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "local"}, nil
+	}
+	snapstateGet = func(s *state.State, name string, snapst *snapstate.SnapState) error {
+		return nil
+	}
+	snapstateInstallPath = func(s *state.State, name, path, channel string, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Fatalf("unexpected unverified install")
+		return nil, nil
+	}
+	var installed *snap.SideInfo
+	snapstateInstallVerifiedPath = func(s *state.State, si *snap.SideInfo, path, channel string, flags snapstate.Flags) (*state.TaskSet, error) {
+		installed = si
+		t := s.NewTask("fake-install-snap", "Doing a fake install")
+		return state.NewTaskSet(t), nil
+	}
+
+	rsp := sideloadSnap(snapsCmd, sideloadRequest(c, "xyzzy", assertions), nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Check(installed, check.DeepEquals, &snap.SideInfo{
+		OfficialName: "local",
+		SnapID:       "local-id",
+		Revision:     snap.R(42),
+		Developer:    "dev",
+		Size:         5,
+	})
+
+	// the assertions are now in the database
+	db := d.overlord.AssertManager().DB()
+	_, err := db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "local-id",
+	})
+	c.Check(err, check.IsNil)
+}
+
+func (s *apiSuite) TestSideloadSnapWithAssertionsDigestMismatch(c *check.C) {
+	assertions := makeSideloadAssertions(c, "xyzzy")
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	readSnapInfo = func(path string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "local"}, nil
+	}
+
+	rsp := sideloadSnap(snapsCmd, sideloadRequest(c, "plugh", assertions), nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `cannot verify snap file: no snap-revision assertion for digest sha512-.*`)
+}
+
+func (s *apiSuite) TestSideloadSnapWithAssertionsWrongName(c *check.C) {
+	assertions := makeSideloadAssertions(c, "xyzzy")
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	readSnapInfo = func(path string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "other"}, nil
+	}
+
+	rsp := sideloadSnap(snapsCmd, sideloadRequest(c, "xyzzy", assertions), nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot verify snap file: snap "other" does not match the snap-declaration for "local"`)
+
+	// none of the assertions was added
+	db := d.overlord.AssertManager().DB()
+	_, err := db.Find(asserts.AccountType, map[string]string{"account-id": "dev-id"})
+	c.Check(err, check.Equals, asserts.ErrNotFound)
+	_, err = db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "local-id",
+	})
+	c.Check(err, check.Equals, asserts.ErrNotFound)
+}
+
+func (s *apiSuite) TestSideloadSnapWithKnownAssertions(c *check.C) {
+	stream := makeSideloadAssertions(c, "xyzzy")
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	// the assertions were acknowledged before
+	assertions, err := decodeAssertions(bytes.NewReader(stream))
+	c.Assert(err, check.IsNil)
+	_, err = assertstate.AddMany(d.overlord.AssertManager().DB(), assertions, nil)
+	c.Assert(err, check.IsNil)
+
+	readSnapInfo = func(path string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "local"}, nil
+	}
+	snapstateGet = func(s *state.State, name string, snapst *snapstate.SnapState) error {
+		return nil
+	}
+	snapstateInstallPath = func(s *state.State, name, path, channel string, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Fatalf("unexpected unverified install")
+		return nil, nil
+	}
+	var installed *snap.SideInfo
+	snapstateInstallVerifiedPath = func(s *state.State, si *snap.SideInfo, path, channel string, flags snapstate.Flags) (*state.TaskSet, error) {
+		installed = si
+		t := s.NewTask("fake-install-snap", "Doing a fake install")
+		return state.NewTaskSet(t), nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := multipart.NewWriter(buf)
+	fw, err := w.CreateFormFile("snap", "local.snap")
+	c.Assert(err, check.IsNil)
+	io.WriteString(fw, "xyzzy")
+	c.Assert(w.Close(), check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	rsp := sideloadSnap(snapsCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Assert(installed, check.NotNil)
+	c.Check(installed.SnapID, check.Equals, "local-id")
+	c.Check(installed.Revision, check.Equals, snap.R(42))
+}
+
+func (s *apiSuite) TestSideloadSnapWithUntrustedAssertions(c *check.C) {
+	assertions := makeSideloadAssertions(c, "xyzzy")
+	// forget about the trusted key
+	c.Assert(os.Remove(dirs.SnapTrustedAccountKey), check.IsNil)
+
+	d := s.daemon(c)
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	readSnapInfo = func(path string) (*snap.Info, error) {
+		return &snap.Info{SuggestedName: "local"}, nil
+	}
+
+	rsp := sideloadSnap(snapsCmd, sideloadRequest(c, "xyzzy", assertions), nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `cannot add assertions: cannot verify signature of account \(dev-id\): cannot find account-key \(canonical; .*\): assertion not found`)
+}
+
 func (s *apiSuite) TestSideloadSnapNotValidFormFile(c *check.C) {
 	d := newTestDaemon(c)
 	d.overlord.Loop()
@@ -1526,6 +2337,93 @@ func (s *apiSuite) TestInstallMissingUbuntuCore(c *check.C) {
 	c.Check(installQueue[3].WaitTasks(), check.HasLen, 2)
 }
 
+func (s *apiSuite) TestInstallMissingDefaultProviders(c *check.C) {
+	installQueue := []string{}
+
+	snapstateGet = func(s *state.State, name string, snapst *snapstate.SnapState) error {
+		if name == "installed-provider" || name == "ubuntu-core" {
+			return nil
+		}
+		return state.ErrNoState
+	}
+	snapstateInstall = func(s *state.State, name, channel string, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		installQueue = append(installQueue, name)
+		return state.NewTaskSet(s.NewTask("fake-install-snap", name)), nil
+	}
+
+	info := &snap.Info{SuggestedName: "some-snap"}
+	info.Plugs = map[string]*snap.PlugInfo{
+		"content": {Snap: info, Name: "content", Interface: "content", Attrs: map[string]interface{}{"default-provider": "provider"}},
+		"other":   {Snap: info, Name: "other", Interface: "content", Attrs: map[string]interface{}{"default-provider": "provider"}},
+		"present": {Snap: info, Name: "present", Interface: "content", Attrs: map[string]interface{}{"default-provider": "installed-provider"}},
+		"core":    {Snap: info, Name: "core", Interface: "network", Attrs: map[string]interface{}{"default-provider": "ubuntu-core"}},
+	}
+	s.rsnaps = []*snap.Info{info}
+
+	d := s.daemon(c)
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	buf := bytes.NewBufferString(`{"action": "install"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/some-snap", buf)
+	c.Assert(err, check.IsNil)
+
+	s.vars = map[string]string{"name": "some-snap"}
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+
+	// the provider is installed once, in the same change and before
+	// the snap
+	c.Check(installQueue, check.DeepEquals, []string{"some-snap", "provider"})
+	c.Assert(chg.Tasks(), check.HasLen, 2)
+	c.Check(chg.Tasks()[0].Summary(), check.Equals, "some-snap")
+	c.Check(chg.Tasks()[0].WaitTasks(), check.DeepEquals, []*state.Task{chg.Tasks()[1]})
+}
+
+func (s *apiSuite) TestInstallDefaultProviderFails(c *check.C) {
+	snapstateGet = func(s *state.State, name string, snapst *snapstate.SnapState) error {
+		if name == "ubuntu-core" {
+			return nil
+		}
+		return state.ErrNoState
+	}
+	snapstateInstall = func(s *state.State, name, channel string, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		if name == "provider" {
+			return nil, fmt.Errorf("snap %q has changes in progress", name)
+		}
+		return state.NewTaskSet(s.NewTask("fake-install-snap", name)), nil
+	}
+
+	info := &snap.Info{SuggestedName: "some-snap"}
+	info.Plugs = map[string]*snap.PlugInfo{
+		"content": {Snap: info, Name: "content", Interface: "content", Attrs: map[string]interface{}{"default-provider": "provider"}},
+	}
+	s.rsnaps = []*snap.Info{info}
+
+	d := s.daemon(c)
+
+	buf := bytes.NewBufferString(`{"action": "install"}`)
+	req, err := http.NewRequest("POST", "/v2/snaps/some-snap", buf)
+	c.Assert(err, check.IsNil)
+
+	s.vars = map[string]string{"name": "some-snap"}
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `cannot install "some-snap": cannot install default provider "provider": snap "provider" has changes in progress`)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	c.Check(st.Changes(), check.HasLen, 0)
+}
+
 // Installing ubuntu-core when not having ubuntu-core doesn't misbehave and try
 // to install ubuntu-core twice.
 func (s *apiSuite) TestInstallUbuntuCoreWhenMissing(c *check.C) {
@@ -1676,6 +2574,7 @@ func (s *apiSuite) TestInterfaces(c *check.C) {
 
 	repo := d.overlord.InterfaceManager().Repository()
 	repo.Connect("consumer", "plug", "producer", "slot")
+	repo.SetConnectionAttrs("consumer", "plug", "producer", "slot", nil, map[string]interface{}{"path": "/dev/ttyUSB0"})
 
 	req, err := http.NewRequest("GET", "/v2/interfaces", nil)
 	c.Assert(err, check.IsNil)
@@ -1713,6 +2612,13 @@ func (s *apiSuite) TestInterfaces(c *check.C) {
 					},
 				},
 			},
+			"connections": []interface{}{
+				map[string]interface{}{
+					"plug":       map[string]interface{}{"snap": "consumer", "plug": "plug"},
+					"slot":       map[string]interface{}{"snap": "producer", "slot": "slot"},
+					"slot-attrs": map[string]interface{}{"path": "/dev/ttyUSB0"},
+				},
+			},
 		},
 		"status":      "OK",
 		"status-code": 200.0,
@@ -1772,6 +2678,64 @@ func (s *apiSuite) TestConnectPlugSuccess(c *check.C) {
 	c.Check(slot.Connections[0], check.DeepEquals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
 }
 
+func (s *apiSuite) TestConnectPlugWithAttrs(c *check.C) {
+	d := s.daemon(c)
+
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	d.overlord.Loop()
+	defer d.overlord.Stop()
+
+	action := &interfaceAction{
+		Action: "connect",
+		Plugs:  []plugJSON{{Snap: "consumer", Name: "plug"}},
+		Slots:  []slotJSON{{Snap: "producer", Name: "slot", Attrs: map[string]interface{}{"path": "/dev/ttyUSB0"}}},
+	}
+	text, err := json.Marshal(action)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/interfaces", bytes.NewBuffer(text))
+	c.Assert(err, check.IsNil)
+	rec := httptest.NewRecorder()
+	interfacesCmd.POST(interfacesCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 202)
+	var body map[string]interface{}
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	id := body["change"].(string)
+
+	st := d.overlord.State()
+	st.Lock()
+	chg := st.Change(id)
+	st.Unlock()
+	c.Assert(chg, check.NotNil)
+
+	<-chg.Ready()
+
+	st.Lock()
+	err = chg.Err()
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+
+	// the attributes are listed with the connection
+	req, err = http.NewRequest("GET", "/v2/interfaces", nil)
+	c.Assert(err, check.IsNil)
+	rec = httptest.NewRecorder()
+	interfacesCmd.GET(interfacesCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	body = nil
+	err = json.Unmarshal(rec.Body.Bytes(), &body)
+	c.Check(err, check.IsNil)
+	c.Check(body["result"].(map[string]interface{})["connections"], check.DeepEquals, []interface{}{
+		map[string]interface{}{
+			"plug":       map[string]interface{}{"snap": "consumer", "plug": "plug"},
+			"slot":       map[string]interface{}{"snap": "producer", "slot": "slot"},
+			"slot-attrs": map[string]interface{}{"path": "/dev/ttyUSB0"},
+		},
+	})
+}
+
 func (s *apiSuite) TestConnectPlugFailureInterfaceMismatch(c *check.C) {
 	d := s.daemon(c)
 
@@ -2227,28 +3191,126 @@ CfXJ3A5el8Xxp5qkFywCsLdJgNtF6+uSQ4dO8SrAwzkM7c3JzntxdiFOjDLUSyZ+rXL42jdRagTY
 ZF5jSvRDLgI=`
 )
 
-func (s *apiSuite) TestAssertOK(c *check.C) {
+func (s *apiSuite) TestAssertMissingPrerequisite(c *check.C) {
 	// Setup
 	os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755)
 	err := ioutil.WriteFile(dirs.SnapTrustedAccountKey, []byte(testTrustedKey), 0640)
 	c.Assert(err, check.IsNil)
-	d := s.daemon(c)
+	s.daemon(c)
 	buf := bytes.NewBufferString(testAccKey)
 	// Execute
 	req, err := http.NewRequest("POST", "/v2/assertions", buf)
 	c.Assert(err, check.IsNil)
 	rsp := doAssert(assertsCmd, req, nil).(*resp)
 	// Verify (external)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "assert failed: cannot add account-key (developer1; adea89b00094c337): cannot find account (developer1): assertion not found")
+}
+
+func (s *apiSuite) TestAssertOK(c *check.C) {
+	// Setup
+	rootKey := assertstest.GenerateKey(752)
+	signingDB := assertstest.NewSigningDB("canonical", rootKey)
+	trustedKey := assertstest.NewAccountKey(signingDB, "canonical", rootKey.PublicKey())
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapTrustedAccountKey), 0755), check.IsNil)
+	err := ioutil.WriteFile(dirs.SnapTrustedAccountKey, asserts.Encode(trustedKey), 0640)
+	c.Assert(err, check.IsNil)
+
+	devKey := assertstest.GenerateKey(752)
+	devAccKey := assertstest.NewAccountKey(signingDB, "dev-id", devKey.PublicKey())
+	acct, err := signingDB.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "dev-id",
+		"username":     "dev",
+		"display-name": "Dev",
+		"validation":   "unproven",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, check.IsNil)
+	s.storeAssertions = []asserts.Assertion{acct}
+
+	d := s.daemon(c)
+	buf := bytes.NewBuffer(asserts.Encode(devAccKey))
+	// Execute
+	req, err := http.NewRequest("POST", "/v2/assertions", buf)
+	c.Assert(err, check.IsNil)
+	rsp := doAssert(assertsCmd, req, nil).(*resp)
+	// Verify (external)
 	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
 	c.Check(rsp.Status, check.Equals, http.StatusOK)
+	c.Check(rsp.Result, check.DeepEquals, []*assertResult{
+		{Type: "account-key", PrimaryKey: []string{"dev-id", devKey.PublicKey().ID()}, Result: "added"},
+	})
 	// Verify (internal)
-	_, err = d.overlord.AssertManager().DB().Find(asserts.AccountKeyType, map[string]string{
-		"account-id":    "developer1",
-		"public-key-id": "adea89b00094c337",
+	db := d.overlord.AssertManager().DB()
+	_, err = db.Find(asserts.AccountKeyType, map[string]string{
+		"account-id":    "dev-id",
+		"public-key-id": devKey.PublicKey().ID(),
+	})
+	c.Check(err, check.IsNil)
+	_, err = db.Find(asserts.AccountType, map[string]string{
+		"account-id": "dev-id",
 	})
 	c.Check(err, check.IsNil)
 }
 
+func (s *apiSuite) TestAssertStream(c *check.C) {
+	// Setup
+	stream := makeSideloadAssertions(c, "xyzzy")
+	d := s.daemon(c)
+
+	post := func() *resp {
+		req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewReader(stream))
+		c.Assert(err, check.IsNil)
+		return doAssert(assertsCmd, req, nil).(*resp)
+	}
+	// Execute
+	rsp := post()
+	// Verify (external)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	results := rsp.Result.([]*assertResult)
+	types := make([]string, len(results))
+	for i, res := range results {
+		c.Check(res.Result, check.Equals, "added")
+		types[i] = res.Type
+	}
+	// sorted by dependency
+	c.Check(types, check.DeepEquals, []string{"account", "account-key", "snap-declaration", "snap-revision"})
+	// Verify (internal)
+	_, err := d.overlord.AssertManager().DB().Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "local-id",
+	})
+	c.Check(err, check.IsNil)
+
+	// all there the second time around
+	rsp = post()
+	c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+	for _, res := range rsp.Result.([]*assertResult) {
+		c.Check(res.Result, check.Equals, "already-present")
+	}
+}
+
+func (s *apiSuite) TestAssertStreamBadSignature(c *check.C) {
+	// Setup
+	stream := makeSideloadAssertions(c, "xyzzy")
+	// tamper with the snap-declaration after signing
+	stream = bytes.Replace(stream, []byte("snap-name: local\n"), []byte("snap-name: other\n"), 1)
+	d := s.daemon(c)
+	// Execute
+	req, err := http.NewRequest("POST", "/v2/assertions", bytes.NewReader(stream))
+	c.Assert(err, check.IsNil)
+	rsp := doAssert(assertsCmd, req, nil).(*resp)
+	// Verify (external)
+	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, http.StatusBadRequest)
+	c.Check(rsp.Result.(*errorResult).Message, check.Matches, `assert failed: cannot verify signature of snap-declaration \(16; local-id\): failed signature verification: .*`)
+	// Verify (internal): nothing was added
+	_, err = d.overlord.AssertManager().DB().Find(asserts.AccountType, map[string]string{
+		"account-id": "dev-id",
+	})
+	c.Check(err, check.Equals, asserts.ErrNotFound)
+}
+
 func (s *apiSuite) TestAssertInvalid(c *check.C) {
 	// Setup
 	buf := bytes.NewBufferString("blargh")
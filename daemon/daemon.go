@@ -24,6 +24,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-systemd/activation"
@@ -48,6 +49,9 @@ type Daemon struct {
 	hub      *notifications.Hub
 	// enableInternalInterfaceActions controls if adding and removing slots and plugs is allowed.
 	enableInternalInterfaceActions bool
+
+	mu          sync.Mutex
+	maintenance *errorResult
 }
 
 // A ResponseFunc handles one of the individual verbs for a method
@@ -149,6 +153,10 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		rsp = rspf(c, r, user)
 	}
 
+	if rsp, ok := rsp.(*resp); ok {
+		rsp.Maintenance = c.d.maintenanceStatus()
+	}
+
 	rsp.ServeHTTP(w, r)
 }
 
@@ -221,6 +229,7 @@ func (d *Daemon) addRoutes() {
 
 // Start the Daemon
 func (d *Daemon) Start() {
+	d.overlord.InterfaceManager().StartHotplug()
 	// the loop runs in its own goroutine
 	d.overlord.Loop()
 	d.tomb.Go(func() error {
@@ -240,6 +249,34 @@ func (d *Daemon) Stop() error {
 	return d.tomb.Wait()
 }
 
+// restartDelay is how long the daemon keeps serving, announcing it is
+// about to restart, before it actually goes away.
+var restartDelay = 2 * time.Second
+
+// HandleRestart arranges for the daemon to go away shortly, to be
+// started again by socket activation, telling clients about it in the
+// meantime so that they can wait for it instead of giving up.
+func (d *Daemon) HandleRestart() {
+	d.mu.Lock()
+	d.maintenance = &errorResult{
+		Kind:    errorKindDaemonRestart,
+		Message: "daemon is restarting",
+	}
+	d.mu.Unlock()
+
+	logger.Noticef("restart requested, exiting in %v", restartDelay)
+	go func() {
+		time.Sleep(restartDelay)
+		d.tomb.Kill(nil)
+	}()
+}
+
+func (d *Daemon) maintenanceStatus() *errorResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.maintenance
+}
+
 // Dying is a tomb-ish thing
 func (d *Daemon) Dying() <-chan struct{} {
 	return d.tomb.Dying()
@@ -263,10 +300,12 @@ func New() (*Daemon, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Daemon{
+	d := &Daemon{
 		overlord: ovld,
 		hub:      notifications.NewHub(),
 		// TODO: Decide when this should be disabled by default.
 		enableInternalInterfaceActions: true,
-	}, nil
+	}
+	ovld.SetRestartHandler(d.HandleRestart)
+	return d, nil
 }
@@ -20,14 +20,17 @@
 package daemon
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/testutil"
 )
 
 // Hook up check.v1 into the "go test" runner
@@ -243,3 +246,42 @@ func (s *daemonSuite) TestAutherValidUser(c *check.C) {
 	c.Check(err, check.IsNil)
 	c.Check(user, check.DeepEquals, expectedUser.Authenticator())
 }
+
+func (s *daemonSuite) TestRestartAnnouncesMaintenance(c *check.C) {
+	oldRestartDelay := restartDelay
+	restartDelay = 10 * time.Millisecond
+	defer func() { restartDelay = oldRestartDelay }()
+
+	d := newTestDaemon(c)
+	cmd := &Command{d: d, GuestOK: true}
+	cmd.GET = func(*Command, *http.Request, *auth.UserState) Response {
+		return SyncResponse(nil, nil)
+	}
+	req, err := http.NewRequest("GET", "", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	cmd.ServeHTTP(rec, req)
+	c.Check(rec.Body.String(), check.Not(testutil.Contains), "maintenance")
+
+	// the overlord asks for the restart, e.g. after linking ubuntu-core
+	st := d.overlord.State()
+	st.Lock()
+	st.RequestRestart()
+	st.Unlock()
+
+	rec = httptest.NewRecorder()
+	cmd.ServeHTTP(rec, req)
+	var rsp map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	c.Check(rsp["maintenance"], check.DeepEquals, map[string]interface{}{
+		"kind":    "daemon-restart",
+		"message": "daemon is restarting",
+	})
+
+	select {
+	case <-d.Dying():
+	case <-time.After(5 * time.Second):
+		c.Fatal("daemon did not go away after a restart was requested")
+	}
+}
@@ -56,6 +56,7 @@ type resp struct {
 	Type   ResponseType `json:"type"`
 	Result interface{}  `json:"result"`
 	*Meta
+	Maintenance *errorResult `json:"maintenance,omitempty"`
 }
 
 // TODO This is being done in a rush to get the proper external
@@ -80,15 +81,17 @@ type respJSON struct {
 	StatusText string       `json:"status"`
 	Result     interface{}  `json:"result"`
 	*Meta
+	Maintenance *errorResult `json:"maintenance,omitempty"`
 }
 
 func (r *resp) MarshalJSON() ([]byte, error) {
 	return json.Marshal(respJSON{
-		Type:       r.Type,
-		Status:     r.Status,
-		StatusText: http.StatusText(r.Status),
-		Result:     r.Result,
-		Meta:       r.Meta,
+		Type:        r.Type,
+		Status:      r.Status,
+		StatusText:  http.StatusText(r.Status),
+		Result:      r.Result,
+		Meta:        r.Meta,
+		Maintenance: r.Maintenance,
 	})
 }
 
@@ -124,6 +127,8 @@ const (
 	errorKindTwoFactorRequired = errorKind("two-factor-required")
 	errorKindTwoFactorFailed   = errorKind("two-factor-failed")
 	errorKindLoginRequired     = errorKind("login-required")
+
+	errorKindDaemonRestart = errorKind("daemon-restart")
 )
 
 type errorValue interface{}
@@ -120,7 +120,45 @@ func effectiveConfinement(snapst *snapstate.SnapState) snap.ConfinementType {
 
 // appJSON contains the json for snap.AppInfo
 type appJSON struct {
-	Name string `json:"name"`
+	Name   string `json:"name"`
+	Daemon string `json:"daemon,omitempty"`
+}
+
+// revisionJSON contains the json for an installed revision of a snap
+type revisionJSON struct {
+	Revision    snap.Revision `json:"revision"`
+	Version     string        `json:"version,omitempty"`
+	Channel     string        `json:"channel,omitempty"`
+	InstallDate time.Time     `json:"install-date"`
+	Size        int64         `json:"size"`
+	Current     bool          `json:"current,omitempty"`
+}
+
+// localRevisions returns the details of all the installed revisions of
+// the snap, most recent first, and the space their snap files take on disk.
+func localRevisions(name string, snapst *snapstate.SnapState) ([]revisionJSON, int64) {
+	cur := snapst.Current()
+	revs := make([]revisionJSON, 0, len(snapst.Sequence))
+	var diskSize int64
+	for i := len(snapst.Sequence) - 1; i >= 0; i-- {
+		si := snapst.Sequence[i]
+		rev := revisionJSON{
+			Revision: si.Revision,
+			Channel:  si.Channel,
+			Current:  si == cur,
+		}
+		if info, err := snap.ReadInfo(name, si); err == nil {
+			rev.Version = info.Version
+			rev.InstallDate = snapDate(info)
+			if st, err := os.Stat(info.MountFile()); err == nil {
+				rev.Size = st.Size()
+			}
+		}
+		diskSize += rev.Size
+		revs = append(revs, rev)
+	}
+
+	return revs, diskSize
 }
 
 func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]interface{} {
@@ -132,29 +170,31 @@ func mapLocal(localSnap *snap.Info, snapst *snapstate.SnapState) map[string]inte
 	apps := make([]appJSON, 0, len(localSnap.Apps))
 	for _, app := range localSnap.Apps {
 		apps = append(apps, appJSON{
-			Name: app.Name,
+			Name:   app.Name,
+			Daemon: app.Daemon,
 		})
 	}
 
 	return map[string]interface{}{
-		"description":    localSnap.Description(),
-		"developer":      localSnap.Developer,
-		"icon":           snapIcon(localSnap),
-		"id":             localSnap.SnapID,
-		"install-date":   snapDate(localSnap),
-		"installed-size": localSnap.Size,
-		"name":           localSnap.Name(),
-		"revision":       localSnap.Revision,
-		"status":         status,
-		"summary":        localSnap.Summary(),
-		"type":           string(localSnap.Type),
-		"version":        localSnap.Version,
-		"channel":        localSnap.Channel,
-		"confinement":    localSnap.Confinement,
-		"devmode":        snapst.DevMode(),
-		"trymode":        snapst.TryMode(),
-		"private":        localSnap.Private,
-		"apps":           apps,
+		"description":      localSnap.Description(),
+		"developer":        localSnap.Developer,
+		"icon":             snapIcon(localSnap),
+		"id":               localSnap.SnapID,
+		"install-date":     snapDate(localSnap),
+		"installed-size":   localSnap.Size,
+		"name":             localSnap.Name(),
+		"revision":         localSnap.Revision,
+		"status":           status,
+		"summary":          localSnap.Summary(),
+		"type":             string(localSnap.Type),
+		"version":          localSnap.Version,
+		"channel":          localSnap.Channel,
+		"tracking-channel": snapst.Channel,
+		"confinement":      localSnap.Confinement,
+		"devmode":          snapst.DevMode(),
+		"trymode":          snapst.TryMode(),
+		"private":          localSnap.Private,
+		"apps":             apps,
 	}
 }
 
@@ -34,9 +34,11 @@ var (
 	SnapDataHomeGlob          string
 	SnapAppArmorDir           string
 	AppArmorCacheDir          string
+	SnapAppArmorCacheDir      string
 	SnapAppArmorAdditionalDir string
 	SnapSeccompDir            string
 	SnapUdevRulesDir          string
+	SnapMountPolicyDir        string
 	LocaleDir                 string
 	SnapMetaDir               string
 	SnapdSocket               string
@@ -44,8 +46,12 @@ var (
 	SnapAssertsDBDir      string
 	SnapTrustedAccountKey string
 
+	SnapDeviceDir string
+
 	SnapStateFile string
 
+	SnapInterfacesConfigFile string
+
 	SnapBinariesDir     string
 	SnapServicesDir     string
 	SnapDesktopFilesDir string
@@ -81,8 +87,10 @@ func SetRootDir(rootdir string) {
 	SnapDataHomeGlob = filepath.Join(rootdir, "/home/*/snap/")
 	SnapAppArmorDir = filepath.Join(rootdir, snappyDir, "apparmor", "profiles")
 	AppArmorCacheDir = filepath.Join(rootdir, "/var/cache/apparmor")
+	SnapAppArmorCacheDir = filepath.Join(rootdir, snappyDir, "apparmor", "cache")
 	SnapAppArmorAdditionalDir = filepath.Join(rootdir, snappyDir, "apparmor", "additional")
 	SnapSeccompDir = filepath.Join(rootdir, snappyDir, "seccomp", "profiles")
+	SnapMountPolicyDir = filepath.Join(rootdir, snappyDir, "mount")
 	SnapMetaDir = filepath.Join(rootdir, snappyDir, "meta")
 	SnapBlobDir = filepath.Join(rootdir, snappyDir, "snaps")
 	SnapDesktopFilesDir = filepath.Join(rootdir, snappyDir, "desktop", "applications")
@@ -92,8 +100,12 @@ func SetRootDir(rootdir string) {
 	SnapAssertsDBDir = filepath.Join(rootdir, snappyDir, "assertions")
 	SnapTrustedAccountKey = filepath.Join(rootdir, "/usr/share/snapd/trusted.acckey")
 
+	SnapDeviceDir = filepath.Join(rootdir, snappyDir, "device")
+
 	SnapStateFile = filepath.Join(rootdir, snappyDir, "state.json")
 
+	SnapInterfacesConfigFile = filepath.Join(rootdir, "/etc/snapd/interfaces.yaml")
+
 	SnapBinariesDir = filepath.Join(SnapSnapsDir, "bin")
 	SnapServicesDir = filepath.Join(rootdir, "/etc/systemd/system")
 	SnapBusPolicyDir = filepath.Join(rootdir, "/etc/dbus-1/system.d")
@@ -26,14 +26,19 @@
 package apparmor
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 )
 
 // LoadProfile loads an apparmor profile from the given file.
@@ -52,10 +57,115 @@ func LoadProfile(fname string) error {
 	return nil
 }
 
+// CompilerKey returns what binary policies depend on besides the content of
+// their profile: the version of apparmor_parser and the apparmor features of
+// the running kernel. Binaries compiled under another key are not reused.
+func CompilerKey() (string, error) {
+	output, err := exec.Command("apparmor_parser", "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("cannot get apparmor_parser version: %s\napparmor_parser output:\n%s", err, string(output))
+	}
+	return fmt.Sprintf("%s\n%s", strings.TrimSpace(string(output)), strings.Join(KernelFeatures(), " ")), nil
+}
+
+// LoadCachedProfile loads an apparmor profile from the given file like
+// LoadProfile, but the profile is compiled only if no binary policy compiled
+// from the same content, under the same compiler key, is found in the cache.
+//
+// The binary policy is cached in dirs.SnapAppArmorCacheDir under the name of
+// the profile followed by the hash of the compiler key and content. The
+// binaries of the last two contents of each profile are kept, so that going
+// back to the previous revision of a snap does not need any compilation.
+// The loaded binary is also copied to dirs.AppArmorCacheDir, where the
+// apparmor boot-time loader finds it.
+func LoadCachedProfile(fname, compilerKey string) error {
+	content, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return fmt.Errorf("cannot load apparmor profile: %s", err)
+	}
+	name := filepath.Base(fname)
+	binary := filepath.Join(dirs.SnapAppArmorCacheDir, fmt.Sprintf("%s.%x", name, sha256.Sum256(append([]byte(compilerKey+"\n"), content...))))
+	if osutil.FileExists(binary) {
+		// mark it as the most recently used binary of the profile
+		now := time.Now()
+		os.Chtimes(binary, now, now)
+	} else {
+		if err := os.MkdirAll(dirs.SnapAppArmorCacheDir, 0755); err != nil {
+			return fmt.Errorf("cannot create apparmor cache directory: %s", err)
+		}
+		// Use no-expr-simplify since expr-simplify is actually slower on armhf (LP: #1383858)
+		output, err := exec.Command(
+			"apparmor_parser", "--skip-kernel-load", "-O", "no-expr-simplify",
+			fmt.Sprintf("--ofile=%s", binary), fname).CombinedOutput()
+		if err != nil {
+			os.Remove(binary)
+			return fmt.Errorf("cannot compile apparmor profile: %s\napparmor_parser output:\n%s", err, string(output))
+		}
+	}
+	pruneCachedBinaries(name, binary)
+	output, err := exec.Command("apparmor_parser", "--replace", "--binary", binary).CombinedOutput()
+	if err != nil {
+		// don't reuse a binary the kernel refused
+		os.Remove(binary)
+		return fmt.Errorf("cannot load apparmor profile: %s\napparmor_parser output:\n%s", err, string(output))
+	}
+	if err := os.MkdirAll(dirs.AppArmorCacheDir, 0755); err != nil {
+		return fmt.Errorf("cannot create apparmor cache directory: %s", err)
+	}
+	if err := osutil.CopyFile(binary, filepath.Join(dirs.AppArmorCacheDir, name), osutil.CopyFlagOverwrite); err != nil {
+		return fmt.Errorf("cannot update apparmor profile cache: %s", err)
+	}
+	return nil
+}
+
+// keptBinaries is the number of binary policies kept in the cache for each
+// profile.
+const keptBinaries = 2
+
+// pruneCachedBinaries removes the least recently used binary policies of the
+// named profile beyond keptBinaries, never removing current.
+func pruneCachedBinaries(name, current string) {
+	var others []os.FileInfo
+	for _, binary := range cachedBinaries(name) {
+		if binary == current {
+			continue
+		}
+		if fi, err := os.Stat(binary); err == nil {
+			others = append(others, fi)
+		}
+	}
+	sort.Sort(byModTimeDesc(others))
+	for i := keptBinaries - 1; i < len(others); i++ {
+		os.Remove(filepath.Join(dirs.SnapAppArmorCacheDir, others[i].Name()))
+	}
+}
+
+type byModTimeDesc []os.FileInfo
+
+func (fis byModTimeDesc) Len() int           { return len(fis) }
+func (fis byModTimeDesc) Swap(i, j int)      { fis[i], fis[j] = fis[j], fis[i] }
+func (fis byModTimeDesc) Less(i, j int) bool { return fis[i].ModTime().After(fis[j].ModTime()) }
+
+// cachedBinaries returns the binary policies of the named profile found in
+// the cache.
+func cachedBinaries(name string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, name+".*"))
+	binaries := matches[:0]
+	for _, match := range matches {
+		// the name is followed by the hex encoded sha256 of the content;
+		// this excludes the profiles of hooks named after an application
+		if len(filepath.Base(match)) == len(name)+1+2*sha256.Size {
+			binaries = append(binaries, match)
+		}
+	}
+	return binaries
+}
+
 // UnloadProfile removes the named profile from the running kernel.
 //
 // The operation is done with: apparmor_parser --remove $name
-// The binary cache file is removed from /var/cache/apparmor
+// The binary cache files are removed from /var/cache/apparmor and from
+// dirs.SnapAppArmorCacheDir.
 func UnloadProfile(name string) error {
 	output, err := exec.Command("apparmor_parser", "--remove", name).CombinedOutput()
 	if err != nil {
@@ -66,6 +176,11 @@ func UnloadProfile(name string) error {
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("cannot remove apparmor profile cache: %s", err)
 	}
+	for _, binary := range cachedBinaries(name) {
+		if err := os.Remove(binary); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("cannot remove apparmor profile cache: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -103,3 +218,26 @@ func LoadedProfiles() ([]string, error) {
 	}
 	return profiles, nil
 }
+
+// featuresSysPath points to the directory describing the features of the
+// apparmor module of the running kernel.
+const realFeaturesSysPath = "/sys/kernel/security/apparmor/features"
+
+var featuresSysPath = realFeaturesSysPath
+
+// KernelFeatures returns the sorted list of the apparmor features supported
+// by the running kernel. The list is empty if apparmor is not available.
+func KernelFeatures() []string {
+	// ReadDir sorts the entries by name.
+	entries, err := ioutil.ReadDir(featuresSysPath)
+	if err != nil {
+		return nil
+	}
+	features := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			features = append(features, entry.Name())
+		}
+	}
+	return features
+}
@@ -109,6 +109,20 @@ func (s *appArmorSuite) TestUnloadRemovesCachedProfile(c *C) {
 	c.Check(os.IsNotExist(err), Equals, true)
 }
 
+// Tests for KernelFeatures()
+
+func (s *appArmorSuite) TestKernelFeatures(c *C) {
+	featuresDir := filepath.Join(c.MkDir(), "features")
+	apparmor.MockFeaturesSysPath(&s.BaseTest, featuresDir)
+	c.Check(apparmor.KernelFeatures(), HasLen, 0)
+
+	for _, name := range []string{"network", "file", "dbus"} {
+		c.Assert(os.MkdirAll(filepath.Join(featuresDir, name), 0755), IsNil)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(featuresDir, "stray"), nil, 0644), IsNil)
+	c.Check(apparmor.KernelFeatures(), DeepEquals, []string{"dbus", "file", "network"})
+}
+
 // Tests for LoadedProfiles()
 
 func (s *appArmorSuite) TestLoadedApparmorProfilesReturnsErrorOnMissingFile(c *C) {
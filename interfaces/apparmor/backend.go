@@ -43,7 +43,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
+	"sync"
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
@@ -66,35 +68,63 @@ func (b *Backend) Name() string {
 // This method should be called after changing plug, slots, connections between
 // them or application present in the snap.
 func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
-	snapName := snapInfo.Name()
-	// Get the snippets that apply to this snap
-	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityAppArmor)
-	if err != nil {
-		return fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
-	}
-	// Get the files that this snap should have
-	content, err := b.combineSnippets(snapInfo, devMode, snippets)
-	if err != nil {
-		return fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
-	}
-	glob := interfaces.SecurityTagGlob(snapInfo.Name())
+	return b.SetupMany([]*snap.Info{snapInfo}, map[string]bool{snapInfo.Name(): devMode}, repo)
+}
+
+// SetupAndReload creates the apparmor profiles of a given snap like Setup
+// but loads all of them, changed or not, as needed when the kernel or the
+// apparmor parser changed.
+func (b *Backend) SetupAndReload(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
+	return b.setupMany([]*snap.Info{snapInfo}, map[string]bool{snapInfo.Name(): devMode}, repo, true)
+}
+
+// SetupMany creates the apparmor profiles of the given snaps and loads those
+// that changed, compiling several of them in parallel.
+func (b *Backend) SetupMany(snapInfos []*snap.Info, devModes map[string]bool, repo *interfaces.Repository) error {
+	return b.setupMany(snapInfos, devModes, repo, false)
+}
+
+func (b *Backend) setupMany(snapInfos []*snap.Info, devModes map[string]bool, repo *interfaces.Repository, reloadAll bool) error {
 	dir := dirs.SnapAppArmorDir
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("cannot create directory for apparmor profiles %q: %s", dir, err)
 	}
-	_, removed, errEnsure := osutil.EnsureDirState(dir, glob, content)
-	// NOTE: load all profiles instead of just the changed profiles.  We're
-	// relying on apparmor cache to make this efficient. This gives us
-	// certainty that each call to Setup ends up with working profiles.
-	all := make([]string, 0, len(content))
-	for name := range content {
-		all = append(all, name)
+	var changed, removed []string
+	var errEnsure error
+	for _, snapInfo := range snapInfos {
+		snapName := snapInfo.Name()
+		// Get the snippets that apply to this snap
+		snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityAppArmor)
+		if err != nil {
+			return fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
+		}
+		// Get the files that this snap should have
+		content, err := b.combineSnippets(snapInfo, devModes[snapName], snippets)
+		if err != nil {
+			return fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+		}
+		glob := interfaces.SecurityTagGlob(snapName)
+		snapChanged, snapRemoved, err := osutil.EnsureDirState(dir, glob, content)
+		if reloadAll && err == nil {
+			snapChanged = snapChanged[:0]
+			for profile := range content {
+				snapChanged = append(snapChanged, profile)
+			}
+		}
+		changed = append(changed, snapChanged...)
+		removed = append(removed, snapRemoved...)
+		if err != nil && errEnsure == nil {
+			errEnsure = fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, err)
+		}
 	}
-	sort.Strings(all)
-	errReload := reloadProfiles(all)
+	// NOTE: unless reloading all, only the changed profiles are loaded, the
+	// others are already loaded. A profile that cannot be loaded is removed
+	// so that it counts as changed when the caller tries again.
+	sort.Strings(changed)
+	errReload := reloadProfiles(changed)
 	errUnload := unloadProfiles(removed)
 	if errEnsure != nil {
-		return fmt.Errorf("cannot synchronize security files for snap %q: %s", snapName, errEnsure)
+		return errEnsure
 	}
 	if errReload != nil {
 		return errReload
@@ -113,6 +143,38 @@ func (b *Backend) Remove(snapName string) error {
 	return errUnload
 }
 
+// Fingerprint returns the template of the apparmor profiles followed by the
+// apparmor features of the running kernel.
+func (b *Backend) Fingerprint() []byte {
+	var buf bytes.Buffer
+	buf.Write(defaultTemplate)
+	for _, feature := range KernelFeatures() {
+		fmt.Fprintf(&buf, "\n%s", feature)
+	}
+	return buf.Bytes()
+}
+
+// Profiles returns the apparmor profiles of the applications of a given snap,
+// with each snippet annotated with its origin.
+func (b *Backend) Profiles(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(snapName, interfaces.SecurityAppArmor)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, devMode, interfaces.AnnotateSnippets(snippets, "# ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string][]byte, len(content))
+	for _, appInfo := range snapInfo.Apps {
+		if fileState, ok := content[appInfo.SecurityTag()]; ok {
+			profiles[appInfo.Name] = fileState.Content
+		}
+	}
+	return profiles, nil
+}
+
 var (
 	templatePattern          = regexp.MustCompile("(###[A-Z]+###)")
 	placeholderVar           = []byte("###VAR###")
@@ -154,12 +216,42 @@ func (b *Backend) combineSnippets(snapInfo *snap.Info, devMode bool, snippets ma
 	return content, nil
 }
 
+// parallelism is the maximum number of profiles compiled at the same time.
+var parallelism = runtime.NumCPU()
+
+// reloadProfiles loads the given profiles, compiling up to parallelism of
+// them at the same time. The profiles that cannot be loaded are removed.
 func reloadProfiles(profiles []string) error {
-	for _, profile := range profiles {
-		fname := filepath.Join(dirs.SnapAppArmorDir, profile)
-		err := LoadProfile(fname)
+	if len(profiles) == 0 {
+		return nil
+	}
+	compilerKey, err := CompilerKey()
+	if err != nil {
+		// none of the profiles is loaded, make sure they are next time
+		for _, profile := range profiles {
+			os.Remove(filepath.Join(dirs.SnapAppArmorDir, profile))
+		}
+		return err
+	}
+	errs := make([]error, len(profiles))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, profile := range profiles {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, profile string) {
+			defer func() { <-sem; wg.Done() }()
+			fname := filepath.Join(dirs.SnapAppArmorDir, profile)
+			if err := LoadCachedProfile(fname, compilerKey); err != nil {
+				os.Remove(fname)
+				errs[i] = fmt.Errorf("cannot load apparmor profile %q: %s", profile, err)
+			}
+		}(i, profile)
+	}
+	wg.Wait()
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("cannot load apparmor profile %q: %s", profile, err)
+			return err
 		}
 	}
 	return nil
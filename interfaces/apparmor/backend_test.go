@@ -20,10 +20,13 @@
 package apparmor_test
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	. "gopkg.in/check.v1"
 
@@ -52,15 +55,23 @@ const fakeAppArmorParser = `
 cache_dir=""
 profile=""
 write=""
+ofile=""
 while [ -n "$1" ]; do
 	case "$1" in
+		--version)
+			echo "AppArmor parser version 2.10.95"
+			exit 0
+			;;
 		--cache-loc=*)
 			cache_dir="$(echo "$1" | cut -d = -f 2)" || exit 1
 			;;
 		--write-cache)
 			write=yes
 			;;
-		--replace|--remove)
+		--ofile=*)
+			ofile="$(echo "$1" | cut -d = -f 2)" || exit 1
+			;;
+		--replace|--remove|--skip-kernel-load|--binary)
 			# Ignore
 			;;
 		-O)
@@ -76,6 +87,9 @@ done
 if [ "$write" = yes ]; then
 	echo fake > "$cache_dir/$profile"
 fi
+if [ -n "$ofile" ]; then
+	echo fake > "$ofile"
+fi
 `
 
 func (s *backendSuite) SetUpTest(c *C) {
@@ -123,6 +137,28 @@ func (s *backendSuite) TestName(c *C) {
 	c.Check(s.backend.Name(), Equals, "apparmor")
 }
 
+// cachedBinary returns the path of the binary policy compiled from the given
+// profile by the fake apparmor_parser.
+func cachedBinary(c *C, profile string) string {
+	content, err := ioutil.ReadFile(profile)
+	c.Assert(err, IsNil)
+	key := "AppArmor parser version 2.10.95\n" + strings.Join(apparmor.KernelFeatures(), " ") + "\n"
+	return filepath.Join(dirs.SnapAppArmorCacheDir, fmt.Sprintf("%s.%x", filepath.Base(profile), sha256.Sum256(append([]byte(key), content...))))
+}
+
+// compileAndLoadCalls returns the calls to apparmor_parser getting its
+// version and then compiling and loading each of the given profiles.
+func compileAndLoadCalls(c *C, profiles ...string) []string {
+	calls := []string{"--version"}
+	for _, profile := range profiles {
+		binary := cachedBinary(c, profile)
+		calls = append(calls,
+			fmt.Sprintf("--skip-kernel-load -O no-expr-simplify --ofile=%s %s", binary, profile),
+			fmt.Sprintf("--replace --binary %s", binary))
+	}
+	return calls
+}
+
 func (s *backendSuite) TestInstallingSnapWritesAndLoadsProfiles(c *C) {
 	devMode := false
 	s.installSnap(c, devMode, sambaYaml, 1)
@@ -130,26 +166,95 @@ func (s *backendSuite) TestInstallingSnapWritesAndLoadsProfiles(c *C) {
 	// file called "snap.sambda.smbd" was created
 	_, err := os.Stat(profile)
 	c.Check(err, IsNil)
-	// apparmor_parser was used to load that file
-	c.Check(s.parserCmd.Calls(), DeepEquals, []string{
-		fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, profile),
-	})
+	// apparmor_parser was used to compile and load that file
+	c.Check(s.parserCmd.Calls(), DeepEquals, compileAndLoadCalls(c, profile))
+	// the binary policy is cached
+	_, err = os.Stat(cachedBinary(c, profile))
+	c.Check(err, IsNil)
+}
+
+func (s *backendSuite) TestInstallingSnapUpdatesBootCache(c *C) {
+	s.installSnap(c, false, sambaYaml, 1)
+	// the boot-time loader finds the binary policy in the apparmor cache
+	data, err := ioutil.ReadFile(filepath.Join(dirs.AppArmorCacheDir, "snap.samba.smbd"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "fake\n")
+}
+
+func (s *backendSuite) TestBinaryNotReusedAfterParserUpgrade(c *C) {
+	snapInfo := s.installSnap(c, false, sambaYaml, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	binary := cachedBinary(c, profile)
+
+	s.parserCmd.Restore()
+	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", strings.Replace(fakeAppArmorParser, "2.10.95", "2.11.0", 1))
+	// force the profile to be loaded again
+	c.Assert(os.Remove(profile), IsNil)
+	c.Assert(s.backend.Setup(snapInfo, false, s.repo), IsNil)
+	calls := s.parserCmd.Calls()
+	c.Assert(calls, HasLen, 3)
+	c.Check(calls[1], Matches, "--skip-kernel-load .*")
+	c.Check(calls[2], Not(Equals), fmt.Sprintf("--replace --binary %s", binary))
+}
+
+func (s *backendSuite) TestBinaryRemovedWhenLoadingFails(c *C) {
+	s.parserCmd.Restore()
+	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", `if [ "$1" = "--replace" ]; then exit 1; fi`+fakeAppArmorParser)
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYaml))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	err = s.backend.Setup(snapInfo, false, s.repo)
+	c.Assert(err, ErrorMatches, `(?s)cannot load apparmor profile "snap.samba.smbd": cannot load apparmor profile: .*`)
+	// the next attempt compiles the profile again
+	binaries, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, "snap.samba.smbd.*"))
+	c.Assert(err, IsNil)
+	c.Check(binaries, HasLen, 0)
 }
 
-func (s *backendSuite) TestProfilesAreAlwaysLoaded(c *C) {
+func (s *backendSuite) TestUnchangedProfilesAreNotReloaded(c *C) {
 	for _, devMode := range []bool{true, false} {
 		snapInfo := s.installSnap(c, devMode, sambaYaml, 1)
 		s.parserCmd.ForgetCalls()
 		err := s.backend.Setup(snapInfo, devMode, s.repo)
 		c.Assert(err, IsNil)
-		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
-		c.Check(s.parserCmd.Calls(), DeepEquals, []string{
-			fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, profile),
-		})
+		c.Check(s.parserCmd.Calls(), HasLen, 0)
 		s.removeSnap(c, snapInfo)
 	}
 }
 
+func (s *backendSuite) TestSetupAndReloadLoadsUnchangedProfiles(c *C) {
+	snapInfo := s.installSnap(c, false, sambaYaml, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	binary := cachedBinary(c, profile)
+	s.parserCmd.ForgetCalls()
+	err := s.backend.SetupAndReload(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	// the unchanged profile is loaded again, from the cache
+	c.Check(s.parserCmd.Calls(), DeepEquals, []string{
+		"--version",
+		fmt.Sprintf("--replace --binary %s", binary),
+	})
+}
+
+func (s *backendSuite) TestProfilesThatFailToLoadAreLoadedAgain(c *C) {
+	s.parserCmd.Restore()
+	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", `[ "$1" = "--version" ] && exit 0; exit 1`)
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYaml))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	err = s.backend.Setup(snapInfo, false, s.repo)
+	c.Assert(err, ErrorMatches, `cannot load apparmor profile "snap.samba.smbd": cannot compile apparmor profile: exit status 1\napparmor_parser output:\n`)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	_, err = os.Stat(profile)
+	c.Check(os.IsNotExist(err), Equals, true)
+
+	s.parserCmd.Restore()
+	s.parserCmd = testutil.MockCommand(c, "apparmor_parser", fakeAppArmorParser)
+	err = s.backend.Setup(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(s.parserCmd.Calls(), DeepEquals, compileAndLoadCalls(c, profile))
+}
+
 func (s *backendSuite) TestRemovingSnapRemovesAndUnloadsProfiles(c *C) {
 	for _, devMode := range []bool{true, false} {
 		snapInfo := s.installSnap(c, devMode, sambaYaml, 1)
@@ -163,6 +268,10 @@ func (s *backendSuite) TestRemovingSnapRemovesAndUnloadsProfiles(c *C) {
 		cache := filepath.Join(dirs.AppArmorCacheDir, "snap.samba.smbd")
 		_, err = os.Stat(cache)
 		c.Check(os.IsNotExist(err), Equals, true)
+		// and so were the cached binary policies
+		binaries, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, "snap.samba.smbd.*"))
+		c.Assert(err, IsNil)
+		c.Check(binaries, HasLen, 0)
 		// apparmor_parser was used to unload the profile
 		c.Check(s.parserCmd.Calls(), DeepEquals, []string{
 			"--remove snap.samba.smbd",
@@ -178,29 +287,46 @@ func (s *backendSuite) TestUpdatingSnapMakesNeccesaryChanges(c *C) {
 		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		// apparmor_parser was used to reload the profile because snap revision
 		// is inside the generated policy.
-		c.Check(s.parserCmd.Calls(), DeepEquals, []string{
-			fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, profile),
-		})
+		c.Check(s.parserCmd.Calls(), DeepEquals, compileAndLoadCalls(c, profile))
 		s.removeSnap(c, snapInfo)
 	}
 }
 
+func (s *backendSuite) TestRevertingSnapUsesCachedBinary(c *C) {
+	snapInfo := s.installSnap(c, false, sambaYaml, 1)
+	profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+	binary1 := cachedBinary(c, profile)
+	snapInfo = s.updateSnap(c, snapInfo, false, sambaYaml, 2)
+	binary2 := cachedBinary(c, profile)
+	s.parserCmd.ForgetCalls()
+	// going back to the first revision needs no compilation
+	snapInfo = s.updateSnap(c, snapInfo, false, sambaYaml, 1)
+	c.Check(s.parserCmd.Calls(), DeepEquals, []string{
+		"--version",
+		fmt.Sprintf("--replace --binary %s", binary1),
+	})
+	// only the binaries of the last two contents are kept
+	snapInfo = s.updateSnap(c, snapInfo, false, sambaYaml, 3)
+	binaries, err := filepath.Glob(filepath.Join(dirs.SnapAppArmorCacheDir, "snap.samba.smbd.*"))
+	c.Assert(err, IsNil)
+	c.Check(binaries, HasLen, 2)
+	c.Check(binaries, testutil.Contains, binary1)
+	c.Check(binaries, Not(testutil.Contains), binary2)
+	s.removeSnap(c, snapInfo)
+}
+
 func (s *backendSuite) TestUpdatingSnapToOneWithMoreApps(c *C) {
 	for _, devMode := range []bool{true, false} {
 		snapInfo := s.installSnap(c, devMode, sambaYaml, 1)
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the new application being added
 		snapInfo = s.updateSnap(c, snapInfo, devMode, sambaYamlWithNmbd, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		// file called "snap.sambda.nmbd" was created
 		_, err := os.Stat(nmbdProfile)
 		c.Check(err, IsNil)
-		// apparmor_parser was used to load the both profiles
-		c.Check(s.parserCmd.Calls(), DeepEquals, []string{
-			fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, nmbdProfile),
-			fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, smbdProfile),
-		})
+		// apparmor_parser was used to load the new profile only
+		c.Check(s.parserCmd.Calls(), DeepEquals, compileAndLoadCalls(c, nmbdProfile))
 		s.removeSnap(c, snapInfo)
 	}
 }
@@ -211,20 +337,53 @@ func (s *backendSuite) TestUpdatingSnapToOneWithFewerApps(c *C) {
 		s.parserCmd.ForgetCalls()
 		// NOTE: the revision is kept the same to just test on the application being removed
 		snapInfo = s.updateSnap(c, snapInfo, devMode, sambaYaml, 1)
-		smbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
 		nmbdProfile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.nmbd")
 		// file called "snap.sambda.nmbd" was removed
 		_, err := os.Stat(nmbdProfile)
 		c.Check(os.IsNotExist(err), Equals, true)
 		// apparmor_parser was used to remove the unused profile
 		c.Check(s.parserCmd.Calls(), DeepEquals, []string{
-			fmt.Sprintf("--replace --write-cache -O no-expr-simplify --cache-loc=%s/var/cache/apparmor %s", s.rootDir, smbdProfile),
 			"--remove snap.samba.nmbd",
 		})
 		s.removeSnap(c, snapInfo)
 	}
 }
 
+func (s *backendSuite) TestSetupManyLoadsProfilesInParallel(c *C) {
+	restore := apparmor.MockParallelism(2)
+	defer restore()
+	var snapInfos []*snap.Info
+	var profiles []string
+	for _, name := range []string{"samba", "other", "third"} {
+		snapInfo, err := snap.InfoFromSnapYaml([]byte(strings.Replace(sambaYamlWithNmbd, "samba", name, 1)))
+		c.Assert(err, IsNil)
+		c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+		snapInfos = append(snapInfos, snapInfo)
+	}
+	var batch interfaces.BatchSecurityBackend = s.backend
+	err := batch.SetupMany(snapInfos, map[string]bool{"other": true}, s.repo)
+	c.Assert(err, IsNil)
+	for _, snapInfo := range snapInfos {
+		for _, app := range []string{"smbd", "nmbd"} {
+			profile := filepath.Join(dirs.SnapAppArmorDir, fmt.Sprintf("snap.%s.%s", snapInfo.Name(), app))
+			profiles = append(profiles, profile)
+		}
+	}
+	expected := compileAndLoadCalls(c, profiles...)
+	// the order of the calls depends on scheduling
+	calls := s.parserCmd.Calls()
+	sort.Strings(calls)
+	sort.Strings(expected)
+	c.Check(calls, DeepEquals, expected)
+	// the devmode of each snap is honored
+	data, err := ioutil.ReadFile(filepath.Join(dirs.SnapAppArmorDir, "snap.other.smbd"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), testutil.Contains, "(attach_disconnected,complain)")
+	data, err = ioutil.ReadFile(filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd"))
+	c.Assert(err, IsNil)
+	c.Check(string(data), Not(testutil.Contains), "complain")
+}
+
 func (s *backendSuite) TestRealDefaultTemplateIsNormallyUsed(c *C) {
 	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYaml))
 	c.Assert(err, IsNil)
@@ -312,6 +471,50 @@ func (s *backendSuite) TestCombineSnippets(c *C) {
 	}
 }
 
+func (s *backendSuite) TestFingerprint(c *C) {
+	restore := apparmor.MockTemplate([]byte("template"))
+	defer restore()
+	var t testutil.BaseTest
+	defer t.TearDownTest(c)
+	featuresDir := c.MkDir()
+	apparmor.MockFeaturesSysPath(&t, featuresDir)
+
+	var fingerprinter interfaces.Fingerprinter = s.backend
+	c.Check(string(fingerprinter.Fingerprint()), Equals, "template")
+	c.Assert(os.Mkdir(filepath.Join(featuresDir, "dbus"), 0755), IsNil)
+	c.Assert(os.Mkdir(filepath.Join(featuresDir, "network"), 0755), IsNil)
+	c.Check(string(fingerprinter.Fingerprint()), Equals, "template\ndbus\nnetwork")
+}
+
+func (s *backendSuite) TestProfiles(c *C) {
+	restore := apparmor.MockTemplate([]byte("\n" +
+		"###PROFILEATTACH### (attach_disconnected) {\n" +
+		"###SNIPPETS###\n" +
+		"}\n"))
+	defer restore()
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("snippet"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYaml))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	var profiler interfaces.SecurityProfiler = s.backend
+	profiles, err := profiler.Profiles(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{
+		"smbd": []byte(`
+profile "snap.samba.smbd" (attach_disconnected) {
+# slot samba:iface (interface iface)
+snippet
+}
+`),
+	})
+	// nothing is written or loaded
+	_, err = os.Stat(filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd"))
+	c.Check(os.IsNotExist(err), Equals, true)
+	c.Check(s.parserCmd.Calls(), HasLen, 0)
+}
+
 // Support code for tests
 
 // installSnap "installs" a snap from YAML.
@@ -31,6 +31,14 @@ func MockProfilesPath(t *testutil.BaseTest, profiles string) {
 	})
 }
 
+// MockFeaturesSysPath mocks the directory read by KernelFeatures()
+func MockFeaturesSysPath(t *testutil.BaseTest, path string) {
+	featuresSysPath = path
+	t.AddCleanup(func() {
+		featuresSysPath = realFeaturesSysPath
+	})
+}
+
 // MockTemplate replaces apprmor template.
 //
 // NOTE: The real apparmor template is long. For testing it is convenient for
@@ -40,3 +48,10 @@ func MockTemplate(fakeTemplate []byte) (restore func()) {
 	defaultTemplate = fakeTemplate
 	return func() { defaultTemplate = orig }
 }
+
+// MockParallelism replaces the number of profiles compiled at the same time.
+func MockParallelism(n int) (restore func()) {
+	old := parallelism
+	parallelism = n
+	return func() { parallelism = old }
+}
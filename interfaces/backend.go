@@ -43,3 +43,39 @@ type SecurityBackend interface {
 	// This method should be called during the process of removing a snap.
 	Remove(snapName string) error
 }
+
+// SecurityProfiler is implemented by the security backends able to show the
+// profiles they would generate for a snap, for diagnostic purposes.
+type SecurityProfiler interface {
+	// Profiles returns the combined profiles of the applications of a given
+	// snap, indexed by application name, without writing or loading them.
+	// Each snippet is annotated with the plug or slot and the interface that
+	// produced it.
+	Profiles(snapInfo *snap.Info, devMode bool, repo *Repository) (map[string][]byte, error)
+}
+
+// Fingerprinter is implemented by the security backends whose artefacts
+// depend on more than the snaps and their connections, such as on templates
+// built into snapd or on the features of the running kernel.
+type Fingerprinter interface {
+	// Fingerprint returns data that changes whenever the artefacts of a snap
+	// would change even though the snap and its connections did not.
+	Fingerprint() []byte
+}
+
+// BatchSecurityBackend is implemented by the security backends able to set up
+// the artefacts of many snaps at once, compiling them in parallel.
+type BatchSecurityBackend interface {
+	// SetupMany is equivalent to calling Setup for each of the given snaps,
+	// with the developer mode of each snap taken from devModes.
+	SetupMany(snapInfos []*snap.Info, devModes map[string]bool, repo *Repository) error
+}
+
+// ReloadingSecurityBackend is implemented by the security backends that only
+// load the artefacts that changed, to load all of them anyway, as needed when
+// what the artefacts are loaded with changed while the artefacts did not.
+type ReloadingSecurityBackend interface {
+	// SetupAndReload is equivalent to Setup but loads all the artefacts of
+	// the snap, changed or not.
+	SetupAndReload(snapInfo *snap.Info, devMode bool, repo *Repository) error
+}
@@ -25,6 +25,7 @@ import (
 
 var allInterfaces = []interfaces.Interface{
 	&BoolFileInterface{},
+	&ContentSharingInterface{},
 	&BluezInterface{},
 	&LocationControlInterface{},
 	&LocationObserveInterface{},
@@ -48,6 +49,9 @@ var allInterfaces = []interfaces.Interface{
 	NewOpenglInterface(),
 	NewPulseAudioInterface(),
 	NewCupsControlInterface(),
+	NewSerialPortInterface(),
+	NewI2CInterface(),
+	NewSpiInterface(),
 }
 
 // Interfaces returns all of the built-in interfaces.
@@ -0,0 +1,312 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ContentSharingInterface allows sharing content between snaps.
+//
+// The slot lists the directories it exports under the "read" and "write"
+// attributes, the plug names the directory where they appear with the
+// "target" attribute. Paths are relative to the directory where the snap
+// is mounted unless they start with $SNAP_DATA or $SNAP_COMMON.
+type ContentSharingInterface struct{}
+
+// String returns the same value as Name().
+func (iface *ContentSharingInterface) String() string {
+	return iface.Name()
+}
+
+// Name returns the name of the content interface.
+func (iface *ContentSharingInterface) Name() string {
+	return "content"
+}
+
+// SanitizeSlot checks and possibly modifies a slot.
+// Valid "content" slots must contain at least one "read" or "write" path.
+func (iface *ContentSharingInterface) SanitizeSlot(slot *interfaces.Slot) error {
+	if iface.Name() != slot.Interface {
+		panic(fmt.Sprintf("slot is not of interface %q", iface))
+	}
+	if slot.Attrs == nil {
+		slot.Attrs = make(map[string]interface{})
+	}
+	if err := defaultContentAttr(slot.Attrs, slot.Name); err != nil {
+		return err
+	}
+	return sanitizeContentPaths(slot)
+}
+
+// SanitizePlug checks and possibly modifies a plug.
+// Valid "content" plugs must contain the attribute "target".
+func (iface *ContentSharingInterface) SanitizePlug(plug *interfaces.Plug) error {
+	if iface.Name() != plug.Interface {
+		panic(fmt.Sprintf("plug is not of interface %q", iface))
+	}
+	if plug.Attrs == nil {
+		plug.Attrs = make(map[string]interface{})
+	}
+	if err := defaultContentAttr(plug.Attrs, plug.Name); err != nil {
+		return err
+	}
+	return sanitizeContentTarget(plug)
+}
+
+// SanitizeConnection checks the plug and slot of a connection again, as the
+// paths and the target may have been set when connecting.
+func (iface *ContentSharingInterface) SanitizeConnection(plug *interfaces.Plug, slot *interfaces.Slot) error {
+	if err := sanitizeContentPaths(slot); err != nil {
+		return err
+	}
+	if err := sanitizeContentTarget(plug); err != nil {
+		return err
+	}
+	_, err := contentMounts(plug, slot)
+	return err
+}
+
+// sanitizeContentPaths checks that the slot shares at least one directory
+// and that all of them are within the slot snap.
+func sanitizeContentPaths(slot *interfaces.Slot) error {
+	readPaths, err := contentPaths(slot, "read")
+	if err != nil {
+		return err
+	}
+	writePaths, err := contentPaths(slot, "write")
+	if err != nil {
+		return err
+	}
+	if len(readPaths) == 0 && len(writePaths) == 0 {
+		return fmt.Errorf("content slot must contain a read or write path")
+	}
+	for _, path := range append(readPaths, writePaths...) {
+		if !isCleanSubPath(path) {
+			return fmt.Errorf("content interface path is not clean: %q", path)
+		}
+	}
+	return nil
+}
+
+// sanitizeContentTarget checks that the plug names a target directory
+// within the plug snap.
+func sanitizeContentTarget(plug *interfaces.Plug) error {
+	value, _ := plug.Attr("target")
+	target, ok := value.(string)
+	if !ok || target == "" {
+		return fmt.Errorf("content plug must contain the target attribute")
+	}
+	if !isCleanSubPath(target) {
+		return fmt.Errorf("content interface target path is not clean: %q", target)
+	}
+	return nil
+}
+
+// defaultContentAttr sets the "content" attribute to the name of the plug
+// or slot unless given explicitly.
+func defaultContentAttr(attrs map[string]interface{}, name string) error {
+	if content, ok := attrs["content"]; ok {
+		if s, ok := content.(string); !ok || s == "" {
+			return fmt.Errorf("content attribute must be a non-empty string")
+		}
+		return nil
+	}
+	attrs["content"] = name
+	return nil
+}
+
+// contentPaths returns the list of paths stored under the given attribute of
+// the slot, which may have been set when connecting.
+func contentPaths(slot *interfaces.Slot, name string) ([]string, error) {
+	value, ok := slot.Attr(name)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("content interface %s attribute must be a list of paths", name)
+	}
+	paths := make([]string, len(list))
+	for i, item := range list {
+		path, ok := item.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("content interface %s attribute must be a list of paths", name)
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+// isCleanSubPath checks that path is relative and stays within the
+// directory it is relative to.
+func isCleanSubPath(path string) bool {
+	return filepath.Clean(path) == path && !filepath.IsAbs(path) && path != ".." && !strings.HasPrefix(path, "../")
+}
+
+// resolveContentPath resolves a content path relative to the given snap.
+func resolveContentPath(path string, info *snap.Info) string {
+	switch {
+	case path == "$SNAP_DATA" || strings.HasPrefix(path, "$SNAP_DATA/"):
+		return filepath.Join(info.DataDir(), strings.TrimPrefix(path, "$SNAP_DATA"))
+	case path == "$SNAP_COMMON" || strings.HasPrefix(path, "$SNAP_COMMON/"):
+		return filepath.Join(info.CommonDataDir(), strings.TrimPrefix(path, "$SNAP_COMMON"))
+	}
+	return filepath.Join(info.MountDir(), path)
+}
+
+// contentMount describes how a shared directory appears to the plug snap.
+type contentMount struct {
+	src, dst string
+	writable bool
+}
+
+// contentMounts returns the bind mounts giving the plug snap access to
+// the directories shared by the slot. Each directory is mounted on its
+// own subdirectory of the target, named after the shared directory, so
+// that they don't shadow each other.
+func contentMounts(plug *interfaces.Plug, slot *interfaces.Slot) ([]contentMount, error) {
+	readPaths, err := contentPaths(slot, "read")
+	if err != nil {
+		return nil, err
+	}
+	writePaths, err := contentPaths(slot, "write")
+	if err != nil {
+		return nil, err
+	}
+	value, _ := plug.Attr("target")
+	target, ok := value.(string)
+	if !ok {
+		panic("plug is not sanitized")
+	}
+	dst := resolveContentPath(target, plug.Snap)
+
+	var mounts []contentMount
+	seen := make(map[string]bool)
+	add := func(path string, writable bool) error {
+		src := resolveContentPath(path, slot.Snap)
+		name := filepath.Base(src)
+		if seen[name] {
+			return fmt.Errorf("content interface paths must have distinct names, %q is used more than once", name)
+		}
+		seen[name] = true
+		mounts = append(mounts, contentMount{src: src, dst: filepath.Join(dst, name), writable: writable})
+		return nil
+	}
+	for _, path := range readPaths {
+		if err := add(path, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range writePaths {
+		if err := add(path, true); err != nil {
+			return nil, err
+		}
+	}
+	return mounts, nil
+}
+
+// ConnectedPlugSnippet returns security snippet specific to a given connection between the content plug and some slot.
+// Applications associated with the plug get each slot directory bind mounted
+// on its own directory below the target, read-only for the read paths.
+func (iface *ContentSharingInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityMount:
+		mounts, err := contentMounts(plug, slot)
+		if err != nil {
+			return nil, err
+		}
+		entries := make([]string, len(mounts))
+		for i, m := range mounts {
+			options := "bind,ro"
+			if m.writable {
+				options = "bind"
+			}
+			entries[i] = fmt.Sprintf("%s %s none %s 0 0", m.src, m.dst, options)
+		}
+		return []byte(strings.Join(entries, "\n")), nil
+	case interfaces.SecurityAppArmor:
+		mounts, err := contentMounts(plug, slot)
+		if err != nil {
+			return nil, err
+		}
+		// The bind mounted content is seen below the target directory.
+		var buf bytes.Buffer
+		for _, m := range mounts {
+			if m.writable {
+				fmt.Fprintf(&buf, "%s/** mrwklix,\n", m.dst)
+			} else {
+				fmt.Fprintf(&buf, "%s/** mrkix,\n", m.dst)
+			}
+		}
+		return buf.Bytes(), nil
+	case interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentPlugSnippet returns the configuration snippet required to use a content interface.
+// Applications associated with the plug don't gain any extra permissions.
+func (iface *ContentSharingInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedSlotSnippet returns security snippet specific to a given connection between the content slot and some plug.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *ContentSharingInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentSlotSnippet returns security snippet permanently granted to content slots.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *ContentSharingInterface) PermanentSlotSnippet(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// AutoConnect returns true if plugs and slots should be implicitly
+// auto-connected when an unambiguous connection candidate is available.
+//
+// This interface does not auto-connect.
+func (iface *ContentSharingInterface) AutoConnect() bool {
+	return false
+}
@@ -0,0 +1,283 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+)
+
+type ContentSuite struct {
+	iface interfaces.Interface
+}
+
+var _ = Suite(&ContentSuite{
+	iface: &builtin.ContentSharingInterface{},
+})
+
+func (s *ContentSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "content")
+}
+
+func (s *ContentSuite) TestSanitizeSlotSimple(c *C) {
+	const mockSnapYaml = `name: content-slot-snap
+version: 1.0
+slots:
+ content-slot:
+  interface: content
+  read:
+   - shared/read
+`
+	info, err := snap.InfoFromSnapYaml([]byte(mockSnapYaml))
+	c.Assert(err, IsNil)
+	slot := &interfaces.Slot{SlotInfo: info.Slots["content-slot"]}
+	err = s.iface.SanitizeSlot(slot)
+	c.Assert(err, IsNil)
+	// content defaults to the name of the slot
+	c.Check(slot.Attrs["content"], Equals, "content-slot")
+}
+
+func (s *ContentSuite) TestSanitizeSlotNoPaths(c *C) {
+	const mockSnapYaml = `name: content-slot-snap
+version: 1.0
+slots:
+ content-slot:
+  interface: content
+  content: mycont
+`
+	info, err := snap.InfoFromSnapYaml([]byte(mockSnapYaml))
+	c.Assert(err, IsNil)
+	slot := &interfaces.Slot{SlotInfo: info.Slots["content-slot"]}
+	err = s.iface.SanitizeSlot(slot)
+	c.Assert(err, ErrorMatches, "content slot must contain a read or write path")
+}
+
+func (s *ContentSuite) TestSanitizeSlotUncleanPath(c *C) {
+	const mockSnapYaml = `name: content-slot-snap
+version: 1.0
+slots:
+ content-slot:
+  interface: content
+  write:
+   - ../../etc
+`
+	info, err := snap.InfoFromSnapYaml([]byte(mockSnapYaml))
+	c.Assert(err, IsNil)
+	slot := &interfaces.Slot{SlotInfo: info.Slots["content-slot"]}
+	err = s.iface.SanitizeSlot(slot)
+	c.Assert(err, ErrorMatches, `content interface path is not clean: "../../etc"`)
+}
+
+func (s *ContentSuite) TestSanitizePlugSimple(c *C) {
+	const mockSnapYaml = `name: content-plug-snap
+version: 1.0
+plugs:
+ content-plug:
+  interface: content
+  content: mycont
+  target: import
+`
+	info, err := snap.InfoFromSnapYaml([]byte(mockSnapYaml))
+	c.Assert(err, IsNil)
+	plug := &interfaces.Plug{PlugInfo: info.Plugs["content-plug"]}
+	err = s.iface.SanitizePlug(plug)
+	c.Assert(err, IsNil)
+	c.Check(plug.Attrs["content"], Equals, "mycont")
+}
+
+func (s *ContentSuite) TestSanitizePlugNoTarget(c *C) {
+	const mockSnapYaml = `name: content-plug-snap
+version: 1.0
+plugs:
+ content-plug:
+  interface: content
+`
+	info, err := snap.InfoFromSnapYaml([]byte(mockSnapYaml))
+	c.Assert(err, IsNil)
+	plug := &interfaces.Plug{PlugInfo: info.Plugs["content-plug"]}
+	err = s.iface.SanitizePlug(plug)
+	c.Assert(err, ErrorMatches, "content plug must contain the target attribute")
+}
+
+func (s *ContentSuite) TestConnectedPlugSnippets(c *C) {
+	const plugSnapYaml = `name: consumer
+version: 1.0
+plugs:
+ content:
+  target: import
+`
+	const slotSnapYaml = `name: producer
+version: 1.0
+slots:
+ content:
+  read:
+   - export
+  write:
+   - $SNAP_DATA/state
+`
+	dirs.SetRootDir("/")
+	plugInfo, err := snap.InfoFromSnapYaml([]byte(plugSnapYaml))
+	c.Assert(err, IsNil)
+	plugInfo.Revision = snap.R(1)
+	slotInfo, err := snap.InfoFromSnapYaml([]byte(slotSnapYaml))
+	c.Assert(err, IsNil)
+	slotInfo.Revision = snap.R(5)
+	plug := &interfaces.Plug{PlugInfo: plugInfo.Plugs["content"]}
+	slot := &interfaces.Slot{SlotInfo: slotInfo.Slots["content"]}
+	c.Assert(s.iface.SanitizePlug(plug), IsNil)
+	c.Assert(s.iface.SanitizeSlot(slot), IsNil)
+
+	snippet, err := s.iface.ConnectedPlugSnippet(plug, slot, interfaces.SecurityMount)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, ""+
+		"/snap/producer/5/export /snap/consumer/1/import/export none bind,ro 0 0\n"+
+		"/var/snap/producer/5/state /snap/consumer/1/import/state none bind 0 0")
+
+	snippet, err = s.iface.ConnectedPlugSnippet(plug, slot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, ""+
+		"/snap/consumer/1/import/export/** mrkix,\n"+
+		"/snap/consumer/1/import/state/** mrwklix,\n")
+
+	snippet, err = s.iface.ConnectedPlugSnippet(plug, slot, interfaces.SecuritySecComp)
+	c.Assert(err, IsNil)
+	c.Check(snippet, IsNil)
+
+	_, err = s.iface.ConnectedPlugSnippet(plug, slot, "foo")
+	c.Check(err, Equals, interfaces.ErrUnknownSecurity)
+}
+
+func (s *ContentSuite) TestConnectedPlugSnippetDuplicateNames(c *C) {
+	const plugSnapYaml = `name: consumer
+version: 1.0
+plugs:
+ content:
+  target: import
+`
+	const slotSnapYaml = `name: producer
+version: 1.0
+slots:
+ content:
+  read:
+   - export
+  write:
+   - $SNAP_DATA/export
+`
+	plugInfo, err := snap.InfoFromSnapYaml([]byte(plugSnapYaml))
+	c.Assert(err, IsNil)
+	slotInfo, err := snap.InfoFromSnapYaml([]byte(slotSnapYaml))
+	c.Assert(err, IsNil)
+	plug := &interfaces.Plug{PlugInfo: plugInfo.Plugs["content"]}
+	slot := &interfaces.Slot{SlotInfo: slotInfo.Slots["content"]}
+	c.Assert(s.iface.SanitizePlug(plug), IsNil)
+	c.Assert(s.iface.SanitizeSlot(slot), IsNil)
+
+	for _, system := range []interfaces.SecuritySystem{interfaces.SecurityMount, interfaces.SecurityAppArmor} {
+		_, err = s.iface.ConnectedPlugSnippet(plug, slot, system)
+		c.Check(err, ErrorMatches, `content interface paths must have distinct names, "export" is used more than once`)
+	}
+}
+
+func (s *ContentSuite) TestConnectedPlugSnippetUsesDynamicSlotAttrs(c *C) {
+	const plugSnapYaml = `name: consumer
+version: 1.0
+plugs:
+ content:
+  target: import
+`
+	const slotSnapYaml = `name: producer
+version: 1.0
+slots:
+ content:
+  read:
+   - export
+`
+	dirs.SetRootDir("/")
+	plugInfo, err := snap.InfoFromSnapYaml([]byte(plugSnapYaml))
+	c.Assert(err, IsNil)
+	plugInfo.Revision = snap.R(1)
+	slotInfo, err := snap.InfoFromSnapYaml([]byte(slotSnapYaml))
+	c.Assert(err, IsNil)
+	slotInfo.Revision = snap.R(5)
+	plug := &interfaces.Plug{PlugInfo: plugInfo.Plugs["content"]}
+	slot := &interfaces.Slot{SlotInfo: slotInfo.Slots["content"]}
+	c.Assert(s.iface.SanitizePlug(plug), IsNil)
+	c.Assert(s.iface.SanitizeSlot(slot), IsNil)
+
+	// The producer shares a writable directory chosen when connecting.
+	slot.DynamicAttrs = map[string]interface{}{
+		"write": []interface{}{"$SNAP_COMMON/session"},
+	}
+	snippet, err := s.iface.ConnectedPlugSnippet(plug, slot, interfaces.SecurityMount)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, ""+
+		"/snap/producer/5/export /snap/consumer/1/import/export none bind,ro 0 0\n"+
+		"/var/snap/producer/common/session /snap/consumer/1/import/session none bind 0 0")
+}
+
+func (s *ContentSuite) TestSanitizeConnection(c *C) {
+	const plugSnapYaml = `name: consumer
+version: 1.0
+plugs:
+ content:
+  target: import
+`
+	const slotSnapYaml = `name: producer
+version: 1.0
+slots:
+ content:
+  read:
+   - export
+`
+	plugInfo, err := snap.InfoFromSnapYaml([]byte(plugSnapYaml))
+	c.Assert(err, IsNil)
+	slotInfo, err := snap.InfoFromSnapYaml([]byte(slotSnapYaml))
+	c.Assert(err, IsNil)
+	plug := &interfaces.Plug{PlugInfo: plugInfo.Plugs["content"]}
+	slot := &interfaces.Slot{SlotInfo: slotInfo.Slots["content"]}
+	c.Assert(s.iface.SanitizePlug(plug), IsNil)
+	c.Assert(s.iface.SanitizeSlot(slot), IsNil)
+	sanitizer, ok := s.iface.(interfaces.ConnectionSanitizer)
+	c.Assert(ok, Equals, true)
+
+	slot.DynamicAttrs = map[string]interface{}{"write": []interface{}{"$SNAP_DATA/session"}}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), IsNil)
+
+	slot.DynamicAttrs = map[string]interface{}{"write": []interface{}{"../../.."}}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), ErrorMatches, `content interface path is not clean: "../../.."`)
+	slot.DynamicAttrs = map[string]interface{}{"write": "session"}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), ErrorMatches, `content interface write attribute must be a list of paths`)
+	slot.DynamicAttrs = map[string]interface{}{"write": []interface{}{"$SNAP_DATA/export"}}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), ErrorMatches, `content interface paths must have distinct names, "export" is used more than once`)
+	slot.DynamicAttrs = nil
+
+	plug.DynamicAttrs = map[string]interface{}{"target": "/etc"}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), ErrorMatches, `content interface target path is not clean: "/etc"`)
+	plug.DynamicAttrs = map[string]interface{}{"target": ""}
+	c.Check(sanitizer.SanitizeConnection(plug, slot), ErrorMatches, `content plug must contain the target attribute`)
+}
+
+func (s *ContentSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// deviceNodeInterface is the type of the interfaces granting access to the
+// device node named by the path attribute of their slots, such as a serial
+// port. The slots are reserved for the gadget and core snaps.
+type deviceNodeInterface struct {
+	name                string
+	description         string
+	allowedPathPatterns []*regexp.Regexp
+}
+
+// String returns the same value as Name().
+func (iface *deviceNodeInterface) String() string {
+	return iface.Name()
+}
+
+// Name returns the name of the interface.
+func (iface *deviceNodeInterface) Name() string {
+	return iface.name
+}
+
+// SanitizeSlot checks and possibly modifies a slot.
+// Valid slots must contain the attribute "path" naming an allowed device node.
+func (iface *deviceNodeInterface) SanitizeSlot(slot *interfaces.Slot) error {
+	if iface.Name() != slot.Interface {
+		panic(fmt.Sprintf("slot is not of interface %q", iface))
+	}
+	if slot.Snap.Type != snap.TypeGadget && slot.Snap.Type != snap.TypeOS {
+		return fmt.Errorf("%s slots are reserved for the gadget and core snaps", iface.name)
+	}
+	path, ok := slot.Attrs["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("%s slot must contain the path attribute", iface.name)
+	}
+	path = filepath.Clean(path)
+	for _, pattern := range iface.allowedPathPatterns {
+		if pattern.MatchString(path) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s path attribute must point at a %s device node", iface.name, iface.description)
+}
+
+// SanitizePlug checks and possibly modifies a plug.
+func (iface *deviceNodeInterface) SanitizePlug(plug *interfaces.Plug) error {
+	if iface.Name() != plug.Interface {
+		panic(fmt.Sprintf("plug is not of interface %q", iface))
+	}
+	// NOTE: currently we don't check anything on the plug side.
+	return nil
+}
+
+// ConnectedSlotSnippet returns security snippet specific to a given connection between the slot and some plug.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *deviceNodeInterface) ConnectedSlotSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentSlotSnippet returns security snippet permanently granted to the slots.
+// Applications associated with the slot don't gain any extra permissions.
+func (iface *deviceNodeInterface) PermanentSlotSnippet(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// ConnectedPlugSnippet returns security snippet specific to a given connection between the plug and some slot.
+// Applications associated with the plug gain permission to read and write
+// the device node, which udev tags for them.
+func (iface *deviceNodeInterface) ConnectedPlugSnippet(plug *interfaces.Plug, slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	path, ok := slot.Attrs["path"].(string)
+	if !ok {
+		panic("slot is not sanitized")
+	}
+	path = filepath.Clean(path)
+	switch securitySystem {
+	case interfaces.SecurityAppArmor:
+		return []byte(fmt.Sprintf("%s rw,\n", path)), nil
+	case interfaces.SecurityUDev:
+		appNames := make([]string, 0, len(plug.Apps))
+		for appName := range plug.Apps {
+			appNames = append(appNames, appName)
+		}
+		sort.Strings(appNames)
+		var buf bytes.Buffer
+		for _, appName := range appNames {
+			fmt.Fprintf(&buf, "KERNEL==\"%s\", TAG+=\"%s\"\n", filepath.Base(path), udevSnapSecurityName(plug.Snap.Name(), appName))
+		}
+		return buf.Bytes(), nil
+	case interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// PermanentPlugSnippet returns the configuration snippet required to use the interface.
+// Applications associated with the plug don't gain any extra permissions.
+func (iface *deviceNodeInterface) PermanentPlugSnippet(plug *interfaces.Plug, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+	switch securitySystem {
+	case interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount:
+		return nil, nil
+	default:
+		return nil, interfaces.ErrUnknownSecurity
+	}
+}
+
+// AutoConnect returns true if plugs and slots should be implicitly
+// auto-connected when an unambiguous connection candidate is available.
+//
+// This interface does not auto-connect.
+func (iface *deviceNodeInterface) AutoConnect() bool {
+	return false
+}
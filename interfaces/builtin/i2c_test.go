@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+)
+
+type I2CInterfaceSuite struct {
+	iface       interfaces.Interface
+	slot        *interfaces.Slot
+	badPathSlot *interfaces.Slot
+	plug        *interfaces.Plug
+}
+
+var _ = Suite(&I2CInterfaceSuite{
+	iface: builtin.NewI2CInterface(),
+})
+
+func (s *I2CInterfaceSuite) SetUpTest(c *C) {
+	gadget, err := snap.InfoFromSnapYaml([]byte(`
+name: board
+type: gadget
+slots:
+    dev:
+        interface: i2c
+        path: /dev/i2c-1
+    bad-path:
+        interface: i2c
+        path: /dev/i2c-x
+`))
+	c.Assert(err, IsNil)
+	s.slot = &interfaces.Slot{SlotInfo: gadget.Slots["dev"]}
+	s.badPathSlot = &interfaces.Slot{SlotInfo: gadget.Slots["bad-path"]}
+
+	app, err := snap.InfoFromSnapYaml([]byte(`
+name: client
+plugs:
+    plug: i2c
+apps:
+    app:
+        command: foo
+`))
+	c.Assert(err, IsNil)
+	s.plug = &interfaces.Plug{PlugInfo: app.Plugs["plug"]}
+}
+
+func (s *I2CInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "i2c")
+}
+
+func (s *I2CInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(s.iface.SanitizeSlot(s.slot), IsNil)
+	c.Assert(s.iface.SanitizeSlot(s.badPathSlot), ErrorMatches,
+		"i2c path attribute must point at a I2C bus device node")
+}
+
+func (s *I2CInterfaceSuite) TestConnectedPlugSnippet(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.slot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, "/dev/i2c-1 rw,\n")
+
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.slot, interfaces.SecurityUDev)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, `KERNEL=="i2c-1", TAG+="snap_client_app"`+"\n")
+}
+
+func (s *I2CInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
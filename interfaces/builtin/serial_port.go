@@ -0,0 +1,76 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+)
+
+var serialPortAllowedPathPatterns = []*regexp.Regexp{
+	// Standard, USB, ACM, ARM AMBA and i.MX serial ports
+	regexp.MustCompile("^/dev/tty(S|USB|ACM|AMA|mxc)[0-9]+$"),
+}
+
+// serialPortInterface is the type of the serial-port interface. Besides
+// the slots of gadget snaps, it defines slots on the core snap for the USB
+// serial adapters plugged in at runtime.
+type serialPortInterface struct {
+	deviceNodeInterface
+}
+
+// NewSerialPortInterface returns a new "serial-port" interface.
+func NewSerialPortInterface() interfaces.Interface {
+	return &serialPortInterface{deviceNodeInterface{
+		name:                "serial-port",
+		description:         "serial port",
+		allowedPathPatterns: serialPortAllowedPathPatterns,
+	}}
+}
+
+var slotNameUnsafeChars = regexp.MustCompile("[^a-z0-9]+")
+
+// HotplugDeviceDetected returns the slot for a USB serial adapter. The
+// slot is named after the model of the adapter.
+func (iface *serialPortInterface) HotplugDeviceDetected(dev *hotplug.DeviceInfo) (*hotplug.SlotSpec, error) {
+	if dev.Subsystem != "tty" || dev.Properties["ID_BUS"] != "usb" {
+		return nil, nil
+	}
+	path := dev.DeviceName()
+	matches := false
+	for _, pattern := range iface.allowedPathPatterns {
+		matches = matches || pattern.MatchString(path)
+	}
+	if !matches {
+		return nil, nil
+	}
+	name := iface.name
+	model := strings.Trim(slotNameUnsafeChars.ReplaceAllString(strings.ToLower(dev.Properties["ID_MODEL"]), "-"), "-")
+	if model != "" {
+		name += "-" + model
+	}
+	return &hotplug.SlotSpec{
+		Name:  name,
+		Attrs: map[string]interface{}{"path": path},
+	}, nil
+}
@@ -0,0 +1,197 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/snap"
+)
+
+type SerialPortInterfaceSuite struct {
+	iface            interfaces.Interface
+	usbSlot          *interfaces.Slot
+	amaSlot          *interfaces.Slot
+	missingPathSlot  *interfaces.Slot
+	badPathSlot      *interfaces.Slot
+	appSlot          *interfaces.Slot
+	badInterfaceSlot *interfaces.Slot
+	plug             *interfaces.Plug
+	badInterfacePlug *interfaces.Plug
+}
+
+var _ = Suite(&SerialPortInterfaceSuite{
+	iface: builtin.NewSerialPortInterface(),
+})
+
+func (s *SerialPortInterfaceSuite) SetUpTest(c *C) {
+	gadget, err := snap.InfoFromSnapYaml([]byte(`
+name: board
+type: gadget
+slots:
+    usb:
+        interface: serial-port
+        path: /dev/ttyUSB0
+    ama:
+        interface: serial-port
+        path: /dev/ttyAMA0
+    missing-path: serial-port
+    bad-path:
+        interface: serial-port
+        path: /dev/sda1
+    bad-interface: other-interface
+`))
+	c.Assert(err, IsNil)
+	s.usbSlot = &interfaces.Slot{SlotInfo: gadget.Slots["usb"]}
+	s.amaSlot = &interfaces.Slot{SlotInfo: gadget.Slots["ama"]}
+	s.missingPathSlot = &interfaces.Slot{SlotInfo: gadget.Slots["missing-path"]}
+	s.badPathSlot = &interfaces.Slot{SlotInfo: gadget.Slots["bad-path"]}
+	s.badInterfaceSlot = &interfaces.Slot{SlotInfo: gadget.Slots["bad-interface"]}
+
+	app, err := snap.InfoFromSnapYaml([]byte(`
+name: client
+slots:
+    serial:
+        interface: serial-port
+        path: /dev/ttyS0
+plugs:
+    plug: serial-port
+    bad-interface: other-interface
+apps:
+    app-a:
+        command: foo
+    app-b:
+        command: bar
+`))
+	c.Assert(err, IsNil)
+	s.appSlot = &interfaces.Slot{SlotInfo: app.Slots["serial"]}
+	s.plug = &interfaces.Plug{PlugInfo: app.Plugs["plug"]}
+	s.badInterfacePlug = &interfaces.Plug{PlugInfo: app.Plugs["bad-interface"]}
+}
+
+func (s *SerialPortInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "serial-port")
+}
+
+func (s *SerialPortInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(s.iface.SanitizeSlot(s.usbSlot), IsNil)
+	c.Assert(s.iface.SanitizeSlot(s.amaSlot), IsNil)
+	c.Assert(s.iface.SanitizeSlot(s.missingPathSlot), ErrorMatches,
+		"serial-port slot must contain the path attribute")
+	c.Assert(s.iface.SanitizeSlot(s.badPathSlot), ErrorMatches,
+		"serial-port path attribute must point at a serial port device node")
+	c.Assert(s.iface.SanitizeSlot(s.appSlot), ErrorMatches,
+		"serial-port slots are reserved for the gadget and core snaps")
+	c.Assert(func() { s.iface.SanitizeSlot(s.badInterfaceSlot) }, PanicMatches,
+		`slot is not of interface "serial-port"`)
+}
+
+func (s *SerialPortInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(s.iface.SanitizePlug(s.plug), IsNil)
+	c.Assert(func() { s.iface.SanitizePlug(s.badInterfacePlug) }, PanicMatches,
+		`plug is not of interface "serial-port"`)
+}
+
+func (s *SerialPortInterfaceSuite) TestConnectedPlugSnippet(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.usbSlot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, "/dev/ttyUSB0 rw,\n")
+
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.usbSlot, interfaces.SecurityUDev)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, ""+
+		`KERNEL=="ttyUSB0", TAG+="snap_client_app-a"`+"\n"+
+		`KERNEL=="ttyUSB0", TAG+="snap_client_app-b"`+"\n")
+
+	for _, system := range []interfaces.SecuritySystem{interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityMount} {
+		snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.usbSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.usbSlot, "foo")
+	c.Assert(err, ErrorMatches, `unknown security system`)
+	c.Assert(snippet, IsNil)
+}
+
+func (s *SerialPortInterfaceSuite) TestConnectedPlugSnippetPanicsOnUnsanitizedSlots(c *C) {
+	c.Assert(func() {
+		s.iface.ConnectedPlugSnippet(s.plug, s.missingPathSlot, interfaces.SecurityAppArmor)
+	}, PanicMatches, "slot is not sanitized")
+}
+
+func (s *SerialPortInterfaceSuite) TestUnusedSnippets(c *C) {
+	for _, system := range []interfaces.SecuritySystem{interfaces.SecurityAppArmor, interfaces.SecuritySecComp, interfaces.SecurityDBus, interfaces.SecurityUDev, interfaces.SecurityMount} {
+		snippet, err := s.iface.PermanentPlugSnippet(s.plug, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.PermanentSlotSnippet(s.usbSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+		snippet, err = s.iface.ConnectedSlotSnippet(s.plug, s.usbSlot, system)
+		c.Assert(err, IsNil)
+		c.Assert(snippet, IsNil)
+	}
+}
+
+func (s *SerialPortInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
+
+func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	definer, ok := s.iface.(hotplug.Definer)
+	c.Assert(ok, Equals, true)
+
+	dev := &hotplug.DeviceInfo{
+		Action:    "add",
+		DevPath:   "/devices/pci0000:00/0000:00:14.0/usb3/3-4/3-4:1.0/ttyUSB0/tty/ttyUSB0",
+		Subsystem: "tty",
+		Properties: map[string]string{
+			"DEVNAME":  "/dev/ttyUSB0",
+			"ID_BUS":   "usb",
+			"ID_MODEL": "FT232R_USB_UART",
+		},
+	}
+	spec, err := definer.HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Check(spec, DeepEquals, &hotplug.SlotSpec{
+		Name:  "serial-port-ft232r-usb-uart",
+		Attrs: map[string]interface{}{"path": "/dev/ttyUSB0"},
+	})
+
+	delete(dev.Properties, "ID_MODEL")
+	spec, err = definer.HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Check(spec.Name, Equals, "serial-port")
+
+	// devices not on USB are left to the gadget snap
+	dev.Properties["ID_BUS"] = "pci"
+	spec, err = definer.HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Check(spec, IsNil)
+
+	dev.Properties["ID_BUS"] = "usb"
+	dev.Subsystem = "block"
+	spec, err = definer.HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Check(spec, IsNil)
+}
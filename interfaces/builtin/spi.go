@@ -0,0 +1,39 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"regexp"
+
+	"github.com/snapcore/snapd/interfaces"
+)
+
+var spiAllowedPathPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^/dev/spidev[0-9]+\.[0-9]+$`),
+}
+
+// NewSpiInterface returns a new "spi" interface.
+func NewSpiInterface() interfaces.Interface {
+	return &deviceNodeInterface{
+		name:                "spi",
+		description:         "SPI device",
+		allowedPathPatterns: spiAllowedPathPatterns,
+	}
+}
@@ -0,0 +1,91 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+)
+
+type SPIInterfaceSuite struct {
+	iface       interfaces.Interface
+	slot        *interfaces.Slot
+	badPathSlot *interfaces.Slot
+	plug        *interfaces.Plug
+}
+
+var _ = Suite(&SPIInterfaceSuite{
+	iface: builtin.NewSpiInterface(),
+})
+
+func (s *SPIInterfaceSuite) SetUpTest(c *C) {
+	gadget, err := snap.InfoFromSnapYaml([]byte(`
+name: board
+type: gadget
+slots:
+    dev:
+        interface: spi
+        path: /dev/spidev0.1
+    bad-path:
+        interface: spi
+        path: /dev/spidev0
+`))
+	c.Assert(err, IsNil)
+	s.slot = &interfaces.Slot{SlotInfo: gadget.Slots["dev"]}
+	s.badPathSlot = &interfaces.Slot{SlotInfo: gadget.Slots["bad-path"]}
+
+	app, err := snap.InfoFromSnapYaml([]byte(`
+name: client
+plugs:
+    plug: spi
+apps:
+    app:
+        command: foo
+`))
+	c.Assert(err, IsNil)
+	s.plug = &interfaces.Plug{PlugInfo: app.Plugs["plug"]}
+}
+
+func (s *SPIInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "spi")
+}
+
+func (s *SPIInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(s.iface.SanitizeSlot(s.slot), IsNil)
+	c.Assert(s.iface.SanitizeSlot(s.badPathSlot), ErrorMatches,
+		"spi path attribute must point at a SPI device device node")
+}
+
+func (s *SPIInterfaceSuite) TestConnectedPlugSnippet(c *C) {
+	snippet, err := s.iface.ConnectedPlugSnippet(s.plug, s.slot, interfaces.SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, "/dev/spidev0.1 rw,\n")
+
+	snippet, err = s.iface.ConnectedPlugSnippet(s.plug, s.slot, interfaces.SecurityUDev)
+	c.Assert(err, IsNil)
+	c.Check(string(snippet), Equals, `KERNEL=="spidev0.1", TAG+="snap_client_app"`+"\n")
+}
+
+func (s *SPIInterfaceSuite) TestAutoConnect(c *C) {
+	c.Check(s.iface.AutoConnect(), Equals, false)
+}
@@ -69,3 +69,9 @@ func slotAppLabelExpr(slot *interfaces.Slot) []byte {
 func plugAppLabelExpr(plug *interfaces.Plug) []byte {
 	return appLabelExpr(plug.Apps, plug.Snap)
 }
+
+// udevSnapSecurityName returns the udev tag of the devices the given app
+// is allowed to access.
+func udevSnapSecurityName(snapName string, appName string) string {
+	return fmt.Sprintf("snap_%s_%s", snapName, appName)
+}
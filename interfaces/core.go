@@ -31,6 +31,20 @@ import (
 type Plug struct {
 	*snap.PlugInfo
 	Connections []SlotRef `json:"connections,omitempty"`
+	// DynamicAttrs holds the attributes set when the plug was connected to a
+	// specific slot. It is only set on the plugs given to ConnectedPlugSnippet
+	// and ConnectedSlotSnippet.
+	DynamicAttrs map[string]interface{} `json:"-"`
+}
+
+// Attr returns the value of an attribute of the plug. Attributes set when the
+// plug was connected are looked up before the ones from snap.yaml.
+func (plug *Plug) Attr(key string) (interface{}, bool) {
+	if value, ok := plug.DynamicAttrs[key]; ok {
+		return value, true
+	}
+	value, ok := plug.Attrs[key]
+	return value, ok
 }
 
 // PlugRef is a reference to a plug.
@@ -43,6 +57,20 @@ type PlugRef struct {
 type Slot struct {
 	*snap.SlotInfo
 	Connections []PlugRef `json:"connections,omitempty"`
+	// DynamicAttrs holds the attributes set when the slot was connected to a
+	// specific plug. It is only set on the slots given to ConnectedPlugSnippet
+	// and ConnectedSlotSnippet.
+	DynamicAttrs map[string]interface{} `json:"-"`
+}
+
+// Attr returns the value of an attribute of the slot. Attributes set when the
+// slot was connected are looked up before the ones from snap.yaml.
+func (slot *Slot) Attr(key string) (interface{}, bool) {
+	if value, ok := slot.DynamicAttrs[key]; ok {
+		return value, true
+	}
+	value, ok := slot.Attrs[key]
+	return value, ok
 }
 
 // SlotRef is a reference to a slot.
@@ -51,10 +79,23 @@ type SlotRef struct {
 	Name string `json:"slot"`
 }
 
+// Connection describes a connection between a plug and a slot along with the
+// attributes set by either side when it was made.
+type Connection struct {
+	Plug      PlugRef                `json:"plug"`
+	Slot      SlotRef                `json:"slot"`
+	PlugAttrs map[string]interface{} `json:"plug-attrs,omitempty"`
+	SlotAttrs map[string]interface{} `json:"slot-attrs,omitempty"`
+	// Auto tells whether the connection was made automatically rather than
+	// on request. It is not known to the repository and left unset by it.
+	Auto bool `json:"auto,omitempty"`
+}
+
 // Interfaces holds information about a list of plugs and slots, and their connections.
 type Interfaces struct {
-	Plugs []*Plug `json:"plugs"`
-	Slots []*Slot `json:"slots"`
+	Plugs       []*Plug       `json:"plugs"`
+	Slots       []*Slot       `json:"slots"`
+	Connections []*Connection `json:"connections,omitempty"`
 }
 
 // Interface describes a group of interchangeable capabilities with common features.
@@ -140,6 +181,15 @@ type Interface interface {
 	AutoConnect() bool
 }
 
+// ConnectionSanitizer is implemented by interfaces that check the attributes
+// set when connecting a plug and a slot.
+type ConnectionSanitizer interface {
+	// SanitizeConnection checks if the plug and slot of a connection are
+	// correct, including the attributes set when connecting, which are
+	// available through their Attr methods.
+	SanitizeConnection(plug *Plug, slot *Slot) error
+}
+
 // SecuritySystem is a name of a security system.
 type SecuritySystem string
 
@@ -152,6 +202,8 @@ const (
 	SecurityDBus SecuritySystem = "dbus"
 	// SecurityUDev identifies the UDev security system.
 	SecurityUDev SecuritySystem = "udev"
+	// SecurityMount identifies the mount security system.
+	SecurityMount SecuritySystem = "mount"
 )
 
 var (
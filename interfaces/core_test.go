@@ -25,6 +25,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	. "github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
 )
 
 func Test(t *testing.T) {
@@ -66,3 +67,31 @@ func (s *CoreSuite) TestValidateName(c *C) {
 		c.Assert(err, ErrorMatches, `invalid interface name: ".*"`)
 	}
 }
+
+func (s *CoreSuite) TestPlugAndSlotAttr(c *C) {
+	plug := &Plug{
+		PlugInfo:     &snap.PlugInfo{Attrs: map[string]interface{}{"static": "plug"}},
+		DynamicAttrs: map[string]interface{}{"dynamic": "plug"},
+	}
+	slot := &Slot{
+		SlotInfo:     &snap.SlotInfo{Attrs: map[string]interface{}{"static": "slot"}},
+		DynamicAttrs: map[string]interface{}{"dynamic": "slot"},
+	}
+	for _, t := range []struct {
+		attr  func(string) (interface{}, bool)
+		key   string
+		value interface{}
+		found bool
+	}{
+		{plug.Attr, "static", "plug", true},
+		{plug.Attr, "dynamic", "plug", true},
+		{plug.Attr, "missing", nil, false},
+		{slot.Attr, "static", "slot", true},
+		{slot.Attr, "dynamic", "slot", true},
+		{slot.Attr, "missing", nil, false},
+	} {
+		value, found := t.attr(t.key)
+		c.Check(value, Equals, t.value)
+		c.Check(found, Equals, t.found)
+	}
+}
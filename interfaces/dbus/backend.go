@@ -38,7 +38,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
-// Backend is responsible for maintaining DBus policy files.
+// Backend is responsible for maintaining DBus configuration files.
 type Backend struct{}
 
 // Name returns the name of the backend.
@@ -85,6 +85,33 @@ func (b *Backend) Remove(snapName string) error {
 	return nil
 }
 
+// Fingerprint returns the envelope of the DBus configuration files.
+func (b *Backend) Fingerprint() []byte {
+	return append(append([]byte(nil), xmlHeader...), xmlFooter...)
+}
+
+// Profiles returns the DBus configuration files of the applications of a given snap,
+// with each snippet annotated with its origin. Applications without snippets
+// have none.
+func (b *Backend) Profiles(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(snapName, interfaces.SecurityDBus)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain DBus security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, interfaces.AnnotateSnippets(snippets, "<!-- ", " -->"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected DBus configuration files for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string][]byte, len(content))
+	for _, appInfo := range snapInfo.Apps {
+		if fileState, ok := content[fmt.Sprintf("%s.conf", appInfo.SecurityTag())]; ok {
+			profiles[appInfo.Name] = fileState.Content
+		}
+	}
+	return profiles, nil
+}
+
 // combineSnippets combines security snippets collected from all the interfaces
 // affecting a given snap into a content map applicable to EnsureDirState.
 func (b *Backend) combineSnippets(snapInfo *snap.Info, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
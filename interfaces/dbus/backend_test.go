@@ -212,6 +212,32 @@ func (s *backendSuite) TestAppBoundIfaces(c *C) {
 	c.Check(err, IsNil)
 }
 
+func (s *backendSuite) TestFingerprint(c *C) {
+	restore := dbus.MockXMLEnvelope([]byte("<?xml>\n"), []byte("</xml>"))
+	defer restore()
+	var fingerprinter interfaces.Fingerprinter = s.backend
+	c.Check(string(fingerprinter.Fingerprint()), Equals, "<?xml>\n</xml>")
+}
+
+func (s *backendSuite) TestProfiles(c *C) {
+	restore := dbus.MockXMLEnvelope([]byte("<?xml>\n"), []byte("</xml>"))
+	defer restore()
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("<policy>...</policy>"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYamlV1))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	var profiler interfaces.SecurityProfiler = s.backend
+	profiles, err := profiler.Profiles(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{
+		"smbd": []byte("<?xml>\n<!-- slot samba:iface (interface iface) -->\n<policy>...</policy>\n</xml>"),
+	})
+	_, err = os.Stat(filepath.Join(dirs.SnapBusPolicyDir, "snap.samba.smbd.conf"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
 // Support code for tests
 
 // installSnap "installs" a snap from YAML.
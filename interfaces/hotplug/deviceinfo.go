@@ -0,0 +1,86 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package hotplug implements the discovery of devices appearing and
+// disappearing at runtime, so that interfaces can expose them as slots.
+package hotplug
+
+import (
+	"strings"
+)
+
+// DeviceInfo describes a device as announced by udev.
+type DeviceInfo struct {
+	// Action is the udev action, e.g. "add" or "remove".
+	Action string
+	// DevPath is the path of the device in sysfs, without the /sys prefix.
+	DevPath string
+	// Subsystem is the kernel subsystem of the device, e.g. "tty".
+	Subsystem string
+	// Properties holds all the udev properties of the device.
+	Properties map[string]string
+}
+
+// Property returns the value of the given udev property of the device.
+func (dev *DeviceInfo) Property(name string) (string, bool) {
+	value, ok := dev.Properties[name]
+	return value, ok
+}
+
+// DeviceName returns the path of the device node, e.g. /dev/ttyUSB0, or
+// the empty string if the device has none.
+func (dev *DeviceInfo) DeviceName() string {
+	name := dev.Properties["DEVNAME"]
+	if name != "" && !strings.HasPrefix(name, "/") {
+		// kernel events carry the name relative to /dev
+		name = "/dev/" + name
+	}
+	return name
+}
+
+// Key returns a string identifying the device that stays the same when
+// the device is unplugged and plugged again, possibly into another port.
+// Devices without a serial number are identified by their sysfs path.
+func (dev *DeviceInfo) Key() string {
+	serial := dev.Properties["ID_SERIAL"]
+	if serial == "" {
+		return dev.DevPath
+	}
+	if ifaceNum := dev.Properties["ID_USB_INTERFACE_NUM"]; ifaceNum != "" {
+		return serial + "/" + ifaceNum
+	}
+	return serial
+}
+
+// SlotSpec describes the slot to create for a hotplugged device.
+type SlotSpec struct {
+	// Name is the preferred name of the slot. A numeric suffix is added
+	// to it when another slot has the same name already.
+	Name string
+	// Attrs are the attributes of the slot.
+	Attrs map[string]interface{}
+}
+
+// Definer is implemented by interfaces able to expose hotplugged devices
+// as slots of the core snap.
+type Definer interface {
+	// HotplugDeviceDetected returns the slot to create for the given
+	// device, or nil if the interface does not handle the device.
+	HotplugDeviceDetected(dev *DeviceInfo) (*SlotSpec, error)
+}
@@ -0,0 +1,231 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// udevEventGroup is the netlink multicast group udevd sends its events
+// to, once it processed the kernel ones and updated its database.
+const udevEventGroup = 2
+
+// udevMonitorMagic is the magic number of the header of the messages
+// sent by udevd.
+const udevMonitorMagic = 0xfeedcafe
+
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 0 {
+		nativeEndian = binary.BigEndian
+	}
+}
+
+// UDevMonitor receives the device events sent by udevd over netlink.
+type UDevMonitor struct {
+	fd int
+}
+
+// NewUDevMonitor returns a monitor listening to the udev netlink events.
+func NewUDevMonitor() (*UDevMonitor, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create udev netlink socket: %v", err)
+	}
+	addr := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: udevEventGroup}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot bind udev netlink socket: %v", err)
+	}
+	// credentials tell events of udevd from forged ones
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_PASSCRED, 1); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot set up udev netlink socket: %v", err)
+	}
+	// wake up regularly to notice when to stop
+	tv := syscall.Timeval{Sec: 1}
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("cannot set up udev netlink socket: %v", err)
+	}
+	return &UDevMonitor{fd: fd}, nil
+}
+
+// Run calls handle for each device event received, until stop is closed.
+// The monitor cannot be used anymore afterwards.
+func (m *UDevMonitor) Run(handle func(dev *DeviceInfo), stop <-chan struct{}) error {
+	defer syscall.Close(m.fd)
+
+	buf := make([]byte, 16384)
+	oob := make([]byte, syscall.CmsgSpace(syscall.SizeofUcred))
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		n, oobn, _, _, err := syscall.Recvmsg(m.fd, buf, oob, 0)
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot receive udev event: %v", err)
+		}
+		if !sentByRoot(oob[:oobn]) {
+			continue
+		}
+		dev, err := ParseUEvent(buf[:n])
+		if err != nil {
+			logger.Noticef("cannot parse udev event: %v", err)
+			continue
+		}
+		handle(dev)
+	}
+}
+
+func sentByRoot(oob []byte) bool {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil || len(msgs) != 1 {
+		return false
+	}
+	cred, err := syscall.ParseUnixCredentials(&msgs[0])
+	return err == nil && cred.Uid == 0
+}
+
+// Enumerate returns the devices known to udev, so that the devices
+// plugged in before the monitor was started are not missed.
+func (m *UDevMonitor) Enumerate() ([]*DeviceInfo, error) {
+	output, err := exec.Command("udevadm", "info", "--export-db").Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot enumerate devices: %v", err)
+	}
+	return parseUDevDatabase(bytes.NewReader(output))
+}
+
+// ParseUEvent parses a device event as sent by udevd, or by the kernel.
+func ParseUEvent(msg []byte) (*DeviceInfo, error) {
+	var props []byte
+	if bytes.HasPrefix(msg, []byte("libudev\x00")) {
+		// the header is followed by the properties
+		if len(msg) < 24 {
+			return nil, fmt.Errorf("message too short")
+		}
+		if binary.BigEndian.Uint32(msg[8:12]) != udevMonitorMagic {
+			return nil, fmt.Errorf("invalid magic number")
+		}
+		off := nativeEndian.Uint32(msg[16:20])
+		size := nativeEndian.Uint32(msg[20:24])
+		if uint64(off)+uint64(size) > uint64(len(msg)) {
+			return nil, fmt.Errorf("invalid properties length")
+		}
+		props = msg[off : off+size]
+	} else {
+		// kernel events start with action@devpath
+		i := bytes.IndexByte(msg, 0)
+		if i < 0 || !bytes.Contains(msg[:i], []byte("@")) {
+			return nil, fmt.Errorf("invalid kernel event")
+		}
+		props = msg[i+1:]
+	}
+
+	properties := make(map[string]string)
+	for _, prop := range bytes.Split(props, []byte{0}) {
+		if len(prop) == 0 {
+			continue
+		}
+		kv := strings.SplitN(string(prop), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid property %q", prop)
+		}
+		properties[kv[0]] = kv[1]
+	}
+	return newDeviceInfo(properties)
+}
+
+func newDeviceInfo(properties map[string]string) (*DeviceInfo, error) {
+	dev := &DeviceInfo{
+		Action:     properties["ACTION"],
+		DevPath:    properties["DEVPATH"],
+		Subsystem:  properties["SUBSYSTEM"],
+		Properties: properties,
+	}
+	if dev.DevPath == "" {
+		return nil, fmt.Errorf("event without DEVPATH")
+	}
+	return dev, nil
+}
+
+// parseUDevDatabase parses the output of udevadm info --export-db: a
+// paragraph per device, with its properties in the E: lines.
+func parseUDevDatabase(r io.Reader) ([]*DeviceInfo, error) {
+	var devices []*DeviceInfo
+	properties := make(map[string]string)
+	flush := func() error {
+		if len(properties) == 0 {
+			return nil
+		}
+		properties["ACTION"] = "add"
+		dev, err := newDeviceInfo(properties)
+		if err != nil {
+			return err
+		}
+		devices = append(devices, dev)
+		properties = make(map[string]string)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "E: ") {
+			continue
+		}
+		kv := strings.SplitN(line[3:], "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid udev database entry %q", line)
+		}
+		properties[kv[0]] = kv[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
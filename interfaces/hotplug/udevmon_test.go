@@ -0,0 +1,137 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func TestHotplug(t *testing.T) { TestingT(t) }
+
+type udevMonitorSuite struct{}
+
+var _ = Suite(&udevMonitorSuite{})
+
+const serialProps = "ACTION=add\x00" +
+	"DEVPATH=/devices/pci0000:00/0000:00:14.0/usb3/3-4/3-4:1.0/ttyUSB0/tty/ttyUSB0\x00" +
+	"SUBSYSTEM=tty\x00" +
+	"DEVNAME=/dev/ttyUSB0\x00" +
+	"ID_SERIAL=FTDI_FT232R_USB_UART_A1B2C3\x00" +
+	"ID_USB_INTERFACE_NUM=00\x00"
+
+func udevMessage(props string) []byte {
+	header := make([]byte, 40)
+	copy(header, "libudev\x00")
+	binary.BigEndian.PutUint32(header[8:], 0xfeedcafe)
+	binary.LittleEndian.PutUint32(header[12:], 40)
+	binary.LittleEndian.PutUint32(header[16:], 40)
+	binary.LittleEndian.PutUint32(header[20:], uint32(len(props)))
+	return append(header, props...)
+}
+
+func (s *udevMonitorSuite) TestParseUDevEvent(c *C) {
+	dev, err := hotplug.ParseUEvent(udevMessage(serialProps))
+	c.Assert(err, IsNil)
+	c.Check(dev.Action, Equals, "add")
+	c.Check(dev.DevPath, Equals, "/devices/pci0000:00/0000:00:14.0/usb3/3-4/3-4:1.0/ttyUSB0/tty/ttyUSB0")
+	c.Check(dev.Subsystem, Equals, "tty")
+	c.Check(dev.DeviceName(), Equals, "/dev/ttyUSB0")
+	c.Check(dev.Key(), Equals, "FTDI_FT232R_USB_UART_A1B2C3/00")
+	value, ok := dev.Property("ID_SERIAL")
+	c.Check(ok, Equals, true)
+	c.Check(value, Equals, "FTDI_FT232R_USB_UART_A1B2C3")
+}
+
+func (s *udevMonitorSuite) TestParseKernelEvent(c *C) {
+	dev, err := hotplug.ParseUEvent([]byte("remove@/devices/virtual/misc/foo\x00" +
+		"ACTION=remove\x00DEVPATH=/devices/virtual/misc/foo\x00SUBSYSTEM=misc\x00DEVNAME=foo\x00"))
+	c.Assert(err, IsNil)
+	c.Check(dev.Action, Equals, "remove")
+	c.Check(dev.Subsystem, Equals, "misc")
+	c.Check(dev.DeviceName(), Equals, "/dev/foo")
+	// without a serial number devices are identified by their path
+	c.Check(dev.Key(), Equals, "/devices/virtual/misc/foo")
+}
+
+func (s *udevMonitorSuite) TestParseInvalidEvents(c *C) {
+	badMagic := udevMessage(serialProps)
+	badMagic[8] = 0
+	badLength := udevMessage(serialProps)
+	binary.LittleEndian.PutUint32(badLength[20:], 4096)
+
+	for _, t := range []struct {
+		msg []byte
+		err string
+	}{
+		{[]byte("libudev\x00"), "message too short"},
+		{badMagic, "invalid magic number"},
+		{badLength, "invalid properties length"},
+		{[]byte("garbage"), "invalid kernel event"},
+		{[]byte("add@/devices/foo\x00ACTION=add\x00DEVPATH"), `invalid property "DEVPATH"`},
+		{udevMessage("ACTION=add\x00"), "event without DEVPATH"},
+	} {
+		_, err := hotplug.ParseUEvent(t.msg)
+		c.Check(err, ErrorMatches, t.err)
+	}
+}
+
+const udevDatabase = `P: /devices/virtual/misc/foo
+N: foo
+E: DEVPATH=/devices/virtual/misc/foo
+E: DEVNAME=/dev/foo
+E: SUBSYSTEM=misc
+
+P: /devices/pci0000:00/0000:00:14.0/usb3/3-4/3-4:1.0/ttyUSB0/tty/ttyUSB0
+N: ttyUSB0
+S: serial/by-id/usb-FTDI_FT232R_USB_UART_A1B2C3-if00-port0
+E: DEVPATH=/devices/pci0000:00/0000:00:14.0/usb3/3-4/3-4:1.0/ttyUSB0/tty/ttyUSB0
+E: DEVNAME=/dev/ttyUSB0
+E: SUBSYSTEM=tty
+E: ID_SERIAL=FTDI_FT232R_USB_UART_A1B2C3
+`
+
+func (s *udevMonitorSuite) TestEnumerate(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "cat <<'EOF'\n"+udevDatabase+"EOF")
+	defer cmd.Restore()
+
+	devices, err := (&hotplug.UDevMonitor{}).Enumerate()
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, []string{"info --export-db"})
+	c.Assert(devices, HasLen, 2)
+	c.Check(devices[0].Action, Equals, "add")
+	c.Check(devices[0].DevPath, Equals, "/devices/virtual/misc/foo")
+	c.Check(devices[0].Subsystem, Equals, "misc")
+	c.Check(devices[1].DeviceName(), Equals, "/dev/ttyUSB0")
+	c.Check(devices[1].Key(), Equals, "FTDI_FT232R_USB_UART_A1B2C3")
+}
+
+func (s *udevMonitorSuite) TestEnumerateError(c *C) {
+	cmd := testutil.MockCommand(c, "udevadm", "exit 1")
+	defer cmd.Restore()
+
+	_, err := (&hotplug.UDevMonitor{}).Enumerate()
+	c.Check(err, ErrorMatches, "cannot enumerate devices: exit status 1")
+}
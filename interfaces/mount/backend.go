@@ -0,0 +1,130 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package mount implements mounts that get mapped into the snap.
+//
+// Snappy creates fstab like configuration files that describe what
+// directories from the system or from other snaps should get mapped
+// into the snap.
+//
+// Each fstab like file contains a list of mount entries, one per line,
+// using the format of fstab(5). The files are stored per application in
+// /var/lib/snapd/mount/snap.$SNAP_NAME.$APP_NAME.fstab and are consumed
+// by the launcher when setting up the mount namespace of the application.
+package mount
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Backend is responsible for maintaining mount files for the snap launcher.
+type Backend struct{}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() string {
+	return "mount"
+}
+
+// Setup creates mount profile files specific to a given snap.
+//
+// Mount entries have no concept of a complain mode so devMode is ignored.
+func (b *Backend) Setup(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) error {
+	snapName := snapInfo.Name()
+	snippets, err := repo.SecuritySnippetsForSnap(snapName, interfaces.SecurityMount)
+	if err != nil {
+		return fmt.Errorf("cannot obtain mount security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, snippets)
+	if err != nil {
+		return fmt.Errorf("cannot obtain expected mount files for snap %q: %s", snapName, err)
+	}
+	glob := fmt.Sprintf("%s.fstab", interfaces.SecurityTagGlob(snapName))
+	dir := dirs.SnapMountPolicyDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for mount files %q: %s", dir, err)
+	}
+	_, _, err = osutil.EnsureDirState(dir, glob, content)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize mount files for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// Remove removes mount files of a given snap.
+//
+// This method should be called after removing a snap.
+func (b *Backend) Remove(snapName string) error {
+	glob := fmt.Sprintf("%s.fstab", interfaces.SecurityTagGlob(snapName))
+	_, _, err := osutil.EnsureDirState(dirs.SnapMountPolicyDir, glob, nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize mount files for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// Profiles returns the mount files of the applications of a given snap,
+// with each snippet annotated with its origin. Applications without snippets
+// have none.
+func (b *Backend) Profiles(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(snapName, interfaces.SecurityMount)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain mount security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, interfaces.AnnotateSnippets(snippets, "# ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected mount files for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string][]byte, len(content))
+	for _, appInfo := range snapInfo.Apps {
+		if fileState, ok := content[fmt.Sprintf("%s.fstab", appInfo.SecurityTag())]; ok {
+			profiles[appInfo.Name] = fileState.Content
+		}
+	}
+	return profiles, nil
+}
+
+// combineSnippets combines security snippets collected from all the interfaces
+// affecting a given snap into a content map applicable to EnsureDirState.
+func (b *Backend) combineSnippets(snapInfo *snap.Info, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
+	for _, appInfo := range snapInfo.Apps {
+		appSnippets := snippets[appInfo.Name]
+		if len(appSnippets) == 0 {
+			continue
+		}
+		var buf bytes.Buffer
+		for _, snippet := range appSnippets {
+			buf.Write(snippet)
+			buf.WriteRune('\n')
+		}
+		if content == nil {
+			content = make(map[string]*osutil.FileState)
+		}
+		fname := fmt.Sprintf("%s.fstab", appInfo.SecurityTag())
+		content[fname] = &osutil.FileState{Content: buf.Bytes(), Mode: 0644}
+	}
+	return content, nil
+}
@@ -0,0 +1,167 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package mount_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/snap"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	backend *mount.Backend
+	repo    *interfaces.Repository
+	iface   *interfaces.TestInterface
+	rootDir string
+}
+
+var _ = Suite(&backendSuite{backend: &mount.Backend{}})
+
+func (s *backendSuite) SetUpTest(c *C) {
+	// Isolate this test to a temporary directory
+	s.rootDir = c.MkDir()
+	dirs.SetRootDir(s.rootDir)
+	// Create a fresh repository for each test
+	s.repo = interfaces.NewRepository()
+	s.iface = &interfaces.TestInterface{InterfaceName: "iface"}
+	err := s.repo.AddInterface(s.iface)
+	c.Assert(err, IsNil)
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("/")
+}
+
+const sambaYamlV1 = `
+name: samba
+version: 1
+developer: acme
+apps:
+    smbd:
+slots:
+    iface:
+`
+const sambaYamlV1WithNmbd = `
+name: samba
+version: 1
+developer: acme
+apps:
+    smbd:
+    nmbd:
+slots:
+    iface:
+`
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.backend.Name(), Equals, "mount")
+}
+
+func (s *backendSuite) TestInstallingSnapWritesMountFiles(c *C) {
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		if securitySystem == interfaces.SecurityMount {
+			return []byte("/src /dst none bind,ro 0 0"), nil
+		}
+		return nil, nil
+	}
+	snapInfo := s.installSnap(c, sambaYamlV1)
+	fstab := filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.smbd.fstab")
+	data, err := ioutil.ReadFile(fstab)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Equals, "/src /dst none bind,ro 0 0\n")
+	stat, err := os.Stat(fstab)
+	c.Assert(err, IsNil)
+	c.Check(stat.Mode(), Equals, os.FileMode(0644))
+
+	err = s.backend.Remove(snapInfo.Name())
+	c.Assert(err, IsNil)
+	_, err = os.Stat(fstab)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestUpdatingSnapToOneWithFewerApps(c *C) {
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("/src /dst none bind,ro 0 0"), nil
+	}
+	s.installSnap(c, sambaYamlV1WithNmbd)
+	fstab := filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.nmbd.fstab")
+	_, err := os.Stat(fstab)
+	c.Check(err, IsNil)
+
+	s.removePlugsSlots(c, "samba")
+	s.installSnap(c, sambaYamlV1)
+	_, err = os.Stat(fstab)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestCombineSnippetsWithoutAnySnippets(c *C) {
+	s.installSnap(c, sambaYamlV1)
+	_, err := os.Stat(filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.smbd.fstab"))
+	// Without any snippets, there the .fstab file is not created.
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *backendSuite) TestProfiles(c *C) {
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("/src /dst none bind,ro 0 0"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYamlV1))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	var profiler interfaces.SecurityProfiler = s.backend
+	profiles, err := profiler.Profiles(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{
+		"smbd": []byte("# slot samba:iface (interface iface)\n/src /dst none bind,ro 0 0\n"),
+	})
+	_, err = os.Stat(filepath.Join(dirs.SnapMountPolicyDir, "snap.samba.smbd.fstab"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+// installSnap "installs" a snap from YAML.
+func (s *backendSuite) installSnap(c *C, snapYaml string) *snap.Info {
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(snapYaml))
+	c.Assert(err, IsNil)
+	for _, slotInfo := range snapInfo.Slots {
+		err := s.repo.AddSlot(&interfaces.Slot{SlotInfo: slotInfo})
+		c.Assert(err, IsNil)
+	}
+	err = s.backend.Setup(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	return snapInfo
+}
+
+func (s *backendSuite) removePlugsSlots(c *C, snapName string) {
+	for _, slot := range s.repo.Slots(snapName) {
+		err := s.repo.RemoveSlot(slot.Snap.Name(), slot.Name)
+		c.Assert(err, IsNil)
+	}
+}
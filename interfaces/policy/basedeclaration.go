@@ -0,0 +1,202 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy
+
+import (
+	"github.com/snapcore/snapd/asserts"
+)
+
+// The base declaration holds the rules about the plugs and slots of the
+// builtin interfaces that apply to the snaps without a snap-declaration
+// saying otherwise for the same interface.
+//
+// Slots of interfaces granting access to privileged system services can
+// only be provided by the OS snap, or by the snaps whose snap-declaration
+// allows their installation. Connections to them are never automatic, and
+// the plugs of the privileged interfaces implicitly provided by the OS snap
+// can only be connected to slots of the OS snap.
+//
+// Content plugs are allowed to be connected automatically, which happens
+// to the slots of the snap they name as their default provider.
+const baseDeclarationPlugs = `
+content:
+  allow-auto-connection: true
+cups-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+firewall-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+locale-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+log-observe:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+mount-observe:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+network-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+network-observe:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+snapd-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+system-observe:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+timeserver-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+timezone-control:
+  allow-connection:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+`
+
+const baseDeclarationSlots = `
+bluez:
+  allow-installation:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+bool-file:
+  allow-installation:
+    slot-snap-type:
+      - os
+      - gadget
+  deny-auto-connection: true
+cups-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+firewall-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+i2c:
+  allow-installation:
+    slot-snap-type:
+      - gadget
+      - os
+  deny-auto-connection: true
+locale-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+location-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+location-observe:
+  allow-installation:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+log-observe:
+  allow-installation:
+    slot-snap-type:
+      - os
+mount-observe:
+  allow-installation:
+    slot-snap-type:
+      - os
+network-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+network-manager:
+  allow-installation:
+    slot-snap-type:
+      - os
+  deny-auto-connection: true
+network-observe:
+  allow-installation:
+    slot-snap-type:
+      - os
+serial-port:
+  allow-installation:
+    slot-snap-type:
+      - gadget
+      - os
+  deny-auto-connection: true
+snapd-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+spi:
+  allow-installation:
+    slot-snap-type:
+      - gadget
+      - os
+  deny-auto-connection: true
+system-observe:
+  allow-installation:
+    slot-snap-type:
+      - os
+timeserver-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+timezone-control:
+  allow-installation:
+    slot-snap-type:
+      - os
+`
+
+var baseDeclaration *asserts.BaseDeclaration
+
+func init() {
+	var err error
+	baseDeclaration, err = asserts.NewBaseDeclaration(baseDeclarationPlugs, baseDeclarationSlots)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// BaseDeclaration returns the base declaration for the builtin interfaces.
+func BaseDeclaration() *asserts.BaseDeclaration {
+	return baseDeclaration
+}
@@ -0,0 +1,228 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package policy implements the evaluation of the rules about the
+// installation and connection of plugs and slots given by the
+// snap-declaration assertions of snaps and by the base declaration.
+package policy
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/snap"
+)
+
+// snapIdentity returns what the constraints of the rules match: the type,
+// snap id and publisher id of a snap.
+func snapIdentity(info *snap.Info, snapDecl *asserts.SnapDeclaration) (snapType, snapID, publisherID string) {
+	snapType = string(info.Type)
+	if snapType == "" {
+		snapType = string(snap.TypeApp)
+	}
+	if snapDecl != nil {
+		return snapType, snapDecl.SnapID(), snapDecl.PublisherID()
+	}
+	return snapType, info.SnapID, ""
+}
+
+// ruleFor returns the rule for the interface combining the one of the
+// snap-declaration with the one of the base declaration. For each of
+// installation, connection and auto-connection the constraints of the
+// snap-declaration rule are used if it sets any, otherwise those of the
+// base declaration rule.
+func ruleFor(snapDeclRule, baseDeclRule func(string) *asserts.InterfaceRule, interfaceName string) *asserts.InterfaceRule {
+	var snapRule, baseRule *asserts.InterfaceRule
+	if snapDeclRule != nil {
+		snapRule = snapDeclRule(interfaceName)
+	}
+	if baseDeclRule != nil {
+		baseRule = baseDeclRule(interfaceName)
+	}
+	if snapRule == nil {
+		return baseRule
+	}
+	if baseRule == nil {
+		return snapRule
+	}
+	rule := *baseRule
+	if snapRule.AllowInstallation != nil || snapRule.DenyInstallation != nil {
+		rule.AllowInstallation, rule.DenyInstallation = snapRule.AllowInstallation, snapRule.DenyInstallation
+	}
+	if snapRule.AllowConnection != nil || snapRule.DenyConnection != nil {
+		rule.AllowConnection, rule.DenyConnection = snapRule.AllowConnection, snapRule.DenyConnection
+	}
+	if snapRule.AllowAutoConnection != nil || snapRule.DenyAutoConnection != nil {
+		rule.AllowAutoConnection, rule.DenyAutoConnection = snapRule.AllowAutoConnection, snapRule.DenyAutoConnection
+	}
+	return &rule
+}
+
+func checkConstraints(allow, deny *asserts.SnapConstraints, snapType, snapID, publisherID string) error {
+	if deny != nil && deny.Matches(snapType, snapID, publisherID) {
+		return fmt.Errorf("denied by rule")
+	}
+	if allow != nil && !allow.Matches(snapType, snapID, publisherID) {
+		return fmt.Errorf("not allowed by rule")
+	}
+	return nil
+}
+
+// InstallCandidate represents a candidate snap for installation.
+type InstallCandidate struct {
+	Snap            *snap.Info
+	SnapDeclaration *asserts.SnapDeclaration
+	BaseDeclaration *asserts.BaseDeclaration
+}
+
+func (c *InstallCandidate) plugRule(interfaceName string) *asserts.InterfaceRule {
+	var snapDeclRule func(string) *asserts.InterfaceRule
+	if c.SnapDeclaration != nil {
+		snapDeclRule = c.SnapDeclaration.PlugRule
+	}
+	return ruleFor(snapDeclRule, c.BaseDeclaration.PlugRule, interfaceName)
+}
+
+func (c *InstallCandidate) slotRule(interfaceName string) *asserts.InterfaceRule {
+	var snapDeclRule func(string) *asserts.InterfaceRule
+	if c.SnapDeclaration != nil {
+		snapDeclRule = c.SnapDeclaration.SlotRule
+	}
+	return ruleFor(snapDeclRule, c.BaseDeclaration.SlotRule, interfaceName)
+}
+
+// Check checks whether the installation of the plugs and slots of the
+// snap is allowed.
+func (c *InstallCandidate) Check() error {
+	snapType, snapID, publisherID := snapIdentity(c.Snap, c.SnapDeclaration)
+	for _, slot := range c.Snap.Slots {
+		rule := c.slotRule(slot.Interface)
+		if rule == nil {
+			continue
+		}
+		if err := checkConstraints(rule.AllowInstallation, rule.DenyInstallation, snapType, snapID, publisherID); err != nil {
+			return fmt.Errorf("installation of slot %q of interface %q %v", slot.Name, slot.Interface, err)
+		}
+	}
+	for _, plug := range c.Snap.Plugs {
+		rule := c.plugRule(plug.Interface)
+		if rule == nil {
+			continue
+		}
+		if err := checkConstraints(rule.AllowInstallation, rule.DenyInstallation, snapType, snapID, publisherID); err != nil {
+			return fmt.Errorf("installation of plug %q of interface %q %v", plug.Name, plug.Interface, err)
+		}
+	}
+	return nil
+}
+
+// ConnectCandidate represents a candidate connection.
+type ConnectCandidate struct {
+	Plug                *interfaces.Plug
+	PlugSnapDeclaration *asserts.SnapDeclaration
+
+	Slot                *interfaces.Slot
+	SlotSnapDeclaration *asserts.SnapDeclaration
+
+	BaseDeclaration *asserts.BaseDeclaration
+}
+
+func (c *ConnectCandidate) plugRule() *asserts.InterfaceRule {
+	var snapDeclRule func(string) *asserts.InterfaceRule
+	if c.PlugSnapDeclaration != nil {
+		snapDeclRule = c.PlugSnapDeclaration.PlugRule
+	}
+	return ruleFor(snapDeclRule, c.BaseDeclaration.PlugRule, c.Plug.Interface)
+}
+
+func (c *ConnectCandidate) slotRule() *asserts.InterfaceRule {
+	var snapDeclRule func(string) *asserts.InterfaceRule
+	if c.SlotSnapDeclaration != nil {
+		snapDeclRule = c.SlotSnapDeclaration.SlotRule
+	}
+	return ruleFor(snapDeclRule, c.BaseDeclaration.SlotRule, c.Plug.Interface)
+}
+
+// check evaluates the constraints picked by pick from the plug rule
+// against the slot snap and from the slot rule against the plug snap.
+func (c *ConnectCandidate) check(what string, pick func(*asserts.InterfaceRule) (allow, deny *asserts.SnapConstraints)) error {
+	if rule := c.plugRule(); rule != nil {
+		allow, deny := pick(rule)
+		snapType, snapID, publisherID := snapIdentity(c.Slot.Snap, c.SlotSnapDeclaration)
+		if err := checkConstraints(allow, deny, snapType, snapID, publisherID); err != nil {
+			return fmt.Errorf("%s %s of plug of interface %q", what, err, c.Plug.Interface)
+		}
+	}
+	if rule := c.slotRule(); rule != nil {
+		allow, deny := pick(rule)
+		snapType, snapID, publisherID := snapIdentity(c.Plug.Snap, c.PlugSnapDeclaration)
+		if err := checkConstraints(allow, deny, snapType, snapID, publisherID); err != nil {
+			return fmt.Errorf("%s %s of slot of interface %q", what, err, c.Plug.Interface)
+		}
+	}
+	return nil
+}
+
+// Check checks whether the connection is allowed.
+func (c *ConnectCandidate) Check() error {
+	return c.check("connection", func(rule *asserts.InterfaceRule) (allow, deny *asserts.SnapConstraints) {
+		return rule.AllowConnection, rule.DenyConnection
+	})
+}
+
+// CheckAutoConnect checks whether the connection is allowed to be
+// established automatically. That requires the connection itself to
+// be allowed.
+func (c *ConnectCandidate) CheckAutoConnect() error {
+	if err := c.Check(); err != nil {
+		return err
+	}
+	return c.check("auto-connection", func(rule *asserts.InterfaceRule) (allow, deny *asserts.SnapConstraints) {
+		return rule.AllowAutoConnection, rule.DenyAutoConnection
+	})
+}
+
+// AutoConnectGranted returns whether the snap-declaration of the plug
+// snap explicitly grants auto-connection to its plugs of the given
+// interface, considering then any matching slot as a candidate.
+func AutoConnectGranted(plugSnapDecl *asserts.SnapDeclaration, interfaceName string) bool {
+	if plugSnapDecl == nil {
+		return false
+	}
+	rule := plugSnapDecl.PlugRule(interfaceName)
+	return rule != nil && rule.AllowAutoConnection != nil && rule.AllowAutoConnection != asserts.NeverMatch
+}
+
+// AutoConnectAllowed returns whether the plugs of the given interface can
+// be connected automatically to the slots of the snap they name as their
+// default provider: either the snap-declaration of the plug snap or the
+// base declaration explicitly allow their auto-connection.
+func AutoConnectAllowed(plugSnapDecl *asserts.SnapDeclaration, baseDecl *asserts.BaseDeclaration, interfaceName string) bool {
+	var snapDeclRule func(string) *asserts.InterfaceRule
+	if plugSnapDecl != nil {
+		snapDeclRule = plugSnapDecl.PlugRule
+	}
+	var baseDeclRule func(string) *asserts.InterfaceRule
+	if baseDecl != nil {
+		baseDeclRule = baseDecl.PlugRule
+	}
+	rule := ruleFor(snapDeclRule, baseDeclRule, interfaceName)
+	return rule != nil && rule.AllowAutoConnection != nil && rule.AllowAutoConnection != asserts.NeverMatch
+}
@@ -0,0 +1,263 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/policy"
+	"github.com/snapcore/snapd/snap"
+)
+
+func TestPolicy(t *testing.T) { TestingT(t) }
+
+type policySuite struct{}
+
+var _ = Suite(&policySuite{})
+
+func snapDeclaration(c *C, snapID, publisherID, rules string) *asserts.SnapDeclaration {
+	encoded := "type: snap-declaration\n" +
+		"authority-id: canonical\n" +
+		"series: 16\n" +
+		"snap-id: " + snapID + "\n" +
+		"snap-name: " + snapID + "\n" +
+		"publisher-id: " + publisherID + "\n" +
+		"gates: \n" +
+		rules +
+		"timestamp: " + time.Now().UTC().Format(time.RFC3339) + "\n" +
+		"body-length: 0" +
+		"\n\n" +
+		"openpgp c2ln"
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	return a.(*asserts.SnapDeclaration)
+}
+
+func mockSnap(c *C, yaml string) *snap.Info {
+	info, err := snap.InfoFromSnapYaml([]byte(yaml))
+	c.Assert(err, IsNil)
+	return info
+}
+
+const consumerYaml = `name: consumer
+plugs:
+  nm:
+    interface: network-manager
+  ctl:
+    interface: snapd-control
+`
+
+const producerYaml = `name: producer
+slots:
+  nm:
+    interface: network-manager
+`
+
+const coreYaml = `name: ubuntu-core
+type: os
+slots:
+  nm:
+    interface: network-manager
+  ctl:
+    interface: snapd-control
+`
+
+func (s *policySuite) TestBaseDeclaration(c *C) {
+	baseDecl := policy.BaseDeclaration()
+	privileged := []string{"cups-control", "firewall-control", "locale-control", "log-observe", "mount-observe", "network-control", "network-observe", "snapd-control", "system-observe", "timeserver-control", "timezone-control"}
+	c.Check(baseDecl.PlugInterfaces(), DeepEquals, append([]string{"content"}, privileged...))
+	c.Check(baseDecl.SlotInterfaces(), DeepEquals, []string{"bluez", "bool-file", "cups-control", "firewall-control", "i2c", "locale-control", "location-control", "location-observe", "log-observe", "mount-observe", "network-control", "network-manager", "network-observe", "serial-port", "snapd-control", "spi", "system-observe", "timeserver-control", "timezone-control"})
+
+	for _, name := range privileged {
+		rule := baseDecl.PlugRule(name)
+		c.Check(rule.DenyAutoConnection, Equals, asserts.AlwaysMatch, Commentf(name))
+		c.Check(rule.AllowConnection.SnapTypes, DeepEquals, []string{"os"}, Commentf(name))
+		c.Check(baseDecl.SlotRule(name).AllowInstallation.SnapTypes, DeepEquals, []string{"os"}, Commentf(name))
+	}
+}
+
+const privilegedYaml = `name: consumer
+plugs:
+  fw:
+    interface: firewall-control
+`
+
+const privilegedSlotsYaml = `name: other
+slots:
+  fw:
+    interface: firewall-control
+`
+
+func (s *policySuite) TestPrivilegedImplicitInterface(c *C) {
+	consumer := mockSnap(c, privilegedYaml)
+	core := mockSnap(c, "name: ubuntu-core\ntype: os\nslots:\n  fw:\n    interface: firewall-control\n")
+	other := mockSnap(c, privilegedSlotsYaml)
+
+	cand := &policy.ConnectCandidate{
+		Plug:            &interfaces.Plug{PlugInfo: consumer.Plugs["fw"]},
+		Slot:            &interfaces.Slot{SlotInfo: core.Slots["fw"]},
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), IsNil)
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by rule of plug of interface "firewall-control"`)
+
+	// only the OS snap can provide the slot
+	cand.Slot = &interfaces.Slot{SlotInfo: other.Slots["fw"]}
+	c.Check(cand.Check(), ErrorMatches, `connection not allowed by rule of plug of interface "firewall-control"`)
+	install := &policy.InstallCandidate{
+		Snap:            other,
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(install.Check(), ErrorMatches, `installation of slot "fw" of interface "firewall-control" not allowed by rule`)
+}
+
+func (s *policySuite) TestSnapDeclarationRuleCombinedWithBase(c *C) {
+	// allowing the installation leaves the base auto-connection rule in place
+	slotDecl := snapDeclaration(c, "producer-id", "producer-dev", "slots:\n"+
+		" network-manager:\n"+
+		"   allow-installation: true\n")
+	producer := mockSnap(c, producerYaml)
+	install := &policy.InstallCandidate{
+		Snap:            producer,
+		SnapDeclaration: slotDecl,
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(install.Check(), IsNil)
+
+	consumer := mockSnap(c, consumerYaml)
+	cand := &policy.ConnectCandidate{
+		Plug:                &interfaces.Plug{PlugInfo: consumer.Plugs["nm"]},
+		Slot:                &interfaces.Slot{SlotInfo: producer.Slots["nm"]},
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), IsNil)
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by rule of slot of interface "network-manager"`)
+}
+
+func (s *policySuite) TestAutoConnectAllowed(c *C) {
+	baseDecl := policy.BaseDeclaration()
+	c.Check(policy.AutoConnectAllowed(nil, baseDecl, "content"), Equals, true)
+	c.Check(policy.AutoConnectAllowed(nil, baseDecl, "firewall-control"), Equals, false)
+	c.Check(policy.AutoConnectAllowed(nil, baseDecl, "network-manager"), Equals, false)
+
+	plugDecl := snapDeclaration(c, "consumer-id", "consumer-dev", "plugs:\n"+
+		" firewall-control:\n"+
+		"   allow-auto-connection: true\n"+
+		" content:\n"+
+		"   allow-auto-connection: false\n")
+	c.Check(policy.AutoConnectAllowed(plugDecl, baseDecl, "firewall-control"), Equals, true)
+	c.Check(policy.AutoConnectAllowed(plugDecl, baseDecl, "content"), Equals, false)
+}
+
+func (s *policySuite) TestInstallPrivilegedSlotOnOS(c *C) {
+	cand := &policy.InstallCandidate{
+		Snap:            mockSnap(c, coreYaml),
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestInstallPrivilegedSlotRefused(c *C) {
+	cand := &policy.InstallCandidate{
+		Snap:            mockSnap(c, producerYaml),
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), ErrorMatches, `installation of slot "nm" of interface "network-manager" not allowed by rule`)
+}
+
+func (s *policySuite) TestInstallPrivilegedSlotAllowedBySnapDeclaration(c *C) {
+	cand := &policy.InstallCandidate{
+		Snap: mockSnap(c, producerYaml),
+		SnapDeclaration: snapDeclaration(c, "producer-id", "producer-dev", "slots:\n"+
+			" network-manager:\n"+
+			"   allow-installation: true\n"),
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), IsNil)
+}
+
+func (s *policySuite) TestInstallPlugDenied(c *C) {
+	cand := &policy.InstallCandidate{
+		Snap: mockSnap(c, consumerYaml),
+		SnapDeclaration: snapDeclaration(c, "consumer-id", "consumer-dev", "plugs:\n"+
+			" snapd-control:\n"+
+			"   deny-installation: true\n"),
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	c.Check(cand.Check(), ErrorMatches, `installation of plug "ctl" of interface "snapd-control" denied by rule`)
+}
+
+func connectCandidate(c *C, plugName, slotName string, plugDecl, slotDecl *asserts.SnapDeclaration) *policy.ConnectCandidate {
+	consumer := mockSnap(c, consumerYaml)
+	core := mockSnap(c, coreYaml)
+	return &policy.ConnectCandidate{
+		Plug:                &interfaces.Plug{PlugInfo: consumer.Plugs[plugName]},
+		PlugSnapDeclaration: plugDecl,
+		Slot:                &interfaces.Slot{SlotInfo: core.Slots[slotName]},
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     policy.BaseDeclaration(),
+	}
+}
+
+func (s *policySuite) TestConnectAllowedByDefault(c *C) {
+	cand := connectCandidate(c, "nm", "nm", nil, nil)
+	c.Check(cand.Check(), IsNil)
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by rule of slot of interface "network-manager"`)
+}
+
+func (s *policySuite) TestConnectDeniedBySnapDeclaration(c *C) {
+	plugDecl := snapDeclaration(c, "consumer-id", "consumer-dev", "plugs:\n"+
+		" network-manager:\n"+
+		"   deny-connection:\n"+
+		"     slot-snap-type: [os]\n")
+	cand := connectCandidate(c, "nm", "nm", plugDecl, nil)
+	c.Check(cand.Check(), ErrorMatches, `connection denied by rule of plug of interface "network-manager"`)
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `connection denied by rule of plug of interface "network-manager"`)
+}
+
+func (s *policySuite) TestConnectNotAllowedForPublisher(c *C) {
+	plugDecl := snapDeclaration(c, "consumer-id", "consumer-dev", "plugs:\n"+
+		" snapd-control:\n"+
+		"   allow-connection:\n"+
+		"     slot-publisher-id: [canonical]\n")
+	cand := connectCandidate(c, "ctl", "ctl", plugDecl, nil)
+	c.Check(cand.Check(), ErrorMatches, `connection not allowed by rule of plug of interface "snapd-control"`)
+}
+
+func (s *policySuite) TestAutoConnectGranted(c *C) {
+	cand := connectCandidate(c, "ctl", "ctl", nil, nil)
+	c.Check(cand.Check(), IsNil)
+	c.Check(cand.CheckAutoConnect(), ErrorMatches, `auto-connection denied by rule of plug of interface "snapd-control"`)
+	c.Check(policy.AutoConnectGranted(nil, "snapd-control"), Equals, false)
+
+	plugDecl := snapDeclaration(c, "consumer-id", "consumer-dev", "plugs:\n"+
+		" snapd-control:\n"+
+		"   allow-auto-connection: true\n")
+	cand = connectCandidate(c, "ctl", "ctl", plugDecl, nil)
+	c.Check(cand.CheckAutoConnect(), IsNil)
+	c.Check(policy.AutoConnectGranted(plugDecl, "snapd-control"), Equals, true)
+	c.Check(policy.AutoConnectGranted(plugDecl, "network-manager"), Equals, false)
+}
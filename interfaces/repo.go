@@ -39,6 +39,20 @@ type Repository struct {
 	slots     map[string]map[string]*Slot
 	slotPlugs map[*Slot]map[*Plug]bool
 	plugSlots map[*Plug]map[*Slot]bool
+	// Attributes set when connecting, indexed by connection
+	connAttrs map[conn]*connAttrs
+}
+
+// conn identifies a connection between a plug and a slot.
+type conn struct {
+	plug *Plug
+	slot *Slot
+}
+
+// connAttrs holds the attributes set by both sides of a connection.
+type connAttrs struct {
+	plug map[string]interface{}
+	slot map[string]interface{}
 }
 
 // NewRepository creates an empty plug repository.
@@ -49,6 +63,7 @@ func NewRepository() *Repository {
 		slots:     make(map[string]map[string]*Slot),
 		slotPlugs: make(map[*Slot]map[*Plug]bool),
 		plugSlots: make(map[*Plug]map[*Slot]bool),
+		connAttrs: make(map[conn]*connAttrs),
 	}
 }
 
@@ -305,6 +320,83 @@ func (r *Repository) Connect(plugSnapName, plugName, slotSnapName, slotName stri
 	return nil
 }
 
+// SetConnectionAttrs sets the attributes of an existing connection between a
+// plug and a slot, replacing those set before. The attributes of each side are
+// given to the interface when computing the connection-specific snippets.
+//
+// Attributes set in snap.yaml cannot be changed this way. Interfaces
+// implementing ConnectionSanitizer get to check and refuse the attributes.
+func (r *Repository) SetConnectionAttrs(plugSnapName, plugName, slotSnapName, slotName string, plugAttrs, slotAttrs map[string]interface{}) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	plug := r.plugs[plugSnapName][plugName]
+	slot := r.slots[slotSnapName][slotName]
+	if plug == nil || slot == nil || !r.slotPlugs[slot][plug] {
+		return fmt.Errorf("cannot set attributes of connection between %s:%s and %s:%s, not connected",
+			plugSnapName, plugName, slotSnapName, slotName)
+	}
+	for key := range plugAttrs {
+		if _, ok := plug.Attrs[key]; ok {
+			return fmt.Errorf("cannot set attribute %q of plug %s:%s, it is already set in snap.yaml", key, plugSnapName, plugName)
+		}
+	}
+	for key := range slotAttrs {
+		if _, ok := slot.Attrs[key]; ok {
+			return fmt.Errorf("cannot set attribute %q of slot %s:%s, it is already set in snap.yaml", key, slotSnapName, slotName)
+		}
+	}
+	if len(plugAttrs) == 0 && len(slotAttrs) == 0 {
+		delete(r.connAttrs, conn{plug, slot})
+		return nil
+	}
+	if sanitizer, ok := r.ifaces[plug.Interface].(ConnectionSanitizer); ok {
+		connPlug := *plug
+		connPlug.DynamicAttrs = plugAttrs
+		connSlot := *slot
+		connSlot.DynamicAttrs = slotAttrs
+		if err := sanitizer.SanitizeConnection(&connPlug, &connSlot); err != nil {
+			return fmt.Errorf("cannot set attributes of connection between %s:%s and %s:%s: %v",
+				plugSnapName, plugName, slotSnapName, slotName, err)
+		}
+	}
+	r.connAttrs[conn{plug, slot}] = &connAttrs{plug: plugAttrs, slot: slotAttrs}
+	return nil
+}
+
+// ConnectionAttrs returns the attributes set on each side of an existing
+// connection between a plug and a slot.
+func (r *Repository) ConnectionAttrs(plugSnapName, plugName, slotSnapName, slotName string) (plugAttrs, slotAttrs map[string]interface{}, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	plug := r.plugs[plugSnapName][plugName]
+	slot := r.slots[slotSnapName][slotName]
+	if plug == nil || slot == nil || !r.slotPlugs[slot][plug] {
+		return nil, nil, fmt.Errorf("cannot get attributes of connection between %s:%s and %s:%s, not connected",
+			plugSnapName, plugName, slotSnapName, slotName)
+	}
+	if attrs := r.connAttrs[conn{plug, slot}]; attrs != nil {
+		return attrs.plug, attrs.slot, nil
+	}
+	return nil, nil, nil
+}
+
+// connected returns the plug and the slot as given to the interface when
+// computing the snippets specific to their connection, that is copies
+// carrying the attributes set when connecting, if any.
+func (r *Repository) connected(plug *Plug, slot *Slot) (*Plug, *Slot) {
+	attrs := r.connAttrs[conn{plug, slot}]
+	if attrs == nil {
+		return plug, slot
+	}
+	connPlug := *plug
+	connPlug.DynamicAttrs = attrs.plug
+	connSlot := *slot
+	connSlot.DynamicAttrs = attrs.slot
+	return &connPlug, &connSlot
+}
+
 // Disconnect disconnects the named plug from the slot of the given snap.
 //
 // Disconnect has three modes of operation that depend on the passed arguments:
@@ -388,6 +480,7 @@ func (r *Repository) disconnectPlugFromSlot(plugSnapName, plugName, slotSnapName
 
 // disconnect disconnects a plug from a slot.
 func (r *Repository) disconnect(plug *Plug, slot *Slot) {
+	delete(r.connAttrs, conn{plug, slot})
 	delete(r.slotPlugs[slot], plug)
 	if len(r.slotPlugs[slot]) == 0 {
 		delete(r.slotPlugs, slot)
@@ -445,8 +538,22 @@ func (r *Repository) Interfaces() *Interfaces {
 			ifaces.Slots = append(ifaces.Slots, s)
 		}
 	}
+	for plug, slots := range r.plugSlots {
+		for slot := range slots {
+			c := &Connection{
+				Plug: PlugRef{Snap: plug.Snap.Name(), Name: plug.Name},
+				Slot: SlotRef{Snap: slot.Snap.Name(), Name: slot.Name},
+			}
+			if attrs := r.connAttrs[conn{plug, slot}]; attrs != nil {
+				c.PlugAttrs = attrs.plug
+				c.SlotAttrs = attrs.slot
+			}
+			ifaces.Connections = append(ifaces.Connections, c)
+		}
+	}
 	sort.Sort(byPlugSnapAndName(ifaces.Plugs))
 	sort.Sort(bySlotSnapAndName(ifaces.Slots))
+	sort.Sort(byConnection(ifaces.Connections))
 	return ifaces
 }
 
@@ -461,10 +568,62 @@ func (r *Repository) SecuritySnippetsForSnap(snapName string, securitySystem Sec
 }
 
 func (r *Repository) securitySnippetsForSnap(snapName string, securitySystem SecuritySystem) (map[string][][]byte, error) {
+	annotated, err := r.annotatedSecuritySnippetsForSnap(snapName, securitySystem)
+	if err != nil {
+		return nil, err
+	}
 	var snippets = make(map[string][][]byte)
+	for appName, appSnippets := range annotated {
+		for _, snippet := range appSnippets {
+			snippets[appName] = append(snippets[appName], snippet.Snippet)
+		}
+	}
+	return snippets, nil
+}
+
+// SecuritySnippet is a security snippet together with the interface and the
+// plug or slot that produced it.
+type SecuritySnippet struct {
+	Snippet   []byte
+	Interface string
+	// Plug is the plug of the connection that produced the snippet, or nil
+	// for the permanent snippets of slots.
+	Plug *PlugRef
+	// Slot is the slot of the connection that produced the snippet, or nil
+	// for the permanent snippets of plugs.
+	Slot *SlotRef
+}
+
+// Origin describes the interface and the plug or slot that produced the
+// snippet, for diagnostic purposes.
+func (s *SecuritySnippet) Origin() string {
+	switch {
+	case s.Plug != nil && s.Slot != nil:
+		return fmt.Sprintf("connection of plug %s:%s to slot %s:%s (interface %s)", s.Plug.Snap, s.Plug.Name, s.Slot.Snap, s.Slot.Name, s.Interface)
+	case s.Plug != nil:
+		return fmt.Sprintf("plug %s:%s (interface %s)", s.Plug.Snap, s.Plug.Name, s.Interface)
+	default:
+		return fmt.Sprintf("slot %s:%s (interface %s)", s.Slot.Snap, s.Slot.Name, s.Interface)
+	}
+}
+
+// AnnotatedSecuritySnippetsForSnap collects all of the snippets of a given
+// security system that affect a given snap, like SecuritySnippetsForSnap, but
+// keeps track of the interface and of the plug or slot that produced each of
+// them. The return value is indexed by app name within that snap.
+func (r *Repository) AnnotatedSecuritySnippetsForSnap(snapName string, securitySystem SecuritySystem) (map[string][]*SecuritySnippet, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.annotatedSecuritySnippetsForSnap(snapName, securitySystem)
+}
+
+func (r *Repository) annotatedSecuritySnippetsForSnap(snapName string, securitySystem SecuritySystem) (map[string][]*SecuritySnippet, error) {
+	var snippets = make(map[string][]*SecuritySnippet)
 	// Find all of the slots that affect this snap because of plug connection.
 	for _, slot := range r.slots[snapName] {
 		iface := r.ifaces[slot.Interface]
+		slotRef := &SlotRef{Snap: slot.Snap.Name(), Name: slot.Name}
 		// Add the static snippet for the slot
 		snippet, err := iface.PermanentSlotSnippet(slot, securitySystem)
 		if err != nil {
@@ -472,26 +631,33 @@ func (r *Repository) securitySnippetsForSnap(snapName string, securitySystem Sec
 		}
 		if snippet != nil {
 			for appName := range slot.Apps {
-				snippets[appName] = append(snippets[appName], snippet)
+				snippets[appName] = append(snippets[appName], &SecuritySnippet{
+					Snippet: snippet, Interface: slot.Interface, Slot: slotRef,
+				})
 			}
 		}
 		// Add connection-specific snippet specific to each plug
 		for plug := range r.slotPlugs[slot] {
-			snippet, err := iface.ConnectedSlotSnippet(plug, slot, securitySystem)
+			connPlug, connSlot := r.connected(plug, slot)
+			snippet, err := iface.ConnectedSlotSnippet(connPlug, connSlot, securitySystem)
 			if err != nil {
 				return nil, err
 			}
 			if snippet == nil {
 				continue
 			}
+			plugRef := &PlugRef{Snap: plug.Snap.Name(), Name: plug.Name}
 			for appName := range slot.Apps {
-				snippets[appName] = append(snippets[appName], snippet)
+				snippets[appName] = append(snippets[appName], &SecuritySnippet{
+					Snippet: snippet, Interface: slot.Interface, Plug: plugRef, Slot: slotRef,
+				})
 			}
 		}
 	}
 	// Find all of the plugs that affect this snap because of slot connection
 	for _, plug := range r.plugs[snapName] {
 		iface := r.ifaces[plug.Interface]
+		plugRef := &PlugRef{Snap: plug.Snap.Name(), Name: plug.Name}
 		// Add the static snippet for the plug
 		snippet, err := iface.PermanentPlugSnippet(plug, securitySystem)
 		if err != nil {
@@ -499,26 +665,48 @@ func (r *Repository) securitySnippetsForSnap(snapName string, securitySystem Sec
 		}
 		if snippet != nil {
 			for appName := range plug.Apps {
-				snippets[appName] = append(snippets[appName], snippet)
+				snippets[appName] = append(snippets[appName], &SecuritySnippet{
+					Snippet: snippet, Interface: plug.Interface, Plug: plugRef,
+				})
 			}
 		}
 		// Add connection-specific snippet specific to each slot
 		for slot := range r.plugSlots[plug] {
-			snippet, err := iface.ConnectedPlugSnippet(plug, slot, securitySystem)
+			connPlug, connSlot := r.connected(plug, slot)
+			snippet, err := iface.ConnectedPlugSnippet(connPlug, connSlot, securitySystem)
 			if err != nil {
 				return nil, err
 			}
 			if snippet == nil {
 				continue
 			}
+			slotRef := &SlotRef{Snap: slot.Snap.Name(), Name: slot.Name}
 			for appName := range plug.Apps {
-				snippets[appName] = append(snippets[appName], snippet)
+				snippets[appName] = append(snippets[appName], &SecuritySnippet{
+					Snippet: snippet, Interface: plug.Interface, Plug: plugRef, Slot: slotRef,
+				})
 			}
 		}
 	}
 	return snippets, nil
 }
 
+// AnnotateSnippets returns the snippets in the form taken by the security
+// backends, each one preceded by a comment line describing its origin. The
+// comment is made of the origin between commentStart and commentEnd.
+func AnnotateSnippets(snippets map[string][]*SecuritySnippet, commentStart, commentEnd string) map[string][][]byte {
+	annotated := make(map[string][][]byte, len(snippets))
+	for appName, appSnippets := range snippets {
+		for _, snippet := range appSnippets {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "%s%s%s\n", commentStart, snippet.Origin(), commentEnd)
+			buf.Write(snippet.Snippet)
+			annotated[appName] = append(annotated[appName], buf.Bytes())
+		}
+	}
+	return annotated
+}
+
 // BadInterfacesError is returned when some snap interfaces could not be registered.
 // Those interfaces not mentioned in the error were successfully registered.
 type BadInterfacesError struct {
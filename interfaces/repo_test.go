@@ -551,6 +551,10 @@ func (s *RepositorySuite) TestConnectSucceedsWhenIdenticalConnectExists(c *C) {
 			SlotInfo:    s.slot.SlotInfo,
 			Connections: []PlugRef{{s.plug.Snap.Name(), s.plug.Name}},
 		}},
+		Connections: []*Connection{{
+			Plug: PlugRef{s.plug.Snap.Name(), s.plug.Name},
+			Slot: SlotRef{s.slot.Snap.Name(), s.slot.Name},
+		}},
 	})
 }
 
@@ -584,6 +588,162 @@ func (s *RepositorySuite) TestConnectSucceeds(c *C) {
 	c.Assert(err, IsNil)
 }
 
+// Tests for Repository.SetConnectionAttrs() and Repository.ConnectionAttrs()
+
+func (s *RepositorySuite) TestSetConnectionAttrs(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	c.Assert(s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	plugAttrs, slotAttrs, err := s.testRepo.ConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, IsNil)
+	c.Check(slotAttrs, IsNil)
+
+	err = s.testRepo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		map[string]interface{}{"port": 8080}, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	c.Assert(err, IsNil)
+	plugAttrs, slotAttrs, err = s.testRepo.ConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, DeepEquals, map[string]interface{}{"port": 8080})
+	c.Check(slotAttrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	// The attributes are shown along with the connection.
+	c.Check(s.testRepo.Interfaces().Connections, DeepEquals, []*Connection{{
+		Plug:      PlugRef{s.plug.Snap.Name(), s.plug.Name},
+		Slot:      SlotRef{s.slot.Snap.Name(), s.slot.Name},
+		PlugAttrs: map[string]interface{}{"port": 8080},
+		SlotAttrs: map[string]interface{}{"path": "/dev/ttyUSB0"},
+	}})
+
+	// Disconnecting forgets them.
+	c.Assert(s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	c.Assert(s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	plugAttrs, slotAttrs, err = s.testRepo.ConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, IsNil)
+	c.Check(slotAttrs, IsNil)
+}
+
+func (s *RepositorySuite) TestSetConnectionAttrsFailsWhenNotConnected(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	err := s.testRepo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		map[string]interface{}{"port": 8080}, nil)
+	c.Assert(err, ErrorMatches, `cannot set attributes of connection between consumer:plug and producer:slot, not connected`)
+	_, _, err = s.testRepo.ConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, ErrorMatches, `cannot get attributes of connection between consumer:plug and producer:slot, not connected`)
+}
+
+func (s *RepositorySuite) TestSetConnectionAttrsFailsForStaticAttrs(c *C) {
+	c.Assert(s.testRepo.AddPlug(s.plug), IsNil)
+	c.Assert(s.testRepo.AddSlot(s.slot), IsNil)
+	c.Assert(s.testRepo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+	err := s.testRepo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		map[string]interface{}{"attr": "other"}, nil)
+	c.Assert(err, ErrorMatches, `cannot set attribute "attr" of plug consumer:plug, it is already set in snap.yaml`)
+	err = s.testRepo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		nil, map[string]interface{}{"attr": "other"})
+	c.Assert(err, ErrorMatches, `cannot set attribute "attr" of slot producer:slot, it is already set in snap.yaml`)
+}
+
+func (s *RepositorySuite) TestSetConnectionAttrsSanitizesConnection(c *C) {
+	var seenPlug *Plug
+	var seenSlot *Slot
+	repo := NewRepository()
+	iface := &TestInterface{
+		InterfaceName: "interface",
+		SanitizeConnectionCallback: func(plug *Plug, slot *Slot) error {
+			seenPlug, seenSlot = plug, slot
+			if path, _ := slot.Attr("path"); path != "/dev/ttyUSB0" {
+				return fmt.Errorf("unexpected path %v", path)
+			}
+			return nil
+		},
+	}
+	c.Assert(repo.AddInterface(iface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+	c.Assert(repo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+
+	err := repo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		map[string]interface{}{"port": 8080}, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	c.Assert(err, IsNil)
+	// The interface sees the attributes set when connecting.
+	port, _ := seenPlug.Attr("port")
+	c.Check(port, Equals, 8080)
+	attr, _ := seenSlot.Attr("attr")
+	c.Check(attr, Equals, "value")
+
+	// Refused attributes are not stored.
+	err = repo.SetConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name,
+		nil, map[string]interface{}{"path": "/etc/shadow"})
+	c.Assert(err, ErrorMatches, `cannot set attributes of connection between consumer:plug and producer:slot: unexpected path /etc/shadow`)
+	plugAttrs, slotAttrs, err := repo.ConnectionAttrs(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, DeepEquals, map[string]interface{}{"port": 8080})
+	c.Check(slotAttrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	// The plug and slot in the repository are left alone.
+	c.Check(s.plug.DynamicAttrs, IsNil)
+	c.Check(s.slot.DynamicAttrs, IsNil)
+}
+
+func (s *RepositorySuite) TestConnectionAttrsAreGivenToConnectedSnippets(c *C) {
+	var seenSlots []*Slot
+	var seenPlugAttrs []map[string]interface{}
+	iface := &TestInterface{
+		InterfaceName: "dynamic",
+		PlugSnippetCallback: func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
+			seenPlugAttrs = append(seenPlugAttrs, plug.DynamicAttrs)
+			port, _ := plug.Attr("port")
+			path, _ := slot.Attr("path")
+			return []byte(fmt.Sprintf("plug port %v path %v", port, path)), nil
+		},
+		SlotSnippetCallback: func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
+			seenSlots = append(seenSlots, slot)
+			attr, _ := slot.Attr("attr")
+			return []byte(fmt.Sprintf("slot attr %v", attr)), nil
+		},
+	}
+	c.Assert(s.testRepo.AddInterface(iface), IsNil)
+	addPlugsSlots(c, s.testRepo, `
+name: consumer
+apps:
+    app:
+        plugs: [plug]
+plugs:
+    plug:
+        interface: dynamic
+`, `
+name: producer
+apps:
+    app:
+        slots: [slot]
+slots:
+    slot:
+        interface: dynamic
+        attr: value
+`)
+	c.Assert(s.testRepo.Connect("consumer", "plug", "producer", "slot"), IsNil)
+	c.Assert(s.testRepo.SetConnectionAttrs("consumer", "plug", "producer", "slot",
+		map[string]interface{}{"port": 8080}, map[string]interface{}{"path": "/dev/ttyUSB0"}), IsNil)
+
+	snippets, err := s.testRepo.SecuritySnippetsForSnap("consumer", SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, map[string][][]byte{
+		"app": {[]byte("plug port 8080 path /dev/ttyUSB0")},
+	})
+	snippets, err = s.testRepo.SecuritySnippetsForSnap("producer", SecurityAppArmor)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, map[string][][]byte{
+		"app": {[]byte("slot attr value")},
+	})
+	// The plug and slot in the repository are left alone.
+	c.Check(s.testRepo.Plug("consumer", "plug").DynamicAttrs, IsNil)
+	c.Check(s.testRepo.Slot("producer", "slot").DynamicAttrs, IsNil)
+	c.Check(seenPlugAttrs, DeepEquals, []map[string]interface{}{{"port": 8080}})
+	c.Assert(seenSlots, HasLen, 1)
+	c.Check(seenSlots[0].DynamicAttrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+}
+
 // Tests for Repository.Disconnect()
 
 func (s *RepositorySuite) TestDisconnectFailsWhenPlugDoesNotExist(c *C) {
@@ -716,6 +876,10 @@ func (s *RepositorySuite) TestInterfacesSmokeTest(c *C) {
 			SlotInfo:    s.slot.SlotInfo,
 			Connections: []PlugRef{{s.plug.Snap.Name(), s.plug.Name}},
 		}},
+		Connections: []*Connection{{
+			Plug: PlugRef{s.plug.Snap.Name(), s.plug.Name},
+			Slot: SlotRef{s.slot.Snap.Name(), s.slot.Name},
+		}},
 	})
 	// After disconnecting the connections become empty
 	err = s.testRepo.Disconnect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name)
@@ -849,6 +1013,60 @@ func (s *RepositorySuite) TestSecuritySnippetsForSnapFailureWithPermanentSnippet
 	c.Check(snippets, IsNil)
 }
 
+func (s *RepositorySuite) TestAnnotatedSecuritySnippetsForSnap(c *C) {
+	const testSecurity SecuritySystem = "security"
+	iface := &TestInterface{
+		InterfaceName: "interface",
+		PermanentPlugSnippetCallback: func(plug *Plug, securitySystem SecuritySystem) ([]byte, error) {
+			return []byte(`static plug snippet`), nil
+		},
+		PlugSnippetCallback: func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
+			return []byte(`connection-specific plug snippet`), nil
+		},
+		SlotSnippetCallback: func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
+			return []byte(`connection-specific slot snippet`), nil
+		},
+	}
+	repo := s.emptyRepo
+	c.Assert(repo.AddInterface(iface), IsNil)
+	c.Assert(repo.AddPlug(s.plug), IsNil)
+	c.Assert(repo.AddSlot(s.slot), IsNil)
+	c.Assert(repo.Connect(s.plug.Snap.Name(), s.plug.Name, s.slot.Snap.Name(), s.slot.Name), IsNil)
+
+	plugRef := &PlugRef{Snap: "consumer", Name: "plug"}
+	slotRef := &SlotRef{Snap: "producer", Name: "slot"}
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(s.plug.Snap.Name(), testSecurity)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, map[string][]*SecuritySnippet{
+		"app": {
+			{Snippet: []byte(`static plug snippet`), Interface: "interface", Plug: plugRef},
+			{Snippet: []byte(`connection-specific plug snippet`), Interface: "interface", Plug: plugRef, Slot: slotRef},
+		},
+	})
+	c.Check(snippets["app"][0].Origin(), Equals, "plug consumer:plug (interface interface)")
+	c.Check(snippets["app"][1].Origin(), Equals, "connection of plug consumer:plug to slot producer:slot (interface interface)")
+
+	snippets, err = repo.AnnotatedSecuritySnippetsForSnap(s.slot.Snap.Name(), testSecurity)
+	c.Assert(err, IsNil)
+	c.Check(snippets, DeepEquals, map[string][]*SecuritySnippet{
+		"app": {
+			{Snippet: []byte(`connection-specific slot snippet`), Interface: "interface", Plug: plugRef, Slot: slotRef},
+		},
+	})
+	c.Check((&SecuritySnippet{Interface: "interface", Slot: slotRef}).Origin(), Equals, "slot producer:slot (interface interface)")
+}
+
+func (s *RepositorySuite) TestAnnotateSnippets(c *C) {
+	snippets := map[string][]*SecuritySnippet{
+		"app": {
+			{Snippet: []byte("snippet"), Interface: "iface", Plug: &PlugRef{Snap: "consumer", Name: "plug"}},
+		},
+	}
+	c.Check(AnnotateSnippets(snippets, "<!-- ", " -->"), DeepEquals, map[string][][]byte{
+		"app": {[]byte("<!-- plug consumer:plug (interface iface) -->\nsnippet")},
+	})
+}
+
 func (s *RepositorySuite) TestAutoConnectBlacklist(c *C) {
 	// Add two interfaces, one with automatic connections, one with manual
 	repo := s.emptyRepo
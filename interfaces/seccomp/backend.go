@@ -91,6 +91,32 @@ func (b *Backend) Remove(snapName string) error {
 	return nil
 }
 
+// Fingerprint returns the template of the seccomp profiles.
+func (b *Backend) Fingerprint() []byte {
+	return defaultTemplate
+}
+
+// Profiles returns the seccomp profiles of the applications of a given snap,
+// with each snippet annotated with its origin.
+func (b *Backend) Profiles(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(snapName, interfaces.SecuritySecComp)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, devMode, interfaces.AnnotateSnippets(snippets, "# ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected security files for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string][]byte, len(content))
+	for _, appInfo := range snapInfo.Apps {
+		if fileState, ok := content[appInfo.SecurityTag()]; ok {
+			profiles[appInfo.Name] = fileState.Content
+		}
+	}
+	return profiles, nil
+}
+
 // combineSnippets combines security snippets collected from all the interfaces
 // affecting a given snap into a content map applicable to EnsureDirState.
 func (b *Backend) combineSnippets(snapInfo *snap.Info, devMode bool, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
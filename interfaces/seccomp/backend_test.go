@@ -203,6 +203,32 @@ func (s *backendSuite) TestCombineSnippets(c *C) {
 	}
 }
 
+func (s *backendSuite) TestFingerprint(c *C) {
+	restore := seccomp.MockTemplate([]byte("default\n"))
+	defer restore()
+	var fingerprinter interfaces.Fingerprinter = s.backend
+	c.Check(string(fingerprinter.Fingerprint()), Equals, "default\n")
+}
+
+func (s *backendSuite) TestProfiles(c *C) {
+	restore := seccomp.MockTemplate([]byte("default\n"))
+	defer restore()
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("snippet"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYamlV1))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	var profiler interfaces.SecurityProfiler = s.backend
+	profiles, err := profiler.Profiles(snapInfo, true, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{
+		"smbd": []byte("@complain\ndefault\n# slot samba:iface (interface iface)\nsnippet\n"),
+	})
+	_, err = os.Stat(filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd"))
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
 // Support code for tests
 
 // installSnap "installs" a snap from YAML.
@@ -62,3 +62,14 @@ func (c bySlotSnapAndName) Less(i, j int) bool {
 	}
 	return c[i].Name < c[j].Name
 }
+
+type byConnection []*Connection
+
+func (c byConnection) Len() int      { return len(c) }
+func (c byConnection) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byConnection) Less(i, j int) bool {
+	if c[i].Plug != c[j].Plug {
+		return byPlugRef{c[i].Plug, c[j].Plug}.Less(0, 1)
+	}
+	return bySlotRef{c[i].Slot, c[j].Slot}.Less(0, 1)
+}
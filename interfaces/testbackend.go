@@ -33,6 +33,8 @@ type TestSecurityBackend struct {
 	SetupCallback func(snapInfo *snap.Info, developerMode bool, repo *Repository) error
 	// RemoveCallback is a callback that is optionally called in Remove
 	RemoveCallback func(snapName string) error
+	// ProfilesCallback is a callback that is optionally called in Profiles
+	ProfilesCallback func(snapInfo *snap.Info, developerMode bool, repo *Repository) (map[string][]byte, error)
 }
 
 // TestSetupCall stores details about calls to TestSecurityBackend.Setup
@@ -41,6 +43,8 @@ type TestSetupCall struct {
 	SnapInfo *snap.Info
 	// DevMode is a copy of the developerMode argument to a particular call to Setup
 	DevMode bool
+	// Reload is set for calls to SetupAndReload
+	Reload bool
 }
 
 // Name returns the name of the security backend.
@@ -57,6 +61,15 @@ func (b *TestSecurityBackend) Setup(snapInfo *snap.Info, devMode bool, repo *Rep
 	return b.SetupCallback(snapInfo, devMode, repo)
 }
 
+// SetupAndReload records information about the call and calls the setup callback if one is defined.
+func (b *TestSecurityBackend) SetupAndReload(snapInfo *snap.Info, devMode bool, repo *Repository) error {
+	b.SetupCalls = append(b.SetupCalls, TestSetupCall{SnapInfo: snapInfo, DevMode: devMode, Reload: true})
+	if b.SetupCallback == nil {
+		return nil
+	}
+	return b.SetupCallback(snapInfo, devMode, repo)
+}
+
 // Remove records information about the call and calls the remove callback if one is defined
 func (b *TestSecurityBackend) Remove(snapName string) error {
 	b.RemoveCalls = append(b.RemoveCalls, snapName)
@@ -65,3 +78,11 @@ func (b *TestSecurityBackend) Remove(snapName string) error {
 	}
 	return b.RemoveCallback(snapName)
 }
+
+// Profiles calls the profiles callback if one is defined.
+func (b *TestSecurityBackend) Profiles(snapInfo *snap.Info, devMode bool, repo *Repository) (map[string][]byte, error) {
+	if b.ProfilesCallback == nil {
+		return nil, nil
+	}
+	return b.ProfilesCallback(snapInfo, devMode, repo)
+}
@@ -21,6 +21,8 @@ package interfaces
 
 import (
 	"fmt"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
 )
 
 // TestInterface is a interface for various kind of tests.
@@ -35,6 +37,8 @@ type TestInterface struct {
 	SanitizePlugCallback func(plug *Plug) error
 	// SanitizeSlotCallback is the callback invoked inside SanitizeSlot()
 	SanitizeSlotCallback func(slot *Slot) error
+	// SanitizeConnectionCallback is the callback invoked inside SanitizeConnection()
+	SanitizeConnectionCallback func(plug *Plug, slot *Slot) error
 	// SlotSnippetCallback is the callback invoked inside ConnectedSlotSnippet()
 	SlotSnippetCallback func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error)
 	// PermanentSlotSnippetCallback is the callback invoked inside PermanentSlotSnippet()
@@ -43,6 +47,8 @@ type TestInterface struct {
 	PlugSnippetCallback func(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error)
 	// PermanentPlugSnippetCallback is the callback invoked inside PermanentPlugSnippet()
 	PermanentPlugSnippetCallback func(plug *Plug, securitySystem SecuritySystem) ([]byte, error)
+	// HotplugDeviceDetectedCallback is the callback invoked inside HotplugDeviceDetected()
+	HotplugDeviceDetectedCallback func(dev *hotplug.DeviceInfo) (*hotplug.SlotSpec, error)
 }
 
 // String() returns the same value as Name().
@@ -77,6 +83,14 @@ func (t *TestInterface) SanitizeSlot(slot *Slot) error {
 	return nil
 }
 
+// SanitizeConnection checks the plug and slot of a connection.
+func (t *TestInterface) SanitizeConnection(plug *Plug, slot *Slot) error {
+	if t.SanitizeConnectionCallback != nil {
+		return t.SanitizeConnectionCallback(plug, slot)
+	}
+	return nil
+}
+
 // ConnectedPlugSnippet returns the configuration snippet "required" to offer a test plug.
 // Providers don't gain any extra permissions.
 func (t *TestInterface) ConnectedPlugSnippet(plug *Plug, slot *Slot, securitySystem SecuritySystem) ([]byte, error) {
@@ -119,3 +133,12 @@ func (t *TestInterface) PermanentSlotSnippet(slot *Slot, securitySystem Security
 func (t *TestInterface) AutoConnect() bool {
 	return t.AutoConnectFlag
 }
+
+// HotplugDeviceDetected returns the slot to create for a hotplugged device.
+// Test interfaces handle no devices unless given a callback.
+func (t *TestInterface) HotplugDeviceDetected(dev *hotplug.DeviceInfo) (*hotplug.SlotSpec, error) {
+	if t.HotplugDeviceDetectedCallback != nil {
+		return t.HotplugDeviceDetectedCallback(dev)
+	}
+	return nil, nil
+}
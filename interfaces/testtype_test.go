@@ -25,6 +25,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	. "github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -154,3 +155,19 @@ func (s *TestInterfaceSuite) TestAutoConnect(c *C) {
 	iface := &TestInterface{AutoConnectFlag: true}
 	c.Assert(iface.AutoConnect(), Equals, true)
 }
+
+func (s *TestInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	dev := &hotplug.DeviceInfo{DevPath: "/devices/foo"}
+	spec, err := s.iface.(hotplug.Definer).HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Assert(spec, IsNil)
+
+	iface := &TestInterface{
+		HotplugDeviceDetectedCallback: func(dev *hotplug.DeviceInfo) (*hotplug.SlotSpec, error) {
+			return &hotplug.SlotSpec{Name: "foo"}, nil
+		},
+	}
+	spec, err = iface.HotplugDeviceDetected(dev)
+	c.Assert(err, IsNil)
+	c.Assert(spec, DeepEquals, &hotplug.SlotSpec{Name: "foo"})
+}
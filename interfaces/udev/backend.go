@@ -91,6 +91,28 @@ func ensureDirState(dir, glob string, content map[string]*osutil.FileState, snap
 	return errReload
 }
 
+// Profiles returns the udev rules of the applications of a given snap,
+// with each snippet annotated with its origin. Applications without snippets
+// have none.
+func (b *Backend) Profiles(snapInfo *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+	snapName := snapInfo.Name()
+	snippets, err := repo.AnnotatedSecuritySnippetsForSnap(snapName, interfaces.SecurityUDev)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain udev security snippets for snap %q: %s", snapName, err)
+	}
+	content, err := b.combineSnippets(snapInfo, interfaces.AnnotateSnippets(snippets, "# ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain expected udev rules for snap %q: %s", snapName, err)
+	}
+	profiles := make(map[string][]byte, len(content))
+	for _, appInfo := range snapInfo.Apps {
+		if fileState, ok := content[fmt.Sprintf("70-%s.rules", appInfo.SecurityTag())]; ok {
+			profiles[appInfo.Name] = fileState.Content
+		}
+	}
+	return profiles, nil
+}
+
 // combineSnippets combines security snippets collected from all the interfaces
 // affecting a given snap into a content map applicable to EnsureDirState.
 func (b *Backend) combineSnippets(snapInfo *snap.Info, snippets map[string][][]byte) (content map[string]*osutil.FileState, err error) {
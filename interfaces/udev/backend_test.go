@@ -227,6 +227,31 @@ func (s *backendSuite) TestCombineSnippetsWithoutAnySnippets(c *C) {
 	}
 }
 
+func (s *backendSuite) TestProfiles(c *C) {
+	s.iface.PermanentSlotSnippetCallback = func(slot *interfaces.Slot, securitySystem interfaces.SecuritySystem) ([]byte, error) {
+		return []byte("dummy"), nil
+	}
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYamlV1))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	profiles, err := s.backend.(interfaces.SecurityProfiler).Profiles(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{
+		"smbd": []byte("# This file is automatically generated.\n# slot samba:iface (interface iface)\ndummy\n"),
+	})
+	// udev is not asked to reload anything
+	c.Check(s.udevadmCmd.Calls(), HasLen, 0)
+}
+
+func (s *backendSuite) TestProfilesWithoutAnySnippets(c *C) {
+	snapInfo, err := snap.InfoFromSnapYaml([]byte(sambaYamlV1))
+	c.Assert(err, IsNil)
+	c.Assert(s.repo.AddSnap(snapInfo), IsNil)
+	profiles, err := s.backend.(interfaces.SecurityProfiler).Profiles(snapInfo, false, s.repo)
+	c.Assert(err, IsNil)
+	c.Check(profiles, HasLen, 0)
+}
+
 // Support code for tests
 
 // installSnap "installs" a snap from YAML.
@@ -0,0 +1,83 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package osutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+var validUsername = regexp.MustCompile(`^[a-z0-9][-a-z0-9+._]*$`)
+
+// AddExtraUser creates a new user in the extrausers database (see
+// libnss-extrausers), with the given full name (gecos) and, if
+// not empty, the given crypt(3) password hash. The ssh keys are
+// written to the authorized keys of the user.
+func AddExtraUser(name string, sshKeys []string, gecos, password string) error {
+	if !validUsername.MatchString(name) {
+		return fmt.Errorf("cannot add user %q: name contains invalid characters", name)
+	}
+
+	cmd := exec.Command("adduser",
+		"--force-badname",
+		"--gecos", gecos,
+		"--extrausers",
+		"--disabled-password",
+		name)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("adduser failed with %s: %s", err, output)
+	}
+
+	if password != "" {
+		// usermod updates the extrausers database as well
+		cmd := exec.Command("usermod", "--password", password, name)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("usermod failed with %s: %s", err, output)
+		}
+	}
+
+	if len(sshKeys) == 0 {
+		return nil
+	}
+
+	sshDir := filepath.Join(dirs.GlobalRootDir, "home", name, ".ssh")
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		return fmt.Errorf("cannot create %s: %s", sshDir, err)
+	}
+	authKeys := filepath.Join(sshDir, "authorized_keys")
+	authKeysContent := strings.Join(sshKeys, "\n") + "\n"
+	if err := AtomicWriteFile(authKeys, []byte(authKeysContent), 0600, 0); err != nil {
+		return fmt.Errorf("cannot write %s: %s", authKeys, err)
+	}
+
+	// the user is only known to nss, let chown resolve it
+	cmd = exec.Command("chown", "-R", name+":"+name, sshDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("chown failed with %s: %s", err, output)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package osutil_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type addUserSuite struct {
+	mockAdduser *testutil.MockCmd
+	mockUsermod *testutil.MockCmd
+	mockChown   *testutil.MockCmd
+}
+
+var _ = Suite(&addUserSuite{})
+
+func (s *addUserSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.mockAdduser = testutil.MockCommand(c, "adduser", "")
+	s.mockUsermod = testutil.MockCommand(c, "usermod", "")
+	s.mockChown = testutil.MockCommand(c, "chown", "")
+}
+
+func (s *addUserSuite) TearDownTest(c *C) {
+	s.mockAdduser.Restore()
+	s.mockUsermod.Restore()
+	s.mockChown.Restore()
+	dirs.SetRootDir("/")
+}
+
+func (s *addUserSuite) TestAddExtraUser(c *C) {
+	err := osutil.AddExtraUser("karl", []string{"ssh-key1", "ssh-key2"}, "my gecos", "$6$salt$hash")
+	c.Assert(err, IsNil)
+
+	c.Check(s.mockAdduser.Calls(), DeepEquals, []string{
+		"--force-badname --gecos my gecos --extrausers --disabled-password karl",
+	})
+	c.Check(s.mockUsermod.Calls(), DeepEquals, []string{
+		"--password $6$salt$hash karl",
+	})
+	sshDir := filepath.Join(dirs.GlobalRootDir, "home", "karl", ".ssh")
+	c.Check(s.mockChown.Calls(), DeepEquals, []string{
+		"-R karl:karl " + sshDir,
+	})
+
+	content, err := ioutil.ReadFile(filepath.Join(sshDir, "authorized_keys"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "ssh-key1\nssh-key2\n")
+}
+
+func (s *addUserSuite) TestAddExtraUserNoPasswordNoKeys(c *C) {
+	err := osutil.AddExtraUser("karl", nil, "", "")
+	c.Assert(err, IsNil)
+
+	c.Check(s.mockAdduser.Calls(), HasLen, 1)
+	c.Check(s.mockUsermod.Calls(), HasLen, 0)
+	c.Check(s.mockChown.Calls(), HasLen, 0)
+	c.Check(osutil.FileExists(filepath.Join(dirs.GlobalRootDir, "home", "karl", ".ssh")), Equals, false)
+}
+
+func (s *addUserSuite) TestAddExtraUserInvalidName(c *C) {
+	err := osutil.AddExtraUser("k!arl", nil, "", "")
+	c.Assert(err, ErrorMatches, `cannot add user "k!arl": name contains invalid characters`)
+	c.Check(s.mockAdduser.Calls(), HasLen, 0)
+}
+
+func (s *addUserSuite) TestAddExtraUserFails(c *C) {
+	s.mockAdduser.Restore()
+	s.mockAdduser = testutil.MockCommand(c, "adduser", "echo some error; exit 1")
+	err := osutil.AddExtraUser("karl", nil, "", "")
+	c.Assert(err, ErrorMatches, "adduser failed with exit status 1: some error\n")
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/release"
 
 	"github.com/snapcore/snapd/overlord/state"
 )
@@ -55,9 +56,43 @@ func Manager(s *state.State) (*AssertManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.Lock()
+	ReplaceDB(s, db)
+	s.Unlock()
 	return &AssertManager{db: db}, nil
 }
 
+type cachedDBKey struct{}
+
+// ReplaceDB replaces the assertion database used by the manager of the
+// given state, as returned by DB. The state must be locked by the caller.
+func ReplaceDB(s *state.State, db *asserts.Database) {
+	s.Cache(cachedDBKey{}, db)
+}
+
+// DB returns the assertion database of the given state. The state must
+// be locked by the caller.
+func DB(s *state.State) *asserts.Database {
+	db := s.Cached(cachedDBKey{})
+	if db == nil {
+		panic("internal error: needing an assertion database before the assertion manager is initialized")
+	}
+	return db.(*asserts.Database)
+}
+
+// SnapDeclaration returns the snap-declaration for the given snap id
+// from the assertion database of the state.
+func SnapDeclaration(s *state.State, snapID string) (*asserts.SnapDeclaration, error) {
+	a, err := DB(s).Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  release.Series,
+		"snap-id": snapID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.SnapDeclaration), nil
+}
+
 // Ensure implements StateManager.Ensure.
 func (m *AssertManager) Ensure() error {
 	return nil
@@ -48,4 +48,15 @@ func (ams *assertMgrSuite) TestManagerAndDB(c *C) {
 
 	db := mgr.DB()
 	c.Check(db, FitsTypeOf, (*asserts.Database)(nil))
+
+	s.Lock()
+	defer s.Unlock()
+	c.Check(assertstate.DB(s), Equals, db)
+}
+
+func (ams *assertMgrSuite) TestDBWithoutManager(c *C) {
+	s := state.New(nil)
+	s.Lock()
+	defer s.Unlock()
+	c.Check(func() { assertstate.DB(s) }, PanicMatches, "internal error: needing an assertion database .*")
 }
@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// Outcomes of adding an assertion with AddMany.
+const (
+	Added                = "added"
+	AlreadyPresent       = "already-present"
+	NewerRevisionPresent = "newer-revision-present"
+)
+
+// AddResult holds the outcome of adding one assertion with AddMany.
+type AddResult struct {
+	Ref     *asserts.Ref
+	Outcome string
+}
+
+// AddMany adds the given assertions, such as those of a bundle, to db,
+// each after its prerequisites. Prerequisites missing from both db and
+// the given assertions are retrieved with retrieve, which can be nil.
+// The account-keys needed to verify the given assertions are retrieved
+// first, and nothing is added if any of the signatures fails
+// verification. The outcome for each assertion is returned, in the order
+// they were added in.
+//
+// Other failures, such as a consistency check failing or a prerequisite
+// that cannot be found, only show up while adding: the assertions added
+// before the failing one are then left in db.
+func AddMany(db *asserts.Database, assertions []asserts.Assertion, retrieve func(*asserts.Ref) (asserts.Assertion, error)) ([]*AddResult, error) {
+	sorted := sortByDependency(assertions)
+	keys, err := checkSignatures(db, sorted, retrieve)
+	if err != nil {
+		return nil, err
+	}
+
+	f := NewFetcher(db, retrieve)
+	f.Supply(keys...)
+	f.Supply(sorted...)
+	results := make([]*AddResult, 0, len(sorted))
+	for _, a := range sorted {
+		ref := a.Ref()
+		outcome := Added
+		cur, err := ref.Resolve(db.Find)
+		switch {
+		case err == nil && cur.Revision() == a.Revision():
+			outcome = AlreadyPresent
+		case err == nil && cur.Revision() > a.Revision():
+			outcome = NewerRevisionPresent
+		case err != nil && err != asserts.ErrNotFound:
+			return nil, err
+		default:
+			if err := f.Save(a); err != nil {
+				return nil, fmt.Errorf("cannot add %s: %v", ref, err)
+			}
+		}
+		results = append(results, &AddResult{Ref: ref, Outcome: outcome})
+	}
+	return results, nil
+}
+
+// sortByDependency returns the given assertions sorted so that each comes
+// after those among them it depends on, otherwise keeping their order.
+func sortByDependency(assertions []asserts.Assertion) []asserts.Assertion {
+	byRef := make(map[string]asserts.Assertion, len(assertions))
+	for _, a := range assertions {
+		u := a.Ref().Unique()
+		if byRef[u] == nil {
+			byRef[u] = a
+		}
+	}
+
+	sorted := make([]asserts.Assertion, 0, len(assertions))
+	visited := make(map[asserts.Assertion]bool, len(assertions))
+	var visit func(a asserts.Assertion)
+	visit = func(a asserts.Assertion) {
+		if visited[a] {
+			return
+		}
+		visited[a] = true
+		// a broken signature is reported by checkSignatures
+		prereqs, _ := prerequisites(a)
+		for _, ref := range prereqs {
+			if prereq := byRef[ref.Unique()]; prereq != nil {
+				visit(prereq)
+			}
+		}
+		sorted = append(sorted, a)
+	}
+	for _, a := range assertions {
+		visit(a)
+	}
+	return sorted
+}
+
+// checkSignatures verifies the signatures of the given assertions, sorted
+// by dependency, with the account-keys among them or in db. Keys found in
+// neither are retrieved with retrieve, which can be nil, and checked in
+// turn, so that every signature is verified before anything is added. The
+// retrieved keys are returned.
+func checkSignatures(db *asserts.Database, sorted []asserts.Assertion, retrieve func(*asserts.Ref) (asserts.Assertion, error)) ([]asserts.Assertion, error) {
+	keys := make(map[string]*asserts.AccountKey)
+	var retrieved []asserts.Assertion
+	var check func(a asserts.Assertion) error
+	check = func(a asserts.Assertion) error {
+		keyID, err := asserts.SignKeyID(a)
+		if err != nil {
+			return fmt.Errorf("cannot verify signature of %s: %v", a.Ref(), err)
+		}
+		keyRef := &asserts.Ref{
+			Type:       asserts.AccountKeyType,
+			PrimaryKey: []string{a.AuthorityID(), keyID},
+		}
+		u := keyRef.Unique()
+		key := keys[u]
+		if key == nil {
+			found, err := keyRef.Resolve(db.Find)
+			if err != nil && err != asserts.ErrNotFound {
+				return err
+			}
+			if found != nil {
+				key = found.(*asserts.AccountKey)
+			}
+		}
+		if key == nil {
+			if retrieve == nil {
+				return fmt.Errorf("cannot verify signature of %s: cannot find %s", a.Ref(), keyRef)
+			}
+			found, err := retrieve(keyRef)
+			if err != nil {
+				return fmt.Errorf("cannot verify signature of %s: cannot find %s: %v", a.Ref(), keyRef, err)
+			}
+			accKey, ok := found.(*asserts.AccountKey)
+			if !ok || accKey.Ref().Unique() != u {
+				return fmt.Errorf("cannot verify signature of %s: retrieved assertion is not %s", a.Ref(), keyRef)
+			}
+			keys[u] = accKey
+			if err := check(accKey); err != nil {
+				return err
+			}
+			retrieved = append(retrieved, accKey)
+			key = accKey
+		}
+		if err := asserts.CheckSignedBy(a, key); err != nil {
+			return fmt.Errorf("cannot verify signature of %s: %v", a.Ref(), err)
+		}
+		return nil
+	}
+	for _, a := range sorted {
+		if err := check(a); err != nil {
+			return nil, err
+		}
+		if accKey, ok := a.(*asserts.AccountKey); ok {
+			keys[accKey.Ref().Unique()] = accKey
+		}
+	}
+	return retrieved, nil
+}
@@ -0,0 +1,146 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate_test
+
+import (
+	"bytes"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+)
+
+func refStrings(results []*assertstate.AddResult) []string {
+	strs := make([]string, len(results))
+	for i, res := range results {
+		strs[i] = res.Ref.String() + ": " + res.Outcome
+	}
+	return strs
+}
+
+func (s *fetcherSuite) TestAddManySortsByDependency(c *C) {
+	results, err := assertstate.AddMany(s.db, []asserts.Assertion{s.snapRev, s.snapDecl, s.devAcct, s.devAccKey}, nil)
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapRev, s.snapDecl, s.devAcct, s.devAccKey)
+	c.Check(refStrings(results), DeepEquals, []string{
+		"account (dev-id): added",
+		"account-key (dev-id; " + s.devAccKey.(*asserts.AccountKey).PublicKeyID() + "): added",
+		"snap-declaration (16; snap-id-1): added",
+		"snap-revision (16; snap-id-1; sha512-AAAA): added",
+	})
+}
+
+func (s *fetcherSuite) TestAddManyOutcomes(c *C) {
+	newerAcct, err := s.storeSigning.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "dev-id",
+		"username":     "dev",
+		"display-name": "Dev Eloper",
+		"validation":   "unproven",
+		"revision":     "1",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(newerAcct), IsNil)
+	c.Assert(s.db.Add(s.devAccKey), IsNil)
+
+	results, err := assertstate.AddMany(s.db, []asserts.Assertion{s.snapDecl, s.devAccKey, s.devAcct}, nil)
+	c.Assert(err, IsNil)
+	c.Check(refStrings(results), DeepEquals, []string{
+		"account (dev-id): newer-revision-present",
+		"account-key (dev-id; " + s.devAccKey.(*asserts.AccountKey).PublicKeyID() + "): already-present",
+		"snap-declaration (16; snap-id-1): added",
+	})
+	acct, err := s.devAcct.Ref().Resolve(s.db.Find)
+	c.Assert(err, IsNil)
+	c.Check(acct.Revision(), Equals, 1)
+}
+
+func (s *fetcherSuite) TestAddManyRetrieves(c *C) {
+	var retrieved []string
+	results, err := assertstate.AddMany(s.db, []asserts.Assertion{s.snapDecl, s.devAccKey}, s.retrieveFrom(&retrieved, s.devAcct))
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapDecl, s.devAcct, s.devAccKey)
+	c.Check(retrieved, DeepEquals, []string{"account (dev-id)"})
+	// only the given assertions have an outcome
+	c.Check(results, HasLen, 2)
+}
+
+func (s *fetcherSuite) TestAddManyBadSignatureAddsNothing(c *C) {
+	tampered, err := asserts.Decode(bytes.Replace(asserts.Encode(s.snapDecl), []byte("snap-name: foo\n"), []byte("snap-name: bar\n"), 1))
+	c.Assert(err, IsNil)
+
+	_, err = assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, s.devAccKey, tampered}, nil)
+	c.Assert(err, ErrorMatches, `cannot verify signature of snap-declaration \(16; snap-id-1\): failed signature verification: .*`)
+	_, err = s.devAcct.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (s *fetcherSuite) TestAddManyRetrievesKeysBeforeAdding(c *C) {
+	var retrieved []string
+	results, err := assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, s.snapDecl}, s.retrieveFrom(&retrieved, s.devAccKey))
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapDecl, s.devAcct, s.devAccKey)
+	// the key is retrieved only once
+	c.Check(retrieved, DeepEquals, []string{"account-key (dev-id; " + s.devAccKey.(*asserts.AccountKey).PublicKeyID() + ")"})
+	c.Check(results, HasLen, 2)
+}
+
+func (s *fetcherSuite) TestAddManyBadSignatureByRetrievedKeyAddsNothing(c *C) {
+	tampered, err := asserts.Decode(bytes.Replace(asserts.Encode(s.snapDecl), []byte("snap-name: foo\n"), []byte("snap-name: bar\n"), 1))
+	c.Assert(err, IsNil)
+
+	var retrieved []string
+	_, err = assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, tampered}, s.retrieveFrom(&retrieved, s.devAccKey))
+	c.Assert(err, ErrorMatches, `cannot verify signature of snap-declaration \(16; snap-id-1\): failed signature verification: .*`)
+	_, err = s.devAcct.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+	_, err = s.devAccKey.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (s *fetcherSuite) TestAddManyMissingKeyAddsNothing(c *C) {
+	_, err := assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, s.snapDecl}, nil)
+	c.Assert(err, ErrorMatches, `cannot verify signature of snap-declaration \(16; snap-id-1\): cannot find account-key \(dev-id; .*\)`)
+	_, err = s.devAcct.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+
+	var retrieved []string
+	_, err = assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, s.snapDecl}, s.retrieveFrom(&retrieved))
+	c.Assert(err, ErrorMatches, `cannot verify signature of snap-declaration \(16; snap-id-1\): cannot find account-key \(dev-id; .*\): not there`)
+	_, err = s.devAcct.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (s *fetcherSuite) TestAddManyFails(c *C) {
+	_, err := assertstate.AddMany(s.db, []asserts.Assertion{s.snapDecl, s.devAccKey}, nil)
+	c.Assert(err, ErrorMatches, `cannot add account-key \(dev-id; .*\): cannot find account \(dev-id\)`)
+}
+
+func (s *fetcherSuite) TestAddManyMissingPrerequisiteAddsPart(c *C) {
+	// the signatures check out but the snap-declaration is missing
+	_, err := assertstate.AddMany(s.db, []asserts.Assertion{s.devAcct, s.devAccKey, s.snapRev}, nil)
+	c.Assert(err, ErrorMatches, `cannot add snap-revision \(16; snap-id-1; sha512-AAAA\): cannot find snap-declaration \(16; snap-id-1\)`)
+	// what came before is left added
+	s.checkInDB(c, s.devAcct, s.devAccKey)
+	_, err = s.snapRev.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
@@ -0,0 +1,153 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+// A Fetcher adds assertions to an assertion database along with their
+// prerequisites: the account-key that signed each of them, and the
+// assertions each refers to. Prerequisites missing from the database are
+// looked up among the supplied assertions first and then retrieved, e.g.
+// from the store. Every assertion is added after its prerequisites.
+type Fetcher struct {
+	known        func(*asserts.Ref) (bool, error)
+	retrieve     func(*asserts.Ref) (asserts.Assertion, error)
+	save         func(asserts.Assertion) error
+	skipNotFound bool
+	supplied     map[string]asserts.Assertion
+	fetching     map[string]bool
+}
+
+// NewFetcher returns a fetcher adding to db and retrieving the assertions
+// neither in db nor supplied with retrieve, which can be nil.
+func NewFetcher(db *asserts.Database, retrieve func(*asserts.Ref) (asserts.Assertion, error)) *Fetcher {
+	known := func(ref *asserts.Ref) (bool, error) {
+		_, err := ref.Resolve(db.Find)
+		if err == asserts.ErrNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return newFetcher(known, retrieve, db.Add)
+}
+
+// NewSavingFetcher returns a fetcher handing the assertions it retrieves
+// to save, each once and after its prerequisites, instead of adding them
+// to a database, for example to write them out for another device. The
+// assertions retrieve reports as asserts.ErrNotFound are skipped, being
+// those, like the trusted ones, that every device knows already.
+func NewSavingFetcher(retrieve func(*asserts.Ref) (asserts.Assertion, error), save func(asserts.Assertion) error) *Fetcher {
+	saved := make(map[string]bool)
+	known := func(ref *asserts.Ref) (bool, error) {
+		return saved[ref.Unique()], nil
+	}
+	f := newFetcher(known, retrieve, func(a asserts.Assertion) error {
+		saved[a.Ref().Unique()] = true
+		return save(a)
+	})
+	f.skipNotFound = true
+	return f
+}
+
+func newFetcher(known func(*asserts.Ref) (bool, error), retrieve func(*asserts.Ref) (asserts.Assertion, error), save func(asserts.Assertion) error) *Fetcher {
+	return &Fetcher{
+		known:    known,
+		retrieve: retrieve,
+		save:     save,
+		supplied: make(map[string]asserts.Assertion),
+		fetching: make(map[string]bool),
+	}
+}
+
+// Supply makes the given assertions, such as those of a bundle, available
+// to the fetcher as prerequisites. They are not added by themselves.
+func (f *Fetcher) Supply(assertions ...asserts.Assertion) {
+	for _, a := range assertions {
+		f.supplied[a.Ref().Unique()] = a
+	}
+}
+
+// Fetch adds the assertion ref refers to, unless already in the database,
+// after its prerequisites.
+func (f *Fetcher) Fetch(ref *asserts.Ref) error {
+	known, err := f.known(ref)
+	if err != nil {
+		return err
+	}
+	if known {
+		return nil
+	}
+	u := ref.Unique()
+	if f.fetching[u] {
+		return fmt.Errorf("cannot fetch %s: circular prerequisites", ref)
+	}
+	a := f.supplied[u]
+	if a == nil {
+		if f.retrieve == nil {
+			return fmt.Errorf("cannot find %s", ref)
+		}
+		a, err = f.retrieve(ref)
+		if err == asserts.ErrNotFound && f.skipNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot find %s: %v", ref, err)
+		}
+	}
+	f.fetching[u] = true
+	defer delete(f.fetching, u)
+	return f.Save(a)
+}
+
+// Save adds the given assertion after its prerequisites.
+func (f *Fetcher) Save(a asserts.Assertion) error {
+	prereqs, err := prerequisites(a)
+	if err != nil {
+		return err
+	}
+	for _, ref := range prereqs {
+		if err := f.Fetch(ref); err != nil {
+			return err
+		}
+	}
+	return f.save(a)
+}
+
+// prerequisites returns references to the account-key that signed the
+// given assertion, unless it is that key itself as with the trusted ones,
+// and to the other assertions it refers to.
+func prerequisites(a asserts.Assertion) ([]*asserts.Ref, error) {
+	keyID, err := asserts.SignKeyID(a)
+	if err != nil {
+		return nil, err
+	}
+	signKey := &asserts.Ref{
+		Type:       asserts.AccountKeyType,
+		PrimaryKey: []string{a.AuthorityID(), keyID},
+	}
+	if signKey.Unique() == a.Ref().Unique() {
+		return a.Prerequisites(), nil
+	}
+	return append([]*asserts.Ref{signKey}, a.Prerequisites()...), nil
+}
@@ -0,0 +1,224 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate_test
+
+import (
+	"errors"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/assertstate"
+)
+
+type fetcherSuite struct {
+	storeSigning *assertstest.SigningDB
+	db           *asserts.Database
+	rootAccKey   asserts.Assertion
+
+	devAcct   asserts.Assertion
+	devAccKey asserts.Assertion
+	snapDecl  asserts.Assertion
+	snapRev   asserts.Assertion
+}
+
+var _ = Suite(&fetcherSuite{})
+
+func (s *fetcherSuite) SetUpTest(c *C) {
+	rootPrivKey := assertstest.GenerateKey(752)
+	s.storeSigning = assertstest.NewSigningDB("canonical", rootPrivKey)
+	rootAccKey := assertstest.NewAccountKey(s.storeSigning, "canonical", rootPrivKey.PublicKey())
+	s.rootAccKey = rootAccKey
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		TrustedKeys:    []*asserts.AccountKey{rootAccKey},
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+
+	now := time.Now().Format(time.RFC3339)
+	devPrivKey := assertstest.GenerateKey(752)
+	devSigning := assertstest.NewSigningDB("dev-id", devPrivKey)
+	s.devAccKey = assertstest.NewAccountKey(s.storeSigning, "dev-id", devPrivKey.PublicKey())
+	s.devAcct, err = s.storeSigning.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "dev-id",
+		"username":     "dev",
+		"display-name": "Dev",
+		"validation":   "unproven",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	// signed by the developer to need their account-key
+	s.snapDecl, err = devSigning.Sign(asserts.SnapDeclarationType, map[string]string{
+		"series":       "16",
+		"snap-id":      "snap-id-1",
+		"snap-name":    "foo",
+		"publisher-id": "dev-id",
+		"gates":        "",
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	s.snapRev, err = s.storeSigning.Sign(asserts.SnapRevisionType, map[string]string{
+		"series":        "16",
+		"snap-id":       "snap-id-1",
+		"snap-digest":   "sha512-AAAA",
+		"snap-size":     "123",
+		"snap-revision": "1",
+		"developer-id":  "dev-id",
+		"timestamp":     now,
+	}, nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *fetcherSuite) retrieveFrom(retrieved *[]string, assertions ...asserts.Assertion) func(*asserts.Ref) (asserts.Assertion, error) {
+	return func(ref *asserts.Ref) (asserts.Assertion, error) {
+		*retrieved = append(*retrieved, ref.String())
+		for _, a := range assertions {
+			if a.Ref().Unique() == ref.Unique() {
+				return a, nil
+			}
+		}
+		return nil, errors.New("not there")
+	}
+}
+
+func (s *fetcherSuite) checkInDB(c *C, assertions ...asserts.Assertion) {
+	for _, a := range assertions {
+		_, err := a.Ref().Resolve(s.db.Find)
+		c.Check(err, IsNil, Commentf("%s", a.Ref()))
+	}
+}
+
+func (s *fetcherSuite) TestSaveSupplied(c *C) {
+	f := assertstate.NewFetcher(s.db, nil)
+	// in an order needing reordering
+	f.Supply(s.snapRev, s.snapDecl, s.devAcct, s.devAccKey)
+
+	err := f.Save(s.snapRev)
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapRev, s.snapDecl, s.devAcct, s.devAccKey)
+}
+
+func (s *fetcherSuite) TestFetchRetrieves(c *C) {
+	var retrieved []string
+	f := assertstate.NewFetcher(s.db, s.retrieveFrom(&retrieved, s.snapRev, s.snapDecl, s.devAcct, s.devAccKey))
+
+	err := f.Fetch(s.snapRev.Ref())
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapRev, s.snapDecl, s.devAcct, s.devAccKey)
+	c.Check(retrieved, DeepEquals, []string{
+		"snap-revision (16; snap-id-1; sha512-AAAA)",
+		"snap-declaration (16; snap-id-1)",
+		"account-key (dev-id; " + s.devAccKey.(*asserts.AccountKey).PublicKeyID() + ")",
+		"account (dev-id)",
+	})
+}
+
+func (s *fetcherSuite) TestFetchPrefersSupplied(c *C) {
+	var retrieved []string
+	f := assertstate.NewFetcher(s.db, s.retrieveFrom(&retrieved, s.snapDecl, s.devAcct))
+	f.Supply(s.devAccKey)
+
+	err := f.Fetch(s.snapDecl.Ref())
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapDecl, s.devAcct, s.devAccKey)
+	c.Check(retrieved, DeepEquals, []string{
+		"snap-declaration (16; snap-id-1)",
+		"account (dev-id)",
+	})
+}
+
+func (s *fetcherSuite) TestFetchSkipsWhatIsInDB(c *C) {
+	c.Assert(s.db.Add(s.devAcct), IsNil)
+	c.Assert(s.db.Add(s.devAccKey), IsNil)
+
+	var retrieved []string
+	f := assertstate.NewFetcher(s.db, s.retrieveFrom(&retrieved, s.snapDecl))
+
+	err := f.Fetch(s.snapDecl.Ref())
+	c.Assert(err, IsNil)
+	s.checkInDB(c, s.snapDecl)
+	c.Check(retrieved, DeepEquals, []string{"snap-declaration (16; snap-id-1)"})
+
+	// nothing to do the second time around
+	retrieved = nil
+	err = f.Fetch(s.snapDecl.Ref())
+	c.Assert(err, IsNil)
+	c.Check(retrieved, HasLen, 0)
+}
+
+func (s *fetcherSuite) TestFetchNotFound(c *C) {
+	var retrieved []string
+	f := assertstate.NewFetcher(s.db, s.retrieveFrom(&retrieved, s.snapDecl))
+
+	err := f.Fetch(s.snapDecl.Ref())
+	c.Assert(err, ErrorMatches, `cannot find account-key \(dev-id; .*\): not there`)
+	_, err = s.snapDecl.Ref().Resolve(s.db.Find)
+	c.Check(err, Equals, asserts.ErrNotFound)
+}
+
+func (s *fetcherSuite) TestSaveNothingToRetrieveFrom(c *C) {
+	f := assertstate.NewFetcher(s.db, nil)
+	f.Supply(s.devAccKey)
+
+	err := f.Save(s.snapDecl)
+	c.Assert(err, ErrorMatches, `cannot find account \(dev-id\)`)
+}
+
+func (s *fetcherSuite) TestSavingFetcher(c *C) {
+	var retrieved []string
+	retrieve := s.retrieveFrom(&retrieved, s.rootAccKey, s.snapRev, s.snapDecl, s.devAcct, s.devAccKey)
+	var saved []string
+	f := assertstate.NewSavingFetcher(func(ref *asserts.Ref) (asserts.Assertion, error) {
+		a, err := retrieve(ref)
+		if err != nil {
+			// the canonical account, as trusted
+			return nil, asserts.ErrNotFound
+		}
+		return a, nil
+	}, func(a asserts.Assertion) error {
+		saved = append(saved, a.Ref().String())
+		return nil
+	})
+
+	err := f.Save(s.snapRev)
+	c.Assert(err, IsNil)
+	err = f.Fetch(s.snapDecl.Ref())
+	c.Assert(err, IsNil)
+	// prerequisites first, each once, skipping the self-signature of the
+	// root key
+	c.Check(saved, DeepEquals, []string{
+		s.rootAccKey.Ref().String(),
+		"account (dev-id)",
+		s.devAccKey.Ref().String(),
+		"snap-declaration (16; snap-id-1)",
+		"snap-revision (16; snap-id-1; sha512-AAAA)",
+	})
+	c.Check(retrieved, DeepEquals, []string{
+		s.rootAccKey.Ref().String(),
+		"account (canonical)",
+		"snap-declaration (16; snap-id-1)",
+		s.devAccKey.Ref().String(),
+		"account (dev-id)",
+	})
+}
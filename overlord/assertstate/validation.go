@@ -0,0 +1,163 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// Validations returns the validation assertions in the assertion
+// database of the state. The state must be locked by the caller.
+func Validations(s *state.State) ([]*asserts.Validation, error) {
+	as, err := DB(s).FindMany(asserts.ValidationType, map[string]string{
+		"series": release.Series,
+	})
+	if err == asserts.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	validations := make([]*asserts.Validation, len(as))
+	for i, a := range as {
+		validations[i] = a.(*asserts.Validation)
+	}
+	sort.Sort(byGatingAndApproved(validations))
+	return validations, nil
+}
+
+type byGatingAndApproved []*asserts.Validation
+
+func (vs byGatingAndApproved) Len() int      { return len(vs) }
+func (vs byGatingAndApproved) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs byGatingAndApproved) Less(i, j int) bool {
+	if vs[i].SnapID() != vs[j].SnapID() {
+		return vs[i].SnapID() < vs[j].SnapID()
+	}
+	if vs[i].ApprovedSnapID() != vs[j].ApprovedSnapID() {
+		return vs[i].ApprovedSnapID() < vs[j].ApprovedSnapID()
+	}
+	return vs[i].ApprovedSnapRevision() < vs[j].ApprovedSnapRevision()
+}
+
+// RefreshValidationError reports the refresh candidates refused for lack
+// of validation, with the reason for each, by snap name.
+type RefreshValidationError struct {
+	Refused map[string]string
+}
+
+func (e *RefreshValidationError) Error() string {
+	names := make([]string, 0, len(e.Refused))
+	for name := range e.Refused {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = e.Refused[name]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateRefreshes checks the given refresh candidates against the
+// validations by the installed snaps, given by snap id, that gate them: a
+// candidate revision is refused unless every installed snap gating it has
+// a validation, not revoked, approving it. The snap-declarations of the
+// installed snaps missing from the database are retrieved with retrieve,
+// and it is an error if any cannot be found. It returns the validated
+// candidates along with a RefreshValidationError if any was refused.
+// The state must be locked by the caller.
+func ValidateRefreshes(s *state.State, candidates []*snap.Info, installedSnapIDs []string, retrieve func(*asserts.Ref) (asserts.Assertion, error)) ([]*snap.Info, error) {
+	f := NewFetcher(DB(s), retrieve)
+	var gating []*asserts.SnapDeclaration
+	for _, snapID := range installedSnapIDs {
+		ref := &asserts.Ref{
+			Type:       asserts.SnapDeclarationType,
+			PrimaryKey: []string{release.Series, snapID},
+		}
+		if err := f.Fetch(ref); err != nil {
+			return nil, fmt.Errorf("cannot validate refreshes: %v", err)
+		}
+		snapDecl, err := SnapDeclaration(s, snapID)
+		if err != nil {
+			return nil, err
+		}
+		if len(snapDecl.Gates()) > 0 {
+			gating = append(gating, snapDecl)
+		}
+	}
+
+	db := DB(s)
+	validated := make([]*snap.Info, 0, len(candidates))
+	refused := make(map[string]string)
+	for _, candidate := range candidates {
+		var reasons []string
+		for _, snapDecl := range gating {
+			if !gates(snapDecl, candidate.SnapID) {
+				continue
+			}
+			a, err := db.Find(asserts.ValidationType, map[string]string{
+				"series":                 release.Series,
+				"snap-id":                snapDecl.SnapID(),
+				"approved-snap-id":       candidate.SnapID,
+				"approved-snap-revision": strconv.Itoa(candidate.Revision.N),
+			})
+			switch {
+			case err == asserts.ErrNotFound:
+				reasons = append(reasons, fmt.Sprintf("no validation by %q", snapDecl.SnapName()))
+			case err != nil:
+				return nil, err
+			case a.(*asserts.Validation).Revoked():
+				reasons = append(reasons, fmt.Sprintf("validation by %q was revoked", snapDecl.SnapName()))
+			}
+		}
+		if len(reasons) > 0 {
+			refused[candidate.Name()] = fmt.Sprintf("cannot refresh %q to revision %s: %s", candidate.Name(), candidate.Revision, strings.Join(reasons, ", "))
+			continue
+		}
+		validated = append(validated, candidate)
+	}
+
+	if len(refused) > 0 {
+		return validated, &RefreshValidationError{Refused: refused}
+	}
+	return validated, nil
+}
+
+// gates returns whether the declared snap gates the snap with the given id.
+func gates(snapDecl *asserts.SnapDeclaration, snapID string) bool {
+	if snapID == "" || snapID == snapDecl.SnapID() {
+		return false
+	}
+	for _, gated := range snapDecl.Gates() {
+		if gated == snapID {
+			return true
+		}
+	}
+	return false
+}
@@ -251,3 +251,30 @@ func (as *authSuite) TestAuthenticatorSetHeaders(c *C) {
 	authorization := req.Header.Get("Authorization")
 	c.Check(authorization, Equals, `Macaroon root="macaroon", discharge="discharge"`)
 }
+
+func (as *authSuite) TestDeviceForNoAuthInState(c *C) {
+	as.state.Lock()
+	device, err := auth.Device(as.state)
+	as.state.Unlock()
+	c.Check(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{})
+}
+
+func (as *authSuite) TestSetDevice(c *C) {
+	as.state.Lock()
+	user, err := auth.NewUser(as.state, "username", "macaroon", []string{"discharge"})
+	c.Assert(err, IsNil)
+	err = auth.SetDevice(as.state, &auth.DeviceState{Brand: "some-brand", Model: "some-model"})
+	as.state.Unlock()
+	c.Check(err, IsNil)
+
+	as.state.Lock()
+	device, err := auth.Device(as.state)
+	c.Check(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{Brand: "some-brand", Model: "some-model"})
+	// users are untouched
+	userFromState, err := auth.User(as.state, user.ID)
+	as.state.Unlock()
+	c.Check(err, IsNil)
+	c.Check(userFromState, DeepEquals, user)
+}
@@ -26,8 +26,9 @@ import (
 )
 
 type overlordStateBackend struct {
-	path         string
-	ensureBefore func(d time.Duration)
+	path           string
+	ensureBefore   func(d time.Duration)
+	requestRestart func()
 }
 
 func (osb *overlordStateBackend) Checkpoint(data []byte) error {
@@ -37,3 +38,7 @@ func (osb *overlordStateBackend) Checkpoint(data []byte) error {
 func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
 	osb.ensureBefore(d)
 }
+
+func (osb *overlordStateBackend) RequestRestart() {
+	osb.requestRestart()
+}
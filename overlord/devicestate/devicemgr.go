@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package devicestate implements the manager and state aspects
+// responsible for the device identity and its registration.
+package devicestate
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+)
+
+// DeviceManager is responsible for managing the device identity: it
+// generates the device key and registers the device with the device
+// service to obtain its serial assertion.
+type DeviceManager struct {
+	state      *state.State
+	keypairMgr asserts.KeypairManager
+	runner     *state.TaskRunner
+	// attempted is set once a registration change was created, a
+	// failed registration is not retried until the next restart
+	attempted bool
+}
+
+// Manager returns a new device manager.
+func Manager(s *state.State) (*DeviceManager, error) {
+	runner := state.NewTaskRunner(s)
+
+	keypairMgr, err := asserts.OpenFSKeypairManager(dirs.SnapDeviceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &DeviceManager{
+		state:      s,
+		keypairMgr: keypairMgr,
+		runner:     runner,
+	}
+
+	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
+	runner.AddHandler("request-serial", m.doRequestSerial, nil)
+
+	return m, nil
+}
+
+func deviceServiceURL() string {
+	if os.Getenv("SNAPPY_FORCE_DEVICE_SERVICE_URL") != "" {
+		return os.Getenv("SNAPPY_FORCE_DEVICE_SERVICE_URL")
+	}
+	return "https://myapps.developer.ubuntu.com/identity/api/v1/"
+}
+
+var serviceURL = deviceServiceURL()
+
+// Model returns the model assertion of the device, as identified by
+// the brand and model recorded in the state.
+func Model(s *state.State) (*asserts.Model, error) {
+	device, err := auth.Device(s)
+	if err != nil {
+		return nil, err
+	}
+	if device.Brand == "" || device.Model == "" {
+		return nil, state.ErrNoState
+	}
+
+	a, err := assertstate.DB(s).Find(asserts.ModelType, map[string]string{
+		"series":   release.Series,
+		"brand-id": device.Brand,
+		"model":    device.Model,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.Model), nil
+}
+
+// identifyModel records in the state the brand and model of the device
+// from the model assertion in the database, if there is exactly one.
+func identifyModel(s *state.State, device *auth.DeviceState) error {
+	models, err := assertstate.DB(s).FindMany(asserts.ModelType, map[string]string{
+		"series": release.Series,
+	})
+	if err == asserts.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(models) != 1 {
+		return fmt.Errorf("cannot identify the device model: %d model assertions found", len(models))
+	}
+
+	model := models[0].(*asserts.Model)
+	device.Brand = model.BrandID()
+	device.Model = model.Model()
+	return auth.SetDevice(s, device)
+}
+
+func (m *DeviceManager) ensureOperational() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	device, err := auth.Device(m.state)
+	if err != nil {
+		return err
+	}
+
+	if device.Serial != "" {
+		// already registered
+		return nil
+	}
+
+	if device.Brand == "" || device.Model == "" {
+		if err := identifyModel(m.state, device); err != nil {
+			return err
+		}
+		if device.Brand == "" {
+			// nothing to register with yet
+			return nil
+		}
+	}
+
+	if m.attempted {
+		return nil
+	}
+
+	for _, chg := range m.state.Changes() {
+		if chg.Kind() == "become-operational" && !chg.Status().Ready() {
+			// registration already in progress
+			m.attempted = true
+			return nil
+		}
+	}
+
+	tasks := []*state.Task{}
+	var prev *state.Task
+	if device.KeyID == "" {
+		genKey := m.state.NewTask("generate-device-key", i18n.G("Generate device key"))
+		tasks = append(tasks, genKey)
+		prev = genKey
+	}
+
+	requestSerial := m.state.NewTask("request-serial", i18n.G("Request device serial"))
+	if prev != nil {
+		requestSerial.WaitFor(prev)
+	}
+	tasks = append(tasks, requestSerial)
+
+	chg := m.state.NewChange("become-operational", i18n.G("Initialize device"))
+	chg.AddAll(state.NewTaskSet(tasks...))
+	m.attempted = true
+
+	return nil
+}
+
+// Ensure implements StateManager.Ensure.
+func (m *DeviceManager) Ensure() error {
+	err := m.ensureOperational()
+
+	m.runner.Ensure()
+	return err
+}
+
+// Wait implements StateManager.Wait.
+func (m *DeviceManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop implements StateManager.Stop.
+func (m *DeviceManager) Stop() {
+	m.runner.Stop()
+}
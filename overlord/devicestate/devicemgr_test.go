@@ -0,0 +1,349 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+func TestDeviceManager(t *testing.T) { TestingT(t) }
+
+type deviceMgrSuite struct {
+	state *state.State
+	mgr   *devicestate.DeviceManager
+	db    *asserts.Database
+
+	storeSigning *assertstest.SigningDB
+	brandSigning *assertstest.SigningDB
+	brandAccount asserts.Assertion
+
+	restoreGenerateKey func()
+}
+
+var _ = Suite(&deviceMgrSuite{})
+
+var testDeviceKey = assertstest.GenerateKey(752)
+
+func (s *deviceMgrSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+
+	rootPrivKey := assertstest.GenerateKey(752)
+	s.storeSigning = assertstest.NewSigningDB("canonical", rootPrivKey)
+
+	brandPrivKey := assertstest.GenerateKey(752)
+	s.brandSigning = assertstest.NewSigningDB("my-brand", brandPrivKey)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		TrustedKeys:    []*asserts.AccountKey{assertstest.NewAccountKey(s.storeSigning, "canonical", rootPrivKey.PublicKey())},
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+
+	brandAccKey := assertstest.NewAccountKey(s.storeSigning, "my-brand", brandPrivKey.PublicKey())
+	c.Assert(s.db.Add(brandAccKey), IsNil)
+	s.brandAccount, err = s.storeSigning.Sign(asserts.AccountType, map[string]string{
+		"account-id":   "my-brand",
+		"username":     "my-brand",
+		"display-name": "My Brand",
+		"validation":   "certified",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+
+	s.state = state.New(nil)
+	s.state.Lock()
+	assertstate.ReplaceDB(s.state, s.db)
+	s.state.Unlock()
+
+	s.mgr, err = devicestate.Manager(s.state)
+	c.Assert(err, IsNil)
+
+	s.restoreGenerateKey = devicestate.MockGenerateKey(func() (asserts.PrivateKey, error) {
+		return testDeviceKey, nil
+	})
+}
+
+func (s *deviceMgrSuite) TearDownTest(c *C) {
+	s.mgr.Stop()
+	s.restoreGenerateKey()
+	dirs.SetRootDir("/")
+}
+
+func (s *deviceMgrSuite) settle() {
+	for i := 0; i < 5; i++ {
+		s.mgr.Ensure()
+		s.mgr.Wait()
+	}
+}
+
+func (s *deviceMgrSuite) addModel(c *C) {
+	model, err := s.brandSigning.Sign(asserts.ModelType, map[string]string{
+		"series":         "16",
+		"brand-id":       "my-brand",
+		"model":          "my-model",
+		"os":             "core",
+		"architecture":   "amd64",
+		"gadget":         "pc",
+		"kernel":         "pc-kernel",
+		"store":          "my-brand-store",
+		"class":          "fixed",
+		"allowed-modes":  "",
+		"required-snaps": "",
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(model), IsNil)
+}
+
+// mockDeviceService starts a stand-in for the device service, replying
+// to serial requests with the given status, after polls 202 replies.
+func (s *deviceMgrSuite) mockDeviceService(c *C, status, polls int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		switch r.URL.Path {
+		case "/identity/request-id":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"request-id": "REQID-1"}`))
+		case "/identity/serial":
+			if polls > 0 {
+				polls--
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			c.Check(r.Header.Get("Content-Type"), Equals, asserts.MediaType)
+			body, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, IsNil)
+			a, err := asserts.Decode(body)
+			c.Assert(err, IsNil)
+			serialReq, ok := a.(*asserts.SerialRequest)
+			c.Assert(ok, Equals, true)
+			c.Check(serialReq.BrandID(), Equals, "my-brand")
+			c.Check(serialReq.Model(), Equals, "my-model")
+			c.Check(serialReq.RequestID(), Equals, "REQID-1")
+			encodedPubKey, err := asserts.EncodePublicKey(serialReq.DeviceKey())
+			c.Assert(err, IsNil)
+			serial, err := s.storeSigning.Sign(asserts.SerialType, map[string]string{
+				"brand-id":   "my-brand",
+				"model":      "my-model",
+				"serial":     "9999",
+				"device-key": string(encodedPubKey),
+				"timestamp":  time.Now().Format(time.RFC3339),
+			}, nil)
+			c.Assert(err, IsNil)
+			w.Header().Set("Content-Type", asserts.MediaType)
+			w.WriteHeader(http.StatusOK)
+			enc := asserts.NewEncoder(w)
+			c.Check(enc.Encode(s.brandAccount), IsNil)
+			c.Check(enc.Encode(serial), IsNil)
+		default:
+			c.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	return server
+}
+
+func (s *deviceMgrSuite) TestFullDeviceRegistrationHappy(c *C) {
+	s.addModel(c)
+	server := s.mockDeviceService(c, http.StatusOK, 0)
+	defer server.Close()
+	restore := devicestate.MockDeviceServiceURL(server.URL + "/identity/")
+	defer restore()
+
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	changes := s.state.Changes()
+	c.Assert(changes, HasLen, 1)
+	chg := changes[0]
+	c.Check(chg.Kind(), Equals, "become-operational")
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(chg.Err(), IsNil)
+
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{
+		Brand:  "my-brand",
+		Model:  "my-model",
+		Serial: "9999",
+		KeyID:  testDeviceKey.PublicKey().ID(),
+	})
+
+	a, err := s.db.Find(asserts.SerialType, map[string]string{
+		"brand-id": "my-brand",
+		"model":    "my-model",
+		"serial":   "9999",
+	})
+	c.Assert(err, IsNil)
+	serial := a.(*asserts.Serial)
+	c.Check(serial.DeviceKey().ID(), Equals, device.KeyID)
+
+	// the device key is in the keypair manager
+	keypairMgr, err := asserts.OpenFSKeypairManager(dirs.SnapDeviceDir)
+	c.Assert(err, IsNil)
+	privKey, err := keypairMgr.Get("my-brand", device.KeyID)
+	c.Assert(err, IsNil)
+	c.Check(privKey.PublicKey().ID(), Equals, device.KeyID)
+
+	// nothing more to do once registered
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+	c.Check(s.state.Changes(), HasLen, 1)
+}
+
+func (s *deviceMgrSuite) TestDeviceRegistrationRetriesWithBackoff(c *C) {
+	s.addModel(c)
+	server := s.mockDeviceService(c, http.StatusOK, 2)
+	defer server.Close()
+	restore := devicestate.MockDeviceServiceURL(server.URL + "/identity/")
+	defer restore()
+	restore = devicestate.MockRetryInterval(time.Minute)
+	defer restore()
+
+	s.settle()
+
+	s.state.Lock()
+	chg := s.state.Changes()[0]
+	c.Check(chg.Status(), Equals, state.DoingStatus)
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	requestSerial := tasks[1]
+	c.Check(requestSerial.Kind(), Equals, "request-serial")
+	var attempts int
+	c.Assert(requestSerial.Get("attempts", &attempts), IsNil)
+	c.Check(attempts, Equals, 1)
+	var nextAttempt time.Time
+	c.Assert(requestSerial.Get("next-attempt", &nextAttempt), IsNil)
+	c.Check(nextAttempt.After(time.Now().Add(59*time.Second)), Equals, true)
+	c.Check(requestSerial.Log(), HasLen, 1)
+	c.Check(requestSerial.Log()[0], Matches, `.* cannot get device serial \(attempt 1\), retrying in 1m0s: device serial request accepted, serial not ready yet`)
+
+	// the next attempt backs off further
+	requestSerial.Set("next-attempt", time.Time{})
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+	c.Assert(requestSerial.Get("attempts", &attempts), IsNil)
+	c.Check(attempts, Equals, 2)
+	c.Assert(requestSerial.Get("next-attempt", &nextAttempt), IsNil)
+	c.Check(nextAttempt.After(time.Now().Add(119*time.Second)), Equals, true)
+
+	// and eventually succeeds
+	requestSerial.Set("next-attempt", time.Time{})
+	s.state.Unlock()
+	s.settle()
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Serial, Equals, "9999")
+}
+
+func (s *deviceMgrSuite) TestDeviceRegistrationError(c *C) {
+	s.addModel(c)
+	server := s.mockDeviceService(c, http.StatusBadRequest, 0)
+	defer server.Close()
+	restore := devicestate.MockDeviceServiceURL(server.URL + "/identity/")
+	defer restore()
+
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	changes := s.state.Changes()
+	c.Assert(changes, HasLen, 1)
+	chg := changes[0]
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot deliver device serial request: unexpected status 400.*`)
+
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Serial, Equals, "")
+	// the key is kept for the next attempt
+	c.Check(device.KeyID, Equals, testDeviceKey.PublicKey().ID())
+}
+
+func (s *deviceMgrSuite) TestNoModelNoRegistration(c *C) {
+	s.settle()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{})
+}
+
+func (s *deviceMgrSuite) TestModel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.Model(s.state)
+	c.Check(err, Equals, state.ErrNoState)
+
+	s.addModel(c)
+	err = auth.SetDevice(s.state, &auth.DeviceState{Brand: "my-brand", Model: "my-model"})
+	c.Assert(err, IsNil)
+
+	model, err := devicestate.Model(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.BrandID(), Equals, "my-brand")
+	c.Check(model.Model(), Equals, "my-model")
+	c.Check(model.Store(), Equals, "my-brand-store")
+}
+
+func (s *deviceMgrSuite) TestRequestIDFromService(c *C) {
+	// sanity check of the stand-in protocol
+	server := s.mockDeviceService(c, http.StatusOK, 0)
+	defer server.Close()
+	resp, err := http.Post(server.URL+"/identity/request-id", "application/json", nil)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	var reqID map[string]string
+	c.Assert(json.NewDecoder(resp.Body).Decode(&reqID), IsNil)
+	c.Check(reqID, DeepEquals, map[string]string{"request-id": "REQID-1"})
+	c.Check(fmt.Sprint(resp.StatusCode), Equals, "200")
+}
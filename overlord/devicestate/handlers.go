@@ -0,0 +1,254 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var generateKey = asserts.GenerateKey
+
+func (m *DeviceManager) doGenerateDeviceKey(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	device, err := auth.Device(st)
+	if err != nil {
+		return err
+	}
+
+	if device.KeyID != "" {
+		// nothing to do
+		return nil
+	}
+
+	// generating a key takes a while, don't hold the state meanwhile
+	st.Unlock()
+	keyPair, err := generateKey()
+	st.Lock()
+	if err != nil {
+		return fmt.Errorf("cannot generate device key pair: %v", err)
+	}
+
+	if err := m.keypairMgr.Put(device.Brand, keyPair); err != nil {
+		return fmt.Errorf("cannot store device key pair: %v", err)
+	}
+
+	device.KeyID = keyPair.PublicKey().ID()
+	return auth.SetDevice(st, device)
+}
+
+var (
+	retryInterval    = 1 * time.Minute
+	maxRetryInterval = 1 * time.Hour
+)
+
+// temporaryError is a failure to obtain the serial worth retrying later.
+type temporaryError struct {
+	err error
+}
+
+func (e *temporaryError) Error() string {
+	return e.err.Error()
+}
+
+func (m *DeviceManager) doRequestSerial(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var nextAttempt time.Time
+	err := t.Get("next-attempt", &nextAttempt)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if wait := nextAttempt.Sub(time.Now()); wait > 0 {
+		st.EnsureBefore(wait)
+		return state.Retry
+	}
+
+	device, err := auth.Device(st)
+	if err != nil {
+		return err
+	}
+
+	if device.Serial != "" {
+		// nothing to do
+		return nil
+	}
+
+	privKey, err := m.keypairMgr.Get(device.Brand, device.KeyID)
+	if err != nil {
+		return fmt.Errorf("cannot read device key pair: %v", err)
+	}
+
+	st.Unlock()
+	assertions, err := requestSerial(privKey, device)
+	st.Lock()
+	if _, ok := err.(*temporaryError); ok {
+		return retryLater(t, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	serial, err := findSerial(assertions, privKey.PublicKey(), device)
+	if err != nil {
+		return err
+	}
+
+	// the prerequisites of the serial are expected to be sent along
+	if _, err := assertstate.AddMany(assertstate.DB(st), assertions, nil); err != nil {
+		return fmt.Errorf("cannot add serial assertion: %v", err)
+	}
+
+	device.Serial = serial.Serial()
+	return auth.SetDevice(st, device)
+}
+
+// retryLater schedules another attempt of the task, backing off
+// exponentially with the number of failed attempts.
+func retryLater(t *state.Task, reason error) error {
+	var attempts int
+	err := t.Get("attempts", &attempts)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+
+	delay := retryInterval << uint(attempts)
+	if delay > maxRetryInterval || delay <= 0 {
+		delay = maxRetryInterval
+	}
+	attempts++
+
+	t.Set("attempts", attempts)
+	t.Set("next-attempt", time.Now().Add(delay))
+	t.Logf("cannot get device serial (attempt %d), retrying in %v: %v", attempts, delay, reason)
+	t.State().EnsureBefore(delay)
+	return state.Retry
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// requestSerial obtains a request id from the device service, submits
+// a serial-request for the device signed with its key and returns the
+// assertions the device service replies with.
+func requestSerial(privKey asserts.PrivateKey, device *auth.DeviceState) ([]asserts.Assertion, error) {
+	resp, err := httpClient.Post(serviceURL+"request-id", "application/json", nil)
+	if err != nil {
+		return nil, &temporaryError{fmt.Errorf("cannot retrieve request-id for making a request for a serial: %v", err)}
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, "retrieve request-id for making a request for a serial"); err != nil {
+		return nil, err
+	}
+
+	var requestID struct {
+		RequestID string `json:"request-id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&requestID); err != nil {
+		return nil, fmt.Errorf("cannot read request-id for making a request for a serial: %v", err)
+	}
+
+	encodedPubKey, err := asserts.EncodePublicKey(privKey.PublicKey())
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode device public key: %v", err)
+	}
+
+	serialReq, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, map[string]string{
+		"brand-id":   device.Brand,
+		"model":      device.Model,
+		"request-id": requestID.RequestID,
+		"device-key": string(encodedPubKey),
+	}, nil, privKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign serial-request: %v", err)
+	}
+
+	resp, err = httpClient.Post(serviceURL+"serial", asserts.MediaType, bytes.NewReader(asserts.Encode(serialReq)))
+	if err != nil {
+		return nil, &temporaryError{fmt.Errorf("cannot deliver device serial request: %v", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, &temporaryError{fmt.Errorf("device serial request accepted, serial not ready yet")}
+	}
+	if err := checkResponse(resp, "deliver device serial request"); err != nil {
+		return nil, err
+	}
+
+	var assertions []asserts.Assertion
+	dec := asserts.NewDecoder(resp.Body)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode device service reply: %v", err)
+		}
+		assertions = append(assertions, a)
+	}
+	return assertions, nil
+}
+
+// checkResponse returns an error for unsuccessful responses, temporary
+// for server side ones.
+func checkResponse(resp *http.Response, what string) error {
+	switch {
+	case resp.StatusCode >= 500:
+		return &temporaryError{fmt.Errorf("cannot %s: unexpected status %d", what, resp.StatusCode)}
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated:
+		return fmt.Errorf("cannot %s: unexpected status %d", what, resp.StatusCode)
+	}
+	return nil
+}
+
+// findSerial returns the serial assertion for the device among the given
+// ones, checking it is bound to the device key.
+func findSerial(assertions []asserts.Assertion, pubKey asserts.PublicKey, device *auth.DeviceState) (*asserts.Serial, error) {
+	for _, a := range assertions {
+		serial, ok := a.(*asserts.Serial)
+		if !ok {
+			continue
+		}
+		if serial.BrandID() != device.Brand || serial.Model() != device.Model {
+			return nil, fmt.Errorf("obtained serial assertion is for %s/%s, not %s/%s", serial.BrandID(), serial.Model(), device.Brand, device.Model)
+		}
+		if serial.DeviceKey().ID() != pubKey.ID() {
+			return nil, fmt.Errorf("obtained serial assertion does not match the device key")
+		}
+		return serial, nil
+	}
+	return nil, fmt.Errorf("device service did not reply with a serial assertion")
+}
@@ -0,0 +1,86 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+)
+
+// autoConnectConfig is the configuration of auto-connection chosen by the
+// administrator of the device, read from dirs.SnapInterfacesConfigFile:
+//
+//	interfaces:
+//	    camera:
+//	        auto-connect: false
+//	snaps:
+//	    some-snap:
+//	        auto-connect: false
+//
+// Interfaces and snaps not mentioned there are auto-connected as usual.
+type autoConnectConfig struct {
+	Interfaces map[string]autoConnectOptions `yaml:"interfaces"`
+	Snaps      map[string]autoConnectOptions `yaml:"snaps"`
+}
+
+type autoConnectOptions struct {
+	AutoConnect *bool `yaml:"auto-connect"`
+}
+
+func (opts autoConnectOptions) disabled() bool {
+	return opts.AutoConnect != nil && !*opts.AutoConnect
+}
+
+// loadAutoConnectConfig reads the auto-connection configuration. A missing
+// configuration file is the same as an empty one.
+func loadAutoConnectConfig() (*autoConnectConfig, error) {
+	data, err := ioutil.ReadFile(dirs.SnapInterfacesConfigFile)
+	if os.IsNotExist(err) {
+		return &autoConnectConfig{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config autoConnectConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", dirs.SnapInterfacesConfigFile, err)
+	}
+	return &config, nil
+}
+
+// disabledReason returns why the configuration forbids auto-connecting the
+// given plug and slot, or an empty string if it does not.
+func (config *autoConnectConfig) disabledReason(plug *interfaces.Plug, slot *interfaces.Slot) string {
+	switch {
+	case config.Interfaces[plug.Interface].disabled():
+		return fmt.Sprintf("auto-connection of interface %q is disabled", plug.Interface)
+	case config.Snaps[plug.Snap.Name()].disabled():
+		return fmt.Sprintf("auto-connection of snap %q is disabled", plug.Snap.Name())
+	case config.Snaps[slot.Snap.Name()].disabled():
+		return fmt.Sprintf("auto-connection of snap %q is disabled", slot.Snap.Name())
+	}
+	return ""
+}
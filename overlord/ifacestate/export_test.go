@@ -18,3 +18,55 @@
  */
 
 package ifacestate
+
+import (
+	"github.com/snapcore/snapd/interfaces/hotplug"
+)
+
+// FakeHotplugEventSource is a hotplug event source for tests: it
+// enumerates Devices and delivers the events sent to Events, confirming
+// the enumeration and each event on Handled if set.
+type FakeHotplugEventSource struct {
+	Devices []*hotplug.DeviceInfo
+	Events  chan *hotplug.DeviceInfo
+	Handled chan bool
+}
+
+func (s *FakeHotplugEventSource) Enumerate() ([]*hotplug.DeviceInfo, error) {
+	return s.Devices, nil
+}
+
+func (s *FakeHotplugEventSource) Run(handle func(dev *hotplug.DeviceInfo), stop <-chan struct{}) error {
+	s.handled()
+	for {
+		select {
+		case dev := <-s.Events:
+			handle(dev)
+			s.handled()
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+func (s *FakeHotplugEventSource) handled() {
+	if s.Handled != nil {
+		s.Handled <- true
+	}
+}
+
+func MockHotplugEventSource(source *FakeHotplugEventSource) (restore func()) {
+	old := newHotplugEventSource
+	newHotplugEventSource = func() (hotplugEventSource, error) {
+		return source, nil
+	}
+	return func() { newHotplugEventSource = old }
+}
+
+var SystemKey = systemKey
+
+func MockAppArmorProfiles(loaded func() ([]string, error), unload func(name string) error) (restore func()) {
+	oldLoaded, oldUnload := apparmorLoadedProfiles, apparmorUnloadProfile
+	apparmorLoadedProfiles, apparmorUnloadProfile = loaded, unload
+	return func() { apparmorLoadedProfiles, apparmorUnloadProfile = oldLoaded, oldUnload }
+}
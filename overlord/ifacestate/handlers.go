@@ -21,6 +21,7 @@ package ifacestate
 
 import (
 	"fmt"
+	"strings"
 
 	"gopkg.in/tomb.v2"
 
@@ -52,6 +53,12 @@ func (m *InterfaceManager) doSetupProfiles(task *state.Task, _ *tomb.Tomb) error
 		return err
 	}
 
+	// Check the installation policy before touching the state or the
+	// repository so that a refused snap leaves everything as it was.
+	if err := checkInstallPolicy(task.State(), snapInfo); err != nil {
+		return err
+	}
+
 	// Set DevMode flag if SnapSetup.Flags indicates it should be done
 	// but remember the old value in the task in case we undo.
 	task.Set("old-devmode", snapState.DevMode())
@@ -85,28 +92,38 @@ func (m *InterfaceManager) doSetupProfiles(task *state.Task, _ *tomb.Tomb) error
 			return err
 		}
 	}
+	if snapInfo.Type == snap.TypeOS {
+		if err := m.addHotplugSlots(snapInfo); err != nil {
+			return err
+		}
+	}
 	if err := m.reloadConnections(snapName); err != nil {
 		return err
 	}
-	if err := m.autoConnect(task, snapName, blacklist); err != nil {
+	autoConnected, err := m.autoConnect(task, snapName, blacklist)
+	if err != nil {
 		return err
 	}
-	if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
-		return state.Retry
-	}
+	affectedSnaps = append(affectedSnaps, autoConnected...)
+	snapInfos := []*snap.Info{snapInfo}
+	seen := map[string]bool{snapInfo.Name(): true}
 	for _, snapName := range affectedSnaps {
 		// The affected snap is setup explicitly so skip it here.
-		if snapName == snapInfo.Name() {
+		if seen[snapName] {
 			continue
 		}
+		seen[snapName] = true
 		snapInfo, err := snapstate.Current(task.State(), snapName)
 		if err != nil {
 			return err
 		}
 		snap.AddImplicitSlots(snapInfo)
-		if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
-			return state.Retry
-		}
+		snapInfos = append(snapInfos, snapInfo)
+	}
+	// All the snaps are setup together so that backends able to do so can
+	// compile their profiles in parallel.
+	if err := setupSnapsSecurity(task, snapInfos, m.repo); err != nil {
+		return state.Retry
 	}
 	return nil
 }
@@ -219,6 +236,20 @@ func (m *InterfaceManager) doDiscardConns(task *state.Task, _ *tomb.Tomb) error
 	}
 	task.Set("removed", removed)
 	setConns(st, conns)
+
+	disconnected, err := getDisconnected(st)
+	if err != nil {
+		return err
+	}
+	var forgotten []string
+	for id := range disconnected {
+		if strings.HasPrefix(id, snapName+":") {
+			forgotten = append(forgotten, id)
+			delete(disconnected, id)
+		}
+	}
+	task.Set("forgotten-disconnected", forgotten)
+	setDisconnected(st, disconnected)
 	return nil
 }
 
@@ -243,6 +274,21 @@ func (m *InterfaceManager) undoDiscardConns(task *state.Task, _ *tomb.Tomb) erro
 	}
 	setConns(st, conns)
 	task.Set("removed", nil)
+
+	var forgotten []string
+	err = task.Get("forgotten-disconnected", &forgotten)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	disconnected, err := getDisconnected(st)
+	if err != nil {
+		return err
+	}
+	for _, id := range forgotten {
+		disconnected[id] = true
+	}
+	setDisconnected(st, disconnected)
+	task.Set("forgotten-disconnected", nil)
 	return nil
 }
 
@@ -255,16 +301,32 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	plugAttrs, slotAttrs, err := getDynamicAttrs(task)
+	if err != nil {
+		return err
+	}
 
 	conns, err := getConns(st)
 	if err != nil {
 		return err
 	}
 
+	if err := m.checkConnectPolicy(st, plugRef, slotRef); err != nil {
+		return err
+	}
+
 	err = m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
 	if err != nil {
 		return err
 	}
+	if len(plugAttrs) != 0 || len(slotAttrs) != 0 {
+		err = m.repo.SetConnectionAttrs(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name, plugAttrs, slotAttrs)
+		if err != nil {
+			// Don't leave a connection without its attributes behind.
+			m.repo.Disconnect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
+			return err
+		}
+	}
 
 	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
 	slot := m.repo.Slot(slotRef.Snap, slotRef.Name)
@@ -275,9 +337,22 @@ func (m *InterfaceManager) doConnect(task *state.Task, _ *tomb.Tomb) error {
 		return state.Retry
 	}
 
-	conns[connID(plugRef, slotRef)] = connState{Interface: plug.Interface}
+	conns[connID(plugRef, slotRef)] = connState{
+		Interface:        plug.Interface,
+		DynamicPlugAttrs: plugAttrs,
+		DynamicSlotAttrs: slotAttrs,
+	}
 	setConns(st, conns)
 
+	disconnected, err := getDisconnected(st)
+	if err != nil {
+		return err
+	}
+	if disconnected[plugID(plugRef)] {
+		delete(disconnected, plugID(plugRef))
+		setDisconnected(st, disconnected)
+	}
+
 	return nil
 }
 
@@ -295,6 +370,10 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	disconnected, err := getDisconnected(st)
+	if err != nil {
+		return err
+	}
 
 	err = m.repo.Disconnect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
 	if err != nil {
@@ -312,5 +391,9 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 
 	delete(conns, connID(plugRef, slotRef))
 	setConns(st, conns)
+	// remember the plug was disconnected on purpose so that it is not
+	// connected again to its default provider behind the user's back
+	disconnected[plugID(plugRef)] = true
+	setDisconnected(st, disconnected)
 	return nil
 }
@@ -23,13 +23,18 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/dbus"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/policy"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -46,26 +51,42 @@ func (m *InterfaceManager) initialize(extra []interfaces.Interface) error {
 	if err := m.addSnaps(); err != nil {
 		return err
 	}
+	if err := m.resetHotplugSlots(); err != nil {
+		return err
+	}
 	if err := m.reloadConnections(""); err != nil {
 		return err
 	}
+	if err := m.regenerateProfilesIfNeeded(); err != nil {
+		return err
+	}
 	return nil
 }
 
 func (m *InterfaceManager) addInterfaces(extra []interfaces.Interface) error {
 	for _, iface := range builtin.Interfaces() {
-		if err := m.repo.AddInterface(iface); err != nil {
+		if err := m.addInterface(iface); err != nil {
 			return err
 		}
 	}
 	for _, iface := range extra {
-		if err := m.repo.AddInterface(iface); err != nil {
+		if err := m.addInterface(iface); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (m *InterfaceManager) addInterface(iface interfaces.Interface) error {
+	if err := m.repo.AddInterface(iface); err != nil {
+		return err
+	}
+	if _, ok := iface.(hotplug.Definer); ok {
+		m.hotplugIfaces = append(m.hotplugIfaces, iface)
+	}
+	return nil
+}
+
 func (m *InterfaceManager) addSnaps() error {
 	snaps, err := snapstate.ActiveInfos(m.state)
 	if err != nil {
@@ -88,7 +109,11 @@ func (m *InterfaceManager) reloadConnections(snapName string) error {
 	if err != nil {
 		return err
 	}
-	for id := range conns {
+	hotplugSlots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	for id, conn := range conns {
 		plugRef, slotRef, err := parseConnID(id)
 		if err != nil {
 			return err
@@ -96,15 +121,43 @@ func (m *InterfaceManager) reloadConnections(snapName string) error {
 		if snapName != "" && plugRef.Snap != snapName && slotRef.Snap != snapName {
 			continue
 		}
-		err = m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name)
-		if err != nil {
+		if hotplugSlots[slotRef.Name] != nil && m.repo.Slot(slotRef.Snap, slotRef.Name) == nil {
+			// reconnected when the device is back
+			continue
+		}
+		if err := m.reconnect(plugRef, slotRef, conn); err != nil {
 			logger.Noticef("%s", err)
 		}
 	}
 	return nil
 }
 
+// reconnect connects a plug and a slot in the repository as recorded in the
+// state, along with the attributes set when the connection was first made.
+func (m *InterfaceManager) reconnect(plugRef *interfaces.PlugRef, slotRef *interfaces.SlotRef, conn connState) error {
+	if err := m.repo.Connect(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name); err != nil {
+		return err
+	}
+	if len(conn.DynamicPlugAttrs) == 0 && len(conn.DynamicSlotAttrs) == 0 {
+		return nil
+	}
+	return m.repo.SetConnectionAttrs(plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name,
+		conn.DynamicPlugAttrs, conn.DynamicSlotAttrs)
+}
+
 func setupSnapSecurity(task *state.Task, snapInfo *snap.Info, repo *interfaces.Repository) error {
+	return setupSnapSecurityWith(task, snapInfo, repo, false)
+}
+
+// reloadSnapSecurity sets up the security of the snap like
+// setupSnapSecurity, but backends implementing
+// interfaces.ReloadingSecurityBackend load all of its artefacts, changed or
+// not.
+func reloadSnapSecurity(task *state.Task, snapInfo *snap.Info, repo *interfaces.Repository) error {
+	return setupSnapSecurityWith(task, snapInfo, repo, true)
+}
+
+func setupSnapSecurityWith(task *state.Task, snapInfo *snap.Info, repo *interfaces.Repository, reload bool) error {
 	st := task.State()
 	var snapState snapstate.SnapState
 	snapName := snapInfo.Name()
@@ -113,8 +166,12 @@ func setupSnapSecurity(task *state.Task, snapInfo *snap.Info, repo *interfaces.R
 		return err
 	}
 	for _, backend := range securityBackends {
+		setup := backend.Setup
+		if reloader, ok := backend.(interfaces.ReloadingSecurityBackend); ok && reload {
+			setup = reloader.SetupAndReload
+		}
 		st.Unlock()
-		err := backend.Setup(snapInfo, snapState.DevMode(), repo)
+		err := setup(snapInfo, snapState.DevMode(), repo)
 		st.Lock()
 		if err != nil {
 			task.Errorf("cannot setup %s for snap %q: %s", backend.Name(), snapName, err)
@@ -124,6 +181,46 @@ func setupSnapSecurity(task *state.Task, snapInfo *snap.Info, repo *interfaces.R
 	return nil
 }
 
+// setupSnapsSecurity sets up the security of many snaps at once. Backends
+// implementing interfaces.BatchSecurityBackend get all the snaps in one go,
+// the others set them up one after another.
+func setupSnapsSecurity(task *state.Task, snapInfos []*snap.Info, repo *interfaces.Repository) error {
+	st := task.State()
+	devModes := make(map[string]bool, len(snapInfos))
+	for _, snapInfo := range snapInfos {
+		var snapState snapstate.SnapState
+		snapName := snapInfo.Name()
+		if err := snapstate.Get(st, snapName, &snapState); err != nil {
+			task.Errorf("cannot get state of snap %q: %s", snapName, err)
+			return err
+		}
+		devModes[snapName] = snapState.DevMode()
+	}
+	for _, backend := range securityBackends {
+		batch, ok := backend.(interfaces.BatchSecurityBackend)
+		if !ok {
+			for _, snapInfo := range snapInfos {
+				st.Unlock()
+				err := backend.Setup(snapInfo, devModes[snapInfo.Name()], repo)
+				st.Lock()
+				if err != nil {
+					task.Errorf("cannot setup %s for snap %q: %s", backend.Name(), snapInfo.Name(), err)
+					return err
+				}
+			}
+			continue
+		}
+		st.Unlock()
+		err := batch.SetupMany(snapInfos, devModes, repo)
+		st.Lock()
+		if err != nil {
+			task.Errorf("cannot setup %s for snaps: %s", backend.Name(), err)
+			return err
+		}
+	}
+	return nil
+}
+
 func removeSnapSecurity(task *state.Task, snapName string) error {
 	st := task.State()
 	for _, backend := range securityBackends {
@@ -141,12 +238,20 @@ func removeSnapSecurity(task *state.Task, snapName string) error {
 type connState struct {
 	Auto      bool   `json:"auto,omitempty"`
 	Interface string `json:"interface,omitempty"`
+	// Attributes set by either side when connecting, such as by their
+	// interface hooks, on top of the ones from snap.yaml.
+	DynamicPlugAttrs map[string]interface{} `json:"plug-dynamic,omitempty"`
+	DynamicSlotAttrs map[string]interface{} `json:"slot-dynamic,omitempty"`
 }
 
 func connID(plug *interfaces.PlugRef, slot *interfaces.SlotRef) string {
 	return fmt.Sprintf("%s:%s %s:%s", plug.Snap, plug.Name, slot.Snap, slot.Name)
 }
 
+func plugID(plug *interfaces.PlugRef) string {
+	return fmt.Sprintf("%s:%s", plug.Snap, plug.Name)
+}
+
 func parseConnID(conn string) (*interfaces.PlugRef, *interfaces.SlotRef, error) {
 	parts := strings.SplitN(conn, " ", 2)
 	if len(parts) != 2 {
@@ -162,36 +267,233 @@ func parseConnID(conn string) (*interfaces.PlugRef, *interfaces.SlotRef, error)
 	return plugRef, slotRef, nil
 }
 
-func (m *InterfaceManager) autoConnect(task *state.Task, snapName string, blacklist map[string]bool) error {
+// autoConnect connects the plugs of the given snap to their unambiguous
+// candidates, and its slots to the plugs of other snaps naming it as their
+// default provider, as far as the interface policy allows. It returns the
+// names of the other snaps that got connected this way, as their security
+// needs to be set up again.
+func (m *InterfaceManager) autoConnect(task *state.Task, snapName string, blacklist map[string]bool) ([]string, error) {
+	st := task.State()
 	var conns map[string]connState
-	err := task.State().Get("conns", &conns)
+	err := st.Get("conns", &conns)
 	if err != nil && err != state.ErrNoState {
-		return err
+		return nil, err
 	}
 	if conns == nil {
 		conns = make(map[string]connState)
 	}
+	config, err := loadAutoConnectConfig()
+	if err != nil {
+		return nil, err
+	}
+	connect := func(plug *interfaces.Plug, slot *interfaces.Slot) bool {
+		if reason := config.disabledReason(plug, slot); reason != "" {
+			task.Logf("not auto connecting %s:%s to %s:%s: %s",
+				plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name, reason)
+			return false
+		}
+		if err := m.repo.Connect(plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name); err != nil {
+			task.Logf("cannot auto connect %s:%s to %s:%s: %s",
+				plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name, err)
+			return false
+		}
+		key := fmt.Sprintf("%s:%s %s:%s", plug.Snap.Name(), plug.Name, slot.Snap.Name(), slot.Name)
+		conns[key] = connState{Interface: plug.Interface, Auto: true}
+		return true
+	}
+	disconnected, err := getDisconnected(st)
+	if err != nil {
+		return nil, err
+	}
 	// XXX: quick hack, auto-connect everything
 	for _, plug := range m.repo.Plugs(snapName) {
 		if blacklist[plug.Name] {
 			continue
 		}
 		candidates := m.repo.AutoConnectCandidates(snapName, plug.Name)
+		if len(candidates) == 0 && !disconnected[plugID(&interfaces.PlugRef{Snap: plug.Snap.Name(), Name: plug.Name})] {
+			candidates, err = m.defaultProviderCandidates(st, plug)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(candidates) == 0 {
+			candidates, err = m.grantedAutoConnectCandidates(st, plug)
+			if err != nil {
+				return nil, err
+			}
+		}
+		candidates, err = m.filterAutoConnect(st, plug, candidates)
+		if err != nil {
+			return nil, err
+		}
 		if len(candidates) != 1 {
 			continue
 		}
-		slot := candidates[0]
-		if err := m.repo.Connect(snapName, plug.Name, slot.Snap.Name(), slot.Name); err != nil {
-			task.Logf("cannot auto connect %s:%s to %s:%s: %s",
-				snapName, plug.Name, slot.Snap.Name(), slot.Name, err)
+		connect(plug, candidates[0])
+	}
+	var affected []string
+	for _, slot := range m.repo.Slots(snapName) {
+		for _, plug := range m.repo.AllPlugs(slot.Interface) {
+			if plug.DefaultProvider() != snapName || len(plug.Connections) != 0 {
+				continue
+			}
+			if disconnected[plugID(&interfaces.PlugRef{Snap: plug.Snap.Name(), Name: plug.Name})] {
+				continue
+			}
+			candidates, err := m.defaultProviderCandidates(st, plug)
+			if err != nil {
+				return nil, err
+			}
+			candidates, err = m.filterAutoConnect(st, plug, candidates)
+			if err != nil {
+				return nil, err
+			}
+			if len(candidates) != 1 || candidates[0] != slot {
+				continue
+			}
+			if connect(plug, candidates[0]) && plug.Snap.Name() != snapName {
+				affected = append(affected, plug.Snap.Name())
+			}
 		}
-		key := fmt.Sprintf("%s:%s %s:%s", snapName, plug.Name, slot.Snap.Name(), slot.Name)
-		conns[key] = connState{Interface: plug.Interface, Auto: true}
 	}
-	task.State().Set("conns", conns)
+	st.Set("conns", conns)
+	return affected, nil
+}
+
+// snapDeclaration returns the snap-declaration of the given snap, or nil
+// if the snap has none, as it happens for snaps not from the store.
+func snapDeclaration(st *state.State, snapInfo *snap.Info) (*asserts.SnapDeclaration, error) {
+	if snapInfo.SnapID == "" {
+		return nil, nil
+	}
+	snapDecl, err := assertstate.SnapDeclaration(st, snapInfo.SnapID)
+	if err == asserts.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot find snap-declaration for snap %q: %v", snapInfo.Name(), err)
+	}
+	return snapDecl, nil
+}
+
+// connectCandidate returns the policy connection candidate for the given
+// plug and slot.
+func connectCandidate(st *state.State, plug *interfaces.Plug, slot *interfaces.Slot) (*policy.ConnectCandidate, error) {
+	plugDecl, err := snapDeclaration(st, plug.Snap)
+	if err != nil {
+		return nil, err
+	}
+	slotDecl, err := snapDeclaration(st, slot.Snap)
+	if err != nil {
+		return nil, err
+	}
+	return &policy.ConnectCandidate{
+		Plug:                plug,
+		PlugSnapDeclaration: plugDecl,
+		Slot:                slot,
+		SlotSnapDeclaration: slotDecl,
+		BaseDeclaration:     policy.BaseDeclaration(),
+	}, nil
+}
+
+// checkInstallPolicy checks whether the plugs and slots of the given snap
+// are allowed to be installed.
+func checkInstallPolicy(st *state.State, snapInfo *snap.Info) error {
+	snapDecl, err := snapDeclaration(st, snapInfo)
+	if err != nil {
+		return err
+	}
+	candidate := &policy.InstallCandidate{
+		Snap:            snapInfo,
+		SnapDeclaration: snapDecl,
+		BaseDeclaration: policy.BaseDeclaration(),
+	}
+	if err := candidate.Check(); err != nil {
+		return fmt.Errorf("cannot install snap %q: %v", snapInfo.Name(), err)
+	}
+	return nil
+}
+
+// checkConnectPolicy checks whether the given plug and slot are allowed to
+// be connected. Missing plugs or slots are left for Connect to report.
+func (m *InterfaceManager) checkConnectPolicy(st *state.State, plugRef *interfaces.PlugRef, slotRef *interfaces.SlotRef) error {
+	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
+	slot := m.repo.Slot(slotRef.Snap, slotRef.Name)
+	if plug == nil || slot == nil || plug.Interface != slot.Interface {
+		return nil
+	}
+	candidate, err := connectCandidate(st, plug, slot)
+	if err != nil {
+		return err
+	}
+	if err := candidate.Check(); err != nil {
+		return fmt.Errorf("cannot connect %s:%s to %s:%s: %v", plugRef.Snap, plugRef.Name, slotRef.Snap, slotRef.Name, err)
+	}
 	return nil
 }
 
+// filterAutoConnect returns the candidate slots the plug is allowed to be
+// automatically connected to.
+func (m *InterfaceManager) filterAutoConnect(st *state.State, plug *interfaces.Plug, candidates []*interfaces.Slot) ([]*interfaces.Slot, error) {
+	var allowed []*interfaces.Slot
+	for _, slot := range candidates {
+		candidate, err := connectCandidate(st, plug, slot)
+		if err != nil {
+			return nil, err
+		}
+		if err := candidate.CheckAutoConnect(); err != nil {
+			continue
+		}
+		allowed = append(allowed, slot)
+	}
+	return allowed, nil
+}
+
+// grantedAutoConnectCandidates returns all the slots of the interface of
+// the plug if its snap-declaration explicitly grants it auto-connection.
+func (m *InterfaceManager) grantedAutoConnectCandidates(st *state.State, plug *interfaces.Plug) ([]*interfaces.Slot, error) {
+	plugDecl, err := snapDeclaration(st, plug.Snap)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.AutoConnectGranted(plugDecl, plug.Interface) {
+		return nil, nil
+	}
+	return m.repo.AllSlots(plug.Interface), nil
+}
+
+// defaultProviderCandidates returns the slots of the snap named by the
+// default-provider attribute of the plug that match its interface, as long
+// as the interface allows its plugs to be connected automatically, either
+// by itself or by the policy.
+func (m *InterfaceManager) defaultProviderCandidates(st *state.State, plug *interfaces.Plug) ([]*interfaces.Slot, error) {
+	provider := plug.DefaultProvider()
+	if provider == "" {
+		return nil, nil
+	}
+	iface := m.repo.Interface(plug.Interface)
+	if iface == nil {
+		return nil, nil
+	}
+	if !iface.AutoConnect() {
+		plugDecl, err := snapDeclaration(st, plug.Snap)
+		if err != nil {
+			return nil, err
+		}
+		if !policy.AutoConnectAllowed(plugDecl, policy.BaseDeclaration(), plug.Interface) {
+			return nil, nil
+		}
+	}
+	var candidates []*interfaces.Slot
+	for _, slot := range m.repo.Slots(provider) {
+		if slot.Interface == plug.Interface {
+			candidates = append(candidates, slot)
+		}
+	}
+	return candidates, nil
+}
+
 func getPlugAndSlotRefs(task *state.Task) (*interfaces.PlugRef, *interfaces.SlotRef, error) {
 	var plugRef interfaces.PlugRef
 	var slotRef interfaces.SlotRef
@@ -204,6 +506,18 @@ func getPlugAndSlotRefs(task *state.Task) (*interfaces.PlugRef, *interfaces.Slot
 	return &plugRef, &slotRef, nil
 }
 
+// getDynamicAttrs returns the attributes set on each side of the connection
+// made by the given connect task, as stored by ConnectWithAttrs.
+func getDynamicAttrs(task *state.Task) (plugAttrs, slotAttrs map[string]interface{}, err error) {
+	if err := task.Get("plug-dynamic", &plugAttrs); err != nil && err != state.ErrNoState {
+		return nil, nil, err
+	}
+	if err := task.Get("slot-dynamic", &slotAttrs); err != nil && err != state.ErrNoState {
+		return nil, nil, err
+	}
+	return plugAttrs, slotAttrs, nil
+}
+
 func getConns(st *state.State) (map[string]connState, error) {
 	// Get information about connections from the state
 	var conns map[string]connState
@@ -221,8 +535,26 @@ func setConns(st *state.State, conns map[string]connState) {
 	st.Set("conns", conns)
 }
 
+// getDisconnected returns the plugs, as "snap:plug", that were disconnected
+// by the user and so must not be connected automatically again.
+func getDisconnected(st *state.State) (map[string]bool, error) {
+	var disconnected map[string]bool
+	err := st.Get("disconnected-plugs", &disconnected)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot obtain data about disconnected plugs: %s", err)
+	}
+	if disconnected == nil {
+		disconnected = make(map[string]bool)
+	}
+	return disconnected, nil
+}
+
+func setDisconnected(st *state.State, disconnected map[string]bool) {
+	st.Set("disconnected-plugs", disconnected)
+}
+
 var securityBackends = []interfaces.SecurityBackend{
-	&seccomp.Backend{}, &dbus.Backend{}, &udev.Backend{},
+	&seccomp.Backend{}, &dbus.Backend{}, &udev.Backend{}, &mount.Backend{},
 }
 
 func init() {
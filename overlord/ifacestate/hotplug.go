@@ -0,0 +1,442 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// hotplugEventSource delivers the events about devices appearing and
+// disappearing at runtime.
+type hotplugEventSource interface {
+	// Enumerate returns the devices present already.
+	Enumerate() ([]*hotplug.DeviceInfo, error)
+	// Run calls handle for each device event until stop is closed.
+	Run(handle func(dev *hotplug.DeviceInfo), stop <-chan struct{}) error
+}
+
+var newHotplugEventSource = func() (hotplugEventSource, error) {
+	monitor, err := hotplug.NewUDevMonitor()
+	if err != nil {
+		return nil, err
+	}
+	return monitor, nil
+}
+
+// hotplugSlotState describes a slot of the core snap created for a
+// hotplugged device. It is remembered after the device is removed, so
+// that the device gets the same slot, with its connections, when it
+// comes back.
+type hotplugSlotState struct {
+	Name      string                 `json:"name"`
+	Interface string                 `json:"interface"`
+	Key       string                 `json:"key"`
+	DevPath   string                 `json:"dev-path,omitempty"`
+	Attrs     map[string]interface{} `json:"attrs,omitempty"`
+	Present   bool                   `json:"present,omitempty"`
+}
+
+func getHotplugSlots(st *state.State) (map[string]*hotplugSlotState, error) {
+	var slots map[string]*hotplugSlotState
+	err := st.Get("hotplug-slots", &slots)
+	if err != nil && err != state.ErrNoState {
+		return nil, fmt.Errorf("cannot obtain data about hotplug slots: %s", err)
+	}
+	if slots == nil {
+		slots = make(map[string]*hotplugSlotState)
+	}
+	return slots, nil
+}
+
+func setHotplugSlots(st *state.State, slots map[string]*hotplugSlotState) {
+	st.Set("hotplug-slots", slots)
+}
+
+// coreSnapInfo returns the information about the active core snap, that
+// gets the slots of the hotplugged devices.
+func coreSnapInfo(st *state.State) (*snap.Info, error) {
+	infos, err := snapstate.ActiveInfos(st)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		if info.Type == snap.TypeOS {
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot find the core snap")
+}
+
+// resetHotplugSlots marks all the hotplug slots as not present, their
+// devices are announced again once hotplug support starts.
+func (m *InterfaceManager) resetHotplugSlots() error {
+	slots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	if len(slots) == 0 {
+		return nil
+	}
+	for _, slot := range slots {
+		slot.Present = false
+		slot.DevPath = ""
+	}
+	setHotplugSlots(m.state, slots)
+	return nil
+}
+
+// StartHotplug starts listening to udev for devices being added and
+// removed, creating and removing the matching hotplug slots. It is meant
+// to be called once by the daemon, the listening stops with Stop.
+func (m *InterfaceManager) StartHotplug() {
+	if m.hotplugTomb != nil {
+		return
+	}
+	source, err := newHotplugEventSource()
+	if err != nil {
+		logger.Noticef("cannot start hotplug support: %v", err)
+		return
+	}
+	m.hotplugTomb = &tomb.Tomb{}
+	m.hotplugTomb.Go(func() error {
+		devices, err := source.Enumerate()
+		if err != nil {
+			logger.Noticef("%v", err)
+		}
+		for _, dev := range devices {
+			m.hotplugDeviceEvent(dev)
+		}
+		return source.Run(m.hotplugDeviceEvent, m.hotplugTomb.Dying())
+	})
+}
+
+// stopHotplug stops listening to the hotplug events.
+func (m *InterfaceManager) stopHotplug() {
+	if m.hotplugTomb == nil {
+		return
+	}
+	m.hotplugTomb.Kill(nil)
+	if err := m.hotplugTomb.Wait(); err != nil {
+		logger.Noticef("%v", err)
+	}
+	m.hotplugTomb = nil
+}
+
+func (m *InterfaceManager) hotplugDeviceEvent(dev *hotplug.DeviceInfo) {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	var err error
+	switch dev.Action {
+	case "add":
+		err = m.hotplugDeviceAdded(dev)
+	case "remove":
+		err = m.hotplugDeviceRemoved(dev)
+	default:
+		return
+	}
+	if err != nil {
+		logger.Noticef("cannot handle %s event of device %s: %v", dev.Action, dev.DevPath, err)
+		return
+	}
+	m.state.EnsureBefore(0)
+}
+
+// hotplugDeviceAdded asks the interfaces to define slots for the device
+// and queues their addition to the core snap.
+func (m *InterfaceManager) hotplugDeviceAdded(dev *hotplug.DeviceInfo) error {
+	slots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	var coreName string
+	key := dev.Key()
+	for _, iface := range m.hotplugIfaces {
+		spec, err := iface.(hotplug.Definer).HotplugDeviceDetected(dev)
+		if err != nil {
+			logger.Noticef("cannot define %s slot for device %s: %v", iface.Name(), dev.DevPath, err)
+			continue
+		}
+		if spec == nil {
+			continue
+		}
+		if coreName == "" {
+			coreInfo, err := coreSnapInfo(m.state)
+			if err != nil {
+				return err
+			}
+			coreName = coreInfo.Name()
+		}
+
+		slot := findHotplugSlot(slots, iface.Name(), key)
+		if slot == nil {
+			slot = &hotplugSlotState{
+				Name:      m.hotplugSlotName(coreName, spec.Name, slots),
+				Interface: iface.Name(),
+				Key:       key,
+			}
+			slots[slot.Name] = slot
+		} else if slot.Present {
+			continue
+		}
+		slot.DevPath = dev.DevPath
+		slot.Attrs = spec.Attrs
+		slot.Present = true
+		summary := fmt.Sprintf(i18n.G("Add slot %s:%s for device %s"), coreName, slot.Name, dev.DevPath)
+		m.queueHotplugTask("hotplug-add-slot", summary, slot.Name)
+	}
+	setHotplugSlots(m.state, slots)
+	return nil
+}
+
+// hotplugDeviceRemoved queues the removal of the slots of the device.
+func (m *InterfaceManager) hotplugDeviceRemoved(dev *hotplug.DeviceInfo) error {
+	slots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	removed := false
+	for _, slot := range slots {
+		if !slot.Present || slot.DevPath != dev.DevPath {
+			continue
+		}
+		slot.Present = false
+		slot.DevPath = ""
+		summary := fmt.Sprintf(i18n.G("Remove slot %s for device %s"), slot.Name, dev.DevPath)
+		m.queueHotplugTask("hotplug-remove-slot", summary, slot.Name)
+		removed = true
+	}
+	if removed {
+		setHotplugSlots(m.state, slots)
+	}
+	return nil
+}
+
+func findHotplugSlot(slots map[string]*hotplugSlotState, ifaceName, key string) *hotplugSlotState {
+	for _, slot := range slots {
+		if slot.Interface == ifaceName && slot.Key == key {
+			return slot
+		}
+	}
+	return nil
+}
+
+// hotplugSlotName returns the name for a new hotplug slot, which is the
+// preferred one unless taken by another slot of the core snap.
+func (m *InterfaceManager) hotplugSlotName(coreName, preferred string, slots map[string]*hotplugSlotState) string {
+	taken := func(name string) bool {
+		return slots[name] != nil || m.repo.Slot(coreName, name) != nil
+	}
+	name := preferred
+	for i := 1; taken(name); i++ {
+		name = fmt.Sprintf("%s-%d", preferred, i)
+	}
+	return name
+}
+
+// queueHotplugTask adds the given hotplug task to the pending hotplug
+// change, so that a burst of events results in a single change. The tasks
+// run in the order of the events and act on the state of their slot when
+// they run, so a task waiting to run already for the same slot is not
+// queued again.
+func (m *InterfaceManager) queueHotplugTask(kind, summary, slotName string) {
+	chg := m.hotplugChange
+	if chg != nil && chg.Status().Ready() {
+		chg = nil
+	}
+	var last *state.Task
+	if chg != nil {
+		for _, t := range chg.Tasks() {
+			var name string
+			if t.Kind() == kind && t.Status() == state.DoStatus && t.Get("slot-name", &name) == nil && name == slotName {
+				return
+			}
+			last = t
+		}
+	}
+	task := m.state.NewTask(kind, summary)
+	task.Set("slot-name", slotName)
+	if last != nil {
+		task.WaitFor(last)
+	} else {
+		chg = m.state.NewChange("hotplug", i18n.G("Update the slots of hotplugged devices"))
+		m.hotplugChange = chg
+	}
+	chg.AddTask(task)
+}
+
+// addHotplugSlot adds the slot of a hotplugged device to the core snap in
+// the repository, unless it is there already.
+func (m *InterfaceManager) addHotplugSlot(coreInfo *snap.Info, hotplugSlot *hotplugSlotState) error {
+	if m.repo.Slot(coreInfo.Name(), hotplugSlot.Name) != nil {
+		return nil
+	}
+	slot := &interfaces.Slot{SlotInfo: &snap.SlotInfo{
+		Snap:      coreInfo,
+		Name:      hotplugSlot.Name,
+		Interface: hotplugSlot.Interface,
+		Attrs:     hotplugSlot.Attrs,
+	}}
+	return m.repo.AddSlot(slot)
+}
+
+// addHotplugSlots adds the slots of the hotplugged devices present to the
+// core snap in the repository.
+func (m *InterfaceManager) addHotplugSlots(coreInfo *snap.Info) error {
+	slots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if !slot.Present {
+			continue
+		}
+		if err := m.addHotplugSlot(coreInfo, slot); err != nil {
+			logger.Noticef("%s", err)
+		}
+	}
+	return nil
+}
+
+func taskHotplugSlot(task *state.Task) (*hotplugSlotState, error) {
+	var name string
+	if err := task.Get("slot-name", &name); err != nil {
+		return nil, err
+	}
+	slots, err := getHotplugSlots(task.State())
+	if err != nil {
+		return nil, err
+	}
+	slot := slots[name]
+	if slot == nil {
+		return nil, fmt.Errorf("internal error: unknown hotplug slot %q", name)
+	}
+	return slot, nil
+}
+
+// doHotplugAddSlot adds the slot of a hotplugged device to the core snap
+// and connects back the plugs it was connected to before.
+func (m *InterfaceManager) doHotplugAddSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	hotplugSlot, err := taskHotplugSlot(task)
+	if err != nil {
+		return err
+	}
+	if !hotplugSlot.Present {
+		// the device went away in the meantime
+		return nil
+	}
+	coreInfo, err := coreSnapInfo(st)
+	if err != nil {
+		return err
+	}
+	if err := m.addHotplugSlot(coreInfo, hotplugSlot); err != nil {
+		return err
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	var affected []*snap.Info
+	for id, conn := range conns {
+		plugRef, slotRef, err := parseConnID(id)
+		if err != nil {
+			return err
+		}
+		if slotRef.Snap != coreInfo.Name() || slotRef.Name != hotplugSlot.Name {
+			continue
+		}
+		if err := m.reconnect(plugRef, slotRef, conn); err != nil {
+			task.Logf("cannot reconnect %s: %s", id, err)
+			continue
+		}
+		affected = append(affected, m.repo.Plug(plugRef.Snap, plugRef.Name).Snap)
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+	for _, snapInfo := range append(affected, coreInfo) {
+		if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
+			return state.Retry
+		}
+	}
+	return nil
+}
+
+// doHotplugRemoveSlot disconnects the slot of a removed device and removes
+// it from the core snap. The connections are kept in the state, to be
+// established again when the device comes back.
+func (m *InterfaceManager) doHotplugRemoveSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	hotplugSlot, err := taskHotplugSlot(task)
+	if err != nil {
+		return err
+	}
+	if hotplugSlot.Present {
+		// the device came back in the meantime
+		return nil
+	}
+	coreInfo, err := coreSnapInfo(st)
+	if err != nil {
+		return err
+	}
+	slot := m.repo.Slot(coreInfo.Name(), hotplugSlot.Name)
+	if slot == nil {
+		return nil
+	}
+
+	var affected []*snap.Info
+	for _, plugRef := range slot.Connections {
+		plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
+		if err := m.repo.Disconnect(plugRef.Snap, plugRef.Name, coreInfo.Name(), hotplugSlot.Name); err != nil {
+			return err
+		}
+		affected = append(affected, plug.Snap)
+	}
+	if err := m.repo.RemoveSlot(coreInfo.Name(), hotplugSlot.Name); err != nil {
+		return err
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+	for _, snapInfo := range append(affected, coreInfo) {
+		if err := setupSnapSecurity(task, snapInfo, m.repo); err != nil {
+			return state.Retry
+		}
+	}
+	return nil
+}
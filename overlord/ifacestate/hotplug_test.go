@@ -0,0 +1,319 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var serialPortYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: serial
+`
+
+func serialDevice(action, devPath, serial string) *hotplug.DeviceInfo {
+	return &hotplug.DeviceInfo{
+		Action:    action,
+		DevPath:   devPath,
+		Subsystem: "tty",
+		Properties: map[string]string{
+			"DEVNAME":   "/dev/" + devPath[len(devPath)-7:],
+			"ID_SERIAL": serial,
+		},
+	}
+}
+
+func (s *interfaceManagerSuite) mockHotplug(c *C, devices ...*hotplug.DeviceInfo) *ifacestate.FakeHotplugEventSource {
+	s.mockIface(c, &interfaces.TestInterface{
+		InterfaceName: "serial",
+		HotplugDeviceDetectedCallback: func(dev *hotplug.DeviceInfo) (*hotplug.SlotSpec, error) {
+			if dev.Subsystem != "tty" {
+				return nil, nil
+			}
+			return &hotplug.SlotSpec{
+				Name:  "serial",
+				Attrs: map[string]interface{}{"path": dev.DeviceName()},
+			}, nil
+		},
+	})
+	source := &ifacestate.FakeHotplugEventSource{
+		Devices: devices,
+		Events:  make(chan *hotplug.DeviceInfo),
+		Handled: make(chan bool),
+	}
+	s.restoreHotplug()
+	s.restoreHotplug = ifacestate.MockHotplugEventSource(source)
+	return source
+}
+
+func (s *interfaceManagerSuite) sendHotplugEvent(c *C, mgr *ifacestate.InterfaceManager, source *ifacestate.FakeHotplugEventSource, dev *hotplug.DeviceInfo) {
+	source.Events <- dev
+	<-source.Handled
+	mgr.Ensure()
+	mgr.Wait()
+}
+
+func (s *interfaceManagerSuite) TestHotplugAddsAndRemovesSlots(c *C) {
+	source := s.mockHotplug(c)
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnap(c, serialPortYaml)
+	mgr := s.manager(c)
+	mgr.StartHotplug()
+	<-source.Handled
+
+	s.sendHotplugEvent(c, mgr, source, serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"))
+	slot := mgr.Repository().Slot("ubuntu-core", "serial")
+	c.Assert(slot, Not(IsNil))
+	c.Check(slot.Interface, Equals, "serial")
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+
+	// connect the plug to the new slot
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "ubuntu-core", "serial")
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+	mgr.Ensure()
+	mgr.Wait()
+	s.state.Lock()
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug.Connections, HasLen, 1)
+
+	// removing the device disconnects the plug and removes the slot
+	s.secBackend.SetupCalls = nil
+	s.sendHotplugEvent(c, mgr, source, serialDevice("remove", "/devices/usb1/1-1/ttyUSB0", "serial-1"))
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial"), IsNil)
+	c.Check(plug.Connections, HasLen, 0)
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "ubuntu-core")
+
+	// the connection is remembered
+	s.state.Lock()
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug ubuntu-core:serial": map[string]interface{}{"interface": "serial"},
+	})
+
+	// the device comes back in another port with the same slot and connection
+	s.secBackend.SetupCalls = nil
+	s.sendHotplugEvent(c, mgr, source, serialDevice("add", "/devices/usb1/1-2/ttyUSB1", "serial-1"))
+	slot = mgr.Repository().Slot("ubuntu-core", "serial")
+	c.Assert(slot, Not(IsNil))
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB1"})
+	c.Check(slot.Connections, DeepEquals, []interfaces.PlugRef{{Snap: "consumer", Name: "plug"}})
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "consumer")
+}
+
+func (s *interfaceManagerSuite) TestHotplugNotStartedByEnsure(c *C) {
+	source := s.mockHotplug(c, serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"))
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	mgr.Ensure()
+	mgr.Wait()
+
+	select {
+	case <-source.Handled:
+		c.Fatal("hotplug started by Ensure")
+	default:
+	}
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial"), IsNil)
+}
+
+func (s *interfaceManagerSuite) TestHotplugStableSlotNames(c *C) {
+	source := s.mockHotplug(c,
+		serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"),
+		serialDevice("add", "/devices/usb1/1-2/ttyUSB1", "serial-2"))
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	mgr.StartHotplug()
+	<-source.Handled
+	// the task of the second device waits for the one of the first
+	for i := 0; i < 2; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+	}
+
+	repo := mgr.Repository()
+	c.Assert(repo.Slot("ubuntu-core", "serial"), Not(IsNil))
+	c.Check(repo.Slot("ubuntu-core", "serial").Attrs["path"], Equals, "/dev/ttyUSB0")
+	c.Assert(repo.Slot("ubuntu-core", "serial-1"), Not(IsNil))
+	c.Check(repo.Slot("ubuntu-core", "serial-1").Attrs["path"], Equals, "/dev/ttyUSB1")
+
+	// the first device goes away, a third one does not take its name
+	s.sendHotplugEvent(c, mgr, source, serialDevice("remove", "/devices/usb1/1-1/ttyUSB0", "serial-1"))
+	s.sendHotplugEvent(c, mgr, source, serialDevice("add", "/devices/usb1/1-3/ttyUSB2", "serial-3"))
+	c.Check(repo.Slot("ubuntu-core", "serial"), IsNil)
+	c.Assert(repo.Slot("ubuntu-core", "serial-2"), Not(IsNil))
+	c.Check(repo.Slot("ubuntu-core", "serial-2").Attrs["path"], Equals, "/dev/ttyUSB2")
+
+	// and it gets its name back when it returns
+	s.sendHotplugEvent(c, mgr, source, serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"))
+	c.Assert(repo.Slot("ubuntu-core", "serial"), Not(IsNil))
+}
+
+func (s *interfaceManagerSuite) TestHotplugIgnoresUnhandledDevices(c *C) {
+	source := s.mockHotplug(c)
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	mgr.StartHotplug()
+	<-source.Handled
+
+	dev := serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1")
+	dev.Subsystem = "block"
+	s.sendHotplugEvent(c, mgr, source, dev)
+
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial"), IsNil)
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestHotplugSlotsAbsentAfterRestart(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "serial"})
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnap(c, serialPortYaml)
+
+	s.state.Lock()
+	s.state.Set("hotplug-slots", map[string]interface{}{
+		"serial": map[string]interface{}{
+			"name": "serial", "interface": "serial", "key": "serial-1",
+			"dev-path": "/devices/usb1/1-1/ttyUSB0", "present": true,
+		},
+	})
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug ubuntu-core:serial": map[string]interface{}{"interface": "serial"},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	c.Check(mgr.Repository().Slot("ubuntu-core", "serial"), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var slots map[string]interface{}
+	c.Assert(s.state.Get("hotplug-slots", &slots), IsNil)
+	c.Check(slots, DeepEquals, map[string]interface{}{
+		"serial": map[string]interface{}{
+			"name": "serial", "interface": "serial", "key": "serial-1",
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestHotplugCoalescesEvents(c *C) {
+	source := s.mockHotplug(c)
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	mgr.StartHotplug()
+	<-source.Handled
+
+	// a burst of events before the tasks get to run
+	for _, dev := range []*hotplug.DeviceInfo{
+		serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"),
+		serialDevice("add", "/devices/usb1/1-2/ttyUSB1", "serial-2"),
+		serialDevice("remove", "/devices/usb1/1-1/ttyUSB0", "serial-1"),
+		serialDevice("add", "/devices/usb1/1-1/ttyUSB0", "serial-1"),
+	} {
+		source.Events <- dev
+		<-source.Handled
+	}
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	change := s.state.Changes()[0]
+	c.Check(change.Kind(), Equals, "hotplug")
+	var kinds []string
+	for _, t := range change.Tasks() {
+		var name string
+		c.Assert(t.Get("slot-name", &name), IsNil)
+		kinds = append(kinds, t.Kind()+" "+name)
+	}
+	// the second addition of the first device is covered by the first one
+	c.Check(kinds, DeepEquals, []string{
+		"hotplug-add-slot serial",
+		"hotplug-add-slot serial-1",
+		"hotplug-remove-slot serial",
+	})
+	s.state.Unlock()
+
+	for i := 0; i < 3; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+	}
+	s.state.Lock()
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+	repo := mgr.Repository()
+	c.Check(repo.Slot("ubuntu-core", "serial"), Not(IsNil))
+	c.Check(repo.Slot("ubuntu-core", "serial-1"), Not(IsNil))
+
+	// the next event, once the change is done, gets a new one
+	s.sendHotplugEvent(c, mgr, source, serialDevice("remove", "/devices/usb1/1-2/ttyUSB1", "serial-2"))
+	c.Check(repo.Slot("ubuntu-core", "serial-1"), IsNil)
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 2)
+}
+
+func (s *interfaceManagerSuite) TestHotplugBuiltinSerialPort(c *C) {
+	source := &ifacestate.FakeHotplugEventSource{
+		Devices: []*hotplug.DeviceInfo{{
+			Action:    "add",
+			DevPath:   "/devices/usb1/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0",
+			Subsystem: "tty",
+			Properties: map[string]string{
+				"DEVNAME":   "/dev/ttyUSB0",
+				"ID_BUS":    "usb",
+				"ID_MODEL":  "FT232R_USB_UART",
+				"ID_SERIAL": "FTDI_FT232R_USB_UART_A1234",
+			},
+		}},
+		Events:  make(chan *hotplug.DeviceInfo),
+		Handled: make(chan bool),
+	}
+	s.restoreHotplug()
+	s.restoreHotplug = ifacestate.MockHotplugEventSource(source)
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	mgr.StartHotplug()
+	<-source.Handled
+	mgr.Ensure()
+	mgr.Wait()
+
+	slot := mgr.Repository().Slot("ubuntu-core", "serial-port-ft232r-usb-uart")
+	c.Assert(slot, Not(IsNil))
+	c.Check(slot.Interface, Equals, "serial-port")
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+}
@@ -22,11 +22,15 @@
 package ifacestate
 
 import (
+	"errors"
 	"fmt"
 
+	"gopkg.in/tomb.v2"
+
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 // InterfaceManager is responsible for the maintenance of interfaces in
@@ -36,6 +40,10 @@ type InterfaceManager struct {
 	state  *state.State
 	runner *state.TaskRunner
 	repo   *interfaces.Repository
+
+	hotplugIfaces []interfaces.Interface
+	hotplugTomb   *tomb.Tomb
+	hotplugChange *state.Change
 }
 
 // Manager returns a new InterfaceManager.
@@ -55,12 +63,23 @@ func Manager(s *state.State, extra []interfaces.Interface) (*InterfaceManager, e
 	runner.AddHandler("setup-profiles", m.doSetupProfiles, m.doRemoveProfiles)
 	runner.AddHandler("remove-profiles", m.doRemoveProfiles, m.doSetupProfiles)
 	runner.AddHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
+	runner.AddHandler("hotplug-add-slot", m.doHotplugAddSlot, nil)
+	runner.AddHandler("hotplug-remove-slot", m.doHotplugRemoveSlot, nil)
+	runner.AddHandler("regenerate-snap-profiles", m.doRegenerateSnapProfiles, nil)
+	runner.AddHandler("update-system-key", m.doUpdateSystemKey, nil)
 	return m, nil
 }
 
 // Connect returns a set of tasks for connecting an interface.
 //
 func Connect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*state.TaskSet, error) {
+	return ConnectWithAttrs(s, plugSnap, plugName, slotSnap, slotName, nil, nil)
+}
+
+// ConnectWithAttrs returns a set of tasks for connecting an interface,
+// setting the given attributes on the plug and the slot side of the
+// connection.
+func ConnectWithAttrs(s *state.State, plugSnap, plugName, slotSnap, slotName string, plugAttrs, slotAttrs map[string]interface{}) (*state.TaskSet, error) {
 	// TODO: Store the intent-to-connect in the state so that we automatically
 	// try to reconnect on reboot (reconnection can fail or can connect with
 	// different parameters so we cannot store the actual connection details).
@@ -69,6 +88,12 @@ func Connect(s *state.State, plugSnap, plugName, slotSnap, slotName string) (*st
 	task := s.NewTask("connect", summary)
 	task.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
 	task.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
+	if len(plugAttrs) != 0 {
+		task.Set("plug-dynamic", plugAttrs)
+	}
+	if len(slotAttrs) != 0 {
+		task.Set("slot-dynamic", slotAttrs)
+	}
 	return state.NewTaskSet(task), nil
 }
 
@@ -97,8 +122,8 @@ func (m *InterfaceManager) Wait() {
 
 // Stop implements StateManager.Stop.
 func (m *InterfaceManager) Stop() {
+	m.stopHotplug()
 	m.runner.Stop()
-
 }
 
 // Repository returns the interface repository used internally by the manager.
@@ -113,6 +138,45 @@ func (m *InterfaceManager) Repository() *interfaces.Repository {
 	return m.repo
 }
 
+// Interfaces returns the plugs, slots and connections in the repository,
+// telling which connections were made automatically.
+func (m *InterfaceManager) Interfaces() (*interfaces.Interfaces, error) {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	conns, err := getConns(m.state)
+	if err != nil {
+		return nil, err
+	}
+	ifaces := m.repo.Interfaces()
+	for _, conn := range ifaces.Connections {
+		conn.Auto = conns[connID(&conn.Plug, &conn.Slot)].Auto
+	}
+	return ifaces, nil
+}
+
+// ErrUnknownSecurityBackend is returned by SecurityProfiles when no security
+// backend in use can show profiles under the given name.
+var ErrUnknownSecurityBackend = errors.New("unknown security backend")
+
+// SecurityProfiles returns the profiles the security backend with the given
+// name generates for the applications of a snap, indexed by application name.
+// Each snippet of the profiles is annotated with the plug or slot and the
+// interface that produced it. Nothing is written or loaded.
+func (m *InterfaceManager) SecurityProfiles(snapInfo *snap.Info, devMode bool, backendName string) (map[string][]byte, error) {
+	for _, backend := range securityBackends {
+		if backend.Name() != backendName {
+			continue
+		}
+		profiler, ok := backend.(interfaces.SecurityProfiler)
+		if !ok {
+			break
+		}
+		return profiler.Profiles(snapInfo, devMode, m.repo)
+	}
+	return nil, ErrUnknownSecurityBackend
+}
+
 // MockSecurityBackends mocks the list of security backends that are used for setting up security.
 //
 // This function is public because it is referenced in the daemon
@@ -20,12 +20,20 @@
 package ifacestate_test
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -37,10 +45,13 @@ func TestInterfaceManager(t *testing.T) { TestingT(t) }
 
 type interfaceManagerSuite struct {
 	state           *state.State
+	db              *asserts.Database
+	storeSigning    *assertstest.SigningDB
 	privateMgr      *ifacestate.InterfaceManager
 	extraIfaces     []interfaces.Interface
 	secBackend      *interfaces.TestSecurityBackend
 	restoreBackends func()
+	restoreHotplug  func()
 }
 
 var _ = Suite(&interfaceManagerSuite{})
@@ -53,6 +64,21 @@ func (s *interfaceManagerSuite) SetUpTest(c *C) {
 	s.extraIfaces = nil
 	s.secBackend = &interfaces.TestSecurityBackend{}
 	s.restoreBackends = ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{s.secBackend})
+	s.restoreHotplug = ifacestate.MockHotplugEventSource(&ifacestate.FakeHotplugEventSource{})
+
+	rootPrivKey := assertstest.GenerateKey(752)
+	s.storeSigning = assertstest.NewSigningDB("canonical", rootPrivKey)
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		TrustedKeys:    []*asserts.AccountKey{assertstest.NewAccountKey(s.storeSigning, "canonical", rootPrivKey.PublicKey())},
+	})
+	c.Assert(err, IsNil)
+	s.db = db
+	state.Lock()
+	assertstate.ReplaceDB(state, s.db)
+	state.Set("system-key", ifacestate.SystemKey())
+	state.Unlock()
 }
 
 func (s *interfaceManagerSuite) TearDownTest(c *C) {
@@ -61,6 +87,7 @@ func (s *interfaceManagerSuite) TearDownTest(c *C) {
 	}
 	dirs.SetRootDir("")
 	s.restoreBackends()
+	s.restoreHotplug()
 }
 
 func (s *interfaceManagerSuite) manager(c *C) *ifacestate.InterfaceManager {
@@ -132,6 +159,133 @@ func (s *interfaceManagerSuite) TestEnsureProcessesConnectTask(c *C) {
 	c.Check(slot.Connections[0], DeepEquals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
 }
 
+func (s *interfaceManagerSuite) TestEnsureProcessesConnectTaskWithDynamicAttrs(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.ConnectWithAttrs(s.state, "consumer", "plug", "producer", "slot",
+		map[string]interface{}{"port": "8080"}, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+
+	plugAttrs, slotAttrs, err := mgr.Repository().ConnectionAttrs("consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, DeepEquals, map[string]interface{}{"port": "8080"})
+	c.Check(slotAttrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-dynamic": map[string]interface{}{"port": "8080"},
+			"slot-dynamic": map[string]interface{}{"path": "/dev/ttyUSB0"},
+		},
+	})
+}
+
+func (s *interfaceManagerSuite) TestEnsureProcessesConnectTaskWithStaticAttrsFails(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, `
+name: producer
+version: 1
+slots:
+ slot:
+  interface: test
+  path: /dev/ttyS0
+`)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.ConnectWithAttrs(s.state, "consumer", "plug", "producer", "slot",
+		nil, map[string]interface{}{"path": "/dev/ttyUSB0"})
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot set attribute "path" of slot producer:slot, it is already set in snap.yaml.*`)
+	// The connection is not left behind.
+	c.Check(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestEnsureProcessesConnectTaskWithRefusedDynamicAttrsFails(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{
+		InterfaceName: "test",
+		SanitizeConnectionCallback: func(plug *interfaces.Plug, slot *interfaces.Slot) error {
+			return fmt.Errorf("path not allowed")
+		},
+	})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.ConnectWithAttrs(s.state, "consumer", "plug", "producer", "slot",
+		nil, map[string]interface{}{"path": "/etc/shadow"})
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot set attributes of connection between consumer:plug and producer:slot: path not allowed.*`)
+	c.Check(mgr.Repository().Plug("consumer", "plug").Connections, HasLen, 0)
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Check(err, Equals, state.ErrNoState)
+}
+
+func (s *interfaceManagerSuite) TestReloadingConnectionsRestoresDynamicAttrs(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"slot-dynamic": map[string]interface{}{"path": "/dev/ttyUSB0"},
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	plugAttrs, slotAttrs, err := mgr.Repository().ConnectionAttrs("consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	c.Check(plugAttrs, IsNil)
+	c.Check(slotAttrs, DeepEquals, map[string]interface{}{"path": "/dev/ttyUSB0"})
+}
+
 func (s *interfaceManagerSuite) TestDisconnectTask(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -210,6 +364,39 @@ func (s *interfaceManagerSuite) mockSnap(c *C, yamlText string) *snap.Info {
 	return snapInfo
 }
 
+func (s *interfaceManagerSuite) mockSnapWithID(c *C, yamlText, snapID string) *snap.Info {
+	sideInfo := &snap.SideInfo{SnapID: snapID}
+	snapInfo := snaptest.MockSnap(c, yamlText, sideInfo)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, snapInfo.Name(), &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{sideInfo},
+	})
+	return snapInfo
+}
+
+// mockSnapDecl adds to the assertion database a snap-declaration for the
+// given snap with the given plugs and slots rules.
+func (s *interfaceManagerSuite) mockSnapDecl(c *C, snapID, snapName string, rules map[string]string) {
+	headers := map[string]string{
+		"series":       "16",
+		"snap-id":      snapID,
+		"snap-name":    snapName,
+		"publisher-id": "publisher-id",
+		"gates":        "",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	for k, v := range rules {
+		headers[k] = v
+	}
+	a, err := s.storeSigning.Sign(asserts.SnapDeclarationType, headers, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(a), IsNil)
+}
+
 func (s *interfaceManagerSuite) mockUpdatedSnap(c *C, yamlText string, revision int) *snap.Info {
 	sideInfo := &snap.SideInfo{Revision: snap.R(revision)}
 	snapInfo := snaptest.MockSnap(c, yamlText, sideInfo)
@@ -376,6 +563,300 @@ func (s *interfaceManagerSuite) TestDoSetupSnapSecuirtyAutoConnects(c *C) {
 	c.Check(plug.Connections, HasLen, 1)
 }
 
+func (s *interfaceManagerSuite) mockAutoConnectConfig(c *C, config string) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapInterfacesConfigFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapInterfacesConfigFile, []byte(config), 0644), IsNil)
+}
+
+// The setup-profiles task will not auto-connect what the administrator
+// disabled auto-connection of.
+func (s *interfaceManagerSuite) testAutoConnectDisabled(c *C, config, reason string) {
+	s.mockAutoConnectConfig(c, config)
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnap(c, sampleSnapYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, HasLen, 0)
+	c.Check(change.Tasks()[0].Log(), HasLen, 1)
+	c.Check(change.Tasks()[0].Log()[0], Matches,
+		`.* not auto connecting snap:network to ubuntu-core:network: `+reason)
+	c.Check(mgr.Repository().Plug("snap", "network").Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityHonorsAutoConnectConfigInterface(c *C) {
+	s.testAutoConnectDisabled(c, "interfaces:\n  network:\n    auto-connect: false\n",
+		`auto-connection of interface "network" is disabled`)
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityHonorsAutoConnectConfigPlugSnap(c *C) {
+	s.testAutoConnectDisabled(c, "snaps:\n  snap:\n    auto-connect: false\n",
+		`auto-connection of snap "snap" is disabled`)
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityHonorsAutoConnectConfigSlotSnap(c *C) {
+	s.testAutoConnectDisabled(c, "snaps:\n  ubuntu-core:\n    auto-connect: false\n",
+		`auto-connection of snap "ubuntu-core" is disabled`)
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectConfigOtherInterface(c *C) {
+	s.mockAutoConnectConfig(c, "interfaces:\n  camera:\n    auto-connect: false\n  network:\n    auto-connect: true\n")
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnap(c, sampleSnapYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	c.Check(mgr.Repository().Plug("snap", "network").Connections, HasLen, 1)
+}
+
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityBrokenAutoConnectConfig(c *C) {
+	s.mockAutoConnectConfig(c, "interfaces: [")
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+	snapInfo := s.mockSnap(c, sampleSnapYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot parse .*/etc/snapd/interfaces.yaml: .*`)
+}
+
+func (s *interfaceManagerSuite) TestInterfacesTellsAutoConnections(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnap(c, sampleSnapYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot":      map[string]interface{}{"interface": "test"},
+		"snap:network ubuntu-core:network": map[string]interface{}{"interface": "network", "auto": true},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	ifaces, err := mgr.Interfaces()
+	c.Assert(err, IsNil)
+	c.Check(ifaces.Connections, DeepEquals, []*interfaces.Connection{{
+		Plug: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		Slot: interfaces.SlotRef{Snap: "producer", Name: "slot"},
+	}, {
+		Plug: interfaces.PlugRef{Snap: "snap", Name: "network"},
+		Slot: interfaces.SlotRef{Snap: "ubuntu-core", Name: "network"},
+		Auto: true,
+	}})
+}
+
+var consumerWithDefaultProviderYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+  default-provider: producer
+`
+
+// The setup-profiles task will connect plugs to the slots of their default provider.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityConnectsDefaultProvider(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test", AutoConnectFlag: true})
+	s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, consumerWithDefaultProviderYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface": "test", "auto": true,
+		},
+	})
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 1)
+}
+
+// The setup-profiles task of a default provider will connect the plugs
+// waiting for it and set up the security of their snaps again.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityOfDefaultProviderConnectsPlugs(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test", AutoConnectFlag: true})
+	s.mockSnap(c, consumerWithDefaultProviderYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, producerYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 1)
+
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "producer")
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "consumer")
+}
+
+var firewallConsumerYaml = `
+name: consumer
+version: 1
+plugs:
+ fw:
+  interface: firewall-control
+  default-provider: ubuntu-core
+`
+
+var firewallCoreYaml = `
+name: ubuntu-core
+version: 1
+type: os
+slots:
+ fw:
+  interface: firewall-control
+`
+
+// Naming a default provider doesn't auto-connect a privileged interface.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityIgnoresDefaultProviderOfPrivilegedInterface(c *C) {
+	s.mockSnap(c, firewallCoreYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, firewallConsumerYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	plug := mgr.Repository().Plug("consumer", "fw")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+}
+
+// Naming a default provider doesn't auto-connect an interface that is not
+// auto-connected and has no policy saying otherwise.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityIgnoresDefaultProviderOfManualInterface(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnap(c, consumerWithDefaultProviderYaml)
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+}
+
+// Refreshing the default provider doesn't connect again a plug the user
+// disconnected from it.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityOfDefaultProviderKeepsDisconnectedPlugs(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test", AutoConnectFlag: true})
+	s.mockSnap(c, consumerWithDefaultProviderYaml)
+	producer := s.mockSnap(c, producerYaml)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	s.state.Unlock()
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	change := s.state.NewChange("disconnect", "")
+	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	s.state.Unlock()
+
+	change = s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: "producer", Revision: producer.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+
+	var conns map[string]interface{}
+	err = s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, HasLen, 0)
+}
+
 // The setup-profiles task will only touch connection state for the task it
 // operates on or auto-connects to and will leave other state intact.
 func (s *interfaceManagerSuite) TestDoSetupSnapSecuirtyKeepsExistingConnectionState(c *C) {
@@ -598,6 +1079,62 @@ func (s *interfaceManagerSuite) TestSetupProfilesUsesFreshSnapInfo(c *C) {
 	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Revision, Equals, coreSnapInfo.Revision)
 }
 
+// batchSecurityBackend is a test security backend able to setup many snaps
+// at once.
+type batchSecurityBackend struct {
+	interfaces.TestSecurityBackend
+	SetupManyCalls [][]string
+	DevModes       map[string]bool
+}
+
+func (b *batchSecurityBackend) SetupMany(snapInfos []*snap.Info, devModes map[string]bool, repo *interfaces.Repository) error {
+	var names []string
+	for _, snapInfo := range snapInfos {
+		names = append(names, snapInfo.Name())
+	}
+	b.SetupManyCalls = append(b.SetupManyCalls, names)
+	b.DevModes = devModes
+	return nil
+}
+
+func (s *interfaceManagerSuite) TestSetupProfilesSetsUpAffectedSnapsInOneBatch(c *C) {
+	batch := &batchSecurityBackend{}
+	restore := ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{batch, s.secBackend})
+	defer restore()
+
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnap(c, sampleSnapYaml)
+	s.state.Lock()
+	s.state.Set("system-key", ifacestate.SystemKey())
+	s.state.Set("conns", map[string]interface{}{
+		"snap:network ubuntu-core:network": map[string]interface{}{"interface": "network"},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	newSnapInfo := s.mockUpdatedSnap(c, sampleSnapYaml, 42)
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: newSnapInfo.Name(), Revision: newSnapInfo.Revision, Flags: snapstate.DevMode})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	// The batch backend got both snaps in one call.
+	c.Check(batch.SetupManyCalls, DeepEquals, [][]string{{"snap", "ubuntu-core"}})
+	c.Check(batch.DevModes, DeepEquals, map[string]bool{"snap": true, "ubuntu-core": false})
+	c.Check(batch.SetupCalls, HasLen, 0)
+	// The other backend set them up one after another.
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "snap")
+	c.Check(s.secBackend.SetupCalls[0].DevMode, Equals, true)
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "ubuntu-core")
+	c.Check(s.secBackend.SetupCalls[1].DevMode, Equals, false)
+}
+
 // The undo handler of the setup-profiles task will honor `old-devmode` that
 // is optionally stored in the task state and use it to set the DevMode flag in
 // the SnapState.
@@ -951,3 +1488,196 @@ func (s *interfaceManagerSuite) TestManagerReloadsConnections(c *C) {
 	c.Check(plug.Connections[0], DeepEquals, interfaces.SlotRef{Snap: "producer", Name: "slot"})
 	c.Check(slot.Connections[0], DeepEquals, interfaces.PlugRef{Snap: "consumer", Name: "plug"})
 }
+
+var snapdControlYaml = `
+name: snap
+version: 1
+plugs:
+ snapd-control:
+`
+
+// The setup-profiles task will not auto-connect plugs the base declaration
+// denies auto-connection to.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityHonorsBaseDeclaration(c *C) {
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnapWithID(c, snapdControlYaml, "snap-id")
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	plug := mgr.Repository().Plug("snap", "snapd-control")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+}
+
+// The setup-profiles task will auto-connect plugs the snap-declaration
+// grants auto-connection to.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectGrantedBySnapDeclaration(c *C) {
+	s.mockSnap(c, osSnapYaml)
+	mgr := s.manager(c)
+
+	snapInfo := s.mockSnapWithID(c, snapdControlYaml, "snap-id")
+	s.mockSnapDecl(c, "snap-id", "snap", map[string]string{
+		"plugs": "snapd-control:\n  allow-auto-connection: true",
+	})
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	var conns map[string]interface{}
+	err := s.state.Get("conns", &conns)
+	c.Assert(err, IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"snap:snapd-control ubuntu-core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control", "auto": true,
+		},
+	})
+}
+
+var sampleSnapWithNetworkManagerSlotYaml = `
+name: snap
+version: 1
+apps:
+ app:
+   command: foo
+plugs:
+ network:
+  interface: network
+slots:
+ network-manager:
+`
+
+// A refresh refused by the installation policy leaves the old revision of
+// the snap and its connections in the repository.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityRefusedKeepsRepository(c *C) {
+	s.mockSnap(c, osSnapYaml)
+	s.mockSnap(c, sampleSnapYaml)
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"snap:network ubuntu-core:network": map[string]interface{}{"interface": "network"},
+	})
+	s.state.Unlock()
+	mgr := s.manager(c)
+
+	newSnapInfo := s.mockUpdatedSnap(c, sampleSnapWithNetworkManagerSlotYaml, 42)
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: newSnapInfo.Name(), Revision: newSnapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot install snap "snap": installation of slot "network-manager".*`)
+	c.Check(mgr.Repository().Slot("snap", "network-manager"), IsNil)
+	plug := mgr.Repository().Plug("snap", "network")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 1)
+	c.Check(s.secBackend.SetupCalls, HasLen, 0)
+}
+
+var networkManagerSlotYaml = `
+name: producer
+version: 1
+slots:
+ network-manager:
+`
+
+// The setup-profiles task will refuse to install a slot the base
+// declaration reserves to the OS snap, unless the snap-declaration
+// allows it.
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityRefusesPrivilegedSlot(c *C) {
+	mgr := s.manager(c)
+	snapInfo := s.mockSnapWithID(c, networkManagerSlotYaml, "producer-id")
+
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+
+	s.state.Lock()
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot install snap "producer": installation of slot "network-manager" of interface "network-manager" not allowed by rule.*`)
+	c.Check(mgr.Repository().Slot("producer", "network-manager"), IsNil)
+	s.state.Unlock()
+
+	s.mockSnapDecl(c, "producer-id", "producer", map[string]string{
+		"slots": "network-manager:\n  allow-installation: true",
+	})
+	change = s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		Name: snapInfo.Name(), Revision: snapInfo.Revision})
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.DoneStatus)
+	c.Check(mgr.Repository().Slot("producer", "network-manager"), Not(IsNil))
+}
+
+// The connect task will refuse connections denied by the snap-declaration.
+func (s *interfaceManagerSuite) TestConnectDeniedBySnapDeclaration(c *C) {
+	s.mockIface(c, &interfaces.TestInterface{InterfaceName: "test"})
+	s.mockSnapWithID(c, consumerYaml, "consumer-id")
+	s.mockSnap(c, producerYaml)
+	s.mockSnapDecl(c, "consumer-id", "consumer", map[string]string{
+		"plugs": "test:\n  deny-connection: true",
+	})
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	change := s.state.NewChange("kind", "summary")
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	change.AddAll(ts)
+	s.state.Unlock()
+
+	mgr.Ensure()
+	mgr.Wait()
+	mgr.Stop()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(change.Status(), Equals, state.ErrorStatus)
+	c.Check(change.Err(), ErrorMatches, `(?s).*cannot connect consumer:plug to producer:slot: connection denied by rule of plug of interface "test".*`)
+	plug := mgr.Repository().Plug("consumer", "plug")
+	c.Assert(plug, Not(IsNil))
+	c.Check(plug.Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestSecurityProfiles(c *C) {
+	mgr := s.manager(c)
+	snapInfo := s.mockSnap(c, sampleSnapYaml)
+	s.secBackend.ProfilesCallback = func(info *snap.Info, devMode bool, repo *interfaces.Repository) (map[string][]byte, error) {
+		c.Check(info, Equals, snapInfo)
+		c.Check(devMode, Equals, true)
+		c.Check(repo, Equals, mgr.Repository())
+		return map[string][]byte{"app": []byte("profile")}, nil
+	}
+
+	profiles, err := mgr.SecurityProfiles(snapInfo, true, "test")
+	c.Assert(err, IsNil)
+	c.Check(profiles, DeepEquals, map[string][]byte{"app": []byte("profile")})
+
+	_, err = mgr.SecurityProfiles(snapInfo, true, "other")
+	c.Check(err, Equals, ifacestate.ErrUnknownSecurityBackend)
+}
@@ -0,0 +1,186 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// systemKey returns a key identifying what the security profiles depend on
+// besides the snaps and their connections: the security backends in use,
+// their templates and the kernel features they use. The profiles of all the
+// snaps are regenerated when the key changes, such as after snapd upgrades.
+func systemKey() string {
+	h := sha256.New()
+	for _, backend := range securityBackends {
+		fmt.Fprintf(h, "%s\n", backend.Name())
+		if fingerprinter, ok := backend.(interfaces.Fingerprinter); ok {
+			h.Write(fingerprinter.Fingerprint())
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// regenerateProfilesIfNeeded compares the system key stored in the state with
+// the current one and, when they differ, queues a change regenerating the
+// security profiles of all the active snaps.
+//
+// It expects the state to be locked.
+func (m *InterfaceManager) regenerateProfilesIfNeeded() error {
+	key := systemKey()
+	var storedKey string
+	if err := m.state.Get("system-key", &storedKey); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if storedKey == key {
+		return nil
+	}
+	for _, chg := range m.state.Changes() {
+		if chg.Kind() == "regenerate-profiles" && !chg.Status().Ready() {
+			// already in progress since a previous run
+			return nil
+		}
+	}
+
+	snapInfos, err := snapstate.ActiveInfos(m.state)
+	if err != nil {
+		return err
+	}
+	if len(snapInfos) == 0 {
+		m.state.Set("system-key", key)
+		return nil
+	}
+	sort.Sort(bySnapName(snapInfos))
+
+	// the snaps are handled one after the other to not load the system
+	// with many concurrent profile compilations
+	chg := m.state.NewChange("regenerate-profiles", i18n.G("Regenerate security profiles"))
+	var prev *state.Task
+	for _, snapInfo := range snapInfos {
+		summary := fmt.Sprintf(i18n.G("Regenerate security profiles of snap %q"), snapInfo.Name())
+		task := m.state.NewTask("regenerate-snap-profiles", summary)
+		task.Set("snap-name", snapInfo.Name())
+		if prev != nil {
+			task.WaitFor(prev)
+		}
+		chg.AddTask(task)
+		prev = task
+	}
+	update := m.state.NewTask("update-system-key", i18n.G("Remove stale security profiles and record the system key"))
+	update.WaitFor(prev)
+	chg.AddTask(update)
+	return nil
+}
+
+type bySnapName []*snap.Info
+
+func (infos bySnapName) Len() int           { return len(infos) }
+func (infos bySnapName) Swap(i, j int)      { infos[i], infos[j] = infos[j], infos[i] }
+func (infos bySnapName) Less(i, j int) bool { return infos[i].Name() < infos[j].Name() }
+
+func (m *InterfaceManager) doRegenerateSnapProfiles(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var snapName string
+	if err := task.Get("snap-name", &snapName); err != nil {
+		return err
+	}
+	var snapst snapstate.SnapState
+	err := snapstate.Get(st, snapName, &snapst)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !snapst.Active {
+		// removed or disabled in the meantime
+		task.Logf("snap %q is no longer active", snapName)
+		return nil
+	}
+	snapInfo, err := snapstate.Info(st, snapName, snapst.Current().Revision)
+	if err != nil {
+		return err
+	}
+	// the profiles may not have changed while what loads them did
+	return reloadSnapSecurity(task, snapInfo, m.repo)
+}
+
+func (m *InterfaceManager) doUpdateSystemKey(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapStates, err := snapstate.All(st)
+	if err != nil {
+		return err
+	}
+	st.Unlock()
+	err = unloadStaleProfiles(snapStates)
+	st.Lock()
+	if err != nil {
+		return err
+	}
+	st.Set("system-key", systemKey())
+	return nil
+}
+
+var (
+	apparmorLoadedProfiles = apparmor.LoadedProfiles
+	apparmorUnloadProfile  = apparmor.UnloadProfile
+)
+
+// unloadStaleProfiles unloads the apparmor profiles of snaps that are not
+// installed anymore. Nothing is done if apparmor is not available.
+func unloadStaleProfiles(installed map[string]*snapstate.SnapState) error {
+	profiles, err := apparmorLoadedProfiles()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot obtain the loaded apparmor profiles: %v", err)
+	}
+	for _, profile := range profiles {
+		// profiles of snaps are named after the security tags of their
+		// applications, snap.<snap>.<app>
+		parts := strings.SplitN(profile, ".", 3)
+		if len(parts) != 3 || installed[parts[1]] != nil {
+			continue
+		}
+		if err := apparmorUnloadProfile(profile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2016 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"os"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func (s *interfaceManagerSuite) TestSystemKeyDependsOnBackends(c *C) {
+	key := ifacestate.SystemKey()
+	c.Check(key, HasLen, 64)
+
+	restore := ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{s.secBackend, &interfaces.TestSecurityBackend{}})
+	defer restore()
+	c.Check(ifacestate.SystemKey(), Not(Equals), key)
+}
+
+func (s *interfaceManagerSuite) TestSystemKeyChangeRegeneratesProfiles(c *C) {
+	var unloaded []string
+	restore := ifacestate.MockAppArmorProfiles(func() ([]string, error) {
+		return []string{"snap.consumer.consumer", "snap.removed.app", "snap.removed.hook.configure"}, nil
+	}, func(name string) error {
+		unloaded = append(unloaded, name)
+		return nil
+	})
+	defer restore()
+	s.mockSnap(c, producerYaml)
+	s.mockSnap(c, consumerYaml)
+	s.state.Lock()
+	s.state.Set("system-key", "old")
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	// a manager started again before the change is done doesn't queue another
+	_, err := ifacestate.Manager(s.state, nil)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	change := s.state.Changes()[0]
+	c.Check(change.Kind(), Equals, "regenerate-profiles")
+	c.Check(change.Tasks(), HasLen, 3)
+	s.state.Unlock()
+
+	// the snaps are regenerated one after the other, then the system key
+	// is recorded
+	for i := 0; i < 3; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+	c.Assert(s.secBackend.SetupCalls, HasLen, 2)
+	c.Check(s.secBackend.SetupCalls[0].SnapInfo.Name(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[0].Reload, Equals, true)
+	c.Check(s.secBackend.SetupCalls[1].SnapInfo.Name(), Equals, "producer")
+	c.Check(s.secBackend.SetupCalls[1].Reload, Equals, true)
+	c.Check(unloaded, DeepEquals, []string{"snap.removed.app", "snap.removed.hook.configure"})
+	var key string
+	c.Assert(s.state.Get("system-key", &key), IsNil)
+	c.Check(key, Equals, ifacestate.SystemKey())
+}
+
+func (s *interfaceManagerSuite) TestSystemKeyChangeReloadsUnchangedAppArmorProfiles(c *C) {
+	restore := ifacestate.MockAppArmorProfiles(func() ([]string, error) {
+		return []string{"snap.app.app"}, nil
+	}, func(name string) error {
+		c.Fatalf("unexpected unload of %q", name)
+		return nil
+	})
+	defer restore()
+	backend := &apparmor.Backend{}
+	restore = ifacestate.MockSecurityBackends([]interfaces.SecurityBackend{backend})
+	defer restore()
+	parserCmd := testutil.MockCommand(c, "apparmor_parser", `
+if [ "$1" = --version ]; then
+	echo "AppArmor parser version 2.10.95"
+fi
+for arg; do
+	case "$arg" in
+		--ofile=*) echo fake > "${arg#--ofile=}";;
+	esac
+done
+`)
+	defer parserCmd.Restore()
+
+	// the profiles were set up already and don't change with the new key
+	snapInfo := s.mockSnap(c, `
+name: app
+version: 1
+apps:
+ app:
+`)
+	c.Assert(backend.Setup(snapInfo, false, interfaces.NewRepository()), IsNil)
+	parserCmd.ForgetCalls()
+	s.state.Lock()
+	s.state.Set("system-key", "old")
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	for i := 0; i < 2; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	c.Assert(s.state.Changes()[0].Status(), Equals, state.DoneStatus)
+	// the unchanged profile was loaded again, for the new kernel or parser
+	calls := parserCmd.Calls()
+	c.Assert(calls, HasLen, 2)
+	c.Check(calls[0], Equals, "--version")
+	c.Check(calls[1], Matches, "--replace --binary .*/snap.app.app.[0-9a-f]+")
+}
+
+func (s *interfaceManagerSuite) TestSystemKeyUnchanged(c *C) {
+	s.mockSnap(c, consumerYaml)
+	s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestSystemKeyRecordedWithoutSnaps(c *C) {
+	s.state.Lock()
+	s.state.Set("system-key", nil)
+	s.state.Unlock()
+	s.manager(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(s.state.Changes(), HasLen, 0)
+	var key string
+	c.Assert(s.state.Get("system-key", &key), IsNil)
+	c.Check(key, Equals, ifacestate.SystemKey())
+}
+
+func (s *interfaceManagerSuite) TestRegenerateProfilesWithoutAppArmor(c *C) {
+	restore := ifacestate.MockAppArmorProfiles(func() ([]string, error) {
+		return nil, &os.PathError{Op: "open", Path: "/sys/kernel/security/apparmor/profiles", Err: os.ErrNotExist}
+	}, func(name string) error {
+		c.Fatalf("unexpected unload of %q", name)
+		return nil
+	})
+	defer restore()
+	s.mockSnap(c, consumerYaml)
+	s.state.Lock()
+	s.state.Set("system-key", "old")
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	for i := 0; i < 2; i++ {
+		mgr.Ensure()
+		mgr.Wait()
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(s.state.Changes(), HasLen, 1)
+	c.Check(s.state.Changes()[0].Status(), Equals, state.DoneStatus)
+}
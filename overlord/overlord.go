@@ -29,9 +29,11 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -54,10 +56,13 @@ type Overlord struct {
 	ensureTimer *time.Timer
 	ensureNext  time.Time
 	pruneTimer  *time.Timer
+	// restarts
+	restartHandler func()
 	// managers
 	snapMgr   *snapstate.SnapManager
 	assertMgr *assertstate.AssertManager
 	ifaceMgr  *ifacestate.InterfaceManager
+	deviceMgr *devicestate.DeviceManager
 }
 
 // New creates a new Overlord with all its state managers.
@@ -67,8 +72,9 @@ func New() (*Overlord, error) {
 	}
 
 	backend := &overlordStateBackend{
-		path:         dirs.SnapStateFile,
-		ensureBefore: o.ensureBefore,
+		path:           dirs.SnapStateFile,
+		ensureBefore:   o.ensureBefore,
+		requestRestart: o.requestRestart,
 	}
 	s, err := loadState(backend)
 	if err != nil {
@@ -77,19 +83,20 @@ func New() (*Overlord, error) {
 
 	o.stateEng = NewStateEngine(s)
 
-	snapMgr, err := snapstate.Manager(s)
+	// the assertion database needs to be available to the other managers
+	assertMgr, err := assertstate.Manager(s)
 	if err != nil {
 		return nil, err
 	}
-	o.snapMgr = snapMgr
-	o.stateEng.AddManager(o.snapMgr)
+	o.assertMgr = assertMgr
+	o.stateEng.AddManager(o.assertMgr)
 
-	assertMgr, err := assertstate.Manager(s)
+	snapMgr, err := snapstate.Manager(s)
 	if err != nil {
 		return nil, err
 	}
-	o.assertMgr = assertMgr
-	o.stateEng.AddManager(o.assertMgr)
+	o.snapMgr = snapMgr
+	o.stateEng.AddManager(o.snapMgr)
 
 	ifaceMgr, err := ifacestate.Manager(s, nil)
 	if err != nil {
@@ -98,6 +105,13 @@ func New() (*Overlord, error) {
 	o.ifaceMgr = ifaceMgr
 	o.stateEng.AddManager(o.ifaceMgr)
 
+	deviceMgr, err := devicestate.Manager(s)
+	if err != nil {
+		return nil, err
+	}
+	o.deviceMgr = deviceMgr
+	o.stateEng.AddManager(o.deviceMgr)
+
 	return o, nil
 }
 
@@ -146,6 +160,19 @@ func (o *Overlord) ensureBefore(d time.Duration) {
 	}
 }
 
+func (o *Overlord) requestRestart() {
+	if o.restartHandler == nil {
+		logger.Noticef("restart requested but no handler set")
+		return
+	}
+	o.restartHandler()
+}
+
+// SetRestartHandler sets a handler to fulfill restart requests asynchronously.
+func (o *Overlord) SetRestartHandler(handleRestart func()) {
+	o.restartHandler = handleRestart
+}
+
 // Loop runs a loop in a goroutine to ensure the current state regularly through StateEngine Ensure.
 func (o *Overlord) Loop() {
 	o.ensureTimerSetup()
@@ -243,3 +270,9 @@ func (o *Overlord) AssertManager() *assertstate.AssertManager {
 func (o *Overlord) InterfaceManager() *ifacestate.InterfaceManager {
 	return o.ifaceMgr
 }
+
+// DeviceManager returns the device manager responsible for the device
+// identity and policies.
+func (o *Overlord) DeviceManager() *devicestate.DeviceManager {
+	return o.deviceMgr
+}
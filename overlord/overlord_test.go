@@ -23,6 +23,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -61,6 +62,7 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	c.Check(o.SnapManager(), NotNil)
 	c.Check(o.AssertManager(), NotNil)
 	c.Check(o.InterfaceManager(), NotNil)
+	c.Check(o.DeviceManager(), NotNil)
 
 	s := o.State()
 	c.Check(s, NotNil)
@@ -82,7 +84,12 @@ func (ovs *overlordSuite) TestNewWithGoodState(c *C) {
 
 	d, err := state.MarshalJSON()
 	c.Assert(err, IsNil)
-	c.Assert(string(d), DeepEquals, string(fakeState))
+	// the interface manager records the system key of a system without snaps
+	var key string
+	c.Assert(state.Get("system-key", &key), IsNil)
+	c.Check(key, HasLen, 64)
+	expected := strings.Replace(string(fakeState), `"some":"data"`, `"some":"data","system-key":"`+key+`"`, 1)
+	c.Assert(string(d), DeepEquals, expected)
 }
 
 func (ovs *overlordSuite) TestNewWithInvalidState(c *C) {
@@ -293,6 +300,34 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	c.Check(string(content), testutil.Contains, `"mark":1`)
 }
 
+func (ovs *overlordSuite) TestRequestRestartHandler(c *C) {
+	o, err := overlord.New()
+	c.Assert(err, IsNil)
+
+	restartRequested := false
+	o.SetRestartHandler(func() {
+		restartRequested = true
+	})
+
+	s := o.State()
+	s.Lock()
+	defer s.Unlock()
+	s.RequestRestart()
+
+	c.Check(restartRequested, Equals, true)
+}
+
+func (ovs *overlordSuite) TestRequestRestartNoHandler(c *C) {
+	o, err := overlord.New()
+	c.Assert(err, IsNil)
+
+	s := o.State()
+	s.Lock()
+	defer s.Unlock()
+	// does not blow up
+	s.RequestRestart()
+}
+
 type runnerManager struct {
 	runner         *state.TaskRunner
 	ensureCallback func()
@@ -20,18 +20,21 @@
 package snapstate
 
 import (
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
 )
 
-// A StoreService can find, list available updates and offer for download snaps.
+// A StoreService can find, list available updates and offer for download snaps,
+// and retrieve assertions.
 type StoreService interface {
 	Snap(string, string, store.Authenticator) (*snap.Info, error)
 	Find(string, string, store.Authenticator) ([]*snap.Info, error)
 	ListRefresh([]*store.RefreshCandidate, store.Authenticator) ([]*snap.Info, error)
 	SuggestedCurrency() string
+	Assertion(*asserts.AssertionType, []string, store.Authenticator) (asserts.Assertion, error)
 
 	Download(*snap.Info, progress.Meter, store.Authenticator) (string, error)
 }
@@ -22,7 +22,10 @@ package snapstate_test
 import (
 	"errors"
 	"strings"
+	"time"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -44,6 +47,39 @@ type fakeOp struct {
 	old string
 }
 
+// fakeStore serves the assertions refreshes are validated with, signed
+// on demand by the store key.
+type fakeStore struct {
+	snapstate.StoreService
+	storeSigning *assertstest.SigningDB
+}
+
+func (f *fakeStore) Assertion(assertType *asserts.AssertionType, primaryKey []string, _ store.Authenticator) (asserts.Assertion, error) {
+	now := time.Now().Format(time.RFC3339)
+	switch assertType {
+	case asserts.AccountType:
+		return f.storeSigning.Sign(asserts.AccountType, map[string]string{
+			"account-id":   primaryKey[0],
+			"display-name": primaryKey[0],
+			"validation":   "certified",
+			"timestamp":    now,
+		}, nil)
+	case asserts.SnapDeclarationType:
+		if primaryKey[1] != "snapIDsnapidsnapidsnapidsnapidsn" {
+			return nil, asserts.ErrNotFound
+		}
+		return f.storeSigning.Sign(asserts.SnapDeclarationType, map[string]string{
+			"series":       primaryKey[0],
+			"snap-id":      primaryKey[1],
+			"snap-name":    "some-snap",
+			"publisher-id": "devdevdev",
+			"gates":        "",
+			"timestamp":    now,
+		}, nil)
+	}
+	return nil, asserts.ErrNotFound
+}
+
 type fakeSnappyBackend struct {
 	ops []fakeOp
 
@@ -122,8 +158,11 @@ func (f *fakeSnappyBackend) SetupSnap(snapFilePath string, si *snap.SideInfo, p
 func (f *fakeSnappyBackend) ReadInfo(name string, si *snap.SideInfo) (*snap.Info, error) {
 	// naive emulation for now, always works
 	info := &snap.Info{SuggestedName: name, SideInfo: *si}
-	if name == "gadget" {
+	switch name {
+	case "gadget":
 		info.Type = snap.TypeGadget
+	case "ubuntu-core":
+		info.Type = snap.TypeOS
 	}
 	return info, nil
 }
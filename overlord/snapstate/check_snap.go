@@ -24,6 +24,8 @@ import (
 	"strings"
 
 	"github.com/snapcore/snapd/arch"
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -52,6 +54,32 @@ func checkAssumes(s *snap.Info) error {
 
 var openSnapFile = backend.OpenSnapFile
 
+// checkModelSnap ensures that a kernel or gadget snap is the one
+// named by the device model, if known.
+func checkModelSnap(st *state.State, s *snap.Info) error {
+	model, err := devicestate.Model(st)
+	if err == state.ErrNoState || err == asserts.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var expected string
+	switch s.Type {
+	case snap.TypeKernel:
+		expected = model.Kernel()
+	case snap.TypeGadget:
+		expected = model.Gadget()
+	default:
+		return nil
+	}
+	if s.Name() != expected {
+		return fmt.Errorf("cannot install %s snap %q, the device model requires %q", s.Type, s.Name(), expected)
+	}
+	return nil
+}
+
 // checkSnap ensures that the snap can be installed.
 func checkSnap(state *state.State, snapFilePath string, curInfo *snap.Info, flags Flags) error {
 	// XXX: actually verify snap before using content from it unless dev-mode
@@ -72,12 +100,20 @@ func checkSnap(state *state.State, snapFilePath string, curInfo *snap.Info, flag
 		return err
 	}
 
-	if s.Type != snap.TypeGadget {
+	if s.Type != snap.TypeGadget && s.Type != snap.TypeKernel {
 		return nil
 	}
 	state.Lock()
 	defer state.Unlock()
 
+	if err := checkModelSnap(state, s); err != nil {
+		return err
+	}
+
+	if s.Type != snap.TypeGadget {
+		return nil
+	}
+
 	if currentGadget, err := GadgetInfo(state); err == nil {
 		// TODO: actually compare snap ids, from current gadget and candidate
 		if currentGadget.Name() == s.Name() {
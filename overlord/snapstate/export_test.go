@@ -34,6 +34,12 @@ func SetSnapManagerBackend(s *SnapManager, b ManagerBackend) {
 	s.backend = b
 }
 
+func SnapManagerStoreID(s *SnapManager) string {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	return s.storeID
+}
+
 type ForeignTaskTracker interface {
 	ForeignTask(kind string, status state.Status, ss *SnapSetup)
 }
@@ -77,9 +83,16 @@ func MockOpenSnapFile(mock func(path string, si *snap.SideInfo) (*snap.Info, sna
 	return func() { openSnapFile = prevOpenSnapFile }
 }
 
+func MockSnapdRunsFromCore(runsFromCore bool) (restore func()) {
+	prev := snapdRunsFromCore
+	snapdRunsFromCore = func() bool { return runsFromCore }
+	return func() { snapdRunsFromCore = prev }
+}
+
 var (
-	CheckSnap = checkSnap
-	CanRemove = canRemove
+	CheckSnap    = checkSnap
+	CanRemove    = canRemove
+	ModelStoreID = modelStoreID
 )
 
 // flagscompat
@@ -20,6 +20,8 @@
 package snapstate_test
 
 import (
+	"time"
+
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -27,11 +29,24 @@ import (
 	"github.com/snapcore/snapd/snap"
 )
 
+type witnessRestartBackend struct {
+	restartRequested bool
+}
+
+func (b *witnessRestartBackend) Checkpoint([]byte) error { return nil }
+
+func (b *witnessRestartBackend) EnsureBefore(time.Duration) {}
+
+func (b *witnessRestartBackend) RequestRestart() {
+	b.restartRequested = true
+}
+
 type linkSnapSuite struct {
 	state   *state.State
 	snapmgr *snapstate.SnapManager
 
-	fakeBackend *fakeSnappyBackend
+	stateBackend *witnessRestartBackend
+	fakeBackend  *fakeSnappyBackend
 
 	reset func()
 }
@@ -39,8 +54,9 @@ type linkSnapSuite struct {
 var _ = Suite(&linkSnapSuite{})
 
 func (s *linkSnapSuite) SetUpTest(c *C) {
+	s.stateBackend = &witnessRestartBackend{}
 	s.fakeBackend = &fakeSnappyBackend{}
-	s.state = state.New(nil)
+	s.state = state.New(s.stateBackend)
 
 	var err error
 	s.snapmgr, err = snapstate.Manager(s.state)
@@ -86,6 +102,64 @@ func (s *linkSnapSuite) TestDoLinkSnapSuccess(c *C) {
 	c.Check(snapst.Candidate, IsNil)
 	c.Check(snapst.Channel, Equals, "beta")
 	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(s.stateBackend.restartRequested, Equals, false)
+}
+
+func (s *linkSnapSuite) TestDoLinkSnapSuccessCoreRestarts(c *C) {
+	restore := snapstate.MockSnapdRunsFromCore(true)
+	defer restore()
+	s.state.Lock()
+	snapstate.Set(s.state, "ubuntu-core", &snapstate.SnapState{
+		Candidate: &snap.SideInfo{
+			OfficialName: "ubuntu-core",
+			Revision:     snap.R(33),
+		},
+	})
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		Name: "ubuntu-core",
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+
+	s.state.Unlock()
+
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(s.stateBackend.restartRequested, Equals, true)
+}
+
+func (s *linkSnapSuite) TestDoLinkSnapSuccessCoreNoRestartOnClassic(c *C) {
+	// snapd comes from the distribution
+	restore := snapstate.MockSnapdRunsFromCore(false)
+	defer restore()
+	s.state.Lock()
+	snapstate.Set(s.state, "ubuntu-core", &snapstate.SnapState{
+		Candidate: &snap.SideInfo{
+			OfficialName: "ubuntu-core",
+			Revision:     snap.R(33),
+		},
+	})
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		Name: "ubuntu-core",
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+
+	s.state.Unlock()
+
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(s.stateBackend.restartRequested, Equals, false)
 }
 
 func (s *linkSnapSuite) TestDoUndoLinkSnap(c *C) {
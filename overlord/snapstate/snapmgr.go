@@ -23,11 +23,17 @@ package snapstate
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
@@ -37,7 +43,13 @@ import (
 type SnapManager struct {
 	state   *state.State
 	backend managerBackend
+
+	storeMu sync.Mutex
 	store   StoreService
+	// storeID is the store ID the default store was made for,
+	// replacedStore is set once the default store was replaced
+	storeID       string
+	replacedStore bool
 
 	runner *state.TaskRunner
 }
@@ -77,6 +89,10 @@ type SnapSetup struct {
 	Flags SnapSetupFlags `json:"flags,omitempty"`
 
 	SnapPath string `json:"snap-path,omitempty"`
+
+	// SideInfo is set for snap files whose store identity was
+	// verified with assertions before installing them.
+	SideInfo *snap.SideInfo `json:"side-info,omitempty"`
 }
 
 func (ss *SnapSetup) placeInfo() snap.PlaceInfo {
@@ -149,26 +165,61 @@ func (snapst *SnapState) SetTryMode(active bool) {
 	}
 }
 
+// modelStoreID returns the store ID to use, the one of the device model
+// if known, otherwise the one given through UBUNTU_STORE_ID.
+func modelStoreID(s *state.State) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	model, err := devicestate.Model(s)
+	if err == state.ErrNoState || err == asserts.ErrNotFound {
+		return os.Getenv("UBUNTU_STORE_ID"), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return model.Store(), nil
+}
+
+// ensureStoreID makes the default store follow the store ID of the device
+// model, which may only be identified after the manager was made.
+func (m *SnapManager) ensureStoreID() error {
+	m.storeMu.Lock()
+	replaced := m.replacedStore
+	m.storeMu.Unlock()
+	if replaced {
+		return nil
+	}
+
+	storeID, err := modelStoreID(m.state)
+	if err != nil {
+		return err
+	}
+
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	if !m.replacedStore && (m.store == nil || storeID != m.storeID) {
+		m.store = store.NewUbuntuStoreSnapRepository(nil, storeID)
+		m.storeID = storeID
+	}
+	return nil
+}
+
 // Manager returns a new snap manager.
 func Manager(s *state.State) (*SnapManager, error) {
 	runner := state.NewTaskRunner(s)
 	backend := &defaultBackend{}
 
-	storeID := ""
-	// TODO: set the store-id here from the model information
-	if cand := os.Getenv("UBUNTU_STORE_ID"); cand != "" {
-		storeID = cand
-	}
-	store := store.NewUbuntuStoreSnapRepository(nil, storeID)
-	// TODO: if needed we could also put the store on the state using
-	// the Cache mechanism and an accessor function
-
 	m := &SnapManager{
 		state:   s,
 		backend: backend,
-		store:   store,
 		runner:  runner,
 	}
+	// TODO: if needed we could also put the store on the state using
+	// the Cache mechanism and an accessor function
+	if err := m.ensureStoreID(); err != nil {
+		return nil, err
+	}
 
 	// this handler does nothing
 	runner.AddHandler("nop", func(t *state.Task, _ *tomb.Tomb) error {
@@ -203,12 +254,17 @@ func Manager(s *state.State) (*SnapManager, error) {
 
 // Store returns the store service used by the manager.
 func (m *SnapManager) Store() StoreService {
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
 	return m.store
 }
 
 // ReplaceStore replaces the store used by manager.
 func (m *SnapManager) ReplaceStore(store StoreService) {
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
 	m.store = store
+	m.replacedStore = true
 }
 
 func checkRevisionIsNew(name string, snapst *SnapState, revision snap.Revision) error {
@@ -250,9 +306,14 @@ func (m *SnapManager) doPrepareSnap(t *state.Task, _ *tomb.Tomb) error {
 		}
 	}
 
+	candidate := &snap.SideInfo{Revision: ss.Revision}
+	if ss.SideInfo != nil {
+		candidate = ss.SideInfo
+	}
+
 	st.Lock()
 	t.Set("snap-setup", ss)
-	snapst.Candidate = &snap.SideInfo{Revision: ss.Revision}
+	snapst.Candidate = candidate
 	Set(st, ss.Name, snapst)
 	st.Unlock()
 	return nil
@@ -281,10 +342,6 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	checker := func(info *snap.Info) error {
-		return checkRevisionIsNew(ss.Name, snapst, info.Revision)
-	}
-
 	pb := &TaskProgressAdapter{task: t}
 
 	var auther store.Authenticator
@@ -298,7 +355,25 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
 		auther = user.Authenticator()
 	}
 
-	storeInfo, downloadedSnapFile, err := m.backend.Download(ss.Name, ss.Channel, checker, pb, m.store, auther)
+	theStore := m.Store()
+	checker := func(info *snap.Info) error {
+		if err := checkRevisionIsNew(ss.Name, snapst, info.Revision); err != nil {
+			return err
+		}
+		if snapst.Current() == nil {
+			// only refreshes are gated
+			return nil
+		}
+		retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+			return theStore.Assertion(ref.Type, ref.PrimaryKey, auther)
+		}
+		st.Lock()
+		defer st.Unlock()
+		_, err := ValidateRefreshes(st, []*snap.Info{info}, retrieve)
+		return err
+	}
+
+	storeInfo, downloadedSnapFile, err := m.backend.Download(ss.Name, ss.Channel, checker, pb, theStore, auther)
 	if err != nil {
 		return err
 	}
@@ -417,8 +492,9 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 
 // Ensure implements StateManager.Ensure.
 func (m *SnapManager) Ensure() error {
+	err := m.ensureStoreID()
 	m.runner.Ensure()
-	return nil
+	return err
 }
 
 // Wait implements StateManager.Wait.
@@ -679,9 +755,26 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	Set(st, ss.Name, snapst)
 	// Make sure if state commits and snapst is mutated we won't be rerun
 	t.SetStatus(state.DoneStatus)
+
+	// restart snapd to run the new one when it comes from the OS snap,
+	// and not from the distribution as on classic
+	if newInfo.Type == snap.TypeOS && snapdRunsFromCore() {
+		st.RequestRestart()
+	}
+
 	return nil
 }
 
+// snapdRunsFromCore tells whether the running snapd is the one of the
+// OS snap.
+var snapdRunsFromCore = func() bool {
+	exe, err := os.Readlink("/proc/self/exe")
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(exe, dirs.SnapSnapsDir+string(filepath.Separator))
+}
+
 func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 
@@ -23,11 +23,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/snapstate/backend"
@@ -44,6 +49,9 @@ type snapmgrTestSuite struct {
 
 	fakeBackend *fakeSnappyBackend
 
+	storeSigning *assertstest.SigningDB
+	db           *asserts.Database
+
 	user *auth.UserState
 
 	reset func()
@@ -81,7 +89,19 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 		restore1()
 	}
 
+	rootPrivKey := assertstest.GenerateKey(752)
+	s.storeSigning = assertstest.NewSigningDB("canonical", rootPrivKey)
+	s.db, err = asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:      asserts.NewMemoryBackstore(),
+		KeypairManager: asserts.NewMemoryKeypairManager(),
+		TrustedKeys:    []*asserts.AccountKey{assertstest.NewAccountKey(s.storeSigning, "canonical", rootPrivKey.PublicKey())},
+	})
+	c.Assert(err, IsNil)
+
+	s.snapmgr.ReplaceStore(&fakeStore{storeSigning: s.storeSigning})
+
 	s.state.Lock()
+	assertstate.ReplaceDB(s.state, s.db)
 	s.user, err = auth.NewUser(s.state, "username", "macaroon", []string{"discharge"})
 	c.Assert(err, IsNil)
 	s.state.Unlock()
@@ -783,6 +803,159 @@ func (s *snapmgrTestSuite) TestUpdateSameRevisionRunThrough(c *C) {
 	})
 }
 
+// mockGatingSnap installs a "gating-snap" whose declaration gates the
+// snap the fake backend downloads, with the given validations of its
+// revisions, by revision.
+func (s *snapmgrTestSuite) mockGatingSnap(c *C, validations map[int]bool) {
+	const gatedSnapID = "snapIDsnapidsnapidsnapidsnapidsn"
+
+	devPrivKey := assertstest.GenerateKey(752)
+	devSigning := assertstest.NewSigningDB("gating-dev", devPrivKey)
+	c.Assert(s.db.Add(assertstest.NewAccountKey(s.storeSigning, "gating-dev", devPrivKey.PublicKey())), IsNil)
+
+	now := time.Now().Format(time.RFC3339)
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]string{
+		"series":       "16",
+		"snap-id":      "gating-snap-id",
+		"snap-name":    "gating-snap",
+		"publisher-id": "gating-dev",
+		"gates":        gatedSnapID,
+		"timestamp":    now,
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(snapDecl), IsNil)
+
+	for rev, revoked := range validations {
+		validation, err := devSigning.Sign(asserts.ValidationType, map[string]string{
+			"series":                 "16",
+			"snap-id":                "gating-snap-id",
+			"approved-snap-id":       gatedSnapID,
+			"approved-snap-revision": strconv.Itoa(rev),
+			"revoked":                strconv.FormatBool(revoked),
+			"timestamp":              now,
+		}, nil)
+		c.Assert(err, IsNil)
+		c.Assert(s.db.Add(validation), IsNil)
+	}
+
+	snapstate.Set(s.state, "gating-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "gating-snap",
+			SnapID:       "gating-snap-id",
+			Revision:     snap.R(1),
+		}},
+	})
+}
+
+func (s *snapmgrTestSuite) testUpdateGated(c *C, validations map[int]bool) *state.Change {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockGatingSnap(c, validations)
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "some-snap",
+			SnapID:       "snapIDsnapidsnapidsnapidsnapidsn",
+			Revision:     snap.R(5),
+		}},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "channel-for-7", s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	return chg
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefusedWithoutValidation(c *C) {
+	chg := s.testUpdateGated(c, nil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 7: no validation by "gating-snap".*`)
+	c.Check(s.fakeBackend.ops, HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefusedWithRevokedValidation(c *C) {
+	chg := s.testUpdateGated(c, map[int]bool{7: true})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot refresh "some-snap" to revision 7: validation by "gating-snap" was revoked.*`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateRefusedWithoutSnapDeclaration(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the store knows nothing about this installed snap
+	snapstate.Set(s.state, "unknown-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "unknown-snap",
+			SnapID:       "unknown-snap-id",
+			Revision:     snap.R(1),
+		}},
+	})
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{{
+			OfficialName: "some-snap",
+			SnapID:       "snapIDsnapidsnapidsnapidsnapidsn",
+			Revision:     snap.R(5),
+		}},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "channel-for-7", s.user.ID, 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot validate refreshes: cannot find snap-declaration \(16; unknown-snap-id\): assertion not found.*`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateValidatedFetchesSnapDeclarations(c *C) {
+	chg := s.testUpdateGated(c, map[int]bool{7: false})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), IsNil)
+
+	// the snap-declaration of the refreshed snap was fetched
+	_, err := assertstate.SnapDeclaration(s.state, "snapIDsnapidsnapidsnapidsnapidsn")
+	c.Check(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateValidated(c *C) {
+	chg := s.testUpdateGated(c, map[int]bool{7: false, 11: true})
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	var snapst snapstate.SnapState
+	err := snapstate.Get(s.state, "some-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.Current().Revision, Equals, snap.R(7))
+}
+
 func makeTestSnap(c *C, snapYamlContent string) (snapFilePath string) {
 	return snaptest.MakeTestSnapWithFiles(c, snapYamlContent, nil)
 }
@@ -846,6 +1019,62 @@ version: 1.0`)
 	c.Assert(snapst.LocalRevision, Equals, snap.R(-1))
 }
 
+func (s *snapmgrTestSuite) TestInstallVerifiedPathTasks(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si := &snap.SideInfo{OfficialName: "mock", SnapID: "mock-id", Revision: snap.R(7)}
+	ts, err := snapstate.InstallVerifiedPath(s.state, si, "/some/path.snap", "", 0)
+	c.Assert(err, IsNil)
+
+	var ss snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &ss)
+	c.Assert(err, IsNil)
+	c.Check(ss, DeepEquals, snapstate.SnapSetup{
+		Name:     "mock",
+		Revision: snap.R(7),
+		SnapPath: "/some/path.snap",
+		SideInfo: si,
+	})
+}
+
+func (s *snapmgrTestSuite) TestInstallVerifiedPathRunThrough(c *C) {
+	// use the real thing for this one
+	snapstate.MockOpenSnapFile(backend.OpenSnapFile)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	mockSnap := makeTestSnap(c, `name: mock
+version: 1.0`)
+	si := &snap.SideInfo{OfficialName: "mock", SnapID: "mock-id", Revision: snap.R(7), Developer: "acme"}
+	chg := s.state.NewChange("install", "install a verified local snap")
+	ts, err := snapstate.InstallVerifiedPath(s.state, si, mockSnap, "", 0)
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle()
+	s.state.Lock()
+
+	c.Assert(s.fakeBackend.ops, HasLen, 6)
+	c.Check(s.fakeBackend.ops[1].op, Equals, "setup-snap")
+	c.Check(s.fakeBackend.ops[1].revno, Equals, snap.R(7))
+	c.Check(s.fakeBackend.ops[4].op, Equals, "candidate")
+	c.Check(s.fakeBackend.ops[4].sinfo, DeepEquals, *si)
+	c.Check(s.fakeBackend.ops[5].op, Equals, "link-snap")
+	c.Check(s.fakeBackend.ops[5].name, Equals, "/snap/mock/7")
+
+	// no local revision was used up
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "mock", &snapst)
+	c.Assert(err, IsNil)
+	c.Assert(snapst.Active, Equals, true)
+	c.Assert(snapst.Sequence, DeepEquals, []*snap.SideInfo{si})
+	c.Assert(snapst.LocalRevision.Unset(), Equals, true)
+}
+
 func (s *snapmgrTestSuite) TestInstallSubsequentLocalRunThrough(c *C) {
 	// use the real thing for this one
 	snapstate.MockOpenSnapFile(backend.OpenSnapFile)
@@ -1185,6 +1414,158 @@ func (s *snapmgrTestSuite) TestRemoveRefused(c *C) {
 	c.Check(err, ErrorMatches, `snap "gadget" is not removable`)
 }
 
+func (s *snapmgrTestSuite) setupModel(c *C) {
+	brandPrivKey := assertstest.GenerateKey(752)
+	brandSigning := assertstest.NewSigningDB("my-brand", brandPrivKey)
+	c.Assert(s.db.Add(assertstest.NewAccountKey(s.storeSigning, "my-brand", brandPrivKey.PublicKey())), IsNil)
+
+	model, err := brandSigning.Sign(asserts.ModelType, map[string]string{
+		"series":         "16",
+		"brand-id":       "my-brand",
+		"model":          "my-model",
+		"os":             "core",
+		"architecture":   "amd64",
+		"gadget":         "my-gadget",
+		"kernel":         "my-kernel",
+		"store":          "my-brand-store",
+		"class":          "fixed",
+		"allowed-modes":  "",
+		"required-snaps": "some-snap, other-snap",
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(s.db.Add(model), IsNil)
+
+	err = auth.SetDevice(s.state, &auth.DeviceState{Brand: "my-brand", Model: "my-model"})
+	c.Assert(err, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestModelStoreID(c *C) {
+	os.Setenv("UBUNTU_STORE_ID", "env-store")
+	defer os.Unsetenv("UBUNTU_STORE_ID")
+
+	storeID, err := snapstate.ModelStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "env-store")
+
+	s.state.Lock()
+	s.setupModel(c)
+	s.state.Unlock()
+
+	storeID, err = snapstate.ModelStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "my-brand-store")
+}
+
+func (s *snapmgrTestSuite) TestModelStoreIDMissingModelAssertion(c *C) {
+	os.Setenv("UBUNTU_STORE_ID", "env-store")
+	defer os.Unsetenv("UBUNTU_STORE_ID")
+
+	s.state.Lock()
+	err := auth.SetDevice(s.state, &auth.DeviceState{Brand: "my-brand", Model: "my-model"})
+	s.state.Unlock()
+	c.Assert(err, IsNil)
+
+	storeID, err := snapstate.ModelStoreID(s.state)
+	c.Assert(err, IsNil)
+	c.Check(storeID, Equals, "env-store")
+
+	// the manager can still be made
+	_, err = snapstate.Manager(s.state)
+	c.Assert(err, IsNil)
+
+	// and nothing is required by the unknown model
+	s.state.Lock()
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{OfficialName: "some-snap", Revision: snap.R(7)}},
+	})
+	_, err = snapstate.Remove(s.state, "some-snap")
+	s.state.Unlock()
+	c.Check(err, IsNil)
+
+	info, err := snap.InfoFromSnapYaml([]byte("name: other-kernel\ntype: kernel\nversion: 1\n"))
+	c.Assert(err, IsNil)
+	restore := snapstate.MockOpenSnapFile(func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+		return info, nil, nil
+	})
+	defer restore()
+	c.Check(snapstate.CheckSnap(s.state, "snap-path", nil, 0), IsNil)
+}
+
+func (s *snapmgrTestSuite) TestStoreFollowsModel(c *C) {
+	os.Setenv("UBUNTU_STORE_ID", "env-store")
+	defer os.Unsetenv("UBUNTU_STORE_ID")
+
+	mgr, err := snapstate.Manager(s.state)
+	c.Assert(err, IsNil)
+	c.Check(snapstate.SnapManagerStoreID(mgr), Equals, "env-store")
+	defaultStore := mgr.Store()
+
+	// nothing changes until the model is known
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Store(), Equals, defaultStore)
+
+	s.state.Lock()
+	s.setupModel(c)
+	s.state.Unlock()
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(snapstate.SnapManagerStoreID(mgr), Equals, "my-brand-store")
+	c.Check(mgr.Store(), NotNil)
+	c.Check(mgr.Store(), Not(Equals), defaultStore)
+
+	// a replaced store is kept
+	mgr.ReplaceStore(nil)
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mgr.Store(), IsNil)
+}
+
+func (s *snapmgrTestSuite) TestRemoveRequiredByModel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupModel(c)
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{OfficialName: "some-snap", Revision: snap.R(7)}},
+	})
+
+	_, err := snapstate.Remove(s.state, "some-snap")
+	c.Check(err, ErrorMatches, `snap "some-snap" is required by the device model and cannot be removed`)
+}
+
+func (s *snapmgrTestSuite) TestCheckSnapKernelAndGadgetFromModel(c *C) {
+	s.state.Lock()
+	s.setupModel(c)
+	s.state.Unlock()
+
+	tests := []struct {
+		yaml string
+		err  string
+	}{
+		{"name: my-kernel\ntype: kernel\nversion: 1\n", ""},
+		{"name: other-kernel\ntype: kernel\nversion: 1\n", `cannot install kernel snap "other-kernel", the device model requires "my-kernel"`},
+		{"name: other-gadget\ntype: gadget\nversion: 1\n", `cannot install gadget snap "other-gadget", the device model requires "my-gadget"`},
+		{"name: other-app\nversion: 1\n", ""},
+	}
+
+	for _, test := range tests {
+		info, err := snap.InfoFromSnapYaml([]byte(test.yaml))
+		c.Assert(err, IsNil)
+		restore := snapstate.MockOpenSnapFile(func(path string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
+			return info, nil, nil
+		})
+		err = snapstate.CheckSnap(s.state, "snap-path", nil, 0)
+		restore()
+		if test.err == "" {
+			c.Check(err, IsNil, Commentf(test.yaml))
+		} else {
+			c.Check(err, ErrorMatches, test.err)
+		}
+	}
+}
+
 type snapmgrQuerySuite struct {
 	st *state.State
 }
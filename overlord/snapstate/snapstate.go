@@ -24,10 +24,14 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 )
 
 // Flags are used to pass additional flags to operations and to keep track of snap modes.
@@ -57,7 +61,7 @@ const (
 	// 0x40000000 >> iota
 )
 
-func doInstall(s *state.State, curActive bool, snapName, snapPath, channel string, userID int, flags Flags) (*state.TaskSet, error) {
+func doInstall(s *state.State, curActive bool, snapName, snapPath, channel string, userID int, flags Flags, si *snap.SideInfo) (*state.TaskSet, error) {
 	if err := checkChangeConflict(s, snapName); err != nil {
 		return nil, err
 	}
@@ -74,6 +78,10 @@ func doInstall(s *state.State, curActive bool, snapName, snapPath, channel strin
 	}
 	ss.Name = snapName
 	ss.SnapPath = snapPath
+	if si != nil {
+		ss.Revision = si.Revision
+		ss.SideInfo = si
+	}
 	if snapPath != "" {
 		prepare = s.NewTask("prepare-snap", fmt.Sprintf(i18n.G("Prepare snap %q"), snapPath))
 	} else {
@@ -148,7 +156,7 @@ func Install(s *state.State, name, channel string, userID int, flags Flags) (*st
 		return nil, fmt.Errorf("snap %q already installed", name)
 	}
 
-	return doInstall(s, false, name, "", channel, userID, flags)
+	return doInstall(s, false, name, "", channel, userID, flags, nil)
 }
 
 // InstallPath returns a set of tasks for installing snap from a file path.
@@ -160,7 +168,23 @@ func InstallPath(s *state.State, name, path, channel string, flags Flags) (*stat
 		return nil, err
 	}
 
-	return doInstall(s, snapst.Active, name, path, channel, 0, flags)
+	return doInstall(s, snapst.Active, name, path, channel, 0, flags, nil)
+}
+
+// InstallVerifiedPath returns a set of tasks for installing a snap from
+// a file path whose store identity, as given by si, was verified against
+// its assertions. The snap gets the store revision and snap id instead
+// of a local revision, so it can be refreshed from the store later.
+// Note that the state must be locked by the caller.
+func InstallVerifiedPath(s *state.State, si *snap.SideInfo, path, channel string, flags Flags) (*state.TaskSet, error) {
+	name := si.OfficialName
+	var snapst SnapState
+	err := Get(s, name, &snapst)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+
+	return doInstall(s, snapst.Active, name, path, channel, 0, flags, si)
 }
 
 // TryPath returns a set of tasks for trying a snap from a file path.
@@ -188,7 +212,25 @@ func Update(s *state.State, name, channel string, userID int, flags Flags) (*sta
 	}
 
 	// TODO: pass the right UserID
-	return doInstall(s, snapst.Active, name, "", channel, userID, flags)
+	return doInstall(s, snapst.Active, name, "", channel, userID, flags, nil)
+}
+
+// ValidateRefreshes checks the refresh candidates against the validations
+// by the installed snaps that gate them, as assertstate.ValidateRefreshes,
+// retrieving the missing snap-declarations with retrieve.
+// Note that the state must be locked by the caller.
+func ValidateRefreshes(s *state.State, candidates []*snap.Info, retrieve func(*asserts.Ref) (asserts.Assertion, error)) ([]*snap.Info, error) {
+	all, err := All(s)
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, snapst := range all {
+		if snapID := snapst.Current().SnapID; snapID != "" {
+			installed = append(installed, snapID)
+		}
+	}
+	return assertstate.ValidateRefreshes(s, candidates, installed, retrieve)
 }
 
 func removeInactiveRevision(s *state.State, name string, revision snap.Revision) *state.TaskSet {
@@ -225,6 +267,19 @@ func canRemove(s *snap.Info, active bool) bool {
 	return true
 }
 
+// requiredByModel returns whether the snap is listed among the
+// required snaps of the device model.
+func requiredByModel(s *state.State, name string) (bool, error) {
+	model, err := devicestate.Model(s)
+	if err == state.ErrNoState || err == asserts.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strutil.ListContains(model.RequiredSnaps(), name), nil
+}
+
 // Remove returns a set of tasks for removing snap.
 // Note that the state must be locked by the caller.
 func Remove(s *state.State, name string) (*state.TaskSet, error) {
@@ -256,6 +311,14 @@ func Remove(s *state.State, name string) (*state.TaskSet, error) {
 		return nil, fmt.Errorf("snap %q is not removable", name)
 	}
 
+	required, err := requiredByModel(s, name)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return nil, fmt.Errorf("snap %q is required by the device model and cannot be removed", name)
+	}
+
 	// main/current SnapSetup
 	ss := SnapSetup{
 		Name:     name,
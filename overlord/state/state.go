@@ -38,6 +38,8 @@ import (
 type Backend interface {
 	Checkpoint(data []byte) error
 	EnsureBefore(d time.Duration)
+	// RequestRestart asks for a restart of the managing process.
+	RequestRestart()
 }
 
 type customData map[string]*json.RawMessage
@@ -220,6 +222,14 @@ func (s *State) EnsureBefore(d time.Duration) {
 	}
 }
 
+// RequestRestart asks for a restart of the managing process, e.g. to
+// run a new snapd after ubuntu-core got refreshed.
+func (s *State) RequestRestart() {
+	if s.backend != nil {
+		s.backend.RequestRestart()
+	}
+}
+
 // ErrNoState represents the case of no state entry for a given key.
 var ErrNoState = errors.New("no state entry for key")
 
@@ -367,6 +377,7 @@ func (s *State) Prune(pruneWait, abortWait time.Duration) {
 // ReadState returns the state deserialized from r.
 func ReadState(backend Backend, r io.Reader) (*State, error) {
 	s := new(State)
+	s.cache = make(map[interface{}]interface{})
 	s.Lock()
 	defer s.unlock()
 	d := json.NewDecoder(r)
@@ -133,10 +133,22 @@ func (ss *stateSuite) TestCache(c *C) {
 	c.Assert(ok, Equals, false)
 }
 
+func (ss *stateSuite) TestCacheAfterReadState(c *C) {
+	st, err := state.ReadState(nil, bytes.NewBufferString("{}"))
+	c.Assert(err, IsNil)
+	st.Lock()
+	defer st.Unlock()
+
+	type key struct{}
+	st.Cache(key{}, "value")
+	c.Assert(st.Cached(key{}), Equals, "value")
+}
+
 type fakeStateBackend struct {
-	checkpoints  [][]byte
-	error        func() error
-	ensureBefore time.Duration
+	checkpoints      [][]byte
+	error            func() error
+	ensureBefore     time.Duration
+	restartRequested bool
 }
 
 func (b *fakeStateBackend) Checkpoint(data []byte) error {
@@ -151,6 +163,10 @@ func (b *fakeStateBackend) EnsureBefore(d time.Duration) {
 	b.ensureBefore = d
 }
 
+func (b *fakeStateBackend) RequestRestart() {
+	b.restartRequested = true
+}
+
 func (ss *stateSuite) TestImplicitCheckpointAndRead(c *C) {
 	b := new(fakeStateBackend)
 	st := state.New(b)
@@ -454,6 +470,15 @@ func (ss *stateSuite) TestEnsureBefore(c *C) {
 	c.Check(b.ensureBefore, Equals, 10*time.Second)
 }
 
+func (ss *stateSuite) TestRequestRestart(c *C) {
+	b := new(fakeStateBackend)
+	st := state.New(b)
+
+	st.RequestRestart()
+
+	c.Check(b.restartRequested, Equals, true)
+}
+
 func (ss *stateSuite) TestCheckpointPreserveLastIds(c *C) {
 	b := new(fakeStateBackend)
 	st := state.New(b)
@@ -42,6 +42,8 @@ type stateBackend struct {
 
 func (b *stateBackend) Checkpoint([]byte) error { return nil }
 
+func (b *stateBackend) RequestRestart() {}
+
 func (b *stateBackend) EnsureBefore(d time.Duration) {
 	b.mu.Lock()
 	if d < b.ensureBefore {
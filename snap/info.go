@@ -248,6 +248,13 @@ func (app *AppInfo) SecurityTag() string {
 	return fmt.Sprintf("snap.%s.%s", app.Snap.Name(), app.Name)
 }
 
+// DefaultProvider returns the name of the snap named by the
+// default-provider attribute of the plug, or the empty string.
+func (plug *PlugInfo) DefaultProvider() string {
+	provider, _ := plug.Attrs["default-provider"].(string)
+	return provider
+}
+
 // WrapperPath returns the path to wrapper invoking the app binary.
 func (app *AppInfo) WrapperPath() string {
 	var binName string
@@ -73,6 +73,19 @@ func (s *infoSuite) TestAppInfoSecurityTag(c *C) {
 	c.Check(appInfo.SecurityTag(), Equals, "snap.http.GET")
 }
 
+func (s *infoSuite) TestPlugInfoDefaultProvider(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
+plugs:
+   plugins:
+     interface: content
+     default-provider: plugin-host
+   network:
+`))
+	c.Assert(err, IsNil)
+	c.Check(info.Plugs["plugins"].DefaultProvider(), Equals, "plugin-host")
+	c.Check(info.Plugs["network"].DefaultProvider(), Equals, "")
+}
+
 func (s *infoSuite) TestAppInfoWrapperPath(c *C) {
 	info, err := snap.InfoFromSnapYaml([]byte(`name: foo
 apps:
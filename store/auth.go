@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
@@ -33,6 +34,8 @@ var (
 	ubuntuoneAPIBase       = authURL()
 	// UbuntuoneDischargeAPI points to SSO endpoint to discharge a macaroon
 	UbuntuoneDischargeAPI = ubuntuoneAPIBase + "/tokens/discharge"
+	// UbuntuoneKeysAPI points to SSO endpoint to look up the ssh keys of an account
+	UbuntuoneKeysAPI = ubuntuoneAPIBase + "/keys/"
 )
 
 // Authenticator interface to set required authorization headers for requests to the store
@@ -162,3 +165,51 @@ func DischargeAuthCaveat(username, password, macaroon, otp string) (string, erro
 	}
 	return responseData.Macaroon, nil
 }
+
+// UserInfo holds the details of an account as known to SSO.
+type UserInfo struct {
+	Username string
+	SSHKeys  []string
+}
+
+// FindUserInfo looks up the username and ssh keys of the account with the given email in SSO.
+func FindUserInfo(email string) (*UserInfo, error) {
+	const errorPrefix = "cannot get user details from store: "
+
+	req, err := http.NewRequest("GET", UbuntuoneKeysAPI+url.QueryEscape(email), nil)
+	if err != nil {
+		return nil, fmt.Errorf(errorPrefix+"%v", err)
+	}
+	req.Header.Set("accept", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf(errorPrefix+"%v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == 404:
+		return nil, fmt.Errorf(errorPrefix+"unknown email %q", email)
+	case !httpStatusCodeSuccess(resp.StatusCode):
+		return nil, fmt.Errorf(errorPrefix+"server returned status %d", resp.StatusCode)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	var responseData struct {
+		Username string   `json:"username"`
+		SSHKeys  []string `json:"ssh_keys"`
+	}
+	if err := dec.Decode(&responseData); err != nil {
+		return nil, fmt.Errorf(errorPrefix+"%v", err)
+	}
+
+	if responseData.Username == "" {
+		return nil, fmt.Errorf(errorPrefix + "empty username returned")
+	}
+	return &UserInfo{
+		Username: responseData.Username,
+		SSHKeys:  responseData.SSHKeys,
+	}, nil
+}
@@ -72,6 +72,14 @@ const mockStoreReturnDischarge = `
 
 const mockStoreReturnNoMacaroon = `{}`
 
+const mockStoreReturnUserInfo = `
+{
+    "username": "guy",
+    "ssh_keys": ["ssh-rsa AAAABcdefg"],
+    "openid": "oHOsfb"
+}
+`
+
 func (s *authTestSuite) TestRequestPackageAccessMacaroon(c *C) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		io.WriteString(w, mockStoreReturnMacaroon)
@@ -182,3 +190,43 @@ func (s *authTestSuite) TestDischargeAuthCaveatError(c *C) {
 	c.Assert(err, ErrorMatches, "cannot get discharge macaroon from store: server returned status 500")
 	c.Assert(discharge, Equals, "")
 }
+
+func (s *authTestSuite) TestFindUserInfo(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/keys/guy@example.com")
+		io.WriteString(w, mockStoreReturnUserInfo)
+	}))
+	defer mockServer.Close()
+	UbuntuoneKeysAPI = mockServer.URL + "/keys/"
+
+	userInfo, err := FindUserInfo("guy@example.com")
+	c.Assert(err, IsNil)
+	c.Check(userInfo, DeepEquals, &UserInfo{
+		Username: "guy",
+		SSHKeys:  []string{"ssh-rsa AAAABcdefg"},
+	})
+}
+
+func (s *authTestSuite) TestFindUserInfoUnknown(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	}))
+	defer mockServer.Close()
+	UbuntuoneKeysAPI = mockServer.URL + "/keys/"
+
+	userInfo, err := FindUserInfo("guy@example.com")
+	c.Assert(err, ErrorMatches, `cannot get user details from store: unknown email "guy@example.com"`)
+	c.Check(userInfo, IsNil)
+}
+
+func (s *authTestSuite) TestFindUserInfoError(c *C) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer mockServer.Close()
+	UbuntuoneKeysAPI = mockServer.URL + "/keys/"
+
+	userInfo, err := FindUserInfo("guy@example.com")
+	c.Assert(err, ErrorMatches, "cannot get user details from store: server returned status 500")
+	c.Check(userInfo, IsNil)
+}
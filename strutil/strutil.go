@@ -44,3 +44,14 @@ func MakeRandomString(length int) string {
 
 	return out
 }
+
+// ListContains determines whether the given string is contained in the
+// given list of strings.
+func ListContains(list []string, str string) bool {
+	for _, k := range list {
+		if k == str {
+			return true
+		}
+	}
+	return false
+}
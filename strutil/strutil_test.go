@@ -42,3 +42,20 @@ func (ts *MakeRandomStringTestSuite) TestMakeRandomString(c *C) {
 	s2 := MakeRandomString(5)
 	c.Assert(s2, Equals, "4PQyl")
 }
+
+type strutilSuite struct{}
+
+var _ = Suite(&strutilSuite{})
+
+func (ts *strutilSuite) TestListContains(c *C) {
+	for _, xs := range [][]string{
+		{},
+		nil,
+		{"foo"},
+		{"foo", "baz", "barbar"},
+	} {
+		c.Check(ListContains(xs, "bar"), Equals, false)
+	}
+
+	c.Check(ListContains([]string{"foo", "bar"}, "bar"), Equals, true)
+}